@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"qap_solver/internal/config"
+	"qap_solver/internal/experiment"
+	"qap_solver/internal/metrics"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runBundle implements the "bundle" subcommand: it runs an experiment with
+// the flags given after "--" and archives its results with everything needed
+// to replay it, see runReplay. The configuration file and environment are
+// resolved once and stored in the manifest, the experiment runs with them
+// alone, like its replay.
+func runBundle(args []string) {
+	fs := flag.NewFlagSet("bundle", flag.ExitOnError)
+	output := fs.String("output", "bundle.tar.gz", "Archive to write")
+	fs.Usage = func() {
+		logger.Printf("Usage: %s bundle [-output bundle.tar.gz] -- <experiment flags>", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	experimentArgs := fs.Args()
+
+	// A replay needs the seeds, so the experiment always gets one
+	seed, _ := strconv.ParseInt(flagValue(experimentArgs, "seed", "0"), 10, 64)
+	if seed == 0 {
+		seed = rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(1<<62) + 1
+		experimentArgs = append(experimentArgs, "-seed="+strconv.FormatInt(seed, 10))
+	}
+
+	defaults, err := config.Load()
+	if err != nil {
+		fatalf("Invalid configuration: %v", err)
+	}
+	settings := defaults.Settings()
+	var profiles []byte
+	if usesProfiles(experimentArgs, settings) {
+		profiles, err = os.ReadFile(flagValue(experimentArgs, "profiles", defaults.Profiles))
+		if err != nil && !os.IsNotExist(err) {
+			fatalf("Cannot read the profiles: %v", err)
+		}
+	}
+
+	resultsDir, err := os.MkdirTemp("", "qap_bundle")
+	if err != nil {
+		fatalf("Cannot create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(resultsDir)
+	if err := runChildExperiment(experimentArgs, settings, resultsDir); err != nil {
+		os.RemoveAll(resultsDir)
+		fatalf("Experiment failed: %v", err)
+	}
+
+	runs := loadExperimentRuns(resultsDir)
+	instanceDir := flagValue(experimentArgs, "instances", defaults.InstancesDir)
+	checksums, err := experiment.InstanceChecksums(instanceDir, instanceNames(runs))
+	if err != nil {
+		os.RemoveAll(resultsDir)
+		fatalf("Cannot checksum the instances: %v", err)
+	}
+
+	manifest := experiment.Manifest{
+		Created:   time.Now(),
+		Args:      experimentArgs,
+		Config:    settings,
+		Seed:      seed,
+		Instances: checksums,
+		Build:     experiment.CurrentBuild(),
+	}
+	if err := experiment.WriteBundle(*output, manifest, profiles, resultsDir); err != nil {
+		os.RemoveAll(resultsDir)
+		fatalf("Error writing %s: %v", *output, err)
+	}
+	recordBestFitness(runs, boolFlag(experimentArgs, "maximize"))
+	logger.Printf("Bundled %d runs on %d instances with seed %d in %s", len(runs), len(checksums), seed, *output)
+}
+
+// runReplay implements the "replay" subcommand: it checks the instances of a
+// bundle, runs its experiment again and compares the outcome of every run
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	instanceDir := fs.String("instances", "", "Directory with the instances (default: the one of the bundled experiment)")
+	outputDir := fs.String("output", "", "Directory for the replayed results (default: a temporary directory)")
+	force := fs.Bool("force", false, "Replay even if instance checksums differ from the bundle")
+	fs.Usage = func() {
+		logger.Printf("Usage: %s replay [flags] bundle.tar.gz", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	bundleDir, err := os.MkdirTemp("", "qap_replay")
+	if err != nil {
+		fatalf("Cannot create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(bundleDir)
+	manifest, err := experiment.ReadBundle(fs.Arg(0), bundleDir)
+	if err != nil {
+		os.RemoveAll(bundleDir)
+		fatalf("Cannot read bundle: %v", err)
+	}
+	build := experiment.CurrentBuild()
+	logger.Printf("Bundle created %s by %s (%s/%s), seed %d", manifest.Created.Format(time.RFC3339),
+		manifest.Build.GoVersion, manifest.Build.OS, manifest.Build.Arch, manifest.Seed)
+	if revision := manifest.Build.Settings["vcs.revision"]; revision != build.Settings["vcs.revision"] {
+		logger.Printf("Warning: the bundle was built from revision %q, this binary from %q", revision, build.Settings["vcs.revision"])
+	}
+	if manifest.Build.GoVersion != build.GoVersion {
+		logger.Printf("Warning: the bundle was built with %s, this binary with %s", manifest.Build.GoVersion, build.GoVersion)
+	}
+
+	// Bundles without a configuration predate it and replay with this
+	// machine's configuration
+	settings := manifest.Config
+	if settings == nil {
+		logger.Printf("Warning: the bundle records no configuration, settings not given as flags come from this machine's")
+	}
+	replayArgs := append([]string(nil), manifest.Args...)
+	if usesProfiles(replayArgs, settings) && settings != nil {
+		// Without a bundled store the path does not exist, as when bundling
+		replayArgs = append(replayArgs, "-profiles="+filepath.Join(bundleDir, experiment.BundleProfiles))
+	}
+	if *instanceDir == "" {
+		*instanceDir = flagValue(replayArgs, "instances", cmp.Or(settings["instances"], defaultInstancesDir()))
+	} else {
+		replayArgs = append(replayArgs, "-instances="+*instanceDir)
+	}
+	var names []string
+	for name := range manifest.Instances {
+		names = append(names, name)
+	}
+	checksums, err := experiment.InstanceChecksums(*instanceDir, names)
+	if err != nil {
+		os.RemoveAll(bundleDir)
+		fatalf("Cannot checksum the instances: %v", err)
+	}
+	changed := 0
+	for name, checksum := range manifest.Instances {
+		if checksums[name] != checksum {
+			logger.Printf("Instance %s differs from the bundled one", name)
+			changed++
+		}
+	}
+	if changed > 0 && !*force {
+		os.RemoveAll(bundleDir)
+		fatalf("%d instances differ from the bundle, use -force to replay anyway", changed)
+	}
+
+	if *outputDir == "" {
+		*outputDir = filepath.Join(bundleDir, "replay")
+	}
+	if err := runChildExperiment(replayArgs, settings, *outputDir); err != nil {
+		os.RemoveAll(bundleDir)
+		fatalf("Replayed experiment failed: %v", err)
+	}
+
+	expected := loadExperimentRuns(filepath.Join(bundleDir, experiment.BundleResultsDir))
+	actual := loadExperimentRuns(*outputDir)
+	differences := experiment.CompareRuns(expected, actual)
+	for _, d := range differences {
+		logger.Printf("  %s on %s run %d: bundled %s, replayed %s", d.SolverName, d.InstanceName, d.Run, d.Expected, d.Actual)
+		summary.JobsFailed++
+	}
+	recordBestFitness(actual, boolFlag(replayArgs, "maximize"))
+	logger.Printf("Replay: %d of %d runs reproduced exactly", len(expected)-len(differences), len(expected))
+	if len(differences) > 0 {
+		logger.Printf("Time-limited runs and solvers without seed support are not expected to reproduce exactly")
+	}
+}
+
+// runChildExperiment runs this binary in experiment mode with args, writing
+// to outputDir; later flags override earlier ones. Unless settings is nil,
+// they replace the configuration file and QAP_SOLVER_* environment of the
+// child. The child's log is passed through, its exit summary line is
+// dropped so the parent's summary stays the only JSON line of the output.
+func runChildExperiment(args []string, settings map[string]string, outputDir string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(executable, append(append([]string(nil), args...), "-experiment", "-output="+outputDir)...)
+	if settings != nil {
+		configDir, err := os.MkdirTemp("", "qap_config")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(configDir)
+		configFile := filepath.Join(configDir, config.FileName)
+		if err := config.WriteFile(configFile, settings); err != nil {
+			return err
+		}
+		for _, entry := range os.Environ() {
+			if !strings.HasPrefix(entry, config.EnvPrefix) {
+				cmd.Env = append(cmd.Env, entry)
+			}
+		}
+		cmd.Env = append(cmd.Env, config.EnvPrefix+"CONFIG="+configFile)
+	}
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); !isExitSummary(line) {
+			os.Stdout.Write(append(line, '\n'))
+		}
+	}
+	// Drain the rest of an overlong line so the child does not block
+	io.Copy(os.Stdout, stdout)
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == exitJobsFailed {
+		logger.Printf("Warning: some runs of the experiment failed")
+		return nil
+	}
+	return err
+}
+
+// isExitSummary reports whether a line of output is the JSON exit summary
+// of a mode, see exitSummary
+func isExitSummary(line []byte) bool {
+	var parsed struct {
+		Mode   *string `json:"mode"`
+		Status *string `json:"status"`
+	}
+	return bytes.HasPrefix(line, []byte("{")) && json.Unmarshal(line, &parsed) == nil &&
+		parsed.Mode != nil && parsed.Status != nil
+}
+
+// loadExperimentRuns reads the results file of an experiment output directory
+func loadExperimentRuns(dir string) []metrics.RunMetrics {
+	path, err := experiment.ResultsFile(dir)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	runs, err := metrics.LoadRunsCSV(path, false)
+	if err != nil {
+		fatalf("Failed to load runs: %v", err)
+	}
+	return runs
+}
+
+// recordBestFitness adds the best objective value of every instance to the
+// exit summary, runs hold objective values as written to the results file
+func recordBestFitness(runs []metrics.RunMetrics, maximize bool) {
+	for _, run := range runs {
+		if best, ok := summary.BestFitness[run.InstanceName]; !ok || (run.FinalFitness < best) != maximize {
+			summary.BestFitness[run.InstanceName] = run.FinalFitness
+		}
+	}
+}
+
+func instanceNames(runs []metrics.RunMetrics) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, run := range runs {
+		if !seen[run.InstanceName] {
+			seen[run.InstanceName] = true
+			names = append(names, run.InstanceName)
+		}
+	}
+	return names
+}
+
+// flagValue returns the last value given to a flag in args, accepting the
+// forms -name value, -name=value and their -- variants
+func flagValue(args []string, name, fallback string) string {
+	value := fallback
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimPrefix(strings.TrimPrefix(args[i], "-"), "-")
+		if arg == args[i] {
+			continue
+		}
+		if key, v, ok := strings.Cut(arg, "="); ok && key == name {
+			value = v
+		} else if arg == name && i+1 < len(args) {
+			value = args[i+1]
+			i++
+		}
+	}
+	return value
+}
+
+// boolFlag reports whether a boolean flag is set in args, the last of
+// -name, -name=true and -name=false wins
+func boolFlag(args []string, name string) bool {
+	set := false
+	for _, arg := range args {
+		arg = strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if arg == name {
+			set = true
+		} else if key, value, ok := strings.Cut(arg, "="); ok && key == name {
+			set, _ = strconv.ParseBool(value)
+		}
+	}
+	return set
+}
+
+// usesProfiles reports whether a solver of the experiment takes its
+// parameters from the profile store, see solvers.Profiles
+func usesProfiles(args []string, settings map[string]string) bool {
+	return strings.Contains(flagValue(args, "solvers", settings["solvers"]), "params=auto")
+}
+
+// defaultInstancesDir is the instance directory of experiments without
+// -instances, see config.Load
+func defaultInstancesDir() string {
+	defaults, err := config.Load()
+	if err != nil {
+		return "instances"
+	}
+	return defaults.InstancesDir
+}
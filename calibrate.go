@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/config"
+	"qap_solver/internal/experiment"
+	"qap_solver/internal/solvers"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runCalibrate implements the "calibrate" subcommand: it measures how the
+// time of fitness evaluations and of solver runs grows with the instance size
+// on this machine, reports the fitted curves and stores them as the host's
+// cost model, which -dry-run uses to predict the duration of an experiment.
+func runCalibrate(args []string) {
+	defaults, err := config.Load()
+	if err != nil {
+		fatalf("Invalid configuration: %v", err)
+	}
+
+	fs := flag.NewFlagSet("calibrate", flag.ExitOnError)
+	solverConfigs := fs.String("solvers", defaults.Solvers, "Solver configurations to calibrate, separated by ;")
+	sizes := fs.String("sizes", "10,20,40,80", "Comma-separated sizes of the random calibration instances")
+	maxRun := fs.Duration("max-run", time.Minute, "Time limit of a calibration run, limited runs make the fit unreliable (0 = none)")
+	modelPath := fs.String("model", defaults.CostModel, "Cost model store updated with the calibration of this host")
+	seed := fs.Int64("seed", 1, "Seed of the random instances and runs")
+	fs.Usage = func() {
+		logger.Printf("Usage: %s calibrate -solvers \"tabu;simanneal\" [flags]", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var ns []int
+	for _, field := range splitPatterns(*sizes) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 2 {
+			fatalf("Invalid size %q", field)
+		}
+		ns = append(ns, n)
+	}
+	if len(ns) < 2 {
+		fatalf("Calibration needs two or more sizes")
+	}
+
+	models, err := experiment.LoadCostModels(*modelPath)
+	if err != nil {
+		fatalf("Error reading cost models: %v", err)
+	}
+	model := models.Local()
+	model.Calibrated = time.Now()
+
+	logger.Printf("Evaluation cost on %s", model.Host)
+	timings, err := experiment.MeasureEvaluations(ns, *seed)
+	if err != nil {
+		fatalf("Measuring evaluations failed: %v", err)
+	}
+	for _, t := range timings {
+		logger.Printf("  n=%-5d full evaluation %12.0f ns  swap delta %8.0f ns", t.Size, t.FullNs, t.DeltaNs)
+	}
+	if err := model.FitEvaluations(timings); err != nil {
+		fatalf("Fitting evaluation cost failed: %v", err)
+	}
+	logger.Printf("  full evaluation ~ %s ns, swap delta ~ %s ns", model.FullEvaluationNs, model.DeltaEvaluationNs)
+
+	factory := solvers.NewSolverFactory()
+	for _, solverConfig := range strings.Split(*solverConfigs, ";") {
+		solverConfig = strings.TrimSpace(solverConfig)
+		if solverConfig == "" {
+			continue
+		}
+		solver, err := factory.Create(solverConfig)
+		if err != nil {
+			logger.Printf("Error creating solver from config '%s': %v", solverConfig, err)
+			summary.JobsFailed++
+			continue
+		}
+		runs, err := experiment.MeasureSolver(solver, ns, *seed, *maxRun)
+		if err != nil {
+			logger.Printf("Cannot calibrate %s: %v", solverConfig, err)
+			summary.JobsFailed++
+			continue
+		}
+		logger.Printf("Run cost of %s", solverConfig)
+		for _, r := range runs {
+			note := ""
+			if r.Termination == solvers.TerminationTimeLimit {
+				note = " (stopped by -max-run)"
+			}
+			logger.Printf("  n=%-5d %12d evaluations %10v%s", r.Size, r.Evaluations, r.Time.Round(time.Microsecond), note)
+		}
+		cost, err := model.FitSolver(solverConfig, solver.Name(), runs)
+		if err != nil {
+			logger.Printf("Cannot fit %s: %v", solverConfig, err)
+			summary.JobsFailed++
+			continue
+		}
+		logger.Printf("  run time ~ %s s, evaluations ~ %s", cost.RunSeconds, cost.Evaluations)
+	}
+
+	if err := models.Save(*modelPath); err != nil {
+		fatalf("Error saving cost models: %v", err)
+	}
+	logger.Printf("Cost model saved to %s, predict experiments with -dry-run", *modelPath)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/config"
+	"qap_solver/internal/experiment"
+	"qap_solver/internal/solvers"
+)
+
+// runChampion implements the "champion" subcommand: it runs a challenger
+// solver configuration on the instances and compares it with the champion
+// of every instance kept in a store, which the challenger replaces where it
+// has the better mean objective. Meant for iterative algorithm development,
+// e.g. after every change to a solver.
+func runChampion(args []string) {
+	defaults, err := config.Load()
+	if err != nil {
+		fatalf("Invalid configuration: %v", err)
+	}
+
+	fs := flag.NewFlagSet("champion", flag.ExitOnError)
+	instanceDir := fs.String("instances", defaults.InstancesDir, "Directory containing instance files")
+	outputDir := fs.String("output", defaults.OutputDir, "Directory for the results of the challenger runs")
+	storePath := fs.String("store", "champions.json", "Champion store, the best configuration per instance")
+	spec := fs.String("solver", "", "Challenger solver configuration, as in -solvers, e.g. \"tabu:p=10\"")
+	runs := fs.Int("runs", defaults.RunsPerInstance, "Runs of the challenger per instance")
+	parallelism := fs.Int("parallel", defaults.Parallelism, "Number of runs executed concurrently")
+	seed := fs.Int64("seed", 0, "Base seed making the challenger runs reproducible (0 = random)")
+	maximize := fs.Bool("maximize", false, "Maximize the QAP objective instead of minimizing it")
+	failOnLoss := fs.Bool("fail-on-loss", false, "Exit with an error when the challenger loses on any instance, e.g. in continuous integration")
+	recursive := fs.Bool("recursive", false, "Also search subdirectories of the instance directory")
+	include := fs.String("include", "", "Comma-separated glob patterns, only matching instances are used")
+	exclude := fs.String("exclude", "", "Comma-separated glob patterns of instances to skip")
+	maxSize := fs.Int("max-size", 0, "Skip instances with more than this many facilities (0 = no limit)")
+	instancesList := fs.String("instances-list", "", "File naming the instances to use, one per line and in this order")
+	limit := fs.Int("limit", 0, "Use at most the first N instances after filtering (0 = all)")
+	fs.Usage = func() {
+		logger.Printf("Usage: %s champion -solver \"tabu:p=10\" [flags]", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *spec == "" {
+		fatalf("Name the challenger with -solver")
+	}
+	solver, err := solvers.NewSolverFactory().Create(*spec)
+	if err != nil {
+		fatalf("Invalid challenger %s: %v", *spec, err)
+	}
+
+	// A fresh directory keeps the results of earlier challenges apart
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		fatalf("Cannot create %s: %v", *outputDir, err)
+	}
+	runDir, err := os.MkdirTemp(*outputDir, "champion_")
+	if err != nil {
+		fatalf("Cannot create challenge directory: %v", err)
+	}
+	results, outcome, err := experiment.RunChallenge(experiment.ExperimentConfig{
+		InstancesDir:    *instanceDir,
+		OutputDir:       runDir,
+		Solvers:         []solvers.Solver{solver},
+		RunsPerInstance: *runs,
+		Parallelism:     *parallelism,
+		Logger:          logger,
+		Validate:        true,
+		Seed:            *seed,
+		Maximize:        *maximize,
+		Filter: experiment.InstanceFilter{
+			Recursive: *recursive,
+			Include:   splitPatterns(*include),
+			Exclude:   splitPatterns(*exclude),
+			MaxSize:   *maxSize,
+			Names:     readInstanceList(*instancesList),
+			Limit:     *limit,
+		},
+	}, *spec, *storePath)
+	summary.BestFitness, summary.JobsFailed = outcome.BestFitness, outcome.JobsFailed
+	if err != nil {
+		fatalf("Challenge failed: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Verdict]++
+		c := r.Challenger
+		switch r.Verdict {
+		case experiment.ChallengeNew:
+			logger.Printf("  %-20s new champion, mean %.1f, best %d", c.Instance, c.Mean, c.Best)
+		case experiment.ChallengeChanged:
+			logger.Printf("  %-20s new champion, the instance changed since %s ran", c.Instance, r.Champion.Config)
+		default:
+			logger.Printf("  %-20s %-5s champion %s: mean %.1f vs %.1f, best %d vs %d", c.Instance, r.Verdict, r.Champion.Config,
+				c.Mean, r.Champion.Mean, c.Best, r.Champion.Best)
+		}
+	}
+	logger.Printf("Challenger %s: beats %d, ties %d, loses %d, new on %d instances; champions saved to %s",
+		*spec, counts[experiment.ChallengeBeats], counts[experiment.ChallengeTies], counts[experiment.ChallengeLoses],
+		counts[experiment.ChallengeNew]+counts[experiment.ChallengeChanged], *storePath)
+	if *failOnLoss && counts[experiment.ChallengeLoses] > 0 {
+		fatalf("The challenger loses on %d instances", counts[experiment.ChallengeLoses])
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"qap_solver/internal/experiment"
+	"qap_solver/internal/qap"
+	"qap_solver/internal/solvers"
+	"sort"
+	"time"
+)
+
+// predictExperiment implements -dry-run: it lists what the experiment would
+// run and predicts its duration from the cost model of this host
+func predictExperiment(modelPath, instanceDir string, filter experiment.InstanceFilter, sample int,
+	configs []string, solverList []solvers.Solver, runs, parallelism int, budget time.Duration) {
+	files, err := experiment.FindInstanceFiles(instanceDir, filter)
+	if err != nil {
+		fatalf("Error finding instance files: %v", err)
+	}
+	if sample > 0 && sample < len(files) {
+		files = files[:sample]
+	}
+	var sizes []int
+	for _, file := range files {
+		n, err := qap.ReadInstanceSize(file)
+		if err != nil {
+			logger.Printf("Skipping %s: %v", file, err)
+			continue
+		}
+		sizes = append(sizes, n)
+	}
+	if len(sizes) == 0 {
+		fatalf("No instance files found in %s", instanceDir)
+	}
+	sorted := append([]int(nil), sizes...)
+	sort.Ints(sorted)
+	logger.Printf("Dry run: %d instances (n=%d..%d), %d solvers, %d runs each, %d in parallel",
+		len(sizes), sorted[0], sorted[len(sorted)-1], len(solverList), runs, max(parallelism, 1))
+
+	models, err := experiment.LoadCostModels(modelPath)
+	if err != nil {
+		fatalf("Error reading cost models: %v", err)
+	}
+	model, ok := models.Host()
+	if !ok {
+		fatalf("No cost model for this host in %s, run calibrate first", modelPath)
+	}
+	logger.Printf("Cost model of %s, calibrated %s: full evaluation ~ %s ns, swap delta ~ %s ns",
+		model.Host, model.Calibrated.Format("2006-01-02"), model.FullEvaluationNs, model.DeltaEvaluationNs)
+
+	names := make([]string, len(solverList))
+	for i, solver := range solverList {
+		names[i] = solver.Name()
+	}
+	p := model.Predict(configs, names, sizes, runs, parallelism, budget)
+	for _, name := range names {
+		if d, ok := p.PerSolver[name]; ok {
+			logger.Printf("  %-30s %v", name, d.Round(time.Millisecond))
+		}
+	}
+	for _, config := range p.Inexact {
+		logger.Printf("Warning: %s was not calibrated, predicted with another configuration of its solver", config)
+	}
+	for _, config := range p.Missing {
+		logger.Printf("Warning: %s was not calibrated and is missing from the prediction", config)
+	}
+	capped := ""
+	if budget > 0 && p.WallTime == budget {
+		capped = ", limited by -time-budget"
+	}
+	logger.Printf("Predicted: %d runs, %v of run time, about %v wall time%s",
+		p.Runs, p.RunTime.Round(time.Millisecond), p.WallTime.Round(time.Millisecond), capped)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/experiment"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"strings"
+)
+
+// runEvaluate implements the "evaluate" subcommand: it scores a solution
+// produced by another tool on an instance and compares it with the best
+// known value.
+func runEvaluate(args []string) {
+	fs := flag.NewFlagSet("evaluate", flag.ExitOnError)
+	instanceFile := fs.String("instance", "", "Instance file the solution belongs to")
+	solutionFile := fs.String("solution", "", "Solution file: .sln, JSON array or object, or space-separated values")
+	instanceDir := fs.String("instances", "", "Directory with .sln files used as best known values (default: directory of the instance)")
+	maximize := fs.Bool("maximize", false, "Maximize the QAP objective instead of minimizing it")
+	detectTriangular := fs.Bool("detect-triangular", true, "Accept instance matrices given as their upper triangle")
+	zeroDiagonal := fs.Bool("zero-diagonal", false, "Clear nonzero diagonals of the instance before evaluating, as the solver flag does")
+	fs.Usage = func() {
+		logger.Printf("Usage: %s evaluate -instance X.dat -solution sol.txt [flags]", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *instanceFile == "" || *solutionFile == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	qap.DetectTriangular = *detectTriangular
+	instance, err := qap.ReadInstance(*instanceFile)
+	if err != nil {
+		fatalf("Error loading instance: %v", err)
+	}
+	instance.Maximize = *maximize
+	if d := instance.Diagonal(); d.Nonzero() {
+		logger.Printf("Warning: %s", experiment.DiagonalWarning(filepath.Base(*instanceFile), d, *zeroDiagonal))
+		if *zeroDiagonal {
+			instance.ZeroDiagonals()
+		}
+	}
+
+	solution, err := qap.ReadSolution(*solutionFile, instance.Size)
+	if err != nil {
+		fatalf("Invalid solution: %v", err)
+	}
+
+	cost := qap.QAPCost(instance, solution)
+	logger.Printf("Instance: %s (n=%d)", filepath.Base(*instanceFile), instance.Size)
+	logger.Printf("Solution: %v", solution)
+	logger.Printf("Fitness: %d", cost)
+	summary.BestFitness[filepath.Base(*instanceFile)] = cost
+
+	// A .sln file states its own value, which should match the recomputed one
+	if strings.EqualFold(filepath.Ext(*solutionFile), ".sln") {
+		if claimed, err := qap.ReadSolutionFile(*solutionFile); err == nil && claimed.Value != cost {
+			logger.Printf("Warning: the solution file states %d, recomputed fitness is %d", claimed.Value, cost)
+		}
+	}
+
+	if *instanceDir == "" {
+		*instanceDir = filepath.Dir(*instanceFile)
+	}
+	optima, err := qap.LoadOptimalSolutions(*instanceDir)
+	if err != nil {
+		logger.Printf("Could not load optimal solutions: %v", err)
+	}
+	known, ok := optima.Lookup(filepath.Base(*instanceFile))
+	if !ok {
+		logger.Printf("Best known: unknown, no .sln file for this instance in %s", *instanceDir)
+		return
+	}
+	sense := instance.Sense()
+	logger.Printf("Best known: %d", known)
+	logger.Printf("Gap: %.4f%%", metrics.GapPercent(sense*cost, sense*known))
+	if sense*cost < sense*known {
+		logger.Printf("The solution beats the best known value")
+	}
+}
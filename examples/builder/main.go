@@ -0,0 +1,69 @@
+// Command builder shows how a Go program composes and runs an experiment
+// with experiment.Builder instead of the command line flags:
+//
+//	go run ./examples/builder -instances instances -include "chr12*"
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"qap_solver/internal/experiment"
+	"qap_solver/internal/metrics"
+	"strings"
+)
+
+// bestRuns is a sink that keeps the best run of every instance and prints
+// the winners once the experiment is done
+type bestRuns struct {
+	best map[string]metrics.RunMetrics
+}
+
+func (s *bestRuns) AddRun(run metrics.RunMetrics) {
+	if best, ok := s.best[run.InstanceName]; !ok || run.FinalFitness < best.FinalFitness {
+		s.best[run.InstanceName] = run
+	}
+}
+
+func (s *bestRuns) Done(collector *metrics.MetricsCollector) error {
+	for instanceName, run := range s.best {
+		fmt.Printf("%-20s %-30s %d\n", instanceName, run.SolverName, run.FinalFitness)
+	}
+	return nil
+}
+
+func main() {
+	instances := flag.String("instances", "instances", "Directory containing instance files")
+	include := flag.String("include", "chr12*", "Comma-separated patterns of the instances to run")
+	output := flag.String("output", "results/builder", "Directory for output files")
+	flag.Parse()
+
+	finished := 0
+	outcome, err := experiment.NewBuilder().
+		AddInstanceDir(*instances).
+		AddSolver("tabu:p=5").
+		AddSolver("simanneal:alpha=0.95").
+		AddSolver("ils:perturb=scramble@label=ILS-scramble").
+		WithRuns(5).
+		WithParallelism(4).
+		WithSeed(42).
+		WithOutputDir(*output).
+		WithLogger(log.New(os.Stderr, "[builder] ", log.LstdFlags)).
+		WithSinks(
+			experiment.SinkFunc(func(run metrics.RunMetrics) {
+				finished++
+				fmt.Fprintf(os.Stderr, "\r%d runs finished", finished)
+			}),
+			&bestRuns{best: make(map[string]metrics.RunMetrics)},
+		).
+		Configure(func(config *experiment.ExperimentConfig) {
+			config.Filter.Include = strings.Split(*include, ",")
+			config.Validate = true
+		}).
+		Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d instances, %d failed runs\n", len(outcome.BestFitness), outcome.JobsFailed)
+}
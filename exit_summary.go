@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Exit codes of every mode
+const (
+	exitOK         = 0
+	exitError      = 1 // the mode failed, see the error of the summary
+	exitJobsFailed = 3 // the mode completed but some jobs failed
+)
+
+// exitSummary is printed as the last line of every mode: a single JSON object,
+// so scripts can check the outcome without parsing the log
+type exitSummary struct {
+	Mode           string         `json:"mode"`
+	Status         string         `json:"status"`
+	Error          string         `json:"error,omitempty"`
+	BestFitness    map[string]int `json:"best_fitness"`
+	RuntimeSeconds float64        `json:"runtime_seconds"`
+	JobsFailed     int            `json:"jobs_failed"`
+
+	start time.Time
+	// atExit runs before the summary is printed, e.g. to stop profiling
+	atExit []func()
+}
+
+var summary = &exitSummary{Mode: "single", BestFitness: make(map[string]int), start: time.Now()}
+
+// exit prints the summary line and terminates with the matching exit code
+func (s *exitSummary) exit() {
+	hooks := s.atExit
+	s.atExit = nil
+	for _, hook := range hooks {
+		hook()
+	}
+
+	code := exitOK
+	s.Status = "ok"
+	switch {
+	case s.Error != "":
+		code, s.Status = exitError, "error"
+	case s.JobsFailed > 0:
+		code, s.Status = exitJobsFailed, "jobs_failed"
+	}
+	s.RuntimeSeconds = time.Since(s.start).Seconds()
+
+	line, err := json.Marshal(s)
+	if err != nil {
+		line = []byte(fmt.Sprintf(`{"mode":%q,"status":"error","error":%q}`, s.Mode, err.Error()))
+		code = exitError
+	}
+	fmt.Println(string(line))
+	os.Exit(code)
+}
+
+// fatalf logs an error and exits with the summary of the failed mode
+func fatalf(format string, args ...any) {
+	logger.Printf(format, args...)
+	summary.Error = fmt.Sprintf(format, args...)
+	summary.exit()
+}
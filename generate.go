@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/qap"
+	"strings"
+	"time"
+)
+
+// runGenerate implements the "generate" subcommand: it writes random
+// instances of a structured family, with .sln files for families whose
+// optimum is known by construction.
+func runGenerate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	family := fs.String("family", qap.FamilyUniform, "Instance family: "+strings.Join(qap.Families, ", "))
+	size := fs.Int("size", 20, "Number of facilities and locations")
+	count := fs.Int("count", 1, "Number of instances to generate")
+	outputDir := fs.String("output", "generated", "Directory for the instance and .sln files")
+	maxValue := fs.Int("max-value", 100, "Largest flow and uniform distance value")
+	rows := fs.Int("rows", 0, "Rows of the grid family (0 = most square grid)")
+	clusters := fs.Int("clusters", 0, "Location clusters of the clustered family (0 = about sqrt(size)/2)")
+	seed := fs.Int64("seed", 0, "Seed of the first instance, the next ones use the following seeds (0 = random)")
+	format := fs.String("format", "qaplib", "Instance file format: qaplib (.dat) or tagged (.qapt, see qap.ParseTagged)")
+	fs.Usage = func() {
+		logger.Printf("Usage: %s generate -family grid -size 30 [flags]", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	extension, write := ".dat", qap.WriteInstance
+	switch *format {
+	case "qaplib":
+	case "tagged":
+		extension, write = qap.TaggedExtension, qap.WriteTagged
+	default:
+		fatalf("Unknown format %q, expected qaplib or tagged", *format)
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	for k := 0; k < *count; k++ {
+		generated, err := qap.Generate(qap.GeneratorOptions{
+			Family: *family, Size: *size, MaxValue: *maxValue, Rows: *rows, Clusters: *clusters, Seed: *seed + int64(k),
+		})
+		if err != nil {
+			fatalf("Cannot generate instance: %v", err)
+		}
+
+		name := fmt.Sprintf("%s%d", strings.ToLower(*family), *size)
+		if *count > 1 {
+			name += fmt.Sprintf("_%d", k+1)
+		}
+		instanceFile := filepath.Join(*outputDir, name+extension)
+		if err := write(instanceFile, generated.Instance); err != nil {
+			fatalf("Error writing %s: %v", instanceFile, err)
+		}
+		if generated.Optimum == nil {
+			logger.Printf("Created %s (seed %d)", instanceFile, *seed+int64(k))
+			continue
+		}
+		solutionFile := filepath.Join(*outputDir, name+".sln")
+		if err := qap.WriteSolutionFile(solutionFile, *generated.Optimum); err != nil {
+			fatalf("Error writing %s: %v", solutionFile, err)
+		}
+		summary.BestFitness[name+extension] = generated.Optimum.Value
+		logger.Printf("Created %s with optimum %d in %s (seed %d)", instanceFile, generated.Optimum.Value, solutionFile, *seed+int64(k))
+	}
+}
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FileName is the name of the user configuration file in the home directory
+const FileName = ".qap_solver.yaml"
+
+// CostModelFileName is the default cost model store in the home directory,
+// shared by all projects of the user
+const CostModelFileName = ".qap_solver_costmodel.json"
+
+// EnvPrefix prefixes environment variables overriding the configuration file,
+// e.g. QAP_SOLVER_OUTPUT=results
+const EnvPrefix = "QAP_SOLVER_"
+
+// RestartPrefix starts the keys attaching a restart policy to a solver type
+// or label, e.g. "restart.tabu: when=stall>50000,perturb=5", see
+// solvers.RestartPolicy. The environment uses QAP_SOLVER_RESTART_TABU.
+const RestartPrefix = "restart."
+
+// Defaults holds the values used for command line flags that are not given
+// explicitly. Precedence is: flags, environment, configuration file, built-in.
+type Defaults struct {
+	InstancesDir    string
+	OutputDir       string
+	Solvers         string
+	RunsPerInstance int
+	Parallelism     int    // defaults to one worker per CPU but one, left for logging and the collector
+	Stop            string // stopping condition, see solvers.StopCondition
+	Profiles        string // parameter profile store, see solvers.Profiles
+	CostModel       string // cost model store of the calibrate subcommand, see experiment.CostModels
+
+	// Output columns and derived metrics, see metrics.OutputOptions
+	Columns        string // comma separated results columns
+	SummaryColumns string // comma separated summary columns
+	Derived        string // "Name=expression" definitions separated by ;
+
+	// Restarts maps solver types or labels to restart policies, see RestartPrefix
+	Restarts map[string]string
+}
+
+// BuiltIn returns the defaults used without a configuration file or environment
+func BuiltIn() Defaults {
+	return Defaults{
+		InstancesDir:    "instances",
+		OutputDir:       "results",
+		Solvers:         "random:iterations=1000",
+		RunsPerInstance: 10,
+		Parallelism:     max(runtime.NumCPU()-1, 1),
+		Profiles:        "profiles.json",
+		CostModel:       CostModelFileName,
+	}
+}
+
+// Load merges the built-in defaults with ~/.qap_solver.yaml (or the file named
+// by QAP_SOLVER_CONFIG) and QAP_SOLVER_* environment variables.
+// A missing configuration file is not an error.
+func Load() (Defaults, error) {
+	d := BuiltIn()
+
+	path := os.Getenv(EnvPrefix + "CONFIG")
+	if home, err := os.UserHomeDir(); err == nil {
+		d.CostModel = filepath.Join(home, CostModelFileName)
+		if path == "" {
+			path = filepath.Join(home, FileName)
+		}
+	}
+	if path != "" {
+		if err := d.loadFile(path); err != nil && !os.IsNotExist(err) {
+			return d, err
+		}
+	}
+
+	for _, key := range []string{"instances", "output", "solvers", "runs", "parallel", "stop", "profiles", "cost_model", "columns", "summary_columns", "derived"} {
+		if value, ok := os.LookupEnv(EnvPrefix + strings.ToUpper(key)); ok {
+			if err := d.set(key, value); err != nil {
+				return d, fmt.Errorf("%s%s: %v", EnvPrefix, strings.ToUpper(key), err)
+			}
+		}
+	}
+	envRestart := EnvPrefix + strings.ToUpper(strings.TrimSuffix(RestartPrefix, ".")) + "_"
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if solver, ok := strings.CutPrefix(name, envRestart); ok {
+			if err := d.set(RestartPrefix+strings.ToLower(solver), value); err != nil {
+				return d, fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+
+	return d, nil
+}
+
+// loadFile reads a flat YAML file of "key: value" lines. Nested structures
+// are not supported; comments and blank lines are ignored.
+func (d *Defaults) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected \"key: value\"", path, lineNumber)
+		}
+		if err := d.set(strings.TrimSpace(key), unquote(value)); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, lineNumber, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// set assigns the value of a key, which is matched case-insensitively
+func (d *Defaults) set(key, value string) error {
+	key = strings.ToLower(key)
+	if solver, ok := strings.CutPrefix(key, RestartPrefix); ok {
+		if solver == "" {
+			return fmt.Errorf("%s needs a solver type or label, e.g. %stabu", key, RestartPrefix)
+		}
+		if d.Restarts == nil {
+			d.Restarts = make(map[string]string)
+		}
+		d.Restarts[solver] = value
+		return nil
+	}
+
+	switch key {
+	case "instances":
+		d.InstancesDir = value
+	case "output":
+		d.OutputDir = value
+	case "solvers":
+		d.Solvers = value
+	case "stop":
+		d.Stop = value
+	case "profiles":
+		d.Profiles = value
+	case "cost_model":
+		d.CostModel = value
+	case "columns":
+		d.Columns = value
+	case "summary_columns":
+		d.SummaryColumns = value
+	case "derived":
+		d.Derived = value
+	case "runs", "parallel":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("%s must be a positive integer, got %q", key, value)
+		}
+		if key == "runs" {
+			d.RunsPerInstance = n
+		} else {
+			d.Parallelism = n
+		}
+	default:
+		return fmt.Errorf("unknown key %q", key)
+	}
+	return nil
+}
+
+// Settings returns the values of d by configuration key, restart policies
+// included, so that a file written by WriteFile loads back to d
+func (d Defaults) Settings() map[string]string {
+	settings := map[string]string{
+		"instances":       d.InstancesDir,
+		"output":          d.OutputDir,
+		"solvers":         d.Solvers,
+		"runs":            strconv.Itoa(d.RunsPerInstance),
+		"parallel":        strconv.Itoa(d.Parallelism),
+		"stop":            d.Stop,
+		"profiles":        d.Profiles,
+		"cost_model":      d.CostModel,
+		"columns":         d.Columns,
+		"summary_columns": d.SummaryColumns,
+		"derived":         d.Derived,
+	}
+	for solver, policy := range d.Restarts {
+		settings[RestartPrefix+solver] = policy
+	}
+	return settings
+}
+
+// WriteFile writes settings as a configuration file, sorted by key, with
+// every value quoted
+func WriteFile(path string, settings map[string]string) error {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		value := settings[key]
+		if strings.Contains(value, "\n") {
+			return fmt.Errorf("%s: the value %q spans several lines", key, value)
+		}
+		quote := `"`
+		if strings.Contains(value, quote) {
+			quote = "'"
+		}
+		fmt.Fprintf(&b, "%s: %s%s%s\n", key, quote, value, quote)
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// unquote strips a trailing comment and surrounding quotes from a YAML scalar
+func unquote(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+	if idx := strings.Index(value, " #"); idx >= 0 {
+		value = strings.TrimSpace(value[:idx])
+	}
+	return value
+}
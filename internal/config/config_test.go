@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a configuration file into a fresh home directory and
+// points Load at it
+func writeConfig(t *testing.T, text string) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(EnvPrefix+"CONFIG", "")
+	path := filepath.Join(home, FileName)
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	writeConfig(t, `# defaults of the lab machine
+instances: qaplib
+Output: "out dir"   # quoted, with a comment
+SOLVERS: 'tabu:p=5'
+Runs: 20
+parallel: 3 # workers
+stop: evals>1000
+restart.Tabu: when=stall>500
+`)
+	d, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.InstancesDir != "qaplib" || d.OutputDir != "out dir" || d.Solvers != "tabu:p=5" || d.Stop != "evals>1000" {
+		t.Errorf("got instances %q, output %q, solvers %q, stop %q", d.InstancesDir, d.OutputDir, d.Solvers, d.Stop)
+	}
+	if d.RunsPerInstance != 20 || d.Parallelism != 3 {
+		t.Errorf("got %d runs and parallelism %d, want 20 and 3", d.RunsPerInstance, d.Parallelism)
+	}
+	if got := d.Restarts["tabu"]; got != "when=stall>500" {
+		t.Errorf("restart policy of tabu is %q", got)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(EnvPrefix+"CONFIG", "")
+	d, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	builtIn := BuiltIn()
+	if d.InstancesDir != builtIn.InstancesDir || d.RunsPerInstance != builtIn.RunsPerInstance || d.Parallelism != builtIn.Parallelism {
+		t.Errorf("got %+v, want the built-in defaults", d)
+	}
+}
+
+// TestLoadPrecedence checks that the environment overrides the file, which
+// overrides the built-in defaults
+func TestLoadPrecedence(t *testing.T) {
+	writeConfig(t, "runs: 20\nparallel: 3\noutput: from-file\nrestart.tabu: when=stall>500\n")
+	t.Setenv(EnvPrefix+"RUNS", "7")
+	t.Setenv(EnvPrefix+"RESTART_TABU", "when=stall>9")
+	d, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.RunsPerInstance != 7 {
+		t.Errorf("runs is %d, want 7 from the environment", d.RunsPerInstance)
+	}
+	if d.Parallelism != 3 || d.OutputDir != "from-file" {
+		t.Errorf("parallelism %d, output %q, want 3 and from-file from the file", d.Parallelism, d.OutputDir)
+	}
+	if d.Solvers != BuiltIn().Solvers {
+		t.Errorf("solvers is %q, want the built-in %q", d.Solvers, BuiltIn().Solvers)
+	}
+	if got := d.Restarts["tabu"]; got != "when=stall>9" {
+		t.Errorf("restart policy of tabu is %q, want the environment's", got)
+	}
+}
+
+// TestLoadConfigVariable reads the file named by QAP_SOLVER_CONFIG instead of
+// the one in the home directory
+func TestLoadConfigVariable(t *testing.T) {
+	writeConfig(t, "runs: 20\n")
+	other := filepath.Join(t.TempDir(), "other.yaml")
+	if err := os.WriteFile(other, []byte("runs: 30\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvPrefix+"CONFIG", other)
+	d, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.RunsPerInstance != 30 {
+		t.Errorf("runs is %d, want 30 from %s", d.RunsPerInstance, other)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name, file, env, want string
+	}{
+		{"unknown key", "colour: red\n", "", `:1: unknown key "colour"`},
+		{"missing colon", "runs: 2\nruns 3\n", "", `:2: expected "key: value"`},
+		{"invalid runs", "Runs: many\n", "", "runs must be a positive integer"},
+		{"zero parallelism", "parallel: 0\n", "", "parallel must be a positive integer"},
+		{"restart without solver", "restart.: when=stall>5\n", "", "needs a solver type or label"},
+		{"invalid environment", "", "-1", EnvPrefix + "RUNS: runs must be a positive integer"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			writeConfig(t, test.file)
+			if test.env != "" {
+				t.Setenv(EnvPrefix+"RUNS", test.env)
+			}
+			if _, err := Load(); err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("got error %v, want one containing %q", err, test.want)
+			}
+		})
+	}
+}
+
+// TestWriteFile writes the settings of a configuration and loads them back
+func TestWriteFile(t *testing.T) {
+	path := writeConfig(t, "")
+	want := BuiltIn()
+	want.Solvers = `tabu:p=5;heuristic:rcl=0.2`
+	want.Stop = "evals>5e6 || gap<0.5"
+	want.Derived = `Ratio=Steps/Evaluations # "quoted"`
+	want.RunsPerInstance, want.Parallelism = 7, 2
+	want.Restarts = map[string]string{"tabu": "when=stall>500,perturb=5"}
+	if err := WriteFile(path, want.Settings()); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loaded %+v, want %+v", got, want)
+	}
+	if err := WriteFile(path, map[string]string{"stop": "a\nb"}); err == nil {
+		t.Error("a value with a line break was written")
+	}
+}
@@ -0,0 +1,94 @@
+package experiment
+
+import (
+	"math"
+	"qap_solver/internal/metrics"
+	"sync"
+)
+
+// adaptiveConfidence is the level of the confidence interval that ends the
+// runs of a solver in adaptive mode
+const adaptiveConfidence = 0.95
+
+// adaptive reports whether the number of runs per solver and instance is
+// chosen by the confidence interval of the final fitness, see AdaptiveCI
+func (config ExperimentConfig) adaptive() bool {
+	return config.AdaptiveCI > 0
+}
+
+// plannedRuns is the largest number of runs of a solver on an instance:
+// MaxRuns in adaptive mode, RunsPerInstance otherwise
+func (config ExperimentConfig) plannedRuns() int {
+	if config.adaptive() {
+		return max(config.MaxRuns, config.minRuns())
+	}
+	return config.RunsPerInstance
+}
+
+// minRuns is the number of runs made before the confidence interval is
+// checked in adaptive mode, at least 2 so it has a width
+func (config ExperimentConfig) minRuns() int {
+	return max(config.RunsPerInstance, 2)
+}
+
+// ciTight reports whether the 95% confidence interval of the final fitness
+// values is narrower than AdaptiveCI percent of their mean on either side,
+// and returns its relative half-width in percent
+func (config ExperimentConfig) ciTight(fitnesses []int) (float64, bool) {
+	if len(fitnesses) < 2 {
+		return math.Inf(1), false
+	}
+	halfWidth := metrics.ConfidenceHalfWidth(fitnesses, adaptiveConfidence)
+	m := math.Abs(mean(fitnesses))
+	if m == 0 {
+		// A relative width is undefined, only a zero width will do
+		return 0, halfWidth == 0
+	}
+	percent := 100 * halfWidth / m
+	return percent, percent <= config.AdaptiveCI
+}
+
+// runAdaptive runs solver on an instance in batches until the confidence
+// interval of its final fitness is tight or plannedRuns is reached. Batches
+// fill the workers, so up to Parallelism-1 runs more than needed are made.
+// dispatch sends a run to the workers; its job notifies the batch when it
+// is done. It returns the number of runs made.
+func runAdaptive(config ExperimentConfig, metricsCollector *metrics.MetricsCollector, j job,
+	dispatch func(job)) int {
+	name := j.solver.Name()
+	limit := config.plannedRuns()
+	batch := config.minRuns()
+	run := 0
+	for run < limit {
+		var done sync.WaitGroup
+		before := len(metricsCollector.FinalFitnesses(j.instanceName)[name])
+		for k := 0; k < batch && run < limit; k++ {
+			run++
+			next := j
+			next.run = run
+			next.batch = &done
+			done.Add(1)
+			dispatch(next)
+		}
+		done.Wait()
+
+		fitnesses := metricsCollector.FinalFitnesses(j.instanceName)[name]
+		percent, tight := config.ciTight(fitnesses)
+		if tight {
+			config.Logger.Printf("Adaptive runs: %s on %s reached a 95%% CI of ±%.3f%% after %d runs",
+				name, j.instanceName, percent, len(fitnesses))
+			return run
+		}
+		if len(fitnesses) == before {
+			// Runs without results, e.g. skipped or aborted, cannot narrow it
+			config.Logger.Printf("Adaptive runs: %s on %s records no results, stopping after %d runs",
+				name, j.instanceName, run)
+			return run
+		}
+		batch = max(config.Parallelism, 1)
+	}
+	percent, _ := config.ciTight(metricsCollector.FinalFitnesses(j.instanceName)[name])
+	config.Logger.Printf("Adaptive runs: %s on %s stopped at the cap of %d runs with a 95%% CI of ±%.3f%%",
+		name, j.instanceName, limit, percent)
+	return run
+}
@@ -0,0 +1,208 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/config"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/solvers"
+	"strings"
+)
+
+// Sink receives the results of an experiment besides the result files, e.g.
+// to stream them into a database or a progress display. AddRun is called
+// with every run as it finishes, never concurrently; robustness and
+// validation results are only in the collector passed to Done once all runs
+// and the result files are complete.
+type Sink interface {
+	AddRun(run metrics.RunMetrics)
+	Done(collector *metrics.MetricsCollector) error
+}
+
+// SinkFunc is a Sink that only looks at the runs
+type SinkFunc func(run metrics.RunMetrics)
+
+func (f SinkFunc) AddRun(run metrics.RunMetrics) {
+	f(run)
+}
+
+func (f SinkFunc) Done(*metrics.MetricsCollector) error {
+	return nil
+}
+
+// Builder composes an experiment from Go code, without the flags of the
+// command line. It starts from the built-in defaults, see config.BuiltIn, and
+// every method returns the builder, so calls chain:
+//
+//	outcome, err := experiment.NewBuilder().
+//		AddInstanceDir("instances").
+//		AddSolver("tabu:p=10").
+//		AddSolver("simanneal:alpha=0.99").
+//		WithRuns(20).
+//		WithParallelism(4).
+//		Run()
+//
+// Errors, such as an invalid solver config, are collected and returned by
+// Build and Run. Settings without a method of their own are made with
+// Configure.
+type Builder struct {
+	config   ExperimentConfig
+	dirs     []string
+	factory  *solvers.SolverFactory
+	problems []error
+}
+
+// NewBuilder creates a builder with the built-in defaults, logging to stderr
+func NewBuilder() *Builder {
+	defaults := config.BuiltIn()
+	return &Builder{
+		config: ExperimentConfig{
+			OutputDir:       defaults.OutputDir,
+			RunsPerInstance: defaults.RunsPerInstance,
+			Parallelism:     defaults.Parallelism,
+			Logger:          log.New(os.Stderr, "[QAP Solver] ", log.LstdFlags),
+		},
+		factory: solvers.NewSolverFactory(),
+	}
+}
+
+// AddInstanceDir adds a directory of instances. Several directories run as
+// separate experiments, each writing to a subdirectory of the output
+// directory named after it.
+func (b *Builder) AddInstanceDir(dir string) *Builder {
+	b.dirs = append(b.dirs, dir)
+	return b
+}
+
+// AddSolver adds a solver configured like an entry of -solvers, e.g.
+// "tabu:p=10@label=Tabu10", created by the builder's factory
+func (b *Builder) AddSolver(spec string) *Builder {
+	solver, err := b.factory.Create(strings.TrimSpace(spec))
+	if err != nil {
+		b.problems = append(b.problems, fmt.Errorf("solver %q: %v", spec, err))
+		return b
+	}
+	return b.AddSolverInstance(solver)
+}
+
+// AddSolverInstance adds a solver created in Go code, such as a custom one
+func (b *Builder) AddSolverInstance(solver solvers.Solver) *Builder {
+	b.config.Solvers = append(b.config.Solvers, solver)
+	return b
+}
+
+// WithFactory creates the solvers of later AddSolver calls with factory, e.g.
+// one with custom solvers registered or parameter profiles set
+func (b *Builder) WithFactory(factory *solvers.SolverFactory) *Builder {
+	b.factory = factory
+	return b
+}
+
+// WithRuns sets the runs per solver and instance
+func (b *Builder) WithRuns(runs int) *Builder {
+	if runs < 1 {
+		b.problems = append(b.problems, fmt.Errorf("runs must be at least 1, got %d", runs))
+	}
+	b.config.RunsPerInstance = runs
+	return b
+}
+
+// WithParallelism sets the number of runs executed concurrently
+func (b *Builder) WithParallelism(parallelism int) *Builder {
+	if parallelism < 1 {
+		b.problems = append(b.problems, fmt.Errorf("parallelism must be at least 1, got %d", parallelism))
+	}
+	b.config.Parallelism = parallelism
+	return b
+}
+
+// WithSinks adds sinks receiving the results, see Sink
+func (b *Builder) WithSinks(sinks ...Sink) *Builder {
+	b.config.Sinks = append(b.config.Sinks, sinks...)
+	return b
+}
+
+// WithOutputDir sets the directory of the result files
+func (b *Builder) WithOutputDir(dir string) *Builder {
+	b.config.OutputDir = dir
+	return b
+}
+
+// WithLogger sets the logger of the experiment, e.g. one discarding its output
+func (b *Builder) WithLogger(logger *log.Logger) *Builder {
+	b.config.Logger = logger
+	return b
+}
+
+// WithSeed makes the runs reproducible, see ExperimentConfig.Seed
+func (b *Builder) WithSeed(seed int64) *Builder {
+	b.config.Seed = seed
+	return b
+}
+
+// Configure changes any other setting of the experiment
+func (b *Builder) Configure(configure func(config *ExperimentConfig)) *Builder {
+	configure(&b.config)
+	return b
+}
+
+// Build returns the configuration of every instance directory, in the order
+// they were added
+func (b *Builder) Build() ([]ExperimentConfig, error) {
+	problems := b.problems
+	if len(b.dirs) == 0 {
+		problems = append(problems, errors.New("no instance directory, call AddInstanceDir"))
+	}
+	if len(b.config.Solvers) == 0 {
+		problems = append(problems, errors.New("no solvers, call AddSolver"))
+	}
+	if b.config.Logger == nil {
+		problems = append(problems, errors.New("no logger"))
+	}
+	if len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+
+	configs := make([]ExperimentConfig, len(b.dirs))
+	for k, dir := range b.dirs {
+		configs[k] = b.config
+		configs[k].InstancesDir = dir
+		configs[k].Solvers = append([]solvers.Solver(nil), b.config.Solvers...)
+		configs[k].Sinks = append([]Sink(nil), b.config.Sinks...)
+		if len(b.dirs) > 1 {
+			configs[k].OutputDir = filepath.Join(b.config.OutputDir, filepath.Base(filepath.Clean(dir)))
+		}
+	}
+	return configs, nil
+}
+
+// Run runs the experiments of Build in turn. With several instance
+// directories the outcome names instances by the directory's base name and
+// their name within it, e.g. "taillard/tai12a.dat". It stops at the first
+// experiment that fails.
+func (b *Builder) Run() (Outcome, error) {
+	configs, err := b.Build()
+	if err != nil {
+		return Outcome{}, err
+	}
+	if len(configs) == 1 {
+		return RunAll(configs[0])
+	}
+
+	total := Outcome{BestFitness: make(map[string]int)}
+	for _, config := range configs {
+		outcome, err := RunAll(config)
+		if err != nil {
+			return total, fmt.Errorf("%s: %v", config.InstancesDir, err)
+		}
+		prefix := filepath.Base(filepath.Clean(config.InstancesDir)) + "/"
+		for instanceName, fitness := range outcome.BestFitness {
+			total.BestFitness[prefix+instanceName] = fitness
+		}
+		total.JobsFailed += outcome.JobsFailed
+	}
+	return total, nil
+}
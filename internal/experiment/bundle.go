@@ -0,0 +1,270 @@
+package experiment
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/metrics"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Layout of a reproducibility bundle: a gzip compressed tar archive with the
+// manifest, the profile store of params=auto solvers if any, and the files
+// written by the experiment under BundleResultsDir
+const (
+	BundleManifest   = "manifest.json"
+	BundleProfiles   = "profiles.json"
+	BundleResultsDir = "results"
+)
+
+// Manifest describes how the results of a bundle were produced
+type Manifest struct {
+	Created   time.Time         `json:"created"`
+	Args      []string          `json:"args"`      // experiment flags, including the seed
+	Config    map[string]string `json:"config"`    // configuration the flags default to, see config.Defaults.Settings
+	Seed      int64             `json:"seed"`      // base seed of the runs, see ExperimentConfig.Seed
+	Instances map[string]string `json:"instances"` // SHA-256 of every instance file by instance name
+	Build     BuildInfo         `json:"build"`
+}
+
+// BuildInfo identifies the binary that ran the experiment
+type BuildInfo struct {
+	GoVersion string            `json:"go_version"`
+	Module    string            `json:"module"`
+	Version   string            `json:"version"`
+	OS        string            `json:"os"`
+	Arch      string            `json:"arch"`
+	Settings  map[string]string `json:"settings,omitempty"` // vcs revision, build flags and the like
+}
+
+// CurrentBuild returns the build information of the running binary
+func CurrentBuild() BuildInfo {
+	b := BuildInfo{GoVersion: runtime.Version(), OS: runtime.GOOS, Arch: runtime.GOARCH}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		b.Module, b.Version = info.Main.Path, info.Main.Version
+		b.Settings = make(map[string]string)
+		for _, s := range info.Settings {
+			b.Settings[s.Key] = s.Value
+		}
+	}
+	return b
+}
+
+// FileChecksum returns the hex encoded SHA-256 of a file
+func FileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// InstanceChecksums returns the checksum of the named instances of dir,
+// keyed by instance name
+func InstanceChecksums(dir string, names []string) (map[string]string, error) {
+	checksums := make(map[string]string)
+	for _, name := range names {
+		checksum, err := FileChecksum(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		checksums[name] = checksum
+	}
+	return checksums, nil
+}
+
+// WriteBundle archives the manifest, the profile store unless profiles is
+// nil and every file in resultsDir
+func WriteBundle(path string, manifest Manifest, profiles []byte, resultsDir string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	archive := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addToArchive(archive, BundleManifest, data); err != nil {
+		return err
+	}
+	if profiles != nil {
+		if err := addToArchive(archive, BundleProfiles, profiles); err != nil {
+			return err
+		}
+	}
+
+	err = filepath.WalkDir(resultsDir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		relative, err := filepath.Rel(resultsDir, p)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return addToArchive(archive, filepath.ToSlash(filepath.Join(BundleResultsDir, relative)), content)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func addToArchive(archive *tar.Writer, name string, content []byte) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: time.Now()}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := archive.Write(content)
+	return err
+}
+
+// ReadBundle extracts the results and the profile store of a bundle to dir
+// and returns its manifest
+func ReadBundle(path, dir string) (Manifest, error) {
+	var manifest Manifest
+	file, err := os.Open(path)
+	if err != nil {
+		return manifest, err
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return manifest, fmt.Errorf("%s: %v", path, err)
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	found := false
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return manifest, fmt.Errorf("%s: %v", path, err)
+		}
+		content, err := io.ReadAll(archive)
+		if err != nil {
+			return manifest, err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if name == BundleManifest {
+			if err := json.Unmarshal(content, &manifest); err != nil {
+				return manifest, fmt.Errorf("%s: invalid manifest: %v", path, err)
+			}
+			found = true
+			continue
+		}
+		// Entries must stay inside dir
+		if name != BundleProfiles && !strings.HasPrefix(name, BundleResultsDir+string(filepath.Separator)) ||
+			strings.Contains(name, "..") {
+			continue
+		}
+		target := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return manifest, err
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return manifest, err
+		}
+	}
+	if !found {
+		return manifest, fmt.Errorf("%s: no %s, not a bundle", path, BundleManifest)
+	}
+	return manifest, nil
+}
+
+// ResultsFile returns the results CSV written to an experiment output directory
+func ResultsFile(dir string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "results_*.csv"))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) != 1 {
+		return "", fmt.Errorf("expected one results file in %s, found %d", dir, len(matches))
+	}
+	return matches[0], nil
+}
+
+// ReplayDifference is a run whose replay did not reproduce the bundled outcome
+type ReplayDifference struct {
+	InstanceName string
+	SolverName   string
+	Run          int
+	Expected     string // bundled fitness and solution, "missing" if not bundled
+	Actual       string // replayed fitness and solution, "missing" if not replayed
+}
+
+// CompareRuns matches runs by instance, solver and run number and returns
+// those whose final fitness or solution differ, plus runs present on one
+// side only. Time-limited runs are rarely reproduced exactly.
+func CompareRuns(expected, actual []metrics.RunMetrics) []ReplayDifference {
+	type key struct {
+		instance, solver string
+		run              int
+	}
+	outcome := func(run metrics.RunMetrics) string {
+		return fmt.Sprintf("%d %v", run.FinalFitness, run.Solution)
+	}
+	replayed := make(map[key]metrics.RunMetrics)
+	for _, run := range actual {
+		replayed[key{run.InstanceName, run.SolverName, run.Run}] = run
+	}
+
+	var differences []ReplayDifference
+	for _, run := range expected {
+		k := key{run.InstanceName, run.SolverName, run.Run}
+		other, ok := replayed[k]
+		delete(replayed, k)
+		switch {
+		case !ok:
+			differences = append(differences, ReplayDifference{k.instance, k.solver, k.run, outcome(run), "missing"})
+		case outcome(run) != outcome(other):
+			differences = append(differences, ReplayDifference{k.instance, k.solver, k.run, outcome(run), outcome(other)})
+		}
+	}
+	for k, run := range replayed {
+		differences = append(differences, ReplayDifference{k.instance, k.solver, k.run, "missing", outcome(run)})
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		a, b := differences[i], differences[j]
+		if a.InstanceName != b.InstanceName {
+			return a.InstanceName < b.InstanceName
+		}
+		if a.SolverName != b.SolverName {
+			return a.SolverName < b.SolverName
+		}
+		return a.Run < b.Run
+	})
+	return differences
+}
@@ -0,0 +1,279 @@
+package experiment
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"sort"
+	"time"
+)
+
+// Verdicts of a challenger on an instance, see Challenge
+const (
+	ChallengeNew     = "new"     // no champion yet, the challenger becomes one
+	ChallengeBeats   = "beats"   // better mean objective, the challenger becomes the champion
+	ChallengeTies    = "ties"    // same mean objective, the champion stays
+	ChallengeLoses   = "loses"   // worse mean objective, the champion stays
+	ChallengeChanged = "changed" // the instance changed since the champion ran, the challenger replaces it
+)
+
+// Champion is the best solver configuration seen on an instance
+type Champion struct {
+	Instance string    `json:"instance"`
+	Hash     string    `json:"hash"`   // qap.ContentHash of the instance the runs were made on
+	Config   string    `json:"config"` // solver config, as given to -solvers
+	Maximize bool      `json:"maximize"`
+	Mean     float64   `json:"mean_objective"`
+	Best     int       `json:"best_objective"`
+	Runs     int       `json:"runs"`
+	Updated  time.Time `json:"updated"`
+}
+
+// better reports whether c has a better mean objective than other
+func (c Champion) better(other Champion) bool {
+	if c.Maximize {
+		return c.Mean > other.Mean
+	}
+	return c.Mean < other.Mean
+}
+
+// Champions is a store of champions kept in a JSON file, one per instance
+type Champions struct {
+	Entries []Champion `json:"champions"`
+}
+
+// LoadChampions reads a champion store. A missing file gives an empty store.
+func LoadChampions(path string) (*Champions, error) {
+	c := &Champions{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the store sorted by instance. The file is written under
+// another name and renamed, so a reader or an interrupted save never sees
+// half a store.
+func (c *Champions) Save(path string) error {
+	sort.Slice(c.Entries, func(i, j int) bool { return c.Entries[i].Instance < c.Entries[j].Instance })
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(append(data, '\n'))
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
+
+// Lookup returns the champion of an instance
+func (c *Champions) Lookup(instance string) (Champion, bool) {
+	for _, e := range c.Entries {
+		if e.Instance == instance {
+			return e, true
+		}
+	}
+	return Champion{}, false
+}
+
+// Set adds a champion, replacing the one of the same instance
+func (c *Champions) Set(champion Champion) {
+	for i, e := range c.Entries {
+		if e.Instance == champion.Instance {
+			c.Entries[i] = champion
+			return
+		}
+	}
+	c.Entries = append(c.Entries, champion)
+}
+
+// Challenge compares a challenger with the champion of its instance and
+// makes it the champion when it wins. A champion whose runs were made on a
+// different instance content, or with the other objective direction, cannot
+// be compared and is replaced.
+func (c *Champions) Challenge(challenger Champion) (verdict string, previous Champion) {
+	previous, ok := c.Lookup(challenger.Instance)
+	switch {
+	case !ok:
+		verdict = ChallengeNew
+	case previous.Hash != challenger.Hash || previous.Maximize != challenger.Maximize:
+		verdict = ChallengeChanged
+	case challenger.better(previous):
+		verdict = ChallengeBeats
+	case previous.better(challenger):
+		verdict = ChallengeLoses
+	default:
+		verdict = ChallengeTies
+	}
+	if verdict == ChallengeNew || verdict == ChallengeBeats || verdict == ChallengeChanged {
+		c.Set(challenger)
+	}
+	return verdict, previous
+}
+
+// ChallengeResult is the verdict of a challenger on one instance
+type ChallengeResult struct {
+	Verdict    string
+	Challenger Champion
+	Champion   Champion // the champion before the challenge, zero for ChallengeNew
+}
+
+// RunChallenge runs config, which must hold one solver given as spec, and
+// challenges the champions of the store at storePath with its runs. After
+// the runs the store is locked, read again, challenged and saved, so
+// challenges of other processes that finished meanwhile or finish at the
+// same time are kept. Runs that failed validation do not count.
+func RunChallenge(config ExperimentConfig, spec, storePath string) ([]ChallengeResult, Outcome, error) {
+	if len(config.Solvers) != 1 {
+		return nil, Outcome{}, fmt.Errorf("a challenge runs one solver, got %d", len(config.Solvers))
+	}
+	if _, err := LoadChampions(storePath); err != nil {
+		return nil, Outcome{}, err
+	}
+
+	var collector *metrics.MetricsCollector
+	config.Sinks = append(config.Sinks, collectorSink{&collector})
+	outcome, err := RunAll(config)
+	if err != nil || collector == nil {
+		return nil, outcome, err
+	}
+
+	sense := 1
+	if config.Maximize {
+		sense = -1
+	}
+	solverName := config.Solvers[0].Name()
+	var challengers []Champion
+	for instanceName, bySolver := range collector.Experiments {
+		experiment, ok := bySolver[solverName]
+		if !ok {
+			continue
+		}
+		instance, err := qap.ReadInstance(filepath.Join(config.InstancesDir, filepath.FromSlash(instanceName)))
+		if err != nil || prepareInstance(config, instance) != nil {
+			continue
+		}
+		challenger := Champion{Instance: instanceName, Hash: qap.ContentHash(instance), Config: spec,
+			Maximize: config.Maximize, Updated: time.Now().UTC()}
+		total := 0
+		for _, run := range experiment.Runs {
+			if run.ValidationError != "" {
+				continue
+			}
+			objective := sense * run.FinalFitness
+			if challenger.Runs == 0 || (objective < challenger.Best) != config.Maximize {
+				challenger.Best = objective
+			}
+			total += objective
+			challenger.Runs++
+		}
+		if challenger.Runs > 0 {
+			challenger.Mean = float64(total) / float64(challenger.Runs)
+			challengers = append(challengers, challenger)
+		}
+	}
+	sort.Slice(challengers, func(i, j int) bool { return challengers[i].Instance < challengers[j].Instance })
+
+	results, err := challengeStore(storePath, challengers)
+	return results, outcome, err
+}
+
+// challengeStore challenges the champions of the store at storePath with
+// challengers while holding the store's lock
+func challengeStore(storePath string, challengers []Champion) ([]ChallengeResult, error) {
+	unlock, err := lockStore(storePath)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	champions, err := LoadChampions(storePath)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]ChallengeResult, len(challengers))
+	for k, challenger := range challengers {
+		verdict, previous := champions.Challenge(challenger)
+		results[k] = ChallengeResult{Verdict: verdict, Challenger: challenger, Champion: previous}
+	}
+	if err := champions.Save(storePath); err != nil {
+		return results, fmt.Errorf("error saving champions: %v", err)
+	}
+	return results, nil
+}
+
+// Lock file settings of the champion store. Holders only load, challenge
+// and save the store, so a lock older than championLockStale was left
+// behind by a process that died and is broken.
+const (
+	championLockTimeout = 30 * time.Second
+	championLockStale   = time.Minute
+	championLockPoll    = 50 * time.Millisecond
+)
+
+// lockStore takes the lock of the store at path, a file next to it created
+// exclusively, waiting for other holders up to championLockTimeout. The
+// returned function releases it.
+func lockStore(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(championLockTimeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > championLockStale {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("champion store %s is locked by another process, remove %s if none is running",
+				path, lockPath)
+		}
+		time.Sleep(championLockPoll)
+	}
+}
+
+// collectorSink keeps the collector of an experiment once it is done
+type collectorSink struct {
+	collector **metrics.MetricsCollector
+}
+
+func (s collectorSink) AddRun(metrics.RunMetrics) {}
+
+func (s collectorSink) Done(collector *metrics.MetricsCollector) error {
+	*s.collector = collector
+	return nil
+}
@@ -0,0 +1,69 @@
+package experiment
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestChallengeStoreConcurrent lets challengers of different instances
+// update one store at the same time; every one of them must be kept
+func TestChallengeStoreConcurrent(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "champions.json")
+	const challengers = 16
+	var wg sync.WaitGroup
+	for k := 0; k < challengers; k++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			challenger := Champion{Instance: fmt.Sprintf("inst%02d.dat", k), Hash: "h", Config: "tabu", Mean: 10, Best: 10, Runs: 1}
+			results, err := challengeStore(storePath, []Champion{challenger})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if results[0].Verdict != ChallengeNew {
+				t.Errorf("%s: verdict %s, want %s", challenger.Instance, results[0].Verdict, ChallengeNew)
+			}
+		}()
+	}
+	wg.Wait()
+
+	champions, err := LoadChampions(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(champions.Entries) != challengers {
+		t.Fatalf("the store keeps %d champions, want %d", len(champions.Entries), challengers)
+	}
+	if _, err := os.Stat(storePath + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("the lock file remains: %v", err)
+	}
+}
+
+// TestChallengeStoreStaleLock breaks a lock left behind by a crashed process
+func TestChallengeStoreStaleLock(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "champions.json")
+	lockPath := storePath + ".lock"
+	if err := os.WriteFile(lockPath, []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * championLockStale)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	challenger := Champion{Instance: "nug12.dat", Hash: "h", Config: "tabu", Mean: 5, Best: 5, Runs: 1}
+	if _, err := challengeStore(storePath, []Champion{challenger}); err != nil {
+		t.Fatal(err)
+	}
+	champions, err := LoadChampions(storePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := champions.Lookup("nug12.dat"); !ok {
+		t.Error("the challenger was not stored")
+	}
+}
@@ -0,0 +1,213 @@
+package experiment
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"qap_solver/internal/metrics"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ControlPollInterval is how often the control file is checked for changes
+const ControlPollInterval = time.Second
+
+// controlAll is the solver name addressing every solver in a control file
+const controlAll = "all"
+
+// Control holds the settings of a running experiment that can be changed by
+// editing a control file. The file describes the desired state, one
+// directive per line:
+//
+//	timelimit <solver|all> <duration>   per-run time limit, e.g. 30s, 0 removes it
+//	abort <solver|all>                  skip the remaining runs
+//
+// Solver names are matched like -trace, case-insensitively and without
+// spaces; a solver-specific line overrides an "all" line. Changes apply to
+// runs that start after the file is reloaded and are recorded as control
+// events of the metrics collector.
+type Control struct {
+	path      string
+	logger    *log.Logger
+	collector *metrics.MetricsCollector
+
+	mu         sync.Mutex
+	modTime    time.Time
+	timeLimits map[string]time.Duration
+	aborted    map[string]bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// WatchControl reads the control file, which may not exist yet, and polls it
+// for changes until Close is called
+func WatchControl(path string, logger *log.Logger, collector *metrics.MetricsCollector) *Control {
+	c := &Control{
+		path:       path,
+		logger:     logger,
+		collector:  collector,
+		timeLimits: make(map[string]time.Duration),
+		aborted:    make(map[string]bool),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	c.reload()
+
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(ControlPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				c.reload()
+			}
+		}
+	}()
+	return c
+}
+
+// Close stops watching the control file. It is a no-op on a nil Control.
+func (c *Control) Close() {
+	if c == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+}
+
+// TimeLimit returns the per-run time limit of a solver, zero when unlimited
+func (c *Control) TimeLimit(solverName string) time.Duration {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if limit, ok := c.timeLimits[controlKey(solverName)]; ok {
+		return limit
+	}
+	return c.timeLimits[controlAll]
+}
+
+// Aborted reports whether the remaining runs of a solver are to be skipped
+func (c *Control) Aborted(solverName string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.aborted[controlKey(solverName)] || c.aborted[controlAll]
+}
+
+// reload rereads the control file when it changed and records the differences
+func (c *Control) reload() {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return // The file may be created later
+	}
+	if info.ModTime().Equal(c.modTime) {
+		return
+	}
+
+	timeLimits, aborted, err := readControl(c.path)
+	if err != nil {
+		c.logger.Printf("Ignoring control file %s: %v", c.path, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.modTime = info.ModTime()
+
+	now := time.Now()
+	record := func(command, solver, value string) {
+		c.logger.Printf("Control: %s %s %s", command, solver, value)
+		c.collector.AddControlEvent(metrics.ControlEvent{Time: now, Command: command, Solver: solver, Value: value})
+	}
+	for _, solver := range unionKeys(c.timeLimits, timeLimits) {
+		old, hadOld := c.timeLimits[solver]
+		limit, hasNew := timeLimits[solver]
+		switch {
+		case !hasNew:
+			record("timelimit", solver, "none")
+		case !hadOld || old != limit:
+			record("timelimit", solver, limit.String())
+		}
+	}
+	for _, solver := range unionKeys(c.aborted, aborted) {
+		if aborted[solver] && !c.aborted[solver] {
+			record("abort", solver, "")
+		} else if !aborted[solver] && c.aborted[solver] {
+			record("resume", solver, "")
+		}
+	}
+
+	c.timeLimits, c.aborted = timeLimits, aborted
+}
+
+// readControl parses a control file, see Control
+func readControl(path string) (map[string]time.Duration, map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	timeLimits := make(map[string]time.Duration)
+	aborted := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToLower(fields[0]) {
+		case "timelimit":
+			if len(fields) != 3 {
+				return nil, nil, fmt.Errorf("line %d: expected timelimit <solver> <duration>", lineNumber)
+			}
+			limit, err := time.ParseDuration(fields[2])
+			if err != nil || limit < 0 {
+				return nil, nil, fmt.Errorf("line %d: invalid duration %q", lineNumber, fields[2])
+			}
+			timeLimits[controlKey(fields[1])] = limit
+		case "abort":
+			if len(fields) != 2 {
+				return nil, nil, fmt.Errorf("line %d: expected abort <solver>", lineNumber)
+			}
+			aborted[controlKey(fields[1])] = true
+		default:
+			return nil, nil, fmt.Errorf("line %d: unknown directive %q", lineNumber, fields[0])
+		}
+	}
+	return timeLimits, aborted, scanner.Err()
+}
+
+// controlKey normalizes a solver name the way OpenTrace matches -trace
+func controlKey(solverName string) string {
+	return strings.ToLower(strings.ReplaceAll(solverName, " ", ""))
+}
+
+// unionKeys returns the keys of both maps in sorted order
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
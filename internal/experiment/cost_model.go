@@ -0,0 +1,341 @@
+package experiment
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"qap_solver/internal/solvers"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PowerFit is the curve y = A * n^B, fitted by least squares on log-log
+// scale. B is the empirical complexity exponent, about 2 for a full fitness
+// evaluation and 1 for a swap delta.
+type PowerFit struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+}
+
+// FitPower fits a PowerFit to positive measurements at two or more sizes
+func FitPower(sizes []int, values []float64) (PowerFit, error) {
+	var xs, ys []float64
+	for i, n := range sizes {
+		if n > 0 && values[i] > 0 {
+			xs = append(xs, math.Log(float64(n)))
+			ys = append(ys, math.Log(values[i]))
+		}
+	}
+	if len(xs) < 2 {
+		return PowerFit{}, fmt.Errorf("need positive measurements at two or more sizes, got %d", len(xs))
+	}
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i]
+		my += ys[i]
+	}
+	mx /= float64(len(xs))
+	my /= float64(len(ys))
+	var sxy, sxx float64
+	for i := range xs {
+		sxy += (xs[i] - mx) * (ys[i] - my)
+		sxx += (xs[i] - mx) * (xs[i] - mx)
+	}
+	if sxx == 0 {
+		return PowerFit{}, fmt.Errorf("need measurements at two or more different sizes")
+	}
+	b := sxy / sxx
+	return PowerFit{A: math.Exp(my - b*mx), B: b}, nil
+}
+
+// At evaluates the curve at size n
+func (f PowerFit) At(n int) float64 {
+	return f.A * math.Pow(float64(n), f.B)
+}
+
+func (f PowerFit) String() string {
+	return fmt.Sprintf("%.3g * n^%.2f", f.A, f.B)
+}
+
+// SolverCost is the calibration of one solver configuration
+type SolverCost struct {
+	Config      string   `json:"config"` // the -solvers entry, predictions look it up by this
+	Name        string   `json:"name"`
+	Evaluations PowerFit `json:"evaluations"` // evaluations per run
+	RunSeconds  PowerFit `json:"run_seconds"` // wall time per run
+}
+
+// HostCostModel holds the measurements of one host: how the time of a fitness
+// evaluation and of the calibrated solvers' runs grows with the instance size
+type HostCostModel struct {
+	Host              string       `json:"host"`
+	Calibrated        time.Time    `json:"calibrated"`
+	Sizes             []int        `json:"sizes"`
+	FullEvaluationNs  PowerFit     `json:"full_evaluation_ns"`  // one qap.CalculateFitness
+	DeltaEvaluationNs PowerFit     `json:"delta_evaluation_ns"` // one qap.SwapDelta
+	Solvers           []SolverCost `json:"solvers,omitempty"`
+}
+
+// Solver returns the calibration of a solver configuration, ignoring its
+// label. Failing that, a calibration of another configuration of the same
+// solver type is returned with exact false, its prediction ignores the
+// differing parameters.
+func (m *HostCostModel) Solver(config string) (cost SolverCost, exact, ok bool) {
+	for _, s := range m.Solvers {
+		if configKey(s.Config) == configKey(config) {
+			return s, true, true
+		}
+	}
+	for _, s := range m.Solvers {
+		if configType(s.Config) == configType(config) {
+			return s, false, true
+		}
+	}
+	return SolverCost{}, false, false
+}
+
+// configKey is a solver configuration without its @label
+func configKey(config string) string {
+	config, _, _ = strings.Cut(config, "@")
+	return strings.ToLower(strings.ReplaceAll(config, " ", ""))
+}
+
+// configType is the solver type of a configuration, e.g. "tabu"
+func configType(config string) string {
+	solverType, _, _ := strings.Cut(configKey(config), ":")
+	return solverType
+}
+
+// setSolver adds or replaces the calibration of a configuration
+func (m *HostCostModel) setSolver(cost SolverCost) {
+	for i, s := range m.Solvers {
+		if s.Config == cost.Config {
+			m.Solvers[i] = cost
+			return
+		}
+	}
+	m.Solvers = append(m.Solvers, cost)
+}
+
+// CostModels is the store of host cost models, one per host name, so a store
+// shared between machines predicts with the measurements of the local one
+type CostModels struct {
+	Hosts map[string]*HostCostModel `json:"hosts"`
+}
+
+// LoadCostModels reads a cost model store. A missing file gives an empty store.
+func LoadCostModels(path string) (*CostModels, error) {
+	c := &CostModels{Hosts: make(map[string]*HostCostModel)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if c.Hosts == nil {
+		c.Hosts = make(map[string]*HostCostModel)
+	}
+	return c, nil
+}
+
+// Save writes the store
+func (c *CostModels) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Host returns the model of the local host, see os.Hostname
+func (c *CostModels) Host() (*HostCostModel, bool) {
+	m, ok := c.Hosts[hostName()]
+	return m, ok
+}
+
+// Local returns the model of the local host, adding an empty one to the store
+// when it has none
+func (c *CostModels) Local() *HostCostModel {
+	host := hostName()
+	if c.Hosts[host] == nil {
+		c.Hosts[host] = &HostCostModel{Host: host}
+	}
+	return c.Hosts[host]
+}
+
+func hostName() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "localhost"
+	}
+	return host
+}
+
+// EvaluationTiming is the measured time of one evaluation at a size
+type EvaluationTiming struct {
+	Size    int
+	FullNs  float64 // ns per full fitness evaluation
+	DeltaNs float64 // ns per swap delta
+}
+
+// measureFor is how long every evaluation kind is repeated per size
+const measureFor = 50 * time.Millisecond
+
+// evaluationSink keeps the measured evaluations from being optimized away
+var evaluationSink int
+
+// MeasureEvaluations times full fitness evaluations and swap deltas on
+// uniform random instances of the given sizes
+func MeasureEvaluations(sizes []int, seed int64) ([]EvaluationTiming, error) {
+	var timings []EvaluationTiming
+	for _, n := range sizes {
+		generated, err := qap.Generate(qap.GeneratorOptions{Family: qap.FamilyUniform, Size: n, Seed: seed})
+		if err != nil {
+			return nil, err
+		}
+		instance := generated.Instance
+		rng := rand.New(rand.NewSource(seed))
+		solution := solvers.RandomSolutionFrom(rng, n)
+
+		timing := EvaluationTiming{Size: n}
+		sink := 0
+		ops, start := 0, time.Now()
+		for time.Since(start) < measureFor {
+			for k := 0; k < 16; k++ {
+				sink += qap.CalculateFitness(instance, solution)
+			}
+			ops += 16
+		}
+		timing.FullNs = float64(time.Since(start).Nanoseconds()) / float64(ops)
+
+		ops, start = 0, time.Now()
+		for time.Since(start) < measureFor {
+			for k := 0; k < 256; k++ {
+				i, j := rng.Intn(n), rng.Intn(n)
+				sink += qap.SwapDelta(instance, solution, i, j)
+			}
+			ops += 256
+		}
+		timing.DeltaNs = float64(time.Since(start).Nanoseconds()) / float64(ops)
+		evaluationSink += sink
+		timings = append(timings, timing)
+	}
+	return timings, nil
+}
+
+// SolverTiming is one measured run of a solver at a size
+type SolverTiming struct {
+	Size        int
+	Evaluations int
+	Time        time.Duration
+	Termination string // the run's termination reason, a time limit spoils the fit
+}
+
+// MeasureSolver runs a solver once per size on uniform random instances,
+// every run limited to maxRun when positive
+func MeasureSolver(solver solvers.Solver, sizes []int, seed int64, maxRun time.Duration) ([]SolverTiming, error) {
+	inner, ok := solver.(MetricsSolver)
+	if !ok {
+		return nil, fmt.Errorf("%s does not record metrics", solver.Name())
+	}
+	var timings []SolverTiming
+	for _, n := range sizes {
+		generated, err := qap.Generate(qap.GeneratorOptions{Family: qap.FamilyUniform, Size: n, Seed: seed})
+		if err != nil {
+			return nil, err
+		}
+		collector := &metrics.MetricsCollector{Experiments: make(map[string]map[string]*metrics.ExperimentMetrics)}
+		inner.SolveWithMetrics(generated.Instance, collector, "calibration", 1, solvers.SolveOptions{Seed: seed, TimeLimit: maxRun})
+		for _, experiment := range collector.Experiments["calibration"] {
+			run := experiment.Runs[0]
+			timings = append(timings, SolverTiming{n, run.EvaluationsCount, run.TimeElapsed, run.TerminationReason})
+		}
+	}
+	return timings, nil
+}
+
+// FitEvaluations fits the evaluation curves of a host model
+func (m *HostCostModel) FitEvaluations(timings []EvaluationTiming) error {
+	sizes := make([]int, len(timings))
+	full := make([]float64, len(timings))
+	delta := make([]float64, len(timings))
+	for i, t := range timings {
+		sizes[i], full[i], delta[i] = t.Size, t.FullNs, t.DeltaNs
+	}
+	var err error
+	if m.FullEvaluationNs, err = FitPower(sizes, full); err != nil {
+		return err
+	}
+	m.DeltaEvaluationNs, err = FitPower(sizes, delta)
+	m.Sizes = sizes
+	return err
+}
+
+// FitSolver fits and stores the curves of a solver configuration
+func (m *HostCostModel) FitSolver(config, name string, timings []SolverTiming) (SolverCost, error) {
+	sizes := make([]int, len(timings))
+	evaluations := make([]float64, len(timings))
+	seconds := make([]float64, len(timings))
+	for i, t := range timings {
+		sizes[i], evaluations[i], seconds[i] = t.Size, float64(t.Evaluations), t.Time.Seconds()
+	}
+	cost := SolverCost{Config: config, Name: name}
+	var err error
+	if cost.RunSeconds, err = FitPower(sizes, seconds); err != nil {
+		return cost, err
+	}
+	if cost.Evaluations, err = FitPower(sizes, evaluations); err != nil {
+		// Solvers without evaluations, e.g. constructions, still predict their time
+		cost.Evaluations = PowerFit{}
+	}
+	m.setSolver(cost)
+	return cost, nil
+}
+
+// Prediction is the predicted duration of an experiment
+type Prediction struct {
+	Runs      int
+	RunTime   time.Duration // summed over all runs
+	WallTime  time.Duration // RunTime shared by the parallel workers, capped by the time budget
+	PerSolver map[string]time.Duration
+	Missing   []string // solver configurations without calibration
+	Inexact   []string // configurations predicted with another configuration of the solver
+}
+
+// Predict estimates the duration of running every solver configuration runs
+// times on instances of the given sizes. configs and names list the -solvers
+// entries and the names of their solvers.
+func (m *HostCostModel) Predict(configs, names []string, sizes []int, runs, parallelism int, budget time.Duration) Prediction {
+	p := Prediction{PerSolver: make(map[string]time.Duration)}
+	for k, config := range configs {
+		cost, exact, ok := m.Solver(config)
+		if !ok {
+			p.Missing = append(p.Missing, config)
+			continue
+		}
+		if !exact {
+			p.Inexact = append(p.Inexact, config)
+		}
+		for _, n := range sizes {
+			d := time.Duration(cost.RunSeconds.At(n) * float64(runs) * float64(time.Second))
+			p.PerSolver[names[k]] += d
+			p.RunTime += d
+			p.Runs += runs
+		}
+	}
+	p.WallTime = p.RunTime / time.Duration(max(parallelism, 1))
+	if budget > 0 && p.WallTime > budget {
+		p.WallTime = budget
+	}
+	sort.Strings(p.Missing)
+	return p
+}
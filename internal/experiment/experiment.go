@@ -1,14 +1,20 @@
 package experiment
 
 import (
+	"cmp"
 	"fmt"
+	"hash/fnv"
+	"io/fs"
 	"log"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"qap_solver/internal/metrics"
 	"qap_solver/internal/qap"
 	"qap_solver/internal/solvers"
 	"strings"
+	"sync"
+	"time"
 )
 
 // ExperimentConfig holds configuration for running experiments
@@ -18,28 +24,286 @@ type ExperimentConfig struct {
 	OutputDir       string
 	Solvers         []solvers.Solver
 	RunsPerInstance int
+	Parallelism     int    // number of runs executed concurrently, at least 1
+	MemoryLimit     uint64 // soft limit on the process heap in bytes, checked by every run, 0 disables it
 	Logger          *log.Logger
+	Output          metrics.OutputOptions // number formatting of the CSV files
+
+	// ControlFile is polled while the experiment runs; editing it changes
+	// per-run time limits or aborts the remaining runs of a solver, see Control
+	ControlFile string
+
+	// Filter selects the instances of InstancesDir. With a recursive search
+	// instances are named by their path relative to InstancesDir.
+	Filter InstanceFilter
+
+	// Validate recomputes the fitness of every stored solution after all runs
+	// and flags runs whose recorded fitness does not match
+	Validate bool
+
+	// Robustness analysis: when RobustnessScenarios is positive the final
+	// solution of every run is evaluated on that many copies of the instance
+	// whose flows are perturbed by up to ±RobustnessNoise (0.1 = 10%)
+	RobustnessNoise     float64
+	RobustnessScenarios int
+
+	// Two-phase experiment: when ScreeningTimeLimit is positive every solver
+	// first makes ScreeningRuns runs of at most that long on every instance.
+	// Only the ScreeningTopK solvers with the smallest mean gap on an instance
+	// then make RunsPerInstance regular runs on it.
+	ScreeningTimeLimit time.Duration
+	ScreeningRuns      int
+	ScreeningTopK      int
+
+	// Racing interleaves the runs of the solvers on an instance and stops a
+	// solver once a Hoeffding bound shows, with RacingConfidence, that another
+	// solver has a lower mean final fitness. Both need RacingMinRuns runs.
+	Racing           bool
+	RacingConfidence float64
+	RacingMinRuns    int
+
+	// StopAtOptimum skips the remaining runs of a solver on an instance once
+	// one of its runs reached the known optimum, counting them in the
+	// SkippedRuns column of the summary
+	StopAtOptimum bool
+
+	// Adaptive run count: when AdaptiveCI is positive a solver keeps running
+	// on an instance until the 95% confidence interval of its final fitness
+	// is within ±AdaptiveCI percent of the mean, or it made MaxRuns runs.
+	// RunsPerInstance, at least 2, are made before the interval is checked.
+	AdaptiveCI float64
+	MaxRuns    int
+
+	// Weighted run allocation: when WeightedPilot is positive every solver
+	// first makes WeightedPilot runs on every instance, then the rest of its
+	// budget of RunsPerInstance runs per instance goes to the instances whose
+	// pilot final fitness varies most, at most MaxRuns runs on each, see
+	// allocateRuns
+	WeightedPilot int
+	allocation    map[string]map[string]runSpan // runs of the second weighted phase
+
+	// Strict fails the experiment before any run when it would otherwise
+	// degrade silently: a solver that records no metrics or ignores a
+	// requested feature, an instance that fails to load, or an instance
+	// without a .sln optimum, see strictProblems
+	Strict bool
+
+	// TimeBudget bounds the whole experiment when positive: every regular run
+	// is limited to an equal share of what is left of it when the run starts
+	TimeBudget time.Duration
+
+	// Stop ends every run early once the condition holds, see
+	// solvers.StopCondition. Its gap refers to the optima of the .sln files.
+	Stop *solvers.StopCondition
+
+	// TrajectoryPoints bounds the trajectory stored per run, 0 keeps all points
+	TrajectoryPoints int
+
+	// Trace names the solver whose accepted moves are written to per-run files
+	// in OutputDir/traces, "all" traces every solver and "" disables tracing
+	Trace string
+
+	// Streaming processes one instance at a time for batches of large
+	// instances: the runs of an instance finish before the next one is
+	// loaded, then its matrices, flow scenarios and robustness copies are
+	// released and garbage collected. The heap per instance is written to
+	// memory_<timestamp>.csv.
+	Streaming bool
+
+	// Diagnostics writes tabu heatmaps and annealing temperature curves of
+	// every run to OutputDir/diagnostics, see metrics.Diagnostics
+	Diagnostics bool
+
+	// Artifacts lets solvers dump algorithm-specific state of every run to
+	// OutputDir/artifacts/<run>, referenced by the Artifacts column of the
+	// results file, see metrics.Artifacts
+	Artifacts bool
+
+	// ConstructionCache is the directory where the solutions of deterministic
+	// constructions are kept across experiments, "" keeps none, see
+	// solvers.ConstructionCache and ConstructionCacheDir
+	ConstructionCache string
+
+	// Verbosity is how much of their internal progress solvers log to the
+	// run's log lines, from solvers.VerbosityQuiet to solvers.VerbositySamples
+	Verbosity int
+
+	// Preflight checks every instance before the runs start and lists its
+	// problems, see qap.CheckSanity. Instances larger than PreflightMaxSize
+	// count as a problem (0 = DefaultPreflightMaxSize, negative = any size).
+	// When problems are found Confirm decides whether the experiment goes on;
+	// a nil Confirm goes on.
+	Preflight        bool
+	PreflightMaxSize int
+	Confirm          func(problems []PreflightProblem) bool
+
+	// JobLogs writes the log lines of every run to its own file in
+	// OutputDir/logs as well, see openJobLog
+	JobLogs bool
+
+	// FlushInterval writes the results and summary of the runs finished so
+	// far every interval when positive, so an interrupted experiment keeps
+	// them, see metrics.MetricsCollector.Flush
+	FlushInterval time.Duration
+
+	// Result files are written again after I/O errors, up to IORetries times
+	// (0 = DefaultIORetries, negative = never) with a wait starting at
+	// IOBackoff and doubling every time. When they keep failing the results
+	// go to a local temporary directory and are moved to OutputDir at the
+	// end of the run, see resultWriter.
+	IORetries int
+	IOBackoff time.Duration
+
+	// Re-layout mode: when CurrentLayout is set every instance is solved as a
+	// weighted sum of QAP cost and relocation cost, see qap.SetRelocation
+	CurrentLayout    []int
+	RelocationCosts  [][]int
+	RelocationWeight float64
+
+	// Constraints are attached to every instance when set, see qap.ReadConstraints.
+	// ForbiddenStrategy selects how their forbidden pairs are handled, see
+	// solvers.ForbiddenPenalty and solvers.ForbiddenHard.
+	Constraints       *qap.Constraints
+	ForbiddenStrategy string
+
+	// FlowUncertainty makes the flows of every instance uncertain, solvers
+	// then optimize an expected or percentile cost, see qap.SetFlowUncertainty
+	FlowUncertainty *qap.FlowUncertainty
+
+	// Names label facilities and locations. When set, the best solution of
+	// every instance is also written to solution_pretty_<timestamp>.txt and
+	// listed in the HTML report.
+	Names *qap.Names
+
+	// ZeroDiagonals clears nonzero diagonals (self-flows and self-distances)
+	// of every instance, see qap.QAPInstance.ZeroDiagonals. When both
+	// diagonals are nonzero this changes the costs, so .sln values no longer
+	// apply and no new best solutions are recorded.
+	ZeroDiagonals bool
+
+	// Maximize turns every instance into a maximization problem, see
+	// qap.QAPInstance.Maximize. Result files then show objective values.
+	Maximize bool
+
+	// Fitness and Delta replace the standard objective for library users, see
+	// solvers.SolveOptions. Validation and robustness analysis use Fitness too.
+	Fitness solvers.FitnessFunc
+	Delta   solvers.DeltaFunc
+
+	// Seed makes the runs reproducible when non-zero, every run gets a seed
+	// derived from it. With CommonRandomNumbers run k of every solver on an
+	// instance uses the same seed, and the experiment writes paired tests
+	// comparing the solvers run by run.
+	Seed                int64
+	CommonRandomNumbers bool
+
+	// AnomalyZ flags runs whose last improvement lies this many standard
+	// deviations before the mean of the other runs of their solver on the
+	// instance in OutputDir/anomalies_<timestamp>.csv, see
+	// metrics.MetricsCollector.Anomalies. Zero uses metrics.DefaultAnomalyZ,
+	// negative values write no file.
+	AnomalyZ float64
+
+	// Sinks receive every run as it finishes and the collector with all
+	// results at the end, besides the result files, see Sink
+	Sinks []Sink
+
+	// Session names the experiment and notes its purpose. RunAll completes it
+	// with the start time and solvers and writes it to session_<timestamp>.json
+	// when the experiment starts, and again with the run count when it ends.
+	Session metrics.Session
+}
+
+// Outcome summarizes a finished experiment
+type Outcome struct {
+	BestFitness map[string]int // best objective value found per instance
+	JobsFailed  int            // runs lost to instances that failed to load, plus runs failing validation
 }
 
 // RunAll runs experiments on all instances with all solvers
-func RunAll(config ExperimentConfig) error {
+func RunAll(config ExperimentConfig) (Outcome, error) {
+	start := time.Now()
+
 	// Create metrics collector
 	metricsCollector := metrics.NewMetricsCollector(config.OutputDir)
+	metricsCollector.Maximize = config.Maximize
+	metricsCollector.AnomalyZ = config.AnomalyZ
+	metricsCollector.Names = config.Names
+	session := config.Session
+	session.Started = start
+	for _, solver := range config.Solvers {
+		session.Solvers = append(session.Solvers, solver.Name())
+	}
+	metricsCollector.Session = &session
+	if config.Output.TimeUnit != "" {
+		metricsCollector.Output = config.Output
+	}
+	if len(config.Sinks) > 0 {
+		metricsCollector.OnRun = func(run metrics.RunMetrics) {
+			for _, sink := range config.Sinks {
+				sink.AddRun(run)
+			}
+		}
+	}
+	solveOptions := solvers.SolveOptions{
+		MemoryLimit:      config.MemoryLimit,
+		TrajectoryPoints: config.TrajectoryPoints,
+		Stop:             config.Stop,
+		Forbidden:        config.ForbiddenStrategy,
+		Fitness:          config.Fitness,
+		Delta:            config.Delta,
+	}
+	if config.ConstructionCache != "" {
+		solveOptions.Constructions = solvers.NewConstructionCache(config.ConstructionCache)
+	}
+
+	// Runs are keyed by solver name, a shared name would mix two solvers' runs
+	names := make(map[string]bool)
+	for _, solver := range config.Solvers {
+		if names[solver.Name()] {
+			return Outcome{}, fmt.Errorf("more than one solver is named %s, label them to tell them apart", solver.Name())
+		}
+		names[solver.Name()] = true
+	}
 
 	// Get list of instance files
-	instanceFiles, err := findInstanceFiles(config.InstancesDir)
+	instanceFiles, err := FindInstanceFiles(config.InstancesDir, config.Filter)
 	if err != nil {
-		return fmt.Errorf("error finding instance files: %v", err)
+		return Outcome{}, fmt.Errorf("error finding instance files: %v", err)
 	}
 
 	if len(instanceFiles) == 0 {
-		return fmt.Errorf("no instance files found in %s", config.InstancesDir)
+		return Outcome{}, fmt.Errorf("no instance files found in %s", config.InstancesDir)
 	}
 
 	config.Logger.Printf("Found %d instance files", len(instanceFiles))
 
+	results := newResultWriter(config, metricsCollector)
+	if err := results.save("the session", metricsCollector.SaveSession); err != nil {
+		return Outcome{}, fmt.Errorf("error saving session: %v", err)
+	}
+	if session.Name != "" {
+		config.Logger.Printf("Session %s", session.Name)
+	}
+
+	if !config.Strict {
+		for _, warning := range capabilityWarnings(config) {
+			config.Logger.Printf("Warning: %s", warning)
+		}
+	}
+	// Heap samples are process-wide, so parallel runs share one heap
+	if config.MemoryLimit > 0 && config.Parallelism > 1 {
+		config.Logger.Printf("Warning: the memory limit applies to the heap of the whole process, shared by %d parallel runs; "+
+			"whichever run samples it first stops, use -parallel 1 to limit each run", config.Parallelism)
+	}
+
+	metricsCollector.Optima, err = qap.LoadOptimalSolutions(config.InstancesDir)
+	if err != nil {
+		config.Logger.Printf("Could not load optimal solutions: %v", err)
+	}
+
 	if config.InstanceSample > len(instanceFiles) {
-		return fmt.Errorf("sample was provided, but sample exceeds the total number of instance files")
+		return Outcome{}, fmt.Errorf("sample was provided, but sample exceeds the total number of instance files")
 	}
 
 	if config.InstanceSample > 0 {
@@ -47,75 +311,910 @@ func RunAll(config ExperimentConfig) error {
 		instanceFiles = instanceFiles[:config.InstanceSample]
 	}
 
+	if config.Preflight {
+		problems := preflight(config, instanceFiles)
+		for _, p := range problems {
+			config.Logger.Printf("Pre-flight: %s %s", p.InstanceName, p.Problem)
+		}
+		if len(problems) == 0 {
+			config.Logger.Printf("Pre-flight: %d instances checked, no problems found", len(instanceFiles))
+		} else if config.Confirm != nil && !config.Confirm(problems) {
+			return Outcome{}, fmt.Errorf("aborted after the pre-flight check found %d problems", len(problems))
+		}
+	}
+
+	// Per-run files are named by instance stems, fixed here in the order of
+	// the instance files so colliding names get the same suffix every time
+	for _, instanceFile := range instanceFiles {
+		metricsCollector.FileStem(InstanceName(config.InstancesDir, instanceFile))
+	}
+	for _, collision := range metricsCollector.FilenameCollisions() {
+		config.Logger.Printf("Warning: output file names collide: %s", collision)
+	}
+
+	if config.Strict {
+		if problems := strictProblems(config, instanceFiles, metricsCollector.Optima); len(problems) > 0 {
+			for _, problem := range problems {
+				config.Logger.Printf("Strict: %s", problem)
+			}
+			return Outcome{}, fmt.Errorf("strict mode: %d problems found, see the log", len(problems))
+		}
+	}
+
+	var control *Control
+	if config.ControlFile != "" {
+		control = WatchControl(config.ControlFile, config.Logger, metricsCollector)
+	}
+	defer control.Close()
+
+	flushing := startFlushing(config, metricsCollector)
+	defer flushing.close()
+
+	// Two-phase experiment: short screening runs decide which solvers are
+	// evaluated on each instance
+	var selected map[string]map[string]bool
+	if config.ScreeningTimeLimit > 0 {
+		selected, err = runScreening(config, instanceFiles, metricsCollector, solveOptions, control)
+		if err != nil {
+			return Outcome{}, err
+		}
+	}
+
+	// The runs of the final phase share the remaining time budget
+	var deadline time.Time
+	if config.TimeBudget > 0 {
+		deadline = start.Add(config.TimeBudget)
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return Outcome{}, fmt.Errorf("time budget of %v exhausted before the evaluation runs", config.TimeBudget)
+		}
+		config.Logger.Printf("Time budget: %v left for %d runs", remaining.Round(time.Millisecond),
+			countRuns(config, instanceFiles, selected))
+	}
+
+	outcome := Outcome{BestFitness: make(map[string]int)}
+	var memory *memoryReport
+	if config.Streaming {
+		memory = &memoryReport{}
+		releaseMemory()
+	}
+	load := newLoadReport(max(config.Parallelism, 1), countRuns(config, instanceFiles, selected))
+	if config.weighted() {
+		outcome.JobsFailed = runWeighted(config, instanceFiles, metricsCollector, selected,
+			func(phase ExperimentConfig, phaseSelected map[string]map[string]bool) int {
+				return runPhase(phase, instanceFiles, metricsCollector, solveOptions, control, phaseSelected, deadline, memory, load)
+			})
+	} else {
+		outcome.JobsFailed = runPhase(config, instanceFiles, metricsCollector, solveOptions, control, selected, deadline, memory, load)
+	}
+	if report, ok := load.finish(); ok {
+		config.Logger.Printf("Parallelism: %s", report)
+	}
+	if top, ok := memory.peak(); ok {
+		config.Logger.Printf("Streaming: peak heap %.1f MB on %s (n=%d)", float64(top.peak)/(1<<20), top.instanceName, top.size)
+	}
+	// The final files replace the partial ones. Flushing stops before any
+	// of them is written, as a failed write may change the output directory.
+	flushing.close()
+
+	if err := results.save("the memory report", func() error {
+		return memory.save(results.dir(), metricsCollector.Timestamp)
+	}); err != nil {
+		return Outcome{}, fmt.Errorf("error saving memory report: %v", err)
+	}
+
+	if skipped := metricsCollector.TotalSkippedRuns(); skipped > 0 {
+		config.Logger.Printf("Stop at optimum: %d runs skipped after reaching the known optimum", skipped)
+	}
+
+	if hits, misses, invalidated := solveOptions.Constructions.Stats(); hits+misses > 0 {
+		config.Logger.Printf("Construction cache: %d constructions reused, %d built (%d replaced for changed instances) in %s",
+			hits, misses, invalidated, solveOptions.Constructions.Dir())
+	}
+
+	if config.Validate {
+		outcome.JobsFailed += validateResults(config, instanceFiles, metricsCollector)
+	}
+
+	// Fitness is minimized, the outcome reports objective values
+	sense := 1
+	if config.Maximize {
+		sense = -1
+	}
+	for _, instanceFile := range instanceFiles {
+		instanceName := InstanceName(config.InstancesDir, instanceFile)
+		best, found := 0, false
+		for _, fitnesses := range metricsCollector.FinalFitnesses(instanceName) {
+			for _, fitness := range fitnesses {
+				if !found || fitness < best {
+					best, found = fitness, true
+				}
+			}
+		}
+		if found {
+			outcome.BestFitness[instanceName] = sense * best
+		}
+	}
+
+	recordSymmetries(config, instanceFiles, metricsCollector)
+	compareWithOptima(config, instanceFiles, metricsCollector)
+
+	if config.plannedRuns() > 1 {
+		reportDuplicateRuns(config, instanceFiles, metricsCollector)
+	}
+
+	if !config.ZeroDiagonals {
+		recordNewBest(config, instanceFiles, metricsCollector)
+	}
+
+	// Save all metrics to CSV
+	err = results.save("the results", metricsCollector.SaveToCSV)
+	if err != nil {
+		return Outcome{}, fmt.Errorf("error saving metrics: %v", err)
+	}
+
+	err = results.save("the summary", metricsCollector.SaveSummaryCSV)
+	if err != nil {
+		return Outcome{}, fmt.Errorf("error saving summary: %v", err)
+	}
+
+	err = results.save("the robustness results", metricsCollector.SaveRobustnessCSV)
+	if err != nil {
+		return Outcome{}, fmt.Errorf("error saving robustness results: %v", err)
+	}
+
+	if config.AnomalyZ >= 0 {
+		if anomalies := len(metricsCollector.Anomalies(cmp.Or(config.AnomalyZ, metrics.DefaultAnomalyZ))); anomalies > 0 {
+			config.Logger.Printf("Anomalies: %d runs stopped improving unusually early, see anomalies_%s.csv",
+				anomalies, metricsCollector.Timestamp)
+		}
+	}
+	if err := results.save("the anomalies", metricsCollector.SaveAnomaliesCSV); err != nil {
+		return Outcome{}, fmt.Errorf("error saving anomalies: %v", err)
+	}
+
+	if err := results.save("the control events", metricsCollector.SaveControlCSV); err != nil {
+		return Outcome{}, fmt.Errorf("error saving control events: %v", err)
+	}
+
+	if config.CommonRandomNumbers {
+		if err := results.save("the paired comparisons", metricsCollector.SavePairedCSV); err != nil {
+			return Outcome{}, fmt.Errorf("error saving paired comparisons: %v", err)
+		}
+	}
+
+	if metricsCollector.Output.Tidy {
+		if err := results.save("the tidy results", metricsCollector.SaveTidyCSV); err != nil {
+			return Outcome{}, fmt.Errorf("error saving tidy results: %v", err)
+		}
+	}
+
+	if config.Names != nil {
+		if err := results.save("the pretty solutions", metricsCollector.SavePrettySolutions); err != nil {
+			return Outcome{}, fmt.Errorf("error saving pretty solutions: %v", err)
+		}
+	}
+
+	if metricsCollector.Output.Report {
+		if err := results.save("the report", metricsCollector.SaveHTMLReport); err != nil {
+			return Outcome{}, fmt.Errorf("error saving report: %v", err)
+		}
+	}
+
+	finished := time.Now()
+	session.Finished = &finished
+	if err := results.save("the session", metricsCollector.SaveSession); err != nil {
+		return Outcome{}, fmt.Errorf("error saving session: %v", err)
+	}
+
+	if err := results.relocate(); err != nil {
+		return Outcome{}, fmt.Errorf("error moving results to %s: %v", config.OutputDir, err)
+	}
+
+	for _, sink := range config.Sinks {
+		if err := sink.Done(metricsCollector); err != nil {
+			return Outcome{}, fmt.Errorf("error in result sink: %v", err)
+		}
+	}
+
+	config.Logger.Printf("Experiments completed. Results saved to %s", config.OutputDir)
+	return outcome, nil
+}
+
+// runPhase runs every solver RunsPerInstance times on every instance, or
+// adaptively in adaptive mode, see runAdaptive. When
+// selected is not nil, a solver only runs on the instances it was selected for.
+// A non-zero deadline is shared among the runs still to start, see job. In
+// streaming mode every instance is released before the next one is loaded
+// and memory records its heap. It returns the number of runs lost to
+// instances that could not be loaded. A non-nil load measures the use of
+// the workers.
+func runPhase(config ExperimentConfig, instanceFiles []string, metricsCollector *metrics.MetricsCollector,
+	solveOptions solvers.SolveOptions, control *Control, selected map[string]map[string]bool, deadline time.Time,
+	memory *memoryReport, load *loadReport) int {
+	// Runs are executed by a pool of workers, instances are loaded in order
+	parallelism := max(config.Parallelism, 1)
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				finished := load.started()
+				runJob(config, j, metricsCollector, solveOptions, control)
+				finished()
+				if j.done != nil {
+					j.done.Done()
+				}
+				if j.batch != nil {
+					j.batch.Done()
+				}
+			}
+		}()
+	}
+
+	// pending counts the runs not started yet, they share the time budget
+	pending := countRuns(config, instanceFiles, selected)
+	failed := 0
+
 	// Process each instance
 	for _, instanceFile := range instanceFiles {
-		instanceName := filepath.Base(instanceFile)
+		instanceName := InstanceName(config.InstancesDir, instanceFile)
+		instanceSolvers := solversFor(config, instanceName, selected)
+		if len(instanceSolvers) == 0 {
+			continue
+		}
 		config.Logger.Printf("Processing instance: %s", instanceName)
 
 		// Load the instance
 		instance, err := qap.ReadInstance(instanceFile)
 		if err != nil {
 			config.Logger.Printf("Error loading instance %s: %v", instanceName, err)
+			pending -= config.instanceRuns(instanceName, instanceSolvers)
+			failed += config.instanceRuns(instanceName, instanceSolvers)
 			continue
 		}
 
-		// Run each solver multiple times
-		for _, solver := range config.Solvers {
-			config.Logger.Printf("Running %s on %s (%d runs)", solver.Name(), instanceName, config.RunsPerInstance)
-
-			// Run solver multiple times
-			for run := 1; run <= config.RunsPerInstance; run++ {
-				config.Logger.Printf("  Run %d/%d", run, config.RunsPerInstance)
-
-				// Check if the solver supports metrics collection
-				if metricsSolver, ok := solver.(MetricsSolver); ok {
-					metricsSolver.SolveWithMetrics(instance, metricsCollector, instanceName, run)
-				} else {
-					// Run standard solver and collect basic metrics
-					result := solver.Solve(instance)
-					config.Logger.Printf("    Fitness: %d", result.Fitness)
+		if d := instance.Diagonal(); d.Nonzero() {
+			config.Logger.Printf("Warning: %s", DiagonalWarning(instanceName, d, config.ZeroDiagonals))
+		}
+		if err := prepareInstance(config, instance); err != nil {
+			config.Logger.Printf("Skipping instance %s: %v", instanceName, err)
+			pending -= config.instanceRuns(instanceName, instanceSolvers)
+			failed += config.instanceRuns(instanceName, instanceSolvers)
+			continue
+		}
+
+		memory.loaded(instanceName, instance.Size)
+
+		// All solvers are evaluated on the same perturbed scenarios
+		scenarios := robustnessScenarios(config, instance, instanceName)
+		var instanceRuns sync.WaitGroup
+
+		// Run each solver multiple times. Racing needs the solvers to
+		// advance together, so their runs are interleaved then.
+		for _, solver := range instanceSolvers {
+			if config.adaptive() {
+				config.Logger.Printf("Running %s on %s (%d to %d runs)", solver.Name(), instanceName,
+					config.minRuns(), config.plannedRuns())
+			} else {
+				config.Logger.Printf("Running %s on %s (%d runs)", solver.Name(), instanceName, config.runsOf(instanceName, solver.Name()).count)
+			}
+		}
+		dispatch := func(j job) {
+			j.instance, j.instanceName, j.scenarios = instance, instanceName, scenarios
+			j.deadline, j.pending = deadline, pending
+			if config.Streaming {
+				j.done = &instanceRuns
+				instanceRuns.Add(1)
+			}
+			pending--
+			load.dispatched(pending)
+			jobs <- j
+		}
+		if config.adaptive() {
+			// The runs of a solver depend on its finished runs, so solvers
+			// run one after another
+			for _, solver := range instanceSolvers {
+				runs := runAdaptive(config, metricsCollector, job{solver: solver, instanceName: instanceName}, dispatch)
+				pending -= config.plannedRuns() - runs
+			}
+		}
+		dropped := make(map[string]bool)
+		// Racing decides on the runs of the earlier rounds only, so a round
+		// waits for the one before it. Otherwise the decisions would depend
+		// on which parallel runs happen to have finished.
+		var round sync.WaitGroup
+		roundRun := 0
+		for _, j := range jobOrder(config, instanceName, instanceSolvers) {
+			name := j.solver.Name()
+			if dropped[name] {
+				continue
+			}
+			if config.Racing && j.run != roundRun {
+				round.Wait()
+				roundRun = j.run
+			}
+			if config.Racing && j.run > 1 {
+				fitnesses := metricsCollector.FinalFitnesses(instanceName)
+				if winner, ok := raceLoser(fitnesses, name, config.racingConfidence(), config.racingMinRuns()); ok {
+					span := config.runsOf(instanceName, name)
+					remaining := span.first + span.count - j.run
+					config.Logger.Printf("Racing: %s on %s is dominated by %s after %d runs, skipping its %d remaining runs",
+						name, instanceName, winner, len(fitnesses[name]), remaining)
+					dropped[name] = true
+					pending -= remaining
+					continue
 				}
 			}
+
+			if config.Racing {
+				j.batch = &round
+				round.Add(1)
+			}
+			dispatch(j)
+		}
+
+		if config.Streaming {
+			// Nothing references the instance once its runs are done
+			instanceRuns.Wait()
+			memory.released(metricsCollector.PeakHeap(instanceName))
 		}
 	}
+	close(jobs)
+	wg.Wait()
+	return failed
+}
 
-	// Save all metrics to CSV
-	err = metricsCollector.SaveToCSV()
-	if err != nil {
-		return fmt.Errorf("error saving metrics: %v", err)
+// solversFor returns the solvers of the phase that run on an instance
+func solversFor(config ExperimentConfig, instanceName string, selected map[string]map[string]bool) []solvers.Solver {
+	if selected == nil {
+		return config.Solvers
 	}
+	var instanceSolvers []solvers.Solver
+	for _, solver := range config.Solvers {
+		if selected[instanceName][solver.Name()] {
+			instanceSolvers = append(instanceSolvers, solver)
+		}
+	}
+	return instanceSolvers
+}
 
-	config.Logger.Printf("Experiments completed. Results saved to %s", config.OutputDir)
+// jobOrder lists the runs of the solvers on one instance, solver by solver or,
+// when racing, run by run. It is empty in adaptive mode, see runAdaptive.
+func jobOrder(config ExperimentConfig, instanceName string, instanceSolvers []solvers.Solver) []job {
+	var order []job
+	if config.adaptive() {
+		return nil
+	}
+	if config.Racing {
+		for run := 1; run <= config.RunsPerInstance; run++ {
+			for _, solver := range instanceSolvers {
+				order = append(order, job{solver: solver, run: run})
+			}
+		}
+		return order
+	}
+	for _, solver := range instanceSolvers {
+		span := config.runsOf(instanceName, solver.Name())
+		for run := span.first; run < span.first+span.count; run++ {
+			order = append(order, job{solver: solver, run: run})
+		}
+	}
+	return order
+}
+
+// runScreening runs every solver ScreeningRuns times on every instance with
+// the screening time limit, writes the ranking to screening_<timestamp>.csv
+// and returns the ScreeningTopK solvers selected per instance. Screening runs
+// are neither traced nor part of the results.
+func runScreening(config ExperimentConfig, instanceFiles []string, metricsCollector *metrics.MetricsCollector,
+	solveOptions solvers.SolveOptions, control *Control) (map[string]map[string]bool, error) {
+	screening := config
+	screening.RunsPerInstance = max(config.ScreeningRuns, 1)
+	screening.AdaptiveCI = 0
+	screening.RobustnessScenarios = 0
+	screening.Trace = ""
+	screening.JobLogs = false
+
+	screeningCollector := metrics.NewMetricsCollector(config.OutputDir)
+	screeningCollector.Timestamp = metricsCollector.Timestamp
+	screeningCollector.Output = metricsCollector.Output
+	screeningCollector.Optima = metricsCollector.Optima
+	screeningCollector.Maximize = metricsCollector.Maximize
+
+	topK := max(config.ScreeningTopK, 1)
+	config.Logger.Printf("Screening: %d runs of at most %v per solver and instance, keeping the best %d solvers",
+		screening.RunsPerInstance, config.ScreeningTimeLimit, topK)
+	solveOptions.TimeLimit = config.ScreeningTimeLimit
+	runPhase(screening, instanceFiles, screeningCollector, solveOptions, control, nil, time.Time{}, nil, nil)
+
+	results := screeningCollector.Screen(topK)
+	if err := screeningCollector.SaveScreeningCSV(results); err != nil {
+		return nil, fmt.Errorf("error saving screening results: %v", err)
+	}
+
+	selected := make(map[string]map[string]bool)
+	for _, r := range results {
+		if !r.Selected {
+			continue
+		}
+		if selected[r.InstanceName] == nil {
+			selected[r.InstanceName] = make(map[string]bool)
+		}
+		selected[r.InstanceName][r.SolverName] = true
+		config.Logger.Printf("Screening: %s selected on %s (rank %d, mean gap %.2f%%)",
+			r.SolverName, r.InstanceName, r.Rank, r.MeanGapPercent)
+	}
+	return selected, nil
+}
+
+// countRuns is the number of runs runPhase schedules with the given
+// selection, before racing drops any, counting MaxRuns per solver in
+// adaptive mode and RunsPerInstance in weighted mode
+func countRuns(config ExperimentConfig, instanceFiles []string, selected map[string]map[string]bool) int {
+	runs := 0
+	for _, instanceFile := range instanceFiles {
+		instanceName := InstanceName(config.InstancesDir, instanceFile)
+		runs += config.instanceRuns(instanceName, solversFor(config, instanceName, selected))
+	}
+	return runs
+}
+
+// instanceRuns is the number of runs of the solvers on an instance, see runsOf
+func (config ExperimentConfig) instanceRuns(instanceName string, instanceSolvers []solvers.Solver) int {
+	runs := 0
+	for _, solver := range instanceSolvers {
+		runs += config.runsOf(instanceName, solver.Name()).count
+	}
+	return runs
+}
+
+// racingConfidence returns RacingConfidence or its default
+func (config ExperimentConfig) racingConfidence() float64 {
+	if config.RacingConfidence <= 0 || config.RacingConfidence >= 1 {
+		return DefaultRacingConfidence
+	}
+	return config.RacingConfidence
+}
+
+// racingMinRuns returns RacingMinRuns or its default, at least 2
+func (config ExperimentConfig) racingMinRuns() int {
+	if config.RacingMinRuns <= 0 {
+		return DefaultRacingMinRuns
+	}
+	return max(config.RacingMinRuns, 2)
+}
+
+// fitness evaluates a solution with the configured fitness function
+func (config ExperimentConfig) fitness(instance *qap.QAPInstance, solution []int) int {
+	if config.Fitness != nil {
+		return config.Fitness(instance, solution)
+	}
+	return qap.CalculateFitness(instance, solution)
+}
+
+// prepareInstance applies the objective direction, the re-layout setup and
+// the constraints of the experiment to a freshly loaded instance
+func prepareInstance(config ExperimentConfig, instance *qap.QAPInstance) error {
+	instance.Maximize = config.Maximize
+	if config.ZeroDiagonals {
+		instance.ZeroDiagonals()
+	}
+	if config.CurrentLayout != nil {
+		err := instance.SetRelocation(config.CurrentLayout, config.RelocationCosts, config.RelocationWeight)
+		if err != nil {
+			return err
+		}
+	}
+	if config.Constraints != nil {
+		if err := config.Constraints.Validate(instance.Size); err != nil {
+			return err
+		}
+		instance.Constraints = config.Constraints
+	}
+	if config.FlowUncertainty != nil {
+		if err := instance.SetFlowUncertainty(config.FlowUncertainty); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// DiagonalWarning describes the nonzero diagonals of an instance and what
+// happens to them
+func DiagonalWarning(instanceName string, d qap.Diagonal, zeroed bool) string {
+	found := fmt.Sprintf("%s has %d nonzero self-flows and %d nonzero self-distances", instanceName, d.Flow, d.Distance)
+	switch {
+	case zeroed && d.AffectsCost():
+		return found + ", zeroed: costs no longer match the original instance or its .sln file"
+	case zeroed:
+		return found + ", zeroed: costs are unchanged as only one diagonal was nonzero"
+	case d.AffectsCost():
+		return found + ", they add flow[i][i]*distance[p[i]][p[i]] to every cost (-zero-diagonal clears them)"
+	}
+	return found + ", they add nothing to the cost as the other diagonal is zero"
+}
+
+// validateResults reloads every instance and recomputes the fitness of the
+// solutions stored in the collector, logging runs that do not match. It
+// returns the number of runs failing validation.
+func validateResults(config ExperimentConfig, instanceFiles []string, metricsCollector *metrics.MetricsCollector) int {
+	totalInvalid := 0
+	for _, instanceFile := range instanceFiles {
+		instanceName := InstanceName(config.InstancesDir, instanceFile)
+		instance, err := qap.ReadInstance(instanceFile)
+		if err != nil {
+			continue
+		}
+		if prepareInstance(config, instance) != nil {
+			continue
+		}
+
+		invalid := metricsCollector.ValidateRuns(instanceName, func(solution []int) (int, error) {
+			if len(solution) != instance.Size {
+				return 0, fmt.Errorf("solution has %d entries, instance size is %d", len(solution), instance.Size)
+			}
+			if err := qap.ValidatePermutation(solution); err != nil {
+				return 0, err
+			}
+			if config.ForbiddenStrategy == solvers.ForbiddenHard && instance.Constraints != nil {
+				if n := instance.Constraints.ForbiddenAssignments(solution); n > 0 {
+					return 0, fmt.Errorf("%d forbidden assignments under the hard strategy", n)
+				}
+			}
+			return config.fitness(instance, solution), nil
+		})
+		if invalid > 0 {
+			config.Logger.Printf("WARNING: %d runs on %s failed validation", invalid, instanceName)
+		}
+		totalInvalid += invalid
+		if config.Streaming {
+			releaseMemory()
+		}
+	}
+
+	if totalInvalid > 0 {
+		config.Logger.Printf("WARNING: %d runs failed validation, see the Validation column of the results", totalInvalid)
+	} else {
+		config.Logger.Printf("Validation passed: all recorded fitness values match their solutions")
+	}
+	return totalInvalid
+}
+
+// reportDuplicateRuns warns about stochastic solvers that returned the same
+// solution in several runs on an instance
+func reportDuplicateRuns(config ExperimentConfig, instanceFiles []string, metricsCollector *metrics.MetricsCollector) {
+	for _, instanceFile := range instanceFiles {
+		instanceName := InstanceName(config.InstancesDir, instanceFile)
+		for _, solver := range config.Solvers {
+			if solvers.IsDeterministic(solver) {
+				continue
+			}
+			if duplicates := metricsCollector.DuplicateRuns(instanceName, solver.Name()); duplicates > 0 {
+				config.Logger.Printf("WARNING: %d of %d runs of %s on %s repeat an earlier solution, check seeding and budget",
+					duplicates, len(metricsCollector.FinalFitnesses(instanceName)[solver.Name()]), solver.Name(), instanceName)
+			}
+		}
+	}
+}
+
+// robustnessScenarios builds the perturbed copies of an instance. The random
+// stream is seeded from the instance name so scenarios are reproducible.
+func robustnessScenarios(config ExperimentConfig, instance *qap.QAPInstance, instanceName string) []*qap.QAPInstance {
+	if config.RobustnessScenarios <= 0 {
+		return nil
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(instanceName))
+	rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+	scenarios := make([]*qap.QAPInstance, config.RobustnessScenarios)
+	for k := range scenarios {
+		scenarios[k] = qap.PerturbFlow(instance, config.RobustnessNoise, rng)
+	}
+	return scenarios
+}
+
+// runSeed derives the seed of a run from the experiment seed. With common
+// random numbers it does not depend on the solver, so runs with the same
+// number start from the same random state. Zero leaves the run unseeded.
+func runSeed(config ExperimentConfig, solver solvers.Solver, instanceName string, run int) int64 {
+	if config.Seed == 0 && !config.CommonRandomNumbers {
+		return 0
+	}
+
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%d/%s/%d", config.Seed, instanceName, run)
+	if !config.CommonRandomNumbers {
+		fmt.Fprintf(hash, "/%s", solver.Name())
+	}
+	if seed := int64(hash.Sum64() >> 1); seed != 0 {
+		return seed
+	}
+	return 1
+}
+
+// job is a single run of a solver on an instance
+type job struct {
+	instance     *qap.QAPInstance
+	instanceName string
+	solver       solvers.Solver
+	run          int
+	scenarios    []*qap.QAPInstance // perturbed copies of instance for robustness analysis
+
+	// With a time budget the run gets an equal share of the time left until
+	// deadline among the pending runs, itself included, that the workers
+	// execute in parallel. Runs dropped by racing leave their share to the others.
+	deadline time.Time
+	pending  int
+
+	// done is notified when the run finished, in streaming mode, and batch
+	// in adaptive mode and when racing
+	done  *sync.WaitGroup
+	batch *sync.WaitGroup
+}
+
+func runJob(config ExperimentConfig, j job, metricsCollector *metrics.MetricsCollector, solveOptions solvers.SolveOptions, control *Control) {
+	logger, logFile := openJobLog(config, j, metricsCollector.FileStem(j.instanceName))
+	if logFile != nil {
+		defer logFile.Close()
+	}
+	if control.Aborted(j.solver.Name()) {
+		logger.Printf("skipped, aborted by control file")
+		return
+	}
+	if config.StopAtOptimum && metricsCollector.ReachedOptimum(j.instanceName, j.solver.Name()) {
+		logger.Printf("skipped, an earlier run reached the known optimum")
+		metricsCollector.AddSkippedRun(j.instanceName, j.solver.Name())
+		return
+	}
+	span := config.runsOf(j.instanceName, j.solver.Name())
+	logger.Printf("started, run %d of %d", j.run, span.first+span.count-1)
+
+	// Check if the solver supports metrics collection
+	if metricsSolver, ok := j.solver.(MetricsSolver); ok && solvers.CapabilitiesOf(j.solver).SupportsMetrics {
+		opts := solveOptions
+		if !j.deadline.IsZero() {
+			left := time.Until(j.deadline)
+			if left <= 0 {
+				logger.Printf("skipped, time budget exhausted")
+				return
+			}
+			parallelism := max(config.Parallelism, 1)
+			opts.TimeLimit = left * time.Duration(parallelism) / time.Duration(max(j.pending, parallelism))
+		}
+		if limit := control.TimeLimit(j.solver.Name()); limit > 0 && (opts.TimeLimit == 0 || limit < opts.TimeLimit) {
+			opts.TimeLimit = limit
+		}
+		stem := metricsCollector.FileStem(j.instanceName)
+		opts.Trace = OpenTrace(config.OutputDir, config.Trace, j.solver, stem, j.run, logger)
+		opts.Diagnostics = OpenDiagnostics(config.OutputDir, config.Diagnostics, j.solver, stem, j.run)
+		opts.Artifacts = OpenArtifacts(config.OutputDir, config.Artifacts, j.solver, stem, j.run)
+		opts.Seed = runSeed(config, j.solver, j.instanceName, j.run)
+		opts.Log, opts.Verbosity = logger, config.Verbosity
+		if optimum, ok := metricsCollector.Optima.Lookup(j.instanceName); ok && opts.Stop != nil {
+			opts.Optimum, opts.HasOptimum = j.instance.Sense()*optimum, true
+		}
+		start := time.Now()
+		result := metricsSolver.SolveWithMetrics(j.instance, metricsCollector, j.instanceName, j.run, opts)
+		logger.Printf("finished with fitness %d after %v", j.instance.Objective(result.Fitness), time.Since(start).Round(time.Millisecond))
+		if err := opts.Trace.Close(); err != nil {
+			logger.Printf("Error writing trace: %v", err)
+		}
+		if err := opts.Diagnostics.Close(); err != nil {
+			logger.Printf("Error writing diagnostics: %v", err)
+		}
+		if err := opts.Artifacts.Close(); err != nil {
+			logger.Printf("Error writing artifacts: %v", err)
+		}
+
+		if len(j.scenarios) > 0 {
+			scenarioFitness := make([]int, len(j.scenarios))
+			for k, scenario := range j.scenarios {
+				scenarioFitness[k] = config.fitness(scenario, result.Solution)
+			}
+			metricsCollector.SetRobustness(j.instanceName, j.solver.Name(), j.run,
+				metrics.NewRobustnessStats(result.Fitness, scenarioFitness))
+		}
+	} else {
+		// Run standard solver and collect basic metrics
+		result := j.solver.Solve(j.instance)
+		logger.Printf("Fitness: %d", result.Fitness)
+	}
+}
+
+// capabilityWarnings lists the requested features that a configured solver
+// does not support and would silently ignore
+func capabilityWarnings(config ExperimentConfig) []string {
+	var warnings []string
+	for _, solver := range config.Solvers {
+		caps := solvers.CapabilitiesOf(solver)
+		name := solver.Name()
+		if !caps.SupportsMetrics {
+			warnings = append(warnings, fmt.Sprintf("%s does not record metrics, its runs are missing from the results", name))
+			continue
+		}
+		if config.ControlFile != "" && !caps.SupportsTimeBudget {
+			warnings = append(warnings, fmt.Sprintf("%s ignores time limits set in the control file", name))
+		}
+		if (config.ScreeningTimeLimit > 0 || config.TimeBudget > 0) && !caps.SupportsTimeBudget {
+			warnings = append(warnings, fmt.Sprintf("%s ignores the screening time limit and the time budget", name))
+		}
+		if config.Stop != nil && !caps.SupportsCancellation {
+			warnings = append(warnings, fmt.Sprintf("%s cannot stop early, the stop condition is ignored", name))
+		}
+		if config.MemoryLimit > 0 && !caps.SupportsCancellation {
+			warnings = append(warnings, fmt.Sprintf("%s cannot stop early, the memory limit is not enforced for it", name))
+		}
+	}
+	return warnings
+}
+
 // MetricsSolver extends the Solver interface with metrics collection
 type MetricsSolver interface {
 	solvers.Solver
 	SolveWithMetrics(instance *qap.QAPInstance, metricsCollector *metrics.MetricsCollector,
-		instanceName string, runNumber int) solvers.SolverResult
+		instanceName string, runNumber int, opts solvers.SolveOptions) solvers.SolverResult
 }
 
-// Helper function to find all instance files in a directory
-func findInstanceFiles(dir string) ([]string, error) {
-	var files []string
+// RunFileName names the output files of a run, such as its trace, from the
+// file stem of its instance, see metrics.MetricsCollector.FileStem, the
+// solver name without spaces and the run number. The name is valid on every
+// platform.
+func RunFileName(stem string, solver solvers.Solver, run int) string {
+	solverName := metrics.SanitizeFilename(strings.ReplaceAll(solver.Name(), " ", ""))
+	return fmt.Sprintf("%s_%s_run%d", stem, solverName, run)
+}
 
-	entries, err := os.ReadDir(dir)
+// OpenTrace creates the trace file of a single run when tracing is enabled for
+// the solver. It returns nil if the solver is not traced or the file cannot be created.
+func OpenTrace(outputDir, trace string, solver solvers.Solver, stem string, run int, logger *log.Logger) *metrics.TraceWriter {
+	solverName := strings.ReplaceAll(solver.Name(), " ", "")
+	if trace == "" || !(strings.EqualFold(trace, "all") || strings.EqualFold(trace, solverName)) {
+		return nil
+	}
+
+	path := filepath.Join(outputDir, "traces", RunFileName(stem, solver, run)+".csv")
+	writer, err := metrics.NewTraceWriter(path)
 	if err != nil {
-		return nil, err
+		logger.Printf("Could not create trace file %s: %v", path, err)
+		return nil
 	}
+	return writer
+}
+
+// OpenDiagnostics returns the diagnostics collector of a run when enabled.
+// Its files are named like trace files, in outputDir/diagnostics.
+func OpenDiagnostics(outputDir string, enabled bool, solver solvers.Solver, stem string, run int) *metrics.Diagnostics {
+	if !enabled {
+		return nil
+	}
+	return metrics.NewDiagnostics(filepath.Join(outputDir, "diagnostics", RunFileName(stem, solver, run)))
+}
 
-	for _, entry := range entries {
+// OpenArtifacts returns the artifacts directory of a run when enabled. It is
+// named like trace files, in outputDir/artifacts.
+func OpenArtifacts(outputDir string, enabled bool, solver solvers.Solver, stem string, run int) *metrics.Artifacts {
+	if !enabled {
+		return nil
+	}
+	return metrics.NewArtifacts(outputDir, filepath.Join("artifacts", RunFileName(stem, solver, run)))
+}
+
+// ConstructionCacheDir resolves the -construction-cache setting: "" keeps no
+// cache, "output" keeps it in outputDir/cache, "user" in the user cache
+// directory shared by all experiments, and anything else is a directory
+func ConstructionCacheDir(spec, outputDir string) (string, error) {
+	switch spec {
+	case "":
+		return "", nil
+	case "output":
+		return filepath.Join(outputDir, "cache"), nil
+	case "user":
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("no user cache directory: %v", err)
+		}
+		return filepath.Join(dir, "qap_solver", "constructions"), nil
+	default:
+		return spec, nil
+	}
+}
+
+// InstanceFilter selects the instance files of a directory. Patterns use
+// filepath.Match syntax and are matched against the file name and against the
+// slash-separated path relative to the directory, so "tai*" and "taillard/*"
+// both work.
+type InstanceFilter struct {
+	Recursive bool     // also search subdirectories
+	Include   []string // if set, a file must match at least one pattern
+	Exclude   []string // files matching any pattern are skipped
+	MaxSize   int      // skip instances with more facilities, 0 disables the check
+	Names     []string // if set, only the named instances are used, in this order, see ReadInstanceList
+	Limit     int      // use at most the first Limit instances, 0 uses all
+}
+
+// FindInstanceFiles lists the instance files in a directory that pass the filter
+func FindInstanceFiles(dir string, filter InstanceFilter) ([]string, error) {
+	for _, pattern := range append(append([]string(nil), filter.Include...), filter.Exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
 		if entry.IsDir() {
-			continue
+			if path != dir && !filter.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
 		name := entry.Name()
-		// Only include files with .dat extension or other QAP formats
-		if strings.HasSuffix(name, ".dat") || strings.HasSuffix(name, ".qap") {
-			files = append(files, filepath.Join(dir, name))
+		// Only include files with .dat extension, other QAP formats or an
+		// extension with a registered reader, plain or gzipped
+		if !qap.IsInstanceFile(name) {
+			return nil
+		}
+
+		relative := InstanceName(dir, path)
+		if len(filter.Include) > 0 && !matchesAny(filter.Include, name, relative) {
+			return nil
+		}
+		if matchesAny(filter.Exclude, name, relative) {
+			return nil
+		}
+		if filter.MaxSize > 0 {
+			size, err := qap.ReadInstanceSize(path)
+			if err != nil || size > filter.MaxSize {
+				return nil
+			}
 		}
+
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	if len(filter.Names) > 0 {
+		if files, err = orderByList(dir, files, filter.Names); err != nil {
+			return nil, err
+		}
+	}
+	if filter.Limit > 0 && len(files) > filter.Limit {
+		files = files[:filter.Limit]
+	}
 	return files, nil
 }
+
+// InstanceName names an instance file by its slash-separated path relative to
+// the instance directory, which is the plain file name for files directly in it
+func InstanceName(dir, file string) string {
+	relative, err := filepath.Rel(dir, file)
+	if err != nil {
+		return filepath.Base(file)
+	}
+	return filepath.ToSlash(relative)
+}
+
+// matchesAny reports whether any pattern matches the file name or relative path
+func matchesAny(patterns []string, name, relative string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, relative); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package experiment
+
+import (
+	"qap_solver/internal/metrics"
+	"sync"
+	"time"
+)
+
+// flusher writes the partial results of a running experiment at a fixed
+// interval, see ExperimentConfig.FlushInterval
+type flusher struct {
+	stop    chan struct{}
+	done    chan struct{}
+	stopped sync.Once
+}
+
+// startFlushing flushes the collector every interval until close is called.
+// It returns nil when interval is not positive.
+func startFlushing(config ExperimentConfig, collector *metrics.MetricsCollector) *flusher {
+	if config.FlushInterval <= 0 {
+		return nil
+	}
+	f := &flusher{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		ticker := time.NewTicker(config.FlushInterval)
+		defer ticker.Stop()
+		flushed := 0
+		for {
+			select {
+			case <-f.stop:
+				return
+			case <-ticker.C:
+				// Nothing new to write since the last flush
+				runs := collector.RunsAdded()
+				if runs == flushed {
+					continue
+				}
+				if err := collector.Flush(); err != nil {
+					config.Logger.Printf("Error flushing partial results: %v", err)
+					continue
+				}
+				flushed = runs
+				config.Logger.Printf("Flushed partial results: %d runs, %d evaluations", runs, collector.EvaluationsAdded())
+			}
+		}
+	}()
+	return f
+}
+
+// close stops flushing, a flush in progress finishes first. It may be called
+// more than once and is a no-op on a nil flusher.
+func (f *flusher) close() {
+	if f == nil {
+		return
+	}
+	f.stopped.Do(func() { close(f.stop) })
+	<-f.done
+}
@@ -0,0 +1,82 @@
+package experiment
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ReadInstanceList reads the instance names of a list file, one per line.
+// Blank lines and text after # are ignored. Names may be given with or without
+// extension and as a base name or a path relative to the instance directory,
+// e.g. "tai12a", "tai12a.dat" or "taillard/tai12a.dat".
+func ReadInstanceList(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if name := strings.TrimSpace(line); name != "" {
+			names = append(names, name)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s lists no instances", filename)
+	}
+	return names, nil
+}
+
+// listKey normalizes an instance name for matching against a list: lower-case,
+// slash-separated, without a .gz suffix and without extension
+func listKey(name string) string {
+	key := strings.TrimSuffix(strings.ToLower(filepath.ToSlash(name)), ".gz")
+	return strings.TrimSuffix(key, path.Ext(key))
+}
+
+// orderByList returns the files named in names, in the order of the list. A
+// name matches a file by its path relative to dir or, failing that, by its
+// base name. Names matching no file are an error, so a curated subset is run
+// completely or not at all.
+func orderByList(dir string, files, names []string) ([]string, error) {
+	byRelative := make(map[string]string)
+	byBase := make(map[string]string)
+	for _, file := range files {
+		relative := listKey(InstanceName(dir, file))
+		byRelative[relative] = file
+		if _, exists := byBase[path.Base(relative)]; !exists {
+			byBase[path.Base(relative)] = file
+		}
+	}
+
+	var ordered, missing []string
+	used := make(map[string]bool)
+	for _, name := range names {
+		key := listKey(name)
+		file, ok := byRelative[key]
+		if !ok {
+			file, ok = byBase[key]
+		}
+		switch {
+		case !ok:
+			missing = append(missing, name)
+		case !used[file]:
+			ordered = append(ordered, file)
+			used[file] = true
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("listed instances not found or filtered out: %s", strings.Join(missing, ", "))
+	}
+	return ordered, nil
+}
@@ -0,0 +1,37 @@
+package experiment
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"qap_solver/pkg"
+)
+
+// JobID identifies a run in the log, e.g. "nug12.dat TabuSearch run 3"
+func JobID(instanceName, solverName string, run int) string {
+	return fmt.Sprintf("%s %s run %d", instanceName, solverName, run)
+}
+
+// openJobLog returns the logger of a run, which tags its lines with the job ID
+// so the lines of parallel runs stay legible. With JobLogs the lines are also
+// written to the run's file in OutputDir/logs, named like its trace file; the
+// returned file is nil otherwise and must be closed after the run.
+func openJobLog(config ExperimentConfig, j job, stem string) (*log.Logger, *os.File) {
+	id := JobID(j.instanceName, j.solver.Name(), j.run)
+	if !config.JobLogs {
+		return pkg.NewJobLogger(config.Logger, id, nil), nil
+	}
+
+	path := filepath.Join(config.OutputDir, "logs", RunFileName(stem, j.solver, j.run)+".log")
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	var file *os.File
+	if err == nil {
+		file, err = os.Create(path)
+	}
+	if err != nil {
+		config.Logger.Printf("Could not create job log %s: %v", path, err)
+		return pkg.NewJobLogger(config.Logger, id, nil), nil
+	}
+	return pkg.NewJobLogger(config.Logger, id, file), file
+}
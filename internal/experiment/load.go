@@ -0,0 +1,166 @@
+package experiment
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// loadSampleInterval is the time between two samples of the worker pool
+const loadSampleInterval = 250 * time.Millisecond
+
+// lowEfficiency is the parallel efficiency below which the report names a
+// bottleneck
+const lowEfficiency = 0.8
+
+// loadReport measures how well the worker pool of an experiment is used.
+// The summed run times estimate the sequential wall time, so their ratio to
+// the actual wall time is the speedup of running in parallel. Samples of the
+// busy workers, the runs waiting to be dispatched and the system load
+// average tell whether idle workers or overloaded CPUs cost the speedup. A
+// nil *loadReport records nothing.
+type loadReport struct {
+	workers int
+	start   time.Time
+
+	busy    atomic.Int64 // workers executing a run
+	waiting atomic.Int64 // runs not dispatched yet
+	runTime atomic.Int64 // summed run times in nanoseconds
+	runs    atomic.Int64
+
+	stop chan struct{}
+	done sync.WaitGroup
+
+	// Sums over the samples, written by the sampling goroutine only
+	samples     int
+	busySum     int64
+	waitingSum  int64
+	idleQueued  int // samples with idle workers while runs were waiting
+	loadSum     float64
+	loadSamples int
+}
+
+// newLoadReport starts sampling a pool of workers, stopped by finish
+func newLoadReport(workers, planned int) *loadReport {
+	r := &loadReport{workers: workers, start: time.Now(), stop: make(chan struct{})}
+	r.waiting.Store(int64(planned))
+	r.done.Add(1)
+	go func() {
+		defer r.done.Done()
+		ticker := time.NewTicker(loadSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.sample()
+			}
+		}
+	}()
+	return r
+}
+
+func (r *loadReport) sample() {
+	busy, waiting := r.busy.Load(), r.waiting.Load()
+	r.samples++
+	r.busySum += busy
+	r.waitingSum += waiting
+	if busy < int64(r.workers) && waiting > 0 {
+		r.idleQueued++
+	}
+	if load, ok := loadAverage(); ok {
+		r.loadSum += load
+		r.loadSamples++
+	}
+}
+
+// dispatched records the runs still to dispatch when a run is handed to the
+// workers
+func (r *loadReport) dispatched(pending int) {
+	if r != nil {
+		r.waiting.Store(int64(pending))
+	}
+}
+
+// started marks a worker busy until the returned function is called
+func (r *loadReport) started() func() {
+	if r == nil {
+		return func() {}
+	}
+	r.busy.Add(1)
+	start := time.Now()
+	return func() {
+		r.runTime.Add(int64(time.Since(start)))
+		r.runs.Add(1)
+		r.busy.Add(-1)
+	}
+}
+
+// finish stops sampling once the workers are done and describes the
+// parallel efficiency, or returns false when no run was made
+func (r *loadReport) finish() (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	wall := time.Since(r.start)
+	close(r.stop)
+	r.done.Wait()
+	if r.runs.Load() == 0 || wall <= 0 {
+		return "", false
+	}
+
+	sequential := time.Duration(r.runTime.Load())
+	speedup := float64(sequential) / float64(wall)
+	efficiency := speedup / float64(r.workers)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d workers on %d CPUs, %d runs in %v, sequential estimate %v, speedup %.2fx, efficiency %.0f%%",
+		r.workers, runtime.NumCPU(), r.runs.Load(), wall.Round(time.Millisecond), sequential.Round(time.Millisecond),
+		speedup, 100*efficiency)
+	busy, load := 0.0, 0.0
+	if r.samples > 0 {
+		busy = float64(r.busySum) / float64(r.samples)
+		fmt.Fprintf(&b, ", on average %.1f workers busy and %.1f runs waiting",
+			busy, float64(r.waitingSum)/float64(r.samples))
+		if r.loadSamples > 0 {
+			load = r.loadSum / float64(r.loadSamples)
+			fmt.Fprintf(&b, ", load average %.1f", load)
+		}
+	}
+
+	cpus := float64(runtime.NumCPU())
+	switch {
+	case busy > cpus || load > cpus:
+		b.WriteString("; the CPUs are oversubscribed, runs waited for a CPU so the sequential estimate and " +
+			"the speedup are too high, lower -parallel or the workers of parallel solvers")
+	case efficiency >= lowEfficiency:
+		b.WriteString("; the workers were kept busy, the solver runs are the bottleneck")
+	case r.samples > 0 && 2*r.idleQueued < r.samples:
+		b.WriteString("; workers were idle with no run left to take, there are too few runs for the workers " +
+			"or a few long runs finished last")
+	default:
+		b.WriteString("; workers were idle while runs were waiting to be dispatched, loading instances, " +
+			"-stream or adaptive batches hold back the dispatch")
+	}
+	return b.String(), true
+}
+
+// loadAverage returns the one-minute system load average where the system
+// reports it
+func loadAverage() (float64, bool) {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, false
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	return load, err == nil
+}
@@ -0,0 +1,96 @@
+package experiment
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+)
+
+// memoryReport records the heap of a streaming experiment instance by
+// instance, see ExperimentConfig.Streaming. A nil *memoryReport records nothing.
+type memoryReport struct {
+	rows []memoryRow
+}
+
+type memoryRow struct {
+	instanceName string
+	size         int
+	loaded       uint64 // heap after loading and preparing the instance
+	peak         uint64 // highest heap sampled while its runs executed
+	released     uint64 // heap after the instance was released and collected
+}
+
+// loaded records the heap right after an instance was loaded
+func (r *memoryReport) loaded(instanceName string, size int) {
+	if r == nil {
+		return
+	}
+	heap := heapAlloc()
+	r.rows = append(r.rows, memoryRow{instanceName: instanceName, size: size, loaded: heap, peak: heap})
+}
+
+// released collects garbage once the runs of the last loaded instance are
+// done and the instance is no longer referenced, and records the heap left
+func (r *memoryReport) released(peak uint64) {
+	if r == nil || len(r.rows) == 0 {
+		return
+	}
+	row := &r.rows[len(r.rows)-1]
+	row.peak = max(row.peak, peak)
+	row.released = releaseMemory()
+}
+
+// peak returns the row with the highest peak heap
+func (r *memoryReport) peak() (memoryRow, bool) {
+	if r == nil || len(r.rows) == 0 {
+		return memoryRow{}, false
+	}
+	top := r.rows[0]
+	for _, row := range r.rows[1:] {
+		if row.peak > top.peak {
+			top = row
+		}
+	}
+	return top, true
+}
+
+// save writes memory_<timestamp>.csv with one row per instance
+func (r *memoryReport) save(outputDir, timestamp string) error {
+	if r == nil {
+		return nil
+	}
+	file, err := os.Create(filepath.Join(outputDir, fmt.Sprintf("memory_%s.csv", timestamp)))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"Instance", "Size", "HeapAtLoadBytes", "PeakHeapBytes", "HeapAfterReleaseBytes"})
+	for _, row := range r.rows {
+		writer.Write([]string{row.instanceName, strconv.Itoa(row.size),
+			strconv.FormatUint(row.loaded, 10), strconv.FormatUint(row.peak, 10), strconv.FormatUint(row.released, 10)})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// releaseMemory forces a garbage collection that also returns freed memory
+// to the operating system and reports the live heap
+func releaseMemory() uint64 {
+	debug.FreeOSMemory()
+	return heapAlloc()
+}
+
+func heapAlloc() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
@@ -0,0 +1,89 @@
+package experiment
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"time"
+)
+
+// NewBestLogFile is appended to in the output directory whenever an
+// experiment matches or beats a best known solution, so it collects these
+// events across experiments
+const NewBestLogFile = "new_best_found.log"
+
+// recordNewBest looks for instances on which the best run matched or beat the
+// value of the instance's .sln file. Its solution is written as a .sln file to
+// OutputDir/solutions, verified by reading it back, and the event is appended
+// to NewBestLogFile. Runs are compared by their plain QAP cost, so relocation
+// costs, constraints and custom fitness terms do not count.
+func recordNewBest(config ExperimentConfig, instanceFiles []string, metricsCollector *metrics.MetricsCollector) {
+	var events []string
+	for _, instanceFile := range instanceFiles {
+		instanceName := InstanceName(config.InstancesDir, instanceFile)
+		known, ok := metricsCollector.Optima.Lookup(instanceName)
+		if !ok {
+			continue
+		}
+
+		best, ok := bestQAPRun(metricsCollector, instanceName, config.Maximize)
+		if !ok {
+			continue
+		}
+		status := "matched"
+		if config.Maximize && best.QAPCost > known || !config.Maximize && best.QAPCost < known {
+			status = "NEW BEST"
+		} else if best.QAPCost != known {
+			continue
+		}
+
+		path := filepath.Join(config.OutputDir, "solutions", metricsCollector.FileStem(instanceName)+".sln")
+		err := qap.WriteSolutionFile(path, qap.SolutionFile{Size: len(best.Solution), Value: best.QAPCost, Permutation: best.Solution})
+		if err != nil {
+			config.Logger.Printf("Could not write solution file for %s: %v", instanceName, err)
+			path = "not written"
+		}
+
+		event := fmt.Sprintf("%s %s: %s run %d reached %d, best known %d, %s",
+			status, instanceName, best.SolverName, best.Run, best.QAPCost, known, path)
+		config.Logger.Print(event)
+		events = append(events, time.Now().Format(time.RFC3339)+" "+event)
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	logPath := filepath.Join(config.OutputDir, NewBestLogFile)
+	file, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		config.Logger.Printf("Could not open %s: %v", logPath, err)
+		return
+	}
+	defer file.Close()
+	for _, event := range events {
+		fmt.Fprintln(file, event)
+	}
+}
+
+// bestQAPRun returns the valid run with the best plain QAP cost on an instance
+func bestQAPRun(metricsCollector *metrics.MetricsCollector, instanceName string, maximize bool) (metrics.RunMetrics, bool) {
+	var best metrics.RunMetrics
+	found := false
+	for _, experiment := range metricsCollector.Experiments[instanceName] {
+		for _, run := range experiment.Runs {
+			if len(run.Solution) == 0 || run.ValidationError != "" {
+				continue
+			}
+			better := run.QAPCost < best.QAPCost
+			if maximize {
+				better = run.QAPCost > best.QAPCost
+			}
+			if !found || better {
+				best, found = run, true
+			}
+		}
+	}
+	return best, found
+}
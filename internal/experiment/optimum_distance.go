@@ -0,0 +1,72 @@
+package experiment
+
+import (
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+)
+
+// distinctOptimumFraction is the share of facilities a solution reaching the
+// optimal value must place differently from the .sln permutation to count as
+// a different optimum rather than a near copy of it
+const distinctOptimumFraction = 0.5
+
+// compareWithOptima gives the collector the optimal permutations of the
+// instances with a .sln file, so runs report their assignment distance to it.
+// The permutation is used in the encoding that reproduces the file's value,
+// see qap.OrientSolution. Runs reaching the optimal value with a very
+// different solution are reported, as the instance then has several optima;
+// symmetric copies of the .sln permutation are the same optimum.
+func compareWithOptima(config ExperimentConfig, instanceFiles []string, metricsCollector *metrics.MetricsCollector) {
+	permutations, err := qap.LoadOptimalPermutations(config.InstancesDir)
+	if err != nil || len(permutations) == 0 {
+		return
+	}
+
+	for _, instanceFile := range instanceFiles {
+		instanceName := InstanceName(config.InstancesDir, instanceFile)
+		s, ok := permutations.Lookup(instanceName)
+		if !ok {
+			continue
+		}
+		instance, err := qap.ReadInstance(instanceFile)
+		if err != nil || prepareInstance(config, instance) != nil {
+			continue
+		}
+
+		optimal, encoding, ok := qap.OrientSolution(instance, s)
+		if !ok {
+			config.Logger.Printf("The .sln permutation of %s does not reproduce its value %d in either encoding, no optimum distances reported",
+				instanceName, s.Value)
+			continue
+		}
+		if encoding == qap.EncodingLocation {
+			config.Logger.Printf("The .sln permutation of %s lists the facility of every location, it is inverted for optimum distances", instanceName)
+		}
+		if metricsCollector.OptimalPermutations == nil {
+			metricsCollector.OptimalPermutations = make(map[string][]int)
+		}
+		metricsCollector.OptimalPermutations[instanceName] = optimal
+
+		symmetries := metricsCollector.Symmetries[instanceName]
+		distinct, farthest := 0, 0
+		for _, experiment := range metricsCollector.Experiments[instanceName] {
+			for _, run := range experiment.Runs {
+				if run.QAPCost != s.Value || run.ValidationError != "" || symmetries.Equivalent(run.Solution, optimal) {
+					continue
+				}
+				distance, ok := metricsCollector.OptimumDistance(run)
+				if ok && float64(distance) >= distinctOptimumFraction*float64(instance.Size) {
+					distinct++
+					farthest = max(farthest, distance)
+				}
+			}
+		}
+		if distinct > 0 {
+			config.Logger.Printf("WARNING: %d runs on %s reach the optimal value %d with solutions placing up to %d of %d facilities differently from the .sln permutation, the instance has several optima",
+				distinct, instanceName, s.Value, farthest, instance.Size)
+		}
+		if config.Streaming {
+			releaseMemory()
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package experiment
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"path/filepath"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"qap_solver/internal/qaptest"
+	"reflect"
+	"testing"
+)
+
+// writeTestInstances writes random instances of the given sizes to dir
+func writeTestInstances(t *testing.T, dir string, sizes ...int) {
+	t.Helper()
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range sizes {
+		instance := &qap.QAPInstance{Size: n, FlowMatrix: qaptest.ZeroDiagonal(qaptest.Matrix(rng, n)),
+			DistanceMatrix: qaptest.ZeroDiagonal(qaptest.Matrix(rng, n))}
+		if err := qap.WriteInstance(filepath.Join(dir, fmt.Sprintf("rand%d.dat", n)), instance); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// runKey identifies a run of an experiment
+type runKey struct {
+	instance, solver string
+	run              int
+}
+
+// runExperiment runs a seeded experiment, racing the solvers if asked, and
+// returns the final fitness of every run. The sink takes no lock: sinks are
+// never called concurrently, which -race checks.
+func runExperiment(t *testing.T, instancesDir string, parallelism int, racing bool) map[runKey]int {
+	t.Helper()
+	fitness := make(map[runKey]int)
+	_, err := NewBuilder().
+		AddInstanceDir(instancesDir).
+		AddSolver("random:iterations=200").
+		AddSolver("steepest").
+		AddSolver("simanneal").
+		WithRuns(6).
+		WithParallelism(parallelism).
+		WithSeed(42).
+		WithOutputDir(t.TempDir()).
+		WithLogger(log.New(io.Discard, "", 0)).
+		Configure(func(config *ExperimentConfig) {
+			// A low confidence drops solvers after the few runs made here
+			config.Racing, config.RacingMinRuns, config.RacingConfidence = racing, 2, 0.01
+		}).
+		WithSinks(SinkFunc(func(run metrics.RunMetrics) {
+			key := runKey{run.InstanceName, run.SolverName, run.Run}
+			if _, ok := fitness[key]; ok {
+				t.Errorf("run %v reported twice", key)
+			}
+			fitness[key] = run.FinalFitness
+		})).
+		Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fitness
+}
+
+// TestRunAllParallel runs the same seeded experiment on one worker and on
+// several. Every run must be made exactly once and reach the same fitness,
+// however the workers interleave. Run it with -race to check the shared
+// collector, sinks and logs.
+func TestRunAllParallel(t *testing.T) {
+	dir := t.TempDir()
+	writeTestInstances(t, dir, 8, 10, 12)
+
+	sequential := runExperiment(t, dir, 1, false)
+	if want := 3 * 3 * 6; len(sequential) != want {
+		t.Fatalf("sequential experiment made %d runs, want %d", len(sequential), want)
+	}
+	for _, parallelism := range []int{2, 4, 16} {
+		t.Run(fmt.Sprintf("parallel=%d", parallelism), func(t *testing.T) {
+			parallel := runExperiment(t, dir, parallelism, false)
+			if len(parallel) != len(sequential) {
+				t.Fatalf("%d runs, the sequential experiment made %d", len(parallel), len(sequential))
+			}
+			for key, want := range sequential {
+				got, ok := parallel[key]
+				if !ok {
+					t.Errorf("run %v is missing", key)
+				} else if got != want {
+					t.Errorf("run %v reached %d, sequentially %d", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestRunAllParallelRacing checks that racing drops the same runs on one
+// worker and on several, since each round waits for the one before it
+func TestRunAllParallelRacing(t *testing.T) {
+	dir := t.TempDir()
+	writeTestInstances(t, dir, 8, 10, 12)
+
+	sequential := runExperiment(t, dir, 1, true)
+	if len(sequential) == 3*3*6 {
+		t.Fatal("racing dropped no runs")
+	}
+	for _, parallelism := range []int{2, 16} {
+		t.Run(fmt.Sprintf("parallel=%d", parallelism), func(t *testing.T) {
+			if parallel := runExperiment(t, dir, parallelism, true); !reflect.DeepEqual(parallel, sequential) {
+				t.Errorf("got runs %v, sequentially %v", parallel, sequential)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package experiment
+
+import (
+	"fmt"
+	"qap_solver/internal/qap"
+)
+
+// DefaultPreflightMaxSize is the instance size above which the pre-flight
+// check reports an instance when the config leaves PreflightMaxSize at zero.
+// It is well above the largest QAPLIB instance, larger ones are usually
+// generated by mistake and take hours per run.
+const DefaultPreflightMaxSize = 1000
+
+// PreflightProblem is a problem of one instance found before the runs
+type PreflightProblem struct {
+	InstanceName string
+	Problem      string
+}
+
+// preflight loads every instance once and lists the problems that would
+// make its runs fail or mislead: files that cannot be read or prepared, the
+// structural problems of qap.CheckSanity and sizes above PreflightMaxSize.
+// The instances are released again, nothing is kept for the runs.
+func preflight(config ExperimentConfig, instanceFiles []string) []PreflightProblem {
+	maxSize := config.PreflightMaxSize
+	if maxSize == 0 {
+		maxSize = DefaultPreflightMaxSize
+	}
+	var problems []PreflightProblem
+	for _, instanceFile := range instanceFiles {
+		instanceName := InstanceName(config.InstancesDir, instanceFile)
+		instance, err := qap.ReadInstance(instanceFile)
+		if err == nil {
+			err = prepareInstance(config, instance)
+		}
+		if err != nil {
+			problems = append(problems, PreflightProblem{instanceName, fmt.Sprintf("cannot be used: %v", err)})
+			continue
+		}
+		if maxSize > 0 && instance.Size > maxSize {
+			problems = append(problems, PreflightProblem{instanceName,
+				fmt.Sprintf("size %d exceeds the pre-flight maximum %d", instance.Size, maxSize)})
+		}
+		for _, problem := range qap.CheckSanity(instance) {
+			problems = append(problems, PreflightProblem{instanceName, problem})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,72 @@
+package experiment
+
+import (
+	"math"
+	"sort"
+)
+
+// Racing defaults, used when the configuration leaves them at zero
+const (
+	DefaultRacingConfidence = 0.95
+	DefaultRacingMinRuns    = 5
+)
+
+// hoeffdingRadius is the half-width of a confidence interval for the mean of
+// n independent values within a range of the given width: by Hoeffding's
+// inequality the true mean lies within it with probability 1-delta
+func hoeffdingRadius(width float64, n int, delta float64) float64 {
+	return width * math.Sqrt(math.Log(2/delta)/(2*float64(n)))
+}
+
+// raceLoser reports whether solver is dominated on an instance: some other
+// solver's mean final fitness is lower with the given confidence. Both need
+// at least minRuns completed runs. The range of the Hoeffding bound is that of
+// all final fitness values on the instance, so no solver is dropped before
+// the runs spread. It returns the dominating solver with the lowest mean.
+func raceLoser(fitnesses map[string][]int, solver string, confidence float64, minRuns int) (string, bool) {
+	own := fitnesses[solver]
+	if len(own) < minRuns {
+		return "", false
+	}
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, values := range fitnesses {
+		for _, v := range values {
+			low, high = math.Min(low, float64(v)), math.Max(high, float64(v))
+		}
+	}
+	width := high - low
+	if width == 0 {
+		return "", false
+	}
+
+	delta := 1 - confidence
+	ownLower := mean(own) - hoeffdingRadius(width, len(own), delta)
+
+	names := make([]string, 0, len(fitnesses))
+	for name := range fitnesses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	winner, winnerMean := "", math.Inf(1)
+	for _, name := range names {
+		values := fitnesses[name]
+		if name == solver || len(values) < minRuns {
+			continue
+		}
+		m := mean(values)
+		if m+hoeffdingRadius(width, len(values), delta) < ownLower && m < winnerMean {
+			winner, winnerMean = name, m
+		}
+	}
+	return winner, winner != ""
+}
+
+func mean(values []int) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += float64(v)
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,151 @@
+package experiment
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/qap"
+	"qap_solver/internal/solvers"
+	"strconv"
+	"time"
+)
+
+// Probe runs are short best-improvement local searches (a depth 1 ejection
+// chain) that stop after probeIterations improving swaps
+const probeIterations = 25
+
+// Difficulty classes and the multiple of the mean probe time suggested as the
+// budget of a single run
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+var budgetFactor = map[string]float64{
+	DifficultyEasy:   10,
+	DifficultyMedium: 50,
+	DifficultyHard:   200,
+}
+
+// minBudget is the smallest suggested run budget
+const minBudget = 100 * time.Millisecond
+
+// Recommendation is the difficulty estimate of an instance together with a
+// suggested per-run time budget and solver configuration
+type Recommendation struct {
+	InstanceName string
+	Features     qap.Features
+
+	Probes      int
+	ProbeTime   time.Duration // mean wall time of a probe run
+	ProbeBest   int           // best probe objective value
+	ProbeSpread float64       // mean probe fitness above the best probe fitness, in percent
+
+	Difficulty float64
+	Class      string
+	Budget     time.Duration
+	Solvers    string
+}
+
+// Recommend estimates the difficulty of an instance from its features and
+// from the spread of a few probe runs. Difficulty grows with the logarithm of
+// the size and with the spread of the probe results, which measures how
+// rugged the landscape is. Structured instances (high flow dominance or
+// sparse flows) get annealing and ejection chains, uniform ones tabu search.
+func Recommend(instance *qap.QAPInstance, instanceName string, probes int) Recommendation {
+	r := Recommendation{
+		InstanceName: instanceName,
+		Features:     qap.ComputeFeatures(instance),
+		Probes:       max(probes, 1),
+	}
+
+	probe := solvers.NewEjectionChainSolver(1, probeIterations)
+	var total time.Duration
+	var sum float64
+	for k := 0; k < r.Probes; k++ {
+		start := time.Now()
+		result := probe.Solve(instance)
+		total += time.Since(start)
+		sum += float64(result.Fitness)
+		if k == 0 || result.Fitness < r.ProbeBest {
+			r.ProbeBest = result.Fitness
+		}
+	}
+	r.ProbeTime = total / time.Duration(r.Probes)
+	if r.ProbeBest != 0 {
+		r.ProbeSpread = 100 * (sum/float64(r.Probes) - float64(r.ProbeBest)) / math.Abs(float64(r.ProbeBest))
+	}
+	r.ProbeBest = instance.Objective(r.ProbeBest)
+
+	r.Difficulty = math.Log2(float64(max(r.Features.Size, 2))) * (1 + r.ProbeSpread/5)
+	switch {
+	case r.Difficulty < 6:
+		r.Class = DifficultyEasy
+	case r.Difficulty < 12:
+		r.Class = DifficultyMedium
+	default:
+		r.Class = DifficultyHard
+	}
+
+	r.Budget = max(time.Duration(float64(r.ProbeTime)*budgetFactor[r.Class]), minBudget)
+	r.Solvers = recommendSolvers(r.Features, r.Class)
+	return r
+}
+
+func recommendSolvers(f qap.Features, class string) string {
+	structured := f.FlowDominance >= 150 || f.FlowSparsity >= 0.5
+	switch {
+	case class == DifficultyEasy:
+		return "steepest;tabu"
+	case structured:
+		return "simanneal:bias=adaptive;ejection"
+	case f.Size >= 100 || class == DifficultyHard:
+		return "ctabu;tabu"
+	default:
+		return "tabu;simanneal"
+	}
+}
+
+// SaveRecommendationsCSV writes one row per recommendation to
+// recommendations_<timestamp>.csv in outputDir and returns the file path
+func SaveRecommendationsCSV(outputDir string, recommendations []Recommendation) (string, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(outputDir, fmt.Sprintf("recommendations_%s.csv", time.Now().Format("2006-01-02T15_04")))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{
+		"Instance", "Size", "FlowDominance", "DistanceDominance", "FlowSparsity", "DistanceSparsity",
+		"Probes", "ProbeTimeMs", "ProbeBest", "ProbeSpreadPercent",
+		"Difficulty", "Class", "BudgetMs", "Solvers",
+	})
+	for _, r := range recommendations {
+		writer.Write([]string{
+			r.InstanceName,
+			strconv.Itoa(r.Features.Size),
+			strconv.FormatFloat(r.Features.FlowDominance, 'f', 2, 64),
+			strconv.FormatFloat(r.Features.DistanceDominance, 'f', 2, 64),
+			strconv.FormatFloat(r.Features.FlowSparsity, 'f', 4, 64),
+			strconv.FormatFloat(r.Features.DistanceSparsity, 'f', 4, 64),
+			strconv.Itoa(r.Probes),
+			strconv.FormatFloat(float64(r.ProbeTime)/float64(time.Millisecond), 'f', 3, 64),
+			strconv.Itoa(r.ProbeBest),
+			strconv.FormatFloat(r.ProbeSpread, 'f', 4, 64),
+			strconv.FormatFloat(r.Difficulty, 'f', 2, 64),
+			r.Class,
+			strconv.FormatInt(r.Budget.Milliseconds(), 10),
+			r.Solvers,
+		})
+	}
+	writer.Flush()
+	return path, writer.Error()
+}
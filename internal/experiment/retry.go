@@ -0,0 +1,176 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/metrics"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// Retry defaults, used when the configuration leaves them at zero
+const (
+	DefaultIORetries = 5
+	DefaultIOBackoff = 500 * time.Millisecond
+)
+
+// resultWriter saves the result files of an experiment, retrying transient
+// I/O failures such as those of a network file system with exponential
+// backoff. When the output directory keeps failing, the collector is
+// switched to a local temporary directory so the batch is not lost, and
+// relocate moves the files back at the end of the run.
+type resultWriter struct {
+	config    ExperimentConfig
+	collector *metrics.MetricsCollector
+	outputDir string // the configured output directory
+	fallback  string // local directory used after the output directory failed, "" before
+}
+
+func newResultWriter(config ExperimentConfig, collector *metrics.MetricsCollector) *resultWriter {
+	return &resultWriter{config: config, collector: collector, outputDir: collector.OutputDir}
+}
+
+// dir returns the directory the result files currently go to
+func (w *resultWriter) dir() string {
+	return w.collector.OutputDir
+}
+
+// save runs a save function that writes to dir, retrying transient I/O
+// errors. Once the retries are used up, or at once for I/O errors that
+// retrying cannot fix such as a missing or read-only output directory, the
+// files go to the fallback directory instead, with the same retries. Other
+// errors, such as invalid output options, are returned at once.
+func (w *resultWriter) save(what string, save func() error) error {
+	err := w.retry(what, save)
+	if err == nil || !isIOError(err) || w.fallback != "" {
+		return err
+	}
+
+	fallback, mkErr := os.MkdirTemp("", "qap_solver_results_")
+	if mkErr != nil {
+		return fmt.Errorf("%v (no local fallback: %v)", err, mkErr)
+	}
+	w.fallback = fallback
+	w.collector.OutputDir = fallback
+	w.config.Logger.Printf("Warning: cannot write %s to %s (%v), writing results to %s instead",
+		what, w.outputDir, err, fallback)
+	return w.retry(what, save)
+}
+
+// retry calls save until it succeeds, fails with an error that is not a
+// transient I/O error, or failed IORetries times, doubling the wait after
+// every failure
+func (w *resultWriter) retry(what string, save func() error) error {
+	retries, backoff := w.config.ioRetries(), w.config.ioBackoff()
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = save(); err == nil || !isTransientIOError(err) || attempt >= retries {
+			return err
+		}
+		w.config.Logger.Printf("Writing %s failed (%v), retrying in %v", what, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// relocate moves the files written to the fallback directory into the output
+// directory, with the same retries. Files that still cannot be moved stay in
+// the fallback directory, which is reported.
+func (w *resultWriter) relocate() error {
+	if w.fallback == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(w.fallback)
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for _, entry := range entries {
+		name := entry.Name()
+		err := w.retry(name, func() error {
+			return moveFile(filepath.Join(w.fallback, name), filepath.Join(w.outputDir, name))
+		})
+		if err != nil {
+			failed = append(failed, name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d result files could not be moved to %s and remain in %s", len(failed), w.outputDir, w.fallback)
+	}
+	w.collector.OutputDir = w.outputDir
+	w.config.Logger.Printf("Moved %d result files from %s to %s", len(entries), w.fallback, w.outputDir)
+	return os.Remove(w.fallback)
+}
+
+// moveFile moves a file, copying it when a rename is not possible, e.g.
+// between file systems
+func moveFile(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	if os.Rename(from, to) == nil {
+		return nil
+	}
+
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	target, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		return err
+	}
+	if err := target.Close(); err != nil {
+		return err
+	}
+	return os.Remove(from)
+}
+
+// isIOError reports whether err comes from the file system, as opposed to
+// an error of the results themselves that another directory cannot fix
+func isIOError(err error) bool {
+	var pathErr *fs.PathError
+	var errno syscall.Errno
+	return errors.As(err, &pathErr) || errors.As(err, &errno)
+}
+
+// transientErrnos are the file system errors that may go away by themselves,
+// such as those of a network file system losing its server for a while.
+// Missing paths, denied permissions or a full disk are not among them.
+var transientErrnos = []syscall.Errno{syscall.EIO, syscall.EAGAIN, syscall.ETIMEDOUT, syscall.ESTALE, syscall.EINTR}
+
+// isTransientIOError reports whether retrying the write that failed with err
+// may succeed
+func isTransientIOError(err error) bool {
+	var errno syscall.Errno
+	return errors.As(err, &errno) && slices.Contains(transientErrnos, errno)
+}
+
+// ioRetries returns IORetries or its default
+func (config ExperimentConfig) ioRetries() int {
+	if config.IORetries < 0 {
+		return 0
+	}
+	if config.IORetries == 0 {
+		return DefaultIORetries
+	}
+	return config.IORetries
+}
+
+// ioBackoff returns IOBackoff or its default
+func (config ExperimentConfig) ioBackoff() time.Duration {
+	if config.IOBackoff <= 0 {
+		return DefaultIOBackoff
+	}
+	return config.IOBackoff
+}
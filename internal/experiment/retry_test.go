@@ -0,0 +1,92 @@
+package experiment
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/metrics"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsTransientIOError(t *testing.T) {
+	pathError := func(errno syscall.Errno) error {
+		return &fs.PathError{Op: "open", Path: "results/summary.csv", Err: errno}
+	}
+	tests := []struct {
+		err       error
+		transient bool
+		io        bool
+	}{
+		{pathError(syscall.EIO), true, true},
+		{pathError(syscall.ESTALE), true, true},
+		{fmt.Errorf("saving results: %w", pathError(syscall.ETIMEDOUT)), true, true},
+		{syscall.EAGAIN, true, true},
+		{syscall.EINTR, true, true},
+		{pathError(syscall.EACCES), false, true},
+		{pathError(syscall.ENOENT), false, true},
+		{pathError(syscall.ENOSPC), false, true},
+		{pathError(syscall.EROFS), false, true},
+		{errors.New("unknown column"), false, false},
+	}
+	for _, test := range tests {
+		if got := isTransientIOError(test.err); got != test.transient {
+			t.Errorf("isTransientIOError(%v) = %t, want %t", test.err, got, test.transient)
+		}
+		if got := isIOError(test.err); got != test.io {
+			t.Errorf("isIOError(%v) = %t, want %t", test.err, got, test.io)
+		}
+	}
+}
+
+// TestResultWriterFallback checks that transient errors are retried in the
+// output directory while other I/O errors go to the fallback at once
+func TestResultWriterFallback(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		errno   syscall.Errno
+		retries int // failed writes to the output directory
+	}{
+		{"transient", syscall.EIO, 3},
+		{"permission", syscall.EACCES, 1},
+		{"missing path", syscall.ENOENT, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			outputDir := t.TempDir()
+			config := ExperimentConfig{IORetries: 2, IOBackoff: time.Millisecond, Logger: log.New(&logs, "", 0)}
+			collector := metrics.NewMetricsCollector(outputDir)
+			w := newResultWriter(config, collector)
+
+			failed := 0
+			err := w.save("results", func() error {
+				if collector.OutputDir == outputDir {
+					failed++
+					return &fs.PathError{Op: "open", Path: filepath.Join(outputDir, "results.csv"), Err: test.errno}
+				}
+				return os.WriteFile(filepath.Join(collector.OutputDir, "results.csv"), nil, 0o644)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(w.fallback)
+			if failed != test.retries {
+				t.Errorf("the output directory was tried %d times, want %d", failed, test.retries)
+			}
+			if w.fallback == "" || collector.OutputDir != w.fallback {
+				t.Fatalf("results went to %s, want a fallback directory\n%s", collector.OutputDir, logs.String())
+			}
+			if err := w.relocate(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(outputDir, "results.csv")); err != nil {
+				t.Errorf("the results were not moved back: %v", err)
+			}
+		})
+	}
+}
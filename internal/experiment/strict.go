@@ -0,0 +1,30 @@
+package experiment
+
+import (
+	"fmt"
+	"qap_solver/internal/qap"
+)
+
+// strictProblems lists what would silently degrade the results of a strict
+// experiment: the capability warnings, such as solvers that record no
+// metrics, instances that fail to load or to prepare, and instances without
+// a .sln optimum, whose gaps would stay empty. Every instance is loaded once
+// for the check and released again.
+func strictProblems(config ExperimentConfig, instanceFiles []string, optima qap.OptimalSolutions) []string {
+	problems := capabilityWarnings(config)
+	for _, instanceFile := range instanceFiles {
+		instanceName := InstanceName(config.InstancesDir, instanceFile)
+		instance, err := qap.ReadInstance(instanceFile)
+		if err == nil {
+			err = prepareInstance(config, instance)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("instance %s is invalid: %v", instanceName, err))
+			continue
+		}
+		if _, ok := optima.Lookup(instanceName); !ok {
+			problems = append(problems, fmt.Sprintf("instance %s has no .sln optimum", instanceName))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,38 @@
+package experiment
+
+import (
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+)
+
+// recordSymmetries gives the collector the automorphisms of the instances
+// that have any, so runs ending in symmetric copies of one solution count as
+// duplicates rather than distinct solutions, see qap.Symmetries. A custom
+// fitness need not share the symmetries of the matrices, none are recorded
+// then.
+func recordSymmetries(config ExperimentConfig, instanceFiles []string, metricsCollector *metrics.MetricsCollector) {
+	if config.Fitness != nil {
+		return
+	}
+	for _, instanceFile := range instanceFiles {
+		instanceName := InstanceName(config.InstancesDir, instanceFile)
+		if _, ran := metricsCollector.Experiments[instanceName]; !ran {
+			continue
+		}
+		instance, err := qap.ReadInstance(instanceFile)
+		if err != nil || prepareInstance(config, instance) != nil {
+			continue
+		}
+		if symmetries := qap.FindSymmetries(instance); symmetries != nil {
+			if metricsCollector.Symmetries == nil {
+				metricsCollector.Symmetries = make(map[string]*qap.Symmetries)
+			}
+			metricsCollector.Symmetries[instanceName] = symmetries
+			config.Logger.Printf("Symmetries: %s has %d, symmetric copies of a solution count as one in duplicate checks",
+				instanceName, symmetries.Count())
+		}
+		if config.Streaming {
+			releaseMemory()
+		}
+	}
+}
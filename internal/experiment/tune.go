@@ -0,0 +1,128 @@
+package experiment
+
+import (
+	"fmt"
+	"math"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/solvers"
+	"sort"
+	"strings"
+)
+
+// TuneCandidates expands a parameter grid into the parameter lists of the
+// candidates: parameters are separated by commas and the values of each by
+// "|", e.g. "alpha=0.95|0.99,p=5|10" gives four candidates
+func TuneCandidates(grid string) ([]string, error) {
+	candidates := []string{""}
+	for _, field := range strings.Split(grid, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		key, values, ok := strings.Cut(field, "=")
+		if !ok || strings.TrimSpace(key) == "" || values == "" {
+			return nil, fmt.Errorf("invalid grid parameter %q, expected key=value|value", field)
+		}
+		var expanded []string
+		for _, candidate := range candidates {
+			for _, value := range strings.Split(values, "|") {
+				param := strings.TrimSpace(key) + "=" + strings.TrimSpace(value)
+				if candidate != "" {
+					param = candidate + "," + param
+				}
+				expanded = append(expanded, param)
+			}
+		}
+		candidates = expanded
+	}
+	return candidates, nil
+}
+
+// SizeRanges splits instance sizes at the given upper bounds, e.g. bounds 30
+// and 60 give the ranges 1-30, 31-60 and 61 and up. The last range has no
+// upper bound, as Profile.MaxSize 0.
+func SizeRanges(bounds []int) [][2]int {
+	sorted := append([]int(nil), bounds...)
+	sort.Ints(sorted)
+	var ranges [][2]int
+	low := 1
+	for _, bound := range sorted {
+		if bound >= low {
+			ranges = append(ranges, [2]int{low, bound})
+			low = bound + 1
+		}
+	}
+	return append(ranges, [2]int{low, 0})
+}
+
+// LearnProfiles picks the candidate with the lowest mean gap for every
+// instance family and size range of the tuning runs, and for AnyFamily over
+// all families of a size range. candidates maps the solver names of the runs
+// to their parameters. Gaps refer to the collector's reference fitness.
+func LearnProfiles(collector *metrics.MetricsCollector, solverType string, candidates map[string]string, ranges [][2]int) []solvers.Profile {
+	type group struct {
+		family string
+		size   [2]int
+	}
+	type tally struct {
+		sum  float64
+		runs int
+	}
+	tallies := make(map[group]map[string]*tally)
+	add := func(g group, params string, gap float64) {
+		if tallies[g] == nil {
+			tallies[g] = make(map[string]*tally)
+		}
+		if tallies[g][params] == nil {
+			tallies[g][params] = &tally{}
+		}
+		tallies[g][params].sum += gap
+		tallies[g][params].runs++
+	}
+
+	for instanceName, experiments := range collector.Experiments {
+		reference := collector.Reference(instanceName)
+		family := metrics.InstanceFamily(instanceName)
+		for solverName, experiment := range experiments {
+			params, ok := candidates[solverName]
+			if !ok {
+				continue
+			}
+			for _, run := range experiment.Runs {
+				size := len(run.Solution)
+				for _, r := range ranges {
+					if size >= r[0] && (r[1] == 0 || size <= r[1]) {
+						gap := metrics.GapPercent(run.FinalFitness, reference)
+						add(group{family, r}, params, gap)
+						add(group{solvers.AnyFamily, r}, params, gap)
+					}
+				}
+			}
+		}
+	}
+
+	var profiles []solvers.Profile
+	for g, byParams := range tallies {
+		best := solvers.Profile{Solver: solverType, Family: g.family, MinSize: g.size[0], MaxSize: g.size[1], MeanGap: math.Inf(1)}
+		// Ties go to the candidate listed first in the sorted parameters
+		params := make([]string, 0, len(byParams))
+		for p := range byParams {
+			params = append(params, p)
+		}
+		sort.Strings(params)
+		for _, p := range params {
+			t := byParams[p]
+			if mean := t.sum / float64(t.runs); mean < best.MeanGap {
+				best.Params, best.MeanGap, best.Runs = p, mean, t.runs
+			}
+		}
+		profiles = append(profiles, best)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		if profiles[i].Family != profiles[j].Family {
+			return profiles[i].Family < profiles[j].Family
+		}
+		return profiles[i].MinSize < profiles[j].MinSize
+	})
+	return profiles
+}
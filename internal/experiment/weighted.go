@@ -0,0 +1,194 @@
+package experiment
+
+import (
+	"math"
+	"qap_solver/internal/metrics"
+	"sort"
+)
+
+// weighted reports whether the runs are allocated by the variance of pilot
+// runs, see WeightedPilot
+func (config ExperimentConfig) weighted() bool {
+	return config.WeightedPilot > 0
+}
+
+// runSpan is the run numbers first to first+count-1 of a solver on an instance
+type runSpan struct {
+	first, count int
+}
+
+// runsOf returns the runs of a solver on an instance in the current phase:
+// the span allocated in the second phase of weighted mode, plannedRuns from
+// run 1 otherwise
+func (config ExperimentConfig) runsOf(instanceName, solverName string) runSpan {
+	if config.allocation != nil {
+		return config.allocation[instanceName][solverName]
+	}
+	return runSpan{first: 1, count: config.plannedRuns()}
+}
+
+// runWeighted makes the WeightedPilot runs of every solver on every
+// instance, then shares out the rest of the RunsPerInstance budget per
+// instance with allocateRuns and makes those runs. run executes a phase with
+// the given config and selection and returns its failed runs.
+func runWeighted(config ExperimentConfig, instanceFiles []string, metricsCollector *metrics.MetricsCollector,
+	selected map[string]map[string]bool, run func(ExperimentConfig, map[string]map[string]bool) int) int {
+	pilot := config
+	pilot.RunsPerInstance, pilot.WeightedPilot = config.WeightedPilot, 0
+	config.Logger.Printf("Weighted runs: %d pilot runs of every solver on every instance", pilot.RunsPerInstance)
+	failed := run(pilot, selected)
+
+	allocation := allocateRuns(config, instanceFiles, metricsCollector, selected)
+	rest := config
+	rest.WeightedPilot, rest.allocation = 0, allocation
+	chosen := make(map[string]map[string]bool)
+	for instanceName, bySolver := range allocation {
+		for solverName, span := range bySolver {
+			if span.count > 0 {
+				if chosen[instanceName] == nil {
+					chosen[instanceName] = make(map[string]bool)
+				}
+				chosen[instanceName][solverName] = true
+			}
+		}
+	}
+	return failed + run(rest, chosen)
+}
+
+// allocateRuns shares the runs left after the pilot, RunsPerInstance minus
+// WeightedPilot per instance, among the instances of every solver in
+// proportion to the coefficient of variation of its pilot final fitness
+// (Neyman allocation on relative values), so instances with noisy results
+// get more runs and those where every run ends alike get none. No instance
+// gets more than MaxRuns in total; when all pilots agree the runs are spread
+// evenly. Instances without pilot results get no further runs.
+func allocateRuns(config ExperimentConfig, instanceFiles []string, metricsCollector *metrics.MetricsCollector,
+	selected map[string]map[string]bool) map[string]map[string]runSpan {
+	allocation := make(map[string]map[string]runSpan)
+	extra := config.RunsPerInstance - config.WeightedPilot
+	for _, solver := range config.Solvers {
+		name := solver.Name()
+		var instances []string
+		var weights []float64
+		var capacity []int
+		for _, instanceFile := range instanceFiles {
+			instanceName := InstanceName(config.InstancesDir, instanceFile)
+			if selected != nil && !selected[instanceName][name] {
+				continue
+			}
+			fitnesses := metricsCollector.FinalFitnesses(instanceName)[name]
+			if len(fitnesses) == 0 {
+				continue
+			}
+			limit := math.MaxInt
+			if config.MaxRuns > 0 {
+				limit = max(config.MaxRuns-len(fitnesses), 0)
+			}
+			instances = append(instances, instanceName)
+			weights = append(weights, variation(fitnesses))
+			capacity = append(capacity, limit)
+		}
+		if len(instances) == 0 || extra <= 0 {
+			continue
+		}
+
+		counts := shareRuns(extra*len(instances), weights, capacity)
+		most := 0
+		for k, instanceName := range instances {
+			if allocation[instanceName] == nil {
+				allocation[instanceName] = make(map[string]runSpan)
+			}
+			made := len(metricsCollector.FinalFitnesses(instanceName)[name])
+			allocation[instanceName][name] = runSpan{first: config.WeightedPilot + 1, count: counts[k]}
+			if counts[k] > counts[most] {
+				most = k
+			}
+			config.Logger.Printf("Weighted runs: %s on %s, pilot CV %.4f%%, %d more runs after %d",
+				name, instanceName, 100*weights[k], counts[k], made)
+		}
+		config.Logger.Printf("Weighted runs: %s shares %d runs among %d instances, most on %s (%d)",
+			name, extra*len(instances), len(instances), instances[most], counts[most])
+	}
+	return allocation
+}
+
+// variation is the coefficient of variation of fitness values, their
+// sample standard deviation relative to the absolute mean
+func variation(fitnesses []int) float64 {
+	if len(fitnesses) < 2 {
+		return 0
+	}
+	m := mean(fitnesses)
+	sum := 0.0
+	for _, f := range fitnesses {
+		sum += (float64(f) - m) * (float64(f) - m)
+	}
+	std := math.Sqrt(sum / float64(len(fitnesses)-1))
+	if m == 0 {
+		return std
+	}
+	return std / math.Abs(m)
+}
+
+// shareRuns splits total runs in proportion to weights without exceeding
+// capacity, rounding by largest remainder. Shares above a capacity are cut
+// and their rest goes to the others; zero weights share evenly what no
+// positive weight can take.
+func shareRuns(total int, weights []float64, capacity []int) []int {
+	counts := make([]int, len(weights))
+	full := make([]bool, len(weights))
+	for total > 0 {
+		open, weight := 0, 0.0
+		for k := range weights {
+			if !full[k] {
+				open++
+				weight += weights[k]
+			}
+		}
+		if open == 0 {
+			break
+		}
+		share := func(k int) float64 {
+			if weight == 0 {
+				return float64(total) / float64(open)
+			}
+			return float64(total) * weights[k] / weight
+		}
+
+		// Shares above the capacity are cut first, then the rest is shared again
+		capped := false
+		for k := range weights {
+			if !full[k] && share(k) >= float64(capacity[k]-counts[k]) {
+				total -= capacity[k] - counts[k]
+				counts[k], full[k], capped = capacity[k], true, true
+			}
+		}
+		if capped {
+			continue
+		}
+
+		order := make([]int, 0, open)
+		for k := range weights {
+			if full[k] {
+				continue
+			}
+			whole := math.Floor(share(k))
+			counts[k] += int(whole)
+			order = append(order, k)
+		}
+		left := total
+		for _, k := range order {
+			left -= int(math.Floor(share(k)))
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			_, ra := math.Modf(share(order[a]))
+			_, rb := math.Modf(share(order[b]))
+			return ra > rb
+		})
+		for _, k := range order[:min(left, len(order))] {
+			counts[k]++
+		}
+		break
+	}
+	return counts
+}
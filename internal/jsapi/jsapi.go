@@ -0,0 +1,142 @@
+// Package jsapi is the JSON interface of the WebAssembly build: requests and
+// results are plain JSON so a browser can call the solvers without a file
+// system. It builds on every platform, the JavaScript binding is in wasm/.
+package jsapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"qap_solver/internal/solvers"
+	"time"
+)
+
+// DefaultSolver is used when a request names no solver
+const DefaultSolver = "simanneal"
+
+// Request describes an instance and the solver to run on it. The instance is
+// given either as QAPLIB text or as flow and distance matrices.
+type Request struct {
+	Instance    string  `json:"instance,omitempty"` // QAPLIB text, see qap.ParseInstance
+	Flow        [][]int `json:"flow,omitempty"`
+	Distance    [][]int `json:"distance,omitempty"`
+	Solver      string  `json:"solver,omitempty"`        // solver configuration as for -solvers, e.g. "tabu:p=10"
+	Seed        int64   `json:"seed,omitempty"`          // equal seeds give equal results, 0 draws one
+	TimeLimitMs int     `json:"time_limit_ms,omitempty"` // stops the run early, 0 = no limit
+	Maximize    bool    `json:"maximize,omitempty"`
+}
+
+// Point is an improvement of the best fitness during the run
+type Point struct {
+	Evaluations int     `json:"evaluations"`
+	TimeMs      float64 `json:"time_ms"`
+	Fitness     int     `json:"fitness"`
+}
+
+// Result is the outcome of a request. Error is set instead of the other
+// fields when the request could not be solved.
+type Result struct {
+	Solver      string  `json:"solver,omitempty"`
+	Solution    []int   `json:"solution,omitempty"` // location of every facility, counting from 0
+	Fitness     int     `json:"fitness"`            // objective value of Solution
+	Seed        int64   `json:"seed,omitempty"`
+	Steps       int     `json:"steps"`
+	Evaluations int     `json:"evaluations"`
+	TimeMs      float64 `json:"time_ms"`
+	Trajectory  []Point `json:"trajectory,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+type metricsSolver interface {
+	SolveWithMetrics(instance *qap.QAPInstance, metricsCollector *metrics.MetricsCollector,
+		instanceName string, runNumber int, opts solvers.SolveOptions) solvers.SolverResult
+}
+
+// SolveJSON decodes a Request, solves it and encodes the Result. Errors are
+// reported in the Result, so the output is always valid JSON.
+func SolveJSON(request []byte) []byte {
+	var req Request
+	result := Result{}
+	if err := json.Unmarshal(request, &req); err != nil {
+		result.Error = fmt.Sprintf("invalid request: %v", err)
+	} else {
+		result = Solve(req)
+	}
+	data, _ := json.Marshal(result)
+	return data
+}
+
+// Solve runs the solver of a request on its instance
+func Solve(req Request) Result {
+	instance, err := req.instance()
+	if err != nil {
+		return Result{Error: err.Error()}
+	}
+	instance.Maximize = req.Maximize
+
+	config := req.Solver
+	if config == "" {
+		config = DefaultSolver
+	}
+	solver, err := solvers.NewSolverFactory().Create(config)
+	if err != nil {
+		return Result{Error: err.Error()}
+	}
+	inner, ok := solver.(metricsSolver)
+	if !ok {
+		return Result{Error: fmt.Sprintf("solver %s does not report metrics", solver.Name())}
+	}
+
+	// A collector without output directory keeps the run in memory
+	collector := &metrics.MetricsCollector{Experiments: make(map[string]map[string]*metrics.ExperimentMetrics)}
+	inner.SolveWithMetrics(instance, collector, "instance", 1, solvers.SolveOptions{
+		Seed:      req.Seed,
+		TimeLimit: time.Duration(req.TimeLimitMs) * time.Millisecond,
+	})
+	var run metrics.RunMetrics
+	for _, experiment := range collector.Experiments["instance"] {
+		run = experiment.Runs[0]
+	}
+
+	result := Result{
+		Solver:      run.SolverName,
+		Solution:    run.Solution,
+		Fitness:     instance.Objective(run.FinalFitness),
+		Seed:        run.Seed,
+		Steps:       run.StepsCount,
+		Evaluations: run.EvaluationsCount,
+		TimeMs:      milliseconds(run.TimeElapsed),
+	}
+	for _, p := range run.Trajectory {
+		result.Trajectory = append(result.Trajectory, Point{p.Evaluations, milliseconds(p.Elapsed), instance.Objective(p.Fitness)})
+	}
+	return result
+}
+
+// instance builds the instance of a request and checks its matrices
+func (req Request) instance() (*qap.QAPInstance, error) {
+	if req.Instance != "" {
+		return qap.ParseInstance([]byte(req.Instance))
+	}
+	n := len(req.Flow)
+	if n < 2 {
+		return nil, fmt.Errorf("request needs an instance or flow and distance matrices of size 2 or more")
+	}
+	for k, matrix := range [][][]int{req.Flow, req.Distance} {
+		name := []string{"flow", "distance"}[k]
+		if len(matrix) != n {
+			return nil, fmt.Errorf("%s matrix has %d rows, expected %d", name, len(matrix), n)
+		}
+		for i, row := range matrix {
+			if len(row) != n {
+				return nil, fmt.Errorf("%s matrix row %d has %d values, expected %d", name, i+1, len(row), n)
+			}
+		}
+	}
+	return &qap.QAPInstance{Size: n, FlowMatrix: req.Flow, DistanceMatrix: req.Distance}, nil
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultAnomalyZ is the z-score threshold of Anomalies used when the
+// collector leaves AnomalyZ at zero
+const DefaultAnomalyZ = 2.0
+
+// minAnomalySiblings is the number of sibling runs a run is compared with at
+// least, fewer give no meaningful spread
+const minAnomalySiblings = 4
+
+// Anomaly is a run whose best fitness stopped improving unusually early
+// compared with the other runs of its solver on the instance
+type Anomaly struct {
+	InstanceName    string
+	SolverName      string
+	Run             int
+	LastImprovement time.Duration // time of the last trajectory point
+	LastEvaluations int           // evaluations at the last trajectory point
+	SiblingMean     time.Duration // mean LastImprovement of the other runs
+	SiblingStd      time.Duration
+	ZScore          float64 // (LastImprovement - SiblingMean) / SiblingStd
+	FinalFitness    int
+	Termination     string
+}
+
+// Anomalies flags the runs whose time of last improvement lies more than z
+// standard deviations before the mean of their sibling runs, the other runs
+// of the solver on the instance. Every run is compared with its siblings
+// alone, so a single stalled run does not hide itself by widening the
+// spread. Runs that reached the known optimum are not flagged, they had
+// nothing left to improve, and groups whose siblings all stall at the same
+// time have no spread to judge by.
+func (c *MetricsCollector) Anomalies(z float64) []Anomaly {
+	var anomalies []Anomaly
+	for instanceName, solvers := range c.Experiments {
+		optimum, hasOptimum := c.optimum(instanceName)
+		for solverName, experiment := range solvers {
+			runs := experiment.Runs
+			if len(runs)-1 < minAnomalySiblings {
+				continue
+			}
+			last := make([]float64, len(runs))
+			for k, run := range runs {
+				last[k] = float64(lastImprovement(run))
+			}
+			for k, run := range runs {
+				if hasOptimum && run.FinalFitness == optimum {
+					continue
+				}
+				mean, std := siblingStats(last, k)
+				if std == 0 {
+					continue
+				}
+				score := (last[k] - mean) / std
+				if score > -z {
+					continue
+				}
+				anomalies = append(anomalies, Anomaly{
+					InstanceName:    instanceName,
+					SolverName:      solverName,
+					Run:             run.Run,
+					LastImprovement: time.Duration(last[k]),
+					LastEvaluations: lastEvaluations(run),
+					SiblingMean:     time.Duration(mean),
+					SiblingStd:      time.Duration(std),
+					ZScore:          score,
+					FinalFitness:    run.FinalFitness,
+					Termination:     run.TerminationReason,
+				})
+			}
+		}
+	}
+	sort.Slice(anomalies, func(i, j int) bool {
+		a, b := anomalies[i], anomalies[j]
+		if a.InstanceName != b.InstanceName {
+			return a.InstanceName < b.InstanceName
+		}
+		if a.SolverName != b.SolverName {
+			return a.SolverName < b.SolverName
+		}
+		return a.Run < b.Run
+	})
+	return anomalies
+}
+
+// lastImprovement is the time of the last improvement of a run, the sampled
+// trajectory always keeps its latest point
+func lastImprovement(run RunMetrics) time.Duration {
+	if len(run.Trajectory) == 0 {
+		return 0
+	}
+	return run.Trajectory[len(run.Trajectory)-1].Elapsed
+}
+
+// lastEvaluations is the evaluation count at the last improvement of a run
+func lastEvaluations(run RunMetrics) int {
+	if len(run.Trajectory) == 0 {
+		return 0
+	}
+	return run.Trajectory[len(run.Trajectory)-1].Evaluations
+}
+
+// siblingStats returns the mean and sample standard deviation of values
+// without the one at skip
+func siblingStats(values []float64, skip int) (mean, std float64) {
+	n := float64(len(values) - 1)
+	for k, v := range values {
+		if k != skip {
+			mean += v
+		}
+	}
+	mean /= n
+	for k, v := range values {
+		if k != skip {
+			std += (v - mean) * (v - mean)
+		}
+	}
+	return mean, math.Sqrt(std / (n - 1))
+}
+
+// SaveAnomaliesCSV writes the runs flagged by Anomalies with the collector's
+// AnomalyZ to anomalies_<timestamp>.csv for manual inspection, nothing when
+// no run is flagged or AnomalyZ is negative
+func (c *MetricsCollector) SaveAnomaliesCSV() error {
+	if c.AnomalyZ < 0 {
+		return nil
+	}
+	anomalies := c.Anomalies(cmp.Or(c.AnomalyZ, DefaultAnomalyZ))
+	if len(anomalies) == 0 {
+		return nil
+	}
+
+	path := filepath.Join(c.OutputDir, fmt.Sprintf("anomalies_%s.csv", c.Timestamp))
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{
+		"Instance", "Solver", "Run", c.Output.TimeColumn("LastImprovement"), c.Output.TimeColumn("SiblingMean"),
+		c.Output.TimeColumn("SiblingStd"), "ZScore", "LastImprovementEvals", "FinalFitness", "Termination",
+	})
+	for _, a := range anomalies {
+		writer.Write([]string{
+			a.InstanceName, a.SolverName, strconv.Itoa(a.Run),
+			c.Output.Duration(a.LastImprovement), c.Output.Duration(a.SiblingMean), c.Output.Duration(a.SiblingStd),
+			c.Output.Float(a.ZScore), strconv.Itoa(a.LastEvaluations), strconv.Itoa(c.objective(a.FinalFitness)), a.Termination,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
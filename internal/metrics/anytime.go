@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"math"
+	"time"
+)
+
+// TrajectoryPoint records the best fitness known at some point of a run
+type TrajectoryPoint struct {
+	Evaluations int
+	Elapsed     time.Duration
+	Fitness     int
+}
+
+// PrimalGap is the normalized distance of fitness to the reference value,
+// 0 when they are equal and 1 when they differ in sign or there is no fitness yet
+func PrimalGap(fitness, reference int) float64 {
+	if fitness == reference {
+		return 0
+	}
+	if (fitness < 0) != (reference < 0) {
+		return 1
+	}
+	f, r := math.Abs(float64(fitness)), math.Abs(float64(reference))
+	return math.Abs(f-r) / math.Max(f, r)
+}
+
+// PrimalIntegral integrates the primal gap of the best-so-far fitness over
+// the run time in seconds. Before the first trajectory point the gap is 1.
+// Lower values mean good solutions were found earlier.
+func PrimalIntegral(trajectory []TrajectoryPoint, reference int, total time.Duration) float64 {
+	integral := 0.0
+	prevTime := time.Duration(0)
+	prevGap := 1.0
+
+	for _, p := range trajectory {
+		integral += prevGap * (p.Elapsed - prevTime).Seconds()
+		prevTime = p.Elapsed
+		prevGap = PrimalGap(p.Fitness, reference)
+	}
+	if total > prevTime {
+		integral += prevGap * (total - prevTime).Seconds()
+	}
+	return integral
+}
+
+// ConvergenceAUC is the area under the primal gap curve over the evaluation
+// budget of the run, normalized to [0, 1]. Unlike PrimalIntegral it does not
+// depend on machine speed.
+func ConvergenceAUC(trajectory []TrajectoryPoint, reference int, totalEvaluations int) float64 {
+	if totalEvaluations <= 0 {
+		if len(trajectory) == 0 {
+			return 1
+		}
+		return PrimalGap(trajectory[len(trajectory)-1].Fitness, reference)
+	}
+
+	area := 0.0
+	prevEvals := 0
+	prevGap := 1.0
+
+	for _, p := range trajectory {
+		area += prevGap * float64(p.Evaluations-prevEvals)
+		prevEvals = p.Evaluations
+		prevGap = PrimalGap(p.Fitness, reference)
+	}
+	if totalEvaluations > prevEvals {
+		area += prevGap * float64(totalEvaluations-prevEvals)
+	}
+	return area / float64(totalEvaluations)
+}
+
+// AnytimePercents are the budget percentages reported by the BestAt columns
+var AnytimePercents = []int{10, 25, 50}
+
+// BestAtTime returns the best fitness found within percent of the run time
+// total, false when the trajectory has no point that early. Thinned
+// trajectories may report a slightly older best value.
+func BestAtTime(trajectory []TrajectoryPoint, total time.Duration, percent int) (int, bool) {
+	limit := total * time.Duration(percent) / 100
+	return bestBefore(trajectory, func(p TrajectoryPoint) bool { return p.Elapsed <= limit })
+}
+
+// BestAtEvaluations returns the best fitness found within percent of the
+// evaluations total, see BestAtTime
+func BestAtEvaluations(trajectory []TrajectoryPoint, total int, percent int) (int, bool) {
+	limit := total * percent / 100
+	return bestBefore(trajectory, func(p TrajectoryPoint) bool { return p.Evaluations <= limit })
+}
+
+// bestBefore returns the fitness of the last point within the budget, the
+// trajectory holds the best fitness so far and so is non-increasing
+func bestBefore(trajectory []TrajectoryPoint, within func(p TrajectoryPoint) bool) (int, bool) {
+	fitness, found := 0, false
+	for _, p := range trajectory {
+		if !within(p) {
+			break
+		}
+		fitness, found = p.Fitness, true
+	}
+	return fitness, found
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Artifacts is the directory of one run where solvers dump algorithm-specific
+// state, such as the final tabu list or the elite pool of a parallel search,
+// as CSV files. The directory is created with the first file; runs without
+// artifacts leave nothing behind. Write errors are kept and reported by
+// Close, a failed dump does not stop the run. A nil *Artifacts is valid and
+// discards everything.
+type Artifacts struct {
+	root    string // output directory the path is relative to
+	path    string
+	written bool
+	err     error
+}
+
+// NewArtifacts collects the artifacts of a run in root/path
+func NewArtifacts(root, path string) *Artifacts {
+	return &Artifacts{root: root, path: path}
+}
+
+// Enabled reports whether artifacts are collected, so solvers can skip
+// bookkeeping that only feeds them
+func (a *Artifacts) Enabled() bool {
+	return a != nil
+}
+
+// WriteCSV writes rows, the first being the header, to the file name of the
+// artifacts directory
+func (a *Artifacts) WriteCSV(name string, rows [][]string) {
+	if a == nil || a.err != nil {
+		return
+	}
+	dir := filepath.Join(a.root, a.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		a.err = err
+		return
+	}
+	if err := writeCSVRows(filepath.Join(dir, name), rows); err != nil {
+		a.err = err
+		return
+	}
+	a.written = true
+}
+
+// WriteMatrix writes a square table of counts with rows labeled by rowLabel
+// and columns by colLabel and their index, e.g. facilities by locations
+func (a *Artifacts) WriteMatrix(name, rowLabel, colLabel string, matrix [][]int) {
+	if a == nil {
+		return
+	}
+	header := []string{rowLabel}
+	if len(matrix) > 0 {
+		for c := range matrix[0] {
+			header = append(header, colLabel+strconv.Itoa(c))
+		}
+	}
+	rows := [][]string{header}
+	for r, values := range matrix {
+		row := []string{strconv.Itoa(r)}
+		for _, v := range values {
+			row = append(row, strconv.Itoa(v))
+		}
+		rows = append(rows, row)
+	}
+	a.WriteCSV(name, rows)
+}
+
+// Path returns the artifacts directory relative to the output directory,
+// empty when nothing was written
+func (a *Artifacts) Path() string {
+	if a == nil || !a.written {
+		return ""
+	}
+	return filepath.ToSlash(a.path)
+}
+
+// Close returns the first error writing the artifacts
+func (a *Artifacts) Close() error {
+	if a == nil {
+		return nil
+	}
+	return a.err
+}
@@ -0,0 +1,230 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// column describes one column of the results or the summary file. Duration
+// columns get the time unit appended to their header, see TimeColumn.
+type column[T any] struct {
+	name     string
+	duration bool
+	value    func(c *MetricsCollector, row T) string
+}
+
+func (col column[T]) header(o OutputOptions) string {
+	if col.duration {
+		return o.TimeColumn(col.name)
+	}
+	return col.name
+}
+
+// runRow is a row of the results file
+type runRow struct {
+	instance, solver string
+	reference        int
+	run              RunMetrics
+}
+
+// runColumns are the columns of the results file in their default order
+var runColumns = slices.Concat([]column[runRow]{
+	{"Instance", false, func(c *MetricsCollector, r runRow) string { return r.instance }},
+	{"Solver", false, func(c *MetricsCollector, r runRow) string { return r.solver }},
+	{"Run", false, func(c *MetricsCollector, r runRow) string { return strconv.Itoa(r.run.Run) }},
+	{"Seed", false, func(c *MetricsCollector, r runRow) string { return strconv.FormatInt(r.run.Seed, 10) }},
+	{"InitialFitness", false, func(c *MetricsCollector, r runRow) string { return strconv.Itoa(c.objective(r.run.InitialFitness)) }},
+	{"FinalFitness", false, func(c *MetricsCollector, r runRow) string { return strconv.Itoa(c.objective(r.run.FinalFitness)) }},
+	{"QAPCost", false, func(c *MetricsCollector, r runRow) string { return strconv.Itoa(r.run.QAPCost) }},
+	{"RelocationCost", false, func(c *MetricsCollector, r runRow) string { return strconv.Itoa(r.run.RelocationCost) }},
+	{"ConstraintViolation", false, func(c *MetricsCollector, r runRow) string { return strconv.Itoa(r.run.ConstraintViolation) }},
+	{"GapPercent", false, func(c *MetricsCollector, r runRow) string {
+		return c.Output.Float(GapPercent(r.run.FinalFitness, r.reference))
+	}},
+	{"OptimumDistance", false, func(c *MetricsCollector, r runRow) string {
+		if distance, ok := c.OptimumDistance(r.run); ok {
+			return strconv.Itoa(distance)
+		}
+		return ""
+	}},
+	{"Time", true, func(c *MetricsCollector, r runRow) string { return c.Output.Duration(r.run.TimeElapsed) }},
+	{"CPUTime", true, func(c *MetricsCollector, r runRow) string { return c.Output.Duration(r.run.CPUTime) }},
+	{"SolverTime", true, func(c *MetricsCollector, r runRow) string { return c.Output.Duration(r.run.SolverTime()) }},
+	{"OverheadTime", true, func(c *MetricsCollector, r runRow) string { return c.Output.Duration(r.run.OverheadTime) }},
+	{"Steps", false, func(c *MetricsCollector, r runRow) string { return strconv.Itoa(r.run.StepsCount) }},
+	{"Evaluations", false, func(c *MetricsCollector, r runRow) string { return strconv.Itoa(r.run.EvaluationsCount) }},
+	{"SolutionsChecked", false, func(c *MetricsCollector, r runRow) string { return strconv.Itoa(r.run.SolutionsChecked) }},
+	{"AcceptedMoves", false, func(c *MetricsCollector, r runRow) string { return strconv.Itoa(r.run.AcceptedMoves) }},
+	{"RejectedMoves", false, func(c *MetricsCollector, r runRow) string { return strconv.Itoa(r.run.RejectedMoves) }},
+	{"ImprovingMoves", false, func(c *MetricsCollector, r runRow) string { return strconv.Itoa(r.run.ImprovingMoves) }},
+	{"AllocatedBytes", false, func(c *MetricsCollector, r runRow) string { return strconv.FormatUint(r.run.AllocatedBytes, 10) }},
+	{"Mallocs", false, func(c *MetricsCollector, r runRow) string { return strconv.FormatUint(r.run.Mallocs, 10) }},
+	{"PeakHeapBytes", false, func(c *MetricsCollector, r runRow) string { return strconv.FormatUint(r.run.PeakHeapBytes, 10) }},
+	{"Termination", false, func(c *MetricsCollector, r runRow) string { return r.run.TerminationReason }},
+	{"Artifacts", false, func(c *MetricsCollector, r runRow) string { return r.run.Artifacts }},
+	{"PrimalIntegral", false, func(c *MetricsCollector, r runRow) string {
+		return c.Output.Float(PrimalIntegral(r.run.Trajectory, r.reference, r.run.TimeElapsed))
+	}},
+	{"ConvergenceAUC", false, func(c *MetricsCollector, r runRow) string {
+		return c.Output.Float(ConvergenceAUC(r.run.Trajectory, r.reference, r.run.EvaluationsCount))
+	}},
+}, anytimeColumns(), []column[runRow]{
+	{"Validation", false, func(c *MetricsCollector, r runRow) string { return validationStatus(r.run) }},
+	{"Solution", false, func(c *MetricsCollector, r runRow) string { return fmt.Sprintf("%v", r.run.Solution) }},
+	{"Trajectory", false, func(c *MetricsCollector, r runRow) string {
+		return FormatTrajectory(c.objectiveTrajectory(r.run.Trajectory))
+	}},
+})
+
+// anytimeColumns report the best fitness after every AnytimePercents of the
+// run time and of the evaluations, e.g. BestAt10Time and BestAt10Evals
+func anytimeColumns() []column[runRow] {
+	var columns []column[runRow]
+	for _, percent := range AnytimePercents {
+		columns = append(columns,
+			column[runRow]{fmt.Sprintf("BestAt%dTime", percent), false, func(c *MetricsCollector, r runRow) string {
+				return c.bestAt(BestAtTime(r.run.Trajectory, r.run.TimeElapsed, percent))
+			}},
+			column[runRow]{fmt.Sprintf("BestAt%dEvals", percent), false, func(c *MetricsCollector, r runRow) string {
+				return c.bestAt(BestAtEvaluations(r.run.Trajectory, r.run.EvaluationsCount, percent))
+			}})
+	}
+	return columns
+}
+
+// bestAt formats a BestAt value as objective value, empty when there is none
+func (c *MetricsCollector) bestAt(fitness int, found bool) string {
+	if !found {
+		return ""
+	}
+	return strconv.Itoa(c.objective(fitness))
+}
+
+// summaryColumns are the columns of the summary file in their default order
+var summaryColumns = slices.Concat([]column[SolverSummary]{
+	{"Instance", false, func(c *MetricsCollector, s SolverSummary) string { return s.InstanceName }},
+	{"Solver", false, func(c *MetricsCollector, s SolverSummary) string { return s.SolverName }},
+	{"Runs", false, func(c *MetricsCollector, s SolverSummary) string { return strconv.Itoa(s.Runs) }},
+	{"Reference", false, func(c *MetricsCollector, s SolverSummary) string { return strconv.Itoa(c.objective(s.Reference)) }},
+	{"BestFitness", false, func(c *MetricsCollector, s SolverSummary) string { return strconv.Itoa(c.objective(s.BestFitness)) }},
+	{"MeanFitness", false, func(c *MetricsCollector, s SolverSummary) string {
+		return c.Output.Float(c.objectiveFloat(s.MeanFitness))
+	}},
+	{"WorstFitness", false, func(c *MetricsCollector, s SolverSummary) string { return strconv.Itoa(c.objective(s.WorstFitness)) }},
+	{"MeanGapPercent", false, func(c *MetricsCollector, s SolverSummary) string { return c.Output.Float(s.MeanGapPercent) }},
+	{"MeanOptimumDistance", false, func(c *MetricsCollector, s SolverSummary) string {
+		if s.OptimumDistanceRuns == 0 {
+			return ""
+		}
+		return c.Output.Float(s.MeanOptimumDistance)
+	}},
+	{"MeanTime", true, func(c *MetricsCollector, s SolverSummary) string { return c.Output.Duration(s.MeanTime) }},
+	{"MeanCPUTime", true, func(c *MetricsCollector, s SolverSummary) string { return c.Output.Duration(s.MeanCPUTime) }},
+	{"MeanSolverTime", true, func(c *MetricsCollector, s SolverSummary) string { return c.Output.Duration(s.MeanSolverTime) }},
+	{"MeanOverheadTime", true, func(c *MetricsCollector, s SolverSummary) string { return c.Output.Duration(s.MeanOverheadTime) }},
+	{"MeanEvaluations", false, func(c *MetricsCollector, s SolverSummary) string { return c.Output.Float(s.MeanEvaluations) }},
+	{"MeanPrimalIntegral", false, func(c *MetricsCollector, s SolverSummary) string { return c.Output.Float(s.MeanPrimalIntegral) }},
+	{"MeanConvergenceAUC", false, func(c *MetricsCollector, s SolverSummary) string { return c.Output.Float(s.MeanConvergenceAUC) }},
+}, meanAnytimeColumns(), []column[SolverSummary]{
+	{"DuplicateRuns", false, func(c *MetricsCollector, s SolverSummary) string { return strconv.Itoa(s.DuplicateRuns) }},
+	{"SkippedRuns", false, func(c *MetricsCollector, s SolverSummary) string { return strconv.Itoa(s.SkippedRuns) }},
+	{"FitnessCI95", false, func(c *MetricsCollector, s SolverSummary) string {
+		if math.IsInf(s.FitnessCIHalfWidth, 1) {
+			return ""
+		}
+		return c.Output.Float(s.FitnessCIHalfWidth)
+	}},
+})
+
+// meanAnytimeColumns are the means of the anytimeColumns, e.g.
+// MeanBestAt10Time, empty when no run has a value
+func meanAnytimeColumns() []column[SolverSummary] {
+	var columns []column[SolverSummary]
+	for _, col := range anytimeColumns() {
+		name := col.name
+		columns = append(columns, column[SolverSummary]{"Mean" + name, false, func(c *MetricsCollector, s SolverSummary) string {
+			mean, ok := s.MeanBestAt[name]
+			if !ok {
+				return ""
+			}
+			return c.Output.Float(mean)
+		}})
+	}
+	return columns
+}
+
+// RunColumnNames lists the built-in columns of the results file
+func RunColumnNames() []string { return columnNames(runColumns) }
+
+// SummaryColumnNames lists the built-in columns of the summary file
+func SummaryColumnNames() []string { return columnNames(summaryColumns) }
+
+func columnNames[T any](columns []column[T]) []string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.name
+	}
+	return names
+}
+
+// selectColumns returns the columns listed in names, in that order, followed
+// by the derived ones; no names select all built-in columns. Names match
+// case-insensitively, duration columns also with their unit, e.g. "TimeMs".
+func selectColumns[T any](all []column[T], names []string, derived []column[T], o OutputOptions) ([]column[T], error) {
+	available := append(append([]column[T]{}, all...), derived...)
+	if len(names) == 0 {
+		return available, nil
+	}
+	var selected []column[T]
+	for _, name := range names {
+		found := false
+		for _, col := range available {
+			if strings.EqualFold(name, col.name) || strings.EqualFold(name, col.header(o)) {
+				selected = append(selected, col)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q, use one of %v or a derived metric", name, columnNames(available))
+		}
+	}
+	return selected, nil
+}
+
+// resultColumns returns the configured columns of the results file
+func (o OutputOptions) resultColumns() ([]column[runRow], error) {
+	var derived []column[runRow]
+	for _, m := range o.Derived {
+		derived = append(derived, column[runRow]{m.Name, false, func(c *MetricsCollector, r runRow) string {
+			return c.Output.Float(m.Eval(c.derivedVariables(r.run, r.reference)))
+		}})
+	}
+	return selectColumns(runColumns, o.Columns, derived, o)
+}
+
+// summaryTableColumns returns the configured columns of the summary file,
+// the mean of a derived metric is the column "Mean" + its name
+func (o OutputOptions) summaryTableColumns() ([]column[SolverSummary], error) {
+	var derived []column[SolverSummary]
+	for _, m := range o.Derived {
+		derived = append(derived, column[SolverSummary]{"Mean" + m.Name, false, func(c *MetricsCollector, s SolverSummary) string {
+			return c.Output.Float(s.MeanDerived[m.Name])
+		}})
+	}
+	return selectColumns(summaryColumns, o.SummaryColumns, derived, o)
+}
+
+// ParseColumns splits a comma separated list of column names
+func ParseColumns(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
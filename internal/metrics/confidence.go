@@ -0,0 +1,42 @@
+package metrics
+
+import "math"
+
+// ConfidenceHalfWidth returns the half-width of the Student t confidence
+// interval for the mean of values at the given level, e.g. 0.95. It is
+// infinite for fewer than two values.
+func ConfidenceHalfWidth(values []int, confidence float64) float64 {
+	n := len(values)
+	if n < 2 {
+		return math.Inf(1)
+	}
+	mean := 0.0
+	for _, v := range values {
+		mean += float64(v)
+	}
+	mean /= float64(n)
+	variance := 0.0
+	for _, v := range values {
+		variance += (float64(v) - mean) * (float64(v) - mean)
+	}
+	std := math.Sqrt(variance / float64(n-1))
+	return studentQuantile(confidence, float64(n-1)) * std / math.Sqrt(float64(n))
+}
+
+// studentQuantile returns the t with P(|T| <= t) = confidence for Student's t
+// with df degrees of freedom, by bisection on studentTwoSided
+func studentQuantile(confidence, df float64) float64 {
+	low, high := 0.0, 1.0
+	for studentTwoSided(high, df) > 1-confidence {
+		high *= 2
+	}
+	for i := 0; i < 100 && high-low > 1e-9; i++ {
+		t := (low + high) / 2
+		if studentTwoSided(t, df) > 1-confidence {
+			low = t
+		} else {
+			high = t
+		}
+	}
+	return (low + high) / 2
+}
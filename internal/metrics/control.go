@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ControlEvent is a change of the experiment settings made while it runs,
+// such as a new time limit or the abort of a solver's remaining runs
+type ControlEvent struct {
+	Time    time.Time
+	Command string
+	Solver  string
+	Value   string
+}
+
+// AddControlEvent records a setting change
+func (c *MetricsCollector) AddControlEvent(event ControlEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ControlEvents = append(c.ControlEvents, event)
+}
+
+// SaveControlCSV writes the setting changes to control_<timestamp>.csv. No
+// file is written when the settings never changed.
+func (c *MetricsCollector) SaveControlCSV() error {
+	if len(c.ControlEvents) == 0 {
+		return nil
+	}
+
+	path := filepath.Join(c.OutputDir, fmt.Sprintf("control_%s.csv", c.Timestamp))
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	writer.Write([]string{"Time", "Command", "Solver", "Value"})
+	for _, event := range c.ControlEvents {
+		writer.Write([]string{event.Time.Format(time.RFC3339), event.Command, event.Solver, event.Value})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
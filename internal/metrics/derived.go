@@ -0,0 +1,241 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DerivedMetric is a user-defined run metric computed from other run metrics
+// with + - * / and parentheses, e.g. "EvalsPerSecond=Evaluations/Time". The
+// variables are listed by DerivedVariables. It becomes a results column, and
+// its mean the summary column "Mean" + Name.
+type DerivedMetric struct {
+	Name       string
+	Expression string
+	root       derivedNode
+}
+
+// ParseDerivedMetrics parses definitions "Name=expression" separated by
+// semicolons
+func ParseDerivedMetrics(spec string) ([]DerivedMetric, error) {
+	var metrics []DerivedMetric
+	for _, definition := range strings.Split(spec, ";") {
+		if strings.TrimSpace(definition) == "" {
+			continue
+		}
+		name, expression, ok := strings.Cut(definition, "=")
+		name, expression = strings.TrimSpace(name), strings.TrimSpace(expression)
+		if !ok || name == "" || strings.IndexFunc(name, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		}) >= 0 {
+			return nil, fmt.Errorf("derived metric %q: expected Name=expression", definition)
+		}
+
+		p := &derivedParser{source: expression}
+		p.next()
+		root, err := p.parseSum()
+		if err == nil && p.token != "" {
+			err = fmt.Errorf("unexpected %q", p.token)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("derived metric %s: %v", name, err)
+		}
+		metrics = append(metrics, DerivedMetric{Name: name, Expression: expression, root: root})
+	}
+	return metrics, nil
+}
+
+// Eval computes the metric for a run, divisions by zero give NaN or ±Inf
+func (m DerivedMetric) Eval(variables map[string]float64) float64 {
+	return m.root.eval(variables)
+}
+
+// derivedVariables returns the run metrics usable in derived metrics: fitness
+// values as objective values and times in seconds
+func (c *MetricsCollector) derivedVariables(run RunMetrics, reference int) map[string]float64 {
+	variables := map[string]float64{
+		"InitialFitness":      float64(c.objective(run.InitialFitness)),
+		"FinalFitness":        float64(c.objective(run.FinalFitness)),
+		"Reference":           float64(c.objective(reference)),
+		"GapPercent":          GapPercent(run.FinalFitness, reference),
+		"QAPCost":             float64(run.QAPCost),
+		"RelocationCost":      float64(run.RelocationCost),
+		"ConstraintViolation": float64(run.ConstraintViolation),
+		"Time":                run.TimeElapsed.Seconds(),
+		"CPUTime":             run.CPUTime.Seconds(),
+		"SolverTime":          run.SolverTime().Seconds(),
+		"OverheadTime":        run.OverheadTime.Seconds(),
+		"Steps":               float64(run.StepsCount),
+		"Evaluations":         float64(run.EvaluationsCount),
+		"SolutionsChecked":    float64(run.SolutionsChecked),
+		"AcceptedMoves":       float64(run.AcceptedMoves),
+		"RejectedMoves":       float64(run.RejectedMoves),
+		"ImprovingMoves":      float64(run.ImprovingMoves),
+		"AllocatedBytes":      float64(run.AllocatedBytes),
+		"Mallocs":             float64(run.Mallocs),
+		"PeakHeapBytes":       float64(run.PeakHeapBytes),
+		"PrimalIntegral":      PrimalIntegral(run.Trajectory, reference, run.TimeElapsed),
+		"ConvergenceAUC":      ConvergenceAUC(run.Trajectory, reference, run.EvaluationsCount),
+	}
+	variables["OptimumDistance"] = math.NaN()
+	if distance, ok := c.OptimumDistance(run); ok {
+		variables["OptimumDistance"] = float64(distance)
+	}
+	// BestAt values without a trajectory point that early are NaN
+	for _, percent := range AnytimePercents {
+		variables[fmt.Sprintf("BestAt%dTime", percent)] = c.bestAtValue(BestAtTime(run.Trajectory, run.TimeElapsed, percent))
+		variables[fmt.Sprintf("BestAt%dEvals", percent)] = c.bestAtValue(BestAtEvaluations(run.Trajectory, run.EvaluationsCount, percent))
+	}
+	return variables
+}
+
+func (c *MetricsCollector) bestAtValue(fitness int, found bool) float64 {
+	if !found {
+		return math.NaN()
+	}
+	return float64(c.objective(fitness))
+}
+
+// DerivedVariables lists the variables of derived metrics
+func DerivedVariables() []string {
+	var names []string
+	for name := range (&MetricsCollector{}).derivedVariables(RunMetrics{}, 0) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type derivedNode interface {
+	eval(variables map[string]float64) float64
+}
+
+type derivedNumber float64
+type derivedVariable string
+type derivedNegation struct{ operand derivedNode }
+type derivedBinary struct {
+	op          byte
+	left, right derivedNode
+}
+
+func (n derivedNumber) eval(map[string]float64) float64     { return float64(n) }
+func (n derivedVariable) eval(v map[string]float64) float64 { return v[string(n)] }
+func (n derivedNegation) eval(v map[string]float64) float64 { return -n.operand.eval(v) }
+
+func (n derivedBinary) eval(v map[string]float64) float64 {
+	left, right := n.left.eval(v), n.right.eval(v)
+	switch n.op {
+	case '+':
+		return left + right
+	case '-':
+		return left - right
+	case '*':
+		return left * right
+	default: // '/'
+		return left / right
+	}
+}
+
+// derivedParser is a recursive descent parser over the tokens of an expression
+type derivedParser struct {
+	source string
+	pos    int
+	token  string // current token, "" at the end
+}
+
+// next advances to the next token: an operator, a parenthesis, a variable
+// name or a number
+func (p *derivedParser) next() {
+	for p.pos < len(p.source) && unicode.IsSpace(rune(p.source[p.pos])) {
+		p.pos++
+	}
+	if p.pos >= len(p.source) {
+		p.token = ""
+		return
+	}
+	rest := p.source[p.pos:]
+	if strings.ContainsRune("+-*/()", rune(rest[0])) {
+		p.token = rest[:1]
+		p.pos++
+		return
+	}
+	end := 0
+	for end < len(rest) {
+		r := rune(rest[end])
+		isExponentSign := (r == '+' || r == '-') && end > 0 && (rest[end-1] == 'e' || rest[end-1] == 'E') &&
+			unicode.IsDigit(rune(rest[0]))
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '_' && !isExponentSign {
+			break
+		}
+		end++
+	}
+	if end == 0 {
+		end = 1 // a single unexpected character
+	}
+	p.token = rest[:end]
+	p.pos += end
+}
+
+func (p *derivedParser) parseSum() (derivedNode, error) {
+	left, err := p.parseProduct()
+	for err == nil && (p.token == "+" || p.token == "-") {
+		op := p.token[0]
+		p.next()
+		var right derivedNode
+		if right, err = p.parseProduct(); err == nil {
+			left = derivedBinary{op, left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *derivedParser) parseProduct() (derivedNode, error) {
+	left, err := p.parseFactor()
+	for err == nil && (p.token == "*" || p.token == "/") {
+		op := p.token[0]
+		p.next()
+		var right derivedNode
+		if right, err = p.parseFactor(); err == nil {
+			left = derivedBinary{op, left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *derivedParser) parseFactor() (derivedNode, error) {
+	token := p.token
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end")
+	case token == "-":
+		p.next()
+		operand, err := p.parseFactor()
+		return derivedNegation{operand}, err
+	case token == "(":
+		p.next()
+		inner, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.token != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.next()
+		return inner, nil
+	}
+
+	p.next()
+	if value, err := strconv.ParseFloat(token, 64); err == nil {
+		return derivedNumber(value), nil
+	}
+	for _, name := range DerivedVariables() {
+		if strings.EqualFold(name, token) {
+			return derivedVariable(name), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown variable %q, use one of %v", token, DerivedVariables())
+}
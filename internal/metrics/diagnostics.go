@@ -0,0 +1,277 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A temperature curve starts with windows of minAnnealingWindow iterations
+// and has at most maxAnnealingRows rows. When a run has more windows,
+// neighboring windows are merged and the window doubles.
+const (
+	minAnnealingWindow = 50
+	maxAnnealingRows   = 1000
+)
+
+// Diagnostics collects solver state of one run for visual parameter tuning:
+// how often tabu search made facility-location assignments tabu, the
+// temperature and acceptance rate of simulated annealing, and the use and
+// success of the operators a solver chooses among. Close writes what
+// was collected as CSV and SVG files. A nil *Diagnostics is valid and
+// discards everything.
+type Diagnostics struct {
+	prefix string // path of the files without suffix
+
+	tabu       [][]int // tabu[facility][location], times the assignment was made tabu
+	blocked    [][]int // candidate moves of a facility to a location rejected as tabu
+	aspiration [][]int // tabu moves accepted by the aspiration criterion
+
+	window    int // annealing iterations per row
+	annealing []AnnealingWindow
+
+	operators []OperatorStats
+}
+
+// AnnealingWindow aggregates consecutive annealing iterations
+type AnnealingWindow struct {
+	Iteration     int     // first iteration of the window
+	Temperature   float64 // temperature at the end of the window
+	Proposed      int
+	Accepted      int
+	WorseProposed int // proposed moves that worsen the fitness
+	WorseAccepted int
+}
+
+// NewDiagnostics collects diagnostics written to files starting with prefix
+func NewDiagnostics(prefix string) *Diagnostics {
+	return &Diagnostics{prefix: prefix, window: minAnnealingWindow}
+}
+
+// TabuAssignment records that facility at location was made tabu
+func (d *Diagnostics) TabuAssignment(n, facility, location int) {
+	if d == nil {
+		return
+	}
+	d.grow(n)
+	d.tabu[facility][location]++
+}
+
+// TabuBlocked records a candidate move of facility to location rejected as
+// tabu, or accepted anyway by the aspiration criterion
+func (d *Diagnostics) TabuBlocked(n, facility, location int, aspiration bool) {
+	if d == nil {
+		return
+	}
+	d.grow(n)
+	if aspiration {
+		d.aspiration[facility][location]++
+	} else {
+		d.blocked[facility][location]++
+	}
+}
+
+func (d *Diagnostics) grow(n int) {
+	if d.tabu != nil {
+		return
+	}
+	d.tabu, d.blocked, d.aspiration = make([][]int, n), make([][]int, n), make([][]int, n)
+	for i := 0; i < n; i++ {
+		d.tabu[i], d.blocked[i], d.aspiration[i] = make([]int, n), make([]int, n), make([]int, n)
+	}
+}
+
+// Anneal records one proposed annealing move at the given temperature
+func (d *Diagnostics) Anneal(iteration int, temperature float64, worse, accepted bool) {
+	if d == nil {
+		return
+	}
+	if len(d.annealing) == 0 || d.annealing[len(d.annealing)-1].Proposed >= d.window {
+		if len(d.annealing) == maxAnnealingRows {
+			d.mergeAnnealing()
+		}
+		if len(d.annealing) == 0 || d.annealing[len(d.annealing)-1].Proposed >= d.window {
+			d.annealing = append(d.annealing, AnnealingWindow{Iteration: iteration})
+		}
+	}
+	w := &d.annealing[len(d.annealing)-1]
+	w.Temperature = temperature
+	w.Proposed++
+	if worse {
+		w.WorseProposed++
+	}
+	if accepted {
+		w.Accepted++
+		if worse {
+			w.WorseAccepted++
+		}
+	}
+}
+
+// mergeAnnealing merges neighboring windows and doubles the window size
+func (d *Diagnostics) mergeAnnealing() {
+	merged := d.annealing[:0]
+	for k := 0; k < len(d.annealing); k += 2 {
+		w := d.annealing[k]
+		if k+1 < len(d.annealing) {
+			next := d.annealing[k+1]
+			w.Temperature = next.Temperature
+			w.Proposed += next.Proposed
+			w.Accepted += next.Accepted
+			w.WorseProposed += next.WorseProposed
+			w.WorseAccepted += next.WorseAccepted
+		}
+		merged = append(merged, w)
+	}
+	d.annealing = merged
+	d.window *= 2
+}
+
+// Close writes the collected diagnostics: prefix_tabu.csv and .svg for tabu
+// search, prefix_temperature.csv and .svg for simulated annealing and
+// prefix_operators.csv for solvers choosing among operators
+func (d *Diagnostics) Close() error {
+	if d == nil || (d.tabu == nil && len(d.annealing) == 0 && len(d.operators) == 0) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(d.prefix), 0755); err != nil {
+		return err
+	}
+	if d.tabu != nil {
+		if err := d.writeTabu(); err != nil {
+			return err
+		}
+	}
+	if len(d.annealing) > 0 {
+		if err := d.writeAnnealing(); err != nil {
+			return err
+		}
+	}
+	if len(d.operators) > 0 {
+		return d.writeOperators()
+	}
+	return nil
+}
+
+func (d *Diagnostics) writeTabu() error {
+	rows := [][]string{{"Facility", "Location", "Tabu", "Blocked", "Aspiration"}}
+	for i := range d.tabu {
+		for l := range d.tabu[i] {
+			if d.tabu[i][l]+d.blocked[i][l]+d.aspiration[i][l] == 0 {
+				continue
+			}
+			rows = append(rows, []string{strconv.Itoa(i), strconv.Itoa(l),
+				strconv.Itoa(d.tabu[i][l]), strconv.Itoa(d.blocked[i][l]), strconv.Itoa(d.aspiration[i][l])})
+		}
+	}
+	if err := writeCSVRows(d.prefix+"_tabu.csv", rows); err != nil {
+		return err
+	}
+
+	// Heatmap of the tabu counts, facilities as rows and locations as columns
+	n := len(d.tabu)
+	cell := max(4, min(16, 640/max(n, 1)))
+	peak := 1
+	for i := range d.tabu {
+		for _, v := range d.tabu[i] {
+			peak = max(peak, v)
+		}
+	}
+	var b strings.Builder
+	margin := 40
+	size := margin + n*cell + 10
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-size=\"12\">\n", size, size+10)
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"14\">location →</text>\n", margin)
+	fmt.Fprintf(&b, "<text x=\"12\" y=\"%d\" transform=\"rotate(-90 12 %d)\" text-anchor=\"end\">facility →</text>\n", margin, margin)
+	for i := range d.tabu {
+		for l, v := range d.tabu[i] {
+			// White for never tabu, dark red for the most frequent assignment
+			shade := 255 - int(math.Round(200*float64(v)/float64(peak)))
+			fmt.Fprintf(&b, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"rgb(255,%d,%d)\"><title>facility %d, location %d: tabu %d, blocked %d, aspiration %d</title></rect>\n",
+				margin+l*cell, margin+i*cell, cell, cell, shade, shade, i, l, v, d.blocked[i][l], d.aspiration[i][l])
+		}
+	}
+	b.WriteString("</svg>\n")
+	return os.WriteFile(d.prefix+"_tabu.svg", []byte(b.String()), 0644)
+}
+
+func (d *Diagnostics) writeAnnealing() error {
+	rows := [][]string{{"Iteration", "Temperature", "Proposed", "AcceptanceRate", "WorseAcceptanceRate"}}
+	for _, w := range d.annealing {
+		rows = append(rows, []string{
+			strconv.Itoa(w.Iteration),
+			strconv.FormatFloat(w.Temperature, 'g', 6, 64),
+			strconv.Itoa(w.Proposed),
+			strconv.FormatFloat(rate(w.Accepted, w.Proposed), 'f', 4, 64),
+			strconv.FormatFloat(rate(w.WorseAccepted, w.WorseProposed), 'f', 4, 64),
+		})
+	}
+	if err := writeCSVRows(d.prefix+"_temperature.csv", rows); err != nil {
+		return err
+	}
+
+	// Temperature on a log scale and both acceptance rates over the iterations
+	const width, height, margin = 640, 240, 50
+	last := d.annealing[len(d.annealing)-1]
+	span := float64(max(last.Iteration, 1))
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, w := range d.annealing {
+		if w.Temperature > 0 {
+			lo, hi = math.Min(lo, math.Log10(w.Temperature)), math.Max(hi, math.Log10(w.Temperature))
+		}
+	}
+	if hi-lo < 1e-9 {
+		lo, hi = lo-1, hi+1
+	}
+	x := func(iteration int) float64 { return margin + float64(iteration)/span*width }
+	y := func(fraction float64) float64 { return 10 + (1-fraction)*height }
+
+	var temperature, accepted, worse strings.Builder
+	for _, w := range d.annealing {
+		if w.Temperature > 0 {
+			fmt.Fprintf(&temperature, "%.1f,%.1f ", x(w.Iteration), y((math.Log10(w.Temperature)-lo)/(hi-lo)))
+		}
+		fmt.Fprintf(&accepted, "%.1f,%.1f ", x(w.Iteration), y(rate(w.Accepted, w.Proposed)))
+		fmt.Fprintf(&worse, "%.1f,%.1f ", x(w.Iteration), y(rate(w.WorseAccepted, w.WorseProposed)))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-size=\"12\">\n", width+2*margin, height+50)
+	fmt.Fprintf(&b, "<rect x=\"%d\" y=\"10\" width=\"%d\" height=\"%d\" fill=\"none\" stroke=\"#333\"/>\n", margin, width, height)
+	fmt.Fprintf(&b, "<polyline points=\"%s\" fill=\"none\" stroke=\"#d62728\"/>\n", temperature.String())
+	fmt.Fprintf(&b, "<polyline points=\"%s\" fill=\"none\" stroke=\"#1f77b4\"/>\n", accepted.String())
+	fmt.Fprintf(&b, "<polyline points=\"%s\" fill=\"none\" stroke=\"#2ca02c\"/>\n", worse.String())
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%.1f\" text-anchor=\"end\">1e%s</text>\n", margin-4, y(1)+4, strconv.FormatFloat(hi, 'f', 1, 64))
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%.1f\" text-anchor=\"end\">1e%s</text>\n", margin-4, y(0)+4, strconv.FormatFloat(lo, 'f', 1, 64))
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%.1f\">0%%</text>\n", margin+width+4, y(0)+4)
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%.1f\">100%%</text>\n", margin+width+4, y(1)+4)
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\">iteration %d</text>\n", margin+width-80, height+26, last.Iteration)
+	fmt.Fprintf(&b, "<text x=\"%d\" y=\"%d\"><tspan fill=\"#d62728\">temperature (log)</tspan>  <tspan fill=\"#1f77b4\">acceptance rate</tspan>  <tspan fill=\"#2ca02c\">worsening moves accepted</tspan></text>\n",
+		margin, height+42)
+	b.WriteString("</svg>\n")
+	return os.WriteFile(d.prefix+"_temperature.svg", []byte(b.String()), 0644)
+}
+
+func rate(count, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) / float64(total)
+}
+
+func writeCSVRows(path string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return file.Close()
+}
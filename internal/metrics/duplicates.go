@@ -0,0 +1,37 @@
+package metrics
+
+import "slices"
+
+// DuplicateRuns counts the runs of a solver on an instance whose solution is
+// identical to the solution of an earlier run, or a symmetric copy of it
+// when the instance has Symmetries. Runs that reached the known
+// optimum are not counted, finding the same optimum is expected. For a
+// stochastic solver duplicates point to an unseeded or mis-seeded random
+// generator or to a budget too small to leave the starting region.
+func (c *MetricsCollector) DuplicateRuns(instanceName, solverName string) int {
+	experiment, ok := c.Experiments[instanceName][solverName]
+	if !ok {
+		return 0
+	}
+	optimum, hasOptimum := c.optimum(instanceName)
+
+	symmetries := c.Symmetries[instanceName]
+	canonical := make([][]int, len(experiment.Runs))
+	for k, run := range experiment.Runs {
+		canonical[k] = symmetries.Canonical(run.Solution)
+	}
+
+	duplicates := 0
+	for k, run := range experiment.Runs {
+		if hasOptimum && run.FinalFitness == optimum {
+			continue
+		}
+		for e, earlier := range experiment.Runs[:k] {
+			if earlier.FinalFitness == run.FinalFitness && slices.Equal(canonical[e], canonical[k]) {
+				duplicates++
+				break
+			}
+		}
+	}
+	return duplicates
+}
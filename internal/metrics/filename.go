@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"fmt"
+	"path/filepath"
+	"qap_solver/internal/qap"
+	"strings"
+)
+
+// windowsReserved are the device names Windows refuses as file names, with
+// or without an extension
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// maxStemLength bounds a sanitized name, leaving room for the solver, the
+// run and the extension within the 255 bytes most file systems allow
+const maxStemLength = 120
+
+// SanitizeFilename turns a name into a file name valid on every platform:
+// letters, digits, hyphens and underscores are kept, any other character,
+// including dots, spaces and both path separators, becomes an underscore.
+// Runs of underscores are collapsed and trimmed, Windows device names such
+// as CON get an underscore appended and an empty result becomes "_".
+func SanitizeFilename(name string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range name {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			b.WriteRune(r)
+			underscore = false
+		} else if !underscore {
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	sanitized := strings.Trim(b.String(), "_")
+	if len(sanitized) > maxStemLength {
+		sanitized = strings.TrimRight(sanitized[:maxStemLength], "_")
+	}
+	if sanitized == "" {
+		return "_"
+	}
+	if windowsReserved[strings.ToUpper(sanitized)] {
+		sanitized += "_"
+	}
+	return sanitized
+}
+
+// InstanceFileStem is the sanitized name of an instance without its
+// extension, e.g. "tai20a" for "tai20a.dat" and "sub_tai20a" for
+// "sub/tai20a.dat.gz", used to name the per-run output files
+func InstanceFileStem(instanceName string) string {
+	base := filepath.ToSlash(instanceName)
+	if qap.IsInstanceFile(base) {
+		base = strings.TrimSuffix(base, ".gz")
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	return SanitizeFilename(base)
+}
+
+// FileStem returns the name the output files of an instance start with: its
+// InstanceFileStem, unless an earlier instance of the experiment already has
+// that stem, e.g. "a.b.dat" and "a_b.dat", in which case a counter is
+// appended. Stems differing only in case collide too, as they name the same
+// file on Windows and macOS. The first call for an instance decides its
+// stem, so the experiment asks for all instances in a fixed order before the
+// runs start. It is safe to call while runs execute.
+func (c *MetricsCollector) FileStem(instanceName string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if stem, ok := c.stems[instanceName]; ok {
+		return stem
+	}
+	if c.stems == nil {
+		c.stems = make(map[string]string)
+		c.stemOwners = make(map[string]string)
+	}
+	stem := InstanceFileStem(instanceName)
+	if owner, taken := c.stemOwners[strings.ToLower(stem)]; taken {
+		base := stem
+		for k := 2; taken; k++ {
+			stem = fmt.Sprintf("%s_%d", base, k)
+			_, taken = c.stemOwners[strings.ToLower(stem)]
+		}
+		c.collisions = append(c.collisions, fmt.Sprintf("%s and %s both map to %s, %s is written as %s",
+			owner, instanceName, base, instanceName, stem))
+	}
+	c.stems[instanceName] = stem
+	c.stemOwners[strings.ToLower(stem)] = instanceName
+	return stem
+}
+
+// FilenameCollisions describes the instances whose FileStem got a counter
+// because another instance's name sanitizes alike
+func (c *MetricsCollector) FilenameCollisions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.collisions...)
+}
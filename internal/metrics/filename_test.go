@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	long := strings.Repeat("a", maxStemLength+30)
+	tests := []struct {
+		name, want string
+	}{
+		{"tai20a", "tai20a"},
+		{"Tai-20a_b", "Tai-20a_b"},
+		{"tai20a.dat", "tai20a_dat"},
+		{"sub/tai20a", "sub_tai20a"},
+		{`sub\dir\tai20a`, "sub_dir_tai20a"},
+		{"C:/qap lib/nug12", "C_qap_lib_nug12"},
+		{"a..b  c", "a_b_c"},
+		{"__a__", "a"},
+		{"é", "_"},
+		{"", "_"},
+		{"...", "_"},
+		{"con", "con_"},
+		{"CON", "CON_"},
+		{"Lpt1", "Lpt1_"},
+		{"nul.dat", "nul_dat"},
+		{"com10", "com10"},
+		{long, long[:maxStemLength]},
+		{strings.Repeat("a", maxStemLength-1) + ".b", strings.Repeat("a", maxStemLength-1)},
+	}
+	for _, test := range tests {
+		if got := SanitizeFilename(test.name); got != test.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestInstanceFileStem(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"tai20a.dat", "tai20a"},
+		{"nug12.qap", "nug12"},
+		{"sub/tai20a.dat.gz", "sub_tai20a"},
+		{"a.b.dat", "a_b"},
+		{"notes.txt", "notes_txt"},
+		{"con.dat", "con_"},
+	}
+	for _, test := range tests {
+		if got := InstanceFileStem(test.name); got != test.want {
+			t.Errorf("InstanceFileStem(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+// TestFileStemCollisions checks that instances whose stems collide, also
+// when they only differ in case, get a counter in the order they are asked for
+func TestFileStemCollisions(t *testing.T) {
+	c := NewMetricsCollector(t.TempDir())
+	for _, test := range []struct {
+		instance, want string
+	}{
+		{"a.b.dat", "a_b"},
+		{"a_b.dat", "a_b_2"},
+		{"a b.dat", "a_b_3"},
+		{"Tai20a.dat", "Tai20a"},
+		{"tai20a.dat", "tai20a_2"},
+		{"TAI20A.dat.gz", "TAI20A_3"},
+		{"a_B_2.dat", "a_B_2_2"},
+		{"nug12.dat", "nug12"},
+		{"a.b.dat", "a_b"},
+	} {
+		if got := c.FileStem(test.instance); got != test.want {
+			t.Errorf("FileStem(%q) = %q, want %q", test.instance, got, test.want)
+		}
+	}
+	if collisions := c.FilenameCollisions(); len(collisions) != 5 {
+		t.Errorf("got %d collisions, want 5: %q", len(collisions), collisions)
+	}
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time units for duration columns
+const (
+	TimeUnitMilliseconds = "ms" // integer milliseconds
+	TimeUnitSeconds      = "s"  // fractional seconds
+)
+
+// OutputOptions control how numbers are written to the CSV files. Numbers are
+// always formatted with strconv, so the decimal separator is '.' regardless
+// of the system locale.
+type OutputOptions struct {
+	Precision int    // digits after the decimal point of fractional columns
+	TimeUnit  string // TimeUnitMilliseconds or TimeUnitSeconds
+
+	// Tidy additionally writes the results in long format, see SaveTidyCSV
+	Tidy bool
+
+	// Report additionally writes an HTML report with gap boxplots, see SaveHTMLReport
+	Report bool
+
+	// Columns and SummaryColumns select and order the columns of the results
+	// and summary files, nil writes all of them; see RunColumnNames and
+	// SummaryColumnNames
+	Columns        []string
+	SummaryColumns []string
+
+	// Derived metrics are appended to both files unless the columns are selected
+	Derived []DerivedMetric
+}
+
+// DefaultOutputOptions returns the options used by NewMetricsCollector
+func DefaultOutputOptions() OutputOptions {
+	return OutputOptions{Precision: 4, TimeUnit: TimeUnitMilliseconds}
+}
+
+// Validate checks that the options are usable
+func (o OutputOptions) Validate() error {
+	if o.Precision < 0 || o.Precision > 15 {
+		return fmt.Errorf("precision must be between 0 and 15, got %d", o.Precision)
+	}
+	if o.TimeUnit != TimeUnitMilliseconds && o.TimeUnit != TimeUnitSeconds {
+		return fmt.Errorf("time unit must be %q or %q, got %q", TimeUnitMilliseconds, TimeUnitSeconds, o.TimeUnit)
+	}
+	for i, m := range o.Derived {
+		for _, other := range o.Derived[:i] {
+			if strings.EqualFold(m.Name, other.Name) {
+				return fmt.Errorf("derived metric %s is defined twice", m.Name)
+			}
+		}
+		for _, name := range RunColumnNames() {
+			if strings.EqualFold(m.Name, name) {
+				return fmt.Errorf("derived metric %s has the name of a built-in column", m.Name)
+			}
+		}
+	}
+	if _, err := o.resultColumns(); err != nil {
+		return err
+	}
+	_, err := o.summaryTableColumns()
+	return err
+}
+
+// Float formats a fractional value with the configured precision
+func (o OutputOptions) Float(v float64) string {
+	return strconv.FormatFloat(v, 'f', o.Precision, 64)
+}
+
+// Duration formats a duration in the configured time unit
+func (o OutputOptions) Duration(d time.Duration) string {
+	if o.TimeUnit == TimeUnitSeconds {
+		return o.Float(d.Seconds())
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
+
+// TimeColumn returns the header of a duration column, e.g. "Time" becomes "TimeMs"
+func (o OutputOptions) TimeColumn(name string) string {
+	if o.TimeUnit == TimeUnitSeconds {
+		return name + "S"
+	}
+	return name + "Ms"
+}
@@ -0,0 +1,188 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatTrajectory encodes a trajectory as space separated
+// "evaluations:microseconds:fitness" triples
+func FormatTrajectory(trajectory []TrajectoryPoint) string {
+	parts := make([]string, len(trajectory))
+	for i, p := range trajectory {
+		parts[i] = fmt.Sprintf("%d:%d:%d", p.Evaluations, p.Elapsed.Microseconds(), p.Fitness)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseTrajectory decodes the output of FormatTrajectory
+func ParseTrajectory(s string) ([]TrajectoryPoint, error) {
+	var trajectory []TrajectoryPoint
+	for _, field := range strings.Fields(s) {
+		parts := strings.Split(field, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid trajectory point %q", field)
+		}
+		var values [3]int64
+		for i, part := range parts {
+			v, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trajectory point %q", field)
+			}
+			values[i] = v
+		}
+		trajectory = append(trajectory, TrajectoryPoint{
+			Evaluations: int(values[0]),
+			Elapsed:     time.Duration(values[1]) * time.Microsecond,
+			Fitness:     int(values[2]),
+		})
+	}
+	return trajectory, nil
+}
+
+// ParseSolution decodes a solution written as "[3 0 2 1]"
+func ParseSolution(s string) ([]int, error) {
+	fields := strings.Fields(strings.Trim(strings.TrimSpace(s), "[]"))
+	solution := make([]int, len(fields))
+	for i, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("invalid solution value %q", f)
+		}
+		solution[i] = v
+	}
+	return solution, nil
+}
+
+// LoadRunsCSV reads a results file written by SaveToCSV. Columns are matched by
+// header name, so files from older versions load with missing values left at zero.
+// Set maximize for results of maximization instances, their objective values
+// are converted back to minimized fitness, see MetricsCollector.Maximize.
+func LoadRunsCSV(path string, maximize bool) ([]RunMetrics, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s: empty file", path)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	for _, required := range []string{"Instance", "Solver", "FinalFitness"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%s: missing column %s", path, required)
+		}
+	}
+
+	var runs []RunMetrics
+	for line, record := range records[1:] {
+		row := runsRow{columns: columns, record: record}
+		run := RunMetrics{
+			InstanceName:        row.text("Instance"),
+			SolverName:          row.text("Solver"),
+			Run:                 row.integer("Run"),
+			Seed:                int64(row.integer("Seed")),
+			InitialFitness:      row.integer("InitialFitness"),
+			FinalFitness:        row.integer("FinalFitness"),
+			QAPCost:             row.integer("QAPCost"),
+			RelocationCost:      row.integer("RelocationCost"),
+			ConstraintViolation: row.integer("ConstraintViolation"),
+			TimeElapsed:         row.duration("Time"),
+			CPUTime:             row.duration("CPUTime"),
+			OverheadTime:        row.duration("OverheadTime"),
+			StepsCount:          row.integer("Steps"),
+			EvaluationsCount:    row.integer("Evaluations"),
+			SolutionsChecked:    row.integer("SolutionsChecked"),
+			AcceptedMoves:       row.integer("AcceptedMoves"),
+			RejectedMoves:       row.integer("RejectedMoves"),
+			ImprovingMoves:      row.integer("ImprovingMoves"),
+			AllocatedBytes:      uint64(row.integer("AllocatedBytes")),
+			Mallocs:             uint64(row.integer("Mallocs")),
+			PeakHeapBytes:       uint64(row.integer("PeakHeapBytes")),
+			TerminationReason:   row.text("Termination"),
+			Artifacts:           row.text("Artifacts"),
+		}
+		if v := row.text("Validation"); v != "ok" {
+			run.ValidationError = v
+		}
+		if run.Solution, err = ParseSolution(row.text("Solution")); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line+2, err)
+		}
+		if run.Trajectory, err = ParseTrajectory(row.text("Trajectory")); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line+2, err)
+		}
+		if row.err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line+2, row.err)
+		}
+		if maximize {
+			run.InitialFitness, run.FinalFitness = -run.InitialFitness, -run.FinalFitness
+			for i := range run.Trajectory {
+				run.Trajectory[i].Fitness = -run.Trajectory[i].Fitness
+			}
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// runsRow reads typed values from a CSV record by column name. The first
+// parse error is kept in err.
+type runsRow struct {
+	columns map[string]int
+	record  []string
+	err     error
+}
+
+func (r *runsRow) text(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return r.record[i]
+}
+
+func (r *runsRow) integer(column string) int {
+	s := r.text(column)
+	if s == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("column %s: invalid integer %q", column, s)
+	}
+	return v
+}
+
+// duration reads a time column written in either unit, e.g. TimeMs or TimeS
+func (r *runsRow) duration(column string) time.Duration {
+	if s := r.text(column + "Ms"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil && r.err == nil {
+			r.err = fmt.Errorf("column %sMs: invalid number %q", column, s)
+		}
+		return time.Duration(v * float64(time.Millisecond))
+	}
+	if s := r.text(column + "S"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil && r.err == nil {
+			r.err = fmt.Errorf("column %sS: invalid number %q", column, s)
+		}
+		return time.Duration(v * float64(time.Second))
+	}
+	return 0
+}
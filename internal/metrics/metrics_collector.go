@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strconv"
+	"qap_solver/internal/qap"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,13 +16,56 @@ type RunMetrics struct {
 	InstanceName     string
 	SolverName       string
 	Run              int
+	Seed             int64 // seed of the run's random generator
 	InitialFitness   int
 	FinalFitness     int
 	TimeElapsed      time.Duration
+	CPUTime          time.Duration // user+system time of the run's thread, zero if unsupported
+	OverheadTime     time.Duration // part of TimeElapsed spent on instrumentation, see SolverTime
 	StepsCount       int
 	EvaluationsCount int
 	SolutionsChecked int
 	Solution         []int
+
+	// Acceptance behavior: accepted moves are the steps taken, improving ones
+	// lower the current fitness, rejected ones were evaluated and turned down
+	// by the acceptance rule of annealing or tabu search
+	AcceptedMoves  int
+	RejectedMoves  int
+	ImprovingMoves int
+
+	// Objective breakdown, RelocationCost is zero unless a current layout is given
+	QAPCost        int
+	RelocationCost int
+
+	// ConstraintViolation is the total violation of the side constraints, zero when feasible
+	ConstraintViolation int
+
+	// Trajectory holds a point for every improvement of the best fitness
+	Trajectory []TrajectoryPoint
+
+	// ValidationError is set when recomputing the fitness of Solution does not
+	// reproduce FinalFitness or Solution is not a permutation
+	ValidationError string
+
+	// Robustness is set when the experiment evaluates solutions on perturbed flows
+	Robustness *RobustnessStats
+
+	// Memory accounting for the run
+	AllocatedBytes    uint64 // bytes allocated by the process during the run, including parallel runs
+	Mallocs           uint64 // heap objects allocated by the process during the run, including parallel runs
+	PeakHeapBytes     uint64 // highest sampled heap size of the process, an estimate
+	TerminationReason string
+
+	// Artifacts is the directory of the solver's artifacts relative to the
+	// output directory, empty when the run wrote none, see Artifacts
+	Artifacts string
+}
+
+// SolverTime is the time of the run without its instrumentation overhead:
+// trajectory recording, traces, diagnostics, memory samples and live counters
+func (run RunMetrics) SolverTime() time.Duration {
+	return max(run.TimeElapsed-run.OverheadTime, 0)
 }
 
 // ExperimentMetrics collects metrics from multiple runs
@@ -30,10 +75,69 @@ type ExperimentMetrics struct {
 	Runs         []RunMetrics
 }
 
-// MetricsCollector manages metrics for multiple experiments
+// MetricsCollector manages metrics for multiple experiments. Runs may be
+// added from many goroutines at once; the Save methods read the collector
+// without locking, so while runs execute they are called on a Snapshot, as
+// Flush does.
 type MetricsCollector struct {
 	Experiments map[string]map[string]*ExperimentMetrics // Map[InstanceName][SolverName]
 	OutputDir   string
+	Output      OutputOptions
+
+	// Optima are the best known fitness values used as reference in anytime metrics.
+	// Instances without a known optimum use the best fitness seen in the experiment.
+	Optima qap.OptimalSolutions
+
+	// OptimalPermutations holds the optimal solution of instances whose .sln
+	// file lists one, in the facility encoding, keyed by instance name. Runs on
+	// these instances report their assignment distance to it.
+	OptimalPermutations map[string][]int
+
+	// Symmetries holds the automorphisms of the instances that have any,
+	// keyed by instance name. Symmetric copies of a solution count as the
+	// same solution, see DuplicateRuns.
+	Symmetries map[string]*qap.Symmetries
+
+	// Timestamp is the suffix shared by all files written by the collector
+	Timestamp string
+
+	// Maximize is set when the instances are maximized. Runs always store the
+	// minimized fitness, the negated objective in that case; the files show
+	// objective values and .sln files hold the optimal objective.
+	Maximize bool
+
+	// Names label facilities and locations in the pretty solution output and
+	// the HTML report; nil leaves those outputs without names
+	Names *qap.Names
+
+	// Session names the experiment and notes its purpose in the session file
+	// and the HTML report, see SaveSession; nil writes no session file
+	Session *Session
+
+	// ControlEvents are the setting changes made while the experiment ran
+	ControlEvents []ControlEvent
+
+	// AnomalyZ is the z-score threshold of the runs listed in the anomalies
+	// file, DefaultAnomalyZ when zero and no file when negative, see Anomalies
+	AnomalyZ float64
+
+	// OnRun is called with every added run when set. Calls are made while the
+	// collector is locked, so they never overlap and must not call back into it.
+	OnRun func(RunMetrics)
+
+	// skipped counts the runs skipped at the optimum, see AddSkippedRun
+	skipped map[string]map[string]int
+
+	// File stems of the instances and the instance of every lowercased stem, see FileStem
+	stems      map[string]string
+	stemOwners map[string]string
+	collisions []string
+
+	mu sync.Mutex // guards Experiments, skipped, the file stems and ControlEvents while runs execute in parallel
+
+	// Totals of the added runs, read without taking mu, see RunsAdded
+	runsAdded        atomic.Int64
+	evaluationsAdded atomic.Int64
 }
 
 // NewMetricsCollector creates a new metrics collector
@@ -44,11 +148,16 @@ func NewMetricsCollector(outputDir string) *MetricsCollector {
 	return &MetricsCollector{
 		Experiments: make(map[string]map[string]*ExperimentMetrics),
 		OutputDir:   outputDir,
+		Output:      DefaultOutputOptions(),
+		Timestamp:   time.Now().Format("2006-01-02T15_04"),
 	}
 }
 
 // AddRunMetrics adds a run's metrics to the collector
 func (c *MetricsCollector) AddRunMetrics(metrics RunMetrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// Ensure we have a map for this instance
 	if _, exists := c.Experiments[metrics.InstanceName]; !exists {
 		c.Experiments[metrics.InstanceName] = make(map[string]*ExperimentMetrics)
@@ -67,12 +176,74 @@ func (c *MetricsCollector) AddRunMetrics(metrics RunMetrics) {
 	// Add the run metrics
 	experiment := instanceSolvers[metrics.SolverName]
 	experiment.Runs = append(experiment.Runs, metrics)
+	c.runsAdded.Add(1)
+	c.evaluationsAdded.Add(int64(metrics.EvaluationsCount))
+	if c.OnRun != nil {
+		c.OnRun(metrics)
+	}
+}
+
+// RunsAdded returns the number of runs added so far. It does not lock the
+// collector, so progress displays can poll it as often as they like.
+func (c *MetricsCollector) RunsAdded() int {
+	return int(c.runsAdded.Load())
+}
+
+// EvaluationsAdded returns the fitness evaluations of the runs added so far,
+// see RunsAdded
+func (c *MetricsCollector) EvaluationsAdded() int {
+	return int(c.evaluationsAdded.Load())
+}
+
+// HasRun reports whether a run with the same instance, solver and run number
+// was already added; adding it again would count it twice in the summaries
+func (c *MetricsCollector) HasRun(instanceName, solverName string, run int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if experiment, ok := c.Experiments[instanceName][solverName]; ok {
+		for _, r := range experiment.Runs {
+			if r.Run == run {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FinalFitnesses returns the final fitness of every run recorded so far on an
+// instance, keyed by solver. It is safe to call while runs execute.
+func (c *MetricsCollector) FinalFitnesses(instanceName string) map[string][]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fitnesses := make(map[string][]int)
+	for solverName, experiment := range c.Experiments[instanceName] {
+		for _, run := range experiment.Runs {
+			fitnesses[solverName] = append(fitnesses[solverName], run.FinalFitness)
+		}
+	}
+	return fitnesses
+}
+
+// PeakHeap returns the highest heap size sampled by the runs recorded so far
+// on an instance. It is safe to call while runs execute.
+func (c *MetricsCollector) PeakHeap(instanceName string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	peak := uint64(0)
+	for _, experiment := range c.Experiments[instanceName] {
+		for _, run := range experiment.Runs {
+			peak = max(peak, run.PeakHeapBytes)
+		}
+	}
+	return peak
 }
 
 func (c *MetricsCollector) SaveToCSV() error {
 	// Create a single results file
-	dateStr := time.Now().Format("2006-01-02T15_04")
-	resultsPath := filepath.Join(c.OutputDir, fmt.Sprintf("results_%s.csv", dateStr))
+	resultsPath := filepath.Join(c.OutputDir, fmt.Sprintf("results_%s.csv", c.Timestamp))
 	resultsFile, err := os.Create(resultsPath)
 	if err != nil {
 		return err
@@ -80,35 +251,139 @@ func (c *MetricsCollector) SaveToCSV() error {
 	defer resultsFile.Close()
 
 	resultsWriter := csv.NewWriter(resultsFile)
-	defer resultsWriter.Flush()
 
-	// Write header (No Optimum, No Aggregated Stats)
-	header := []string{
-		"Instance", "Solver", "Run",
-		"InitialFitness", "FinalFitness",
-		"TimeMs", "Steps", "Evaluations", "SolutionsChecked",
-		"Solution",
+	columns, err := c.Output.resultColumns()
+	if err != nil {
+		return err
+	}
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.header(c.Output)
 	}
 	resultsWriter.Write(header)
 
 	// Process each experiment
 	for instanceName, solvers := range c.Experiments {
+		reference := c.Reference(instanceName)
 		for solverName, experiment := range solvers {
 			// Write each run's details
-			for i, run := range experiment.Runs {
-				resultsWriter.Write([]string{
-					instanceName, solverName, strconv.Itoa(i + 1),
-					strconv.Itoa(run.InitialFitness),
-					strconv.Itoa(run.FinalFitness),
-					strconv.FormatFloat(float64(run.TimeElapsed.Milliseconds()), 'f', 2, 64),
-					strconv.Itoa(run.StepsCount),
-					strconv.Itoa(run.EvaluationsCount),
-					strconv.Itoa(run.SolutionsChecked),
-					fmt.Sprintf("%v", run.Solution),
-				})
+			for _, run := range experiment.Runs {
+				row := runRow{instance: instanceName, solver: solverName, reference: reference, run: run}
+				record := make([]string, len(columns))
+				for i, col := range columns {
+					record[i] = col.value(c, row)
+				}
+				resultsWriter.Write(record)
 			}
 		}
 	}
 
-	return nil
+	// Write errors, e.g. of a network file system, may only show on close
+	resultsWriter.Flush()
+	if err := resultsWriter.Error(); err != nil {
+		return err
+	}
+	return resultsFile.Close()
+}
+
+// objective converts a minimized fitness value into the objective value
+func (c *MetricsCollector) objective(fitness int) int {
+	if c.Maximize {
+		return -fitness
+	}
+	return fitness
+}
+
+// objectiveFloat is objective for averaged fitness values
+func (c *MetricsCollector) objectiveFloat(fitness float64) float64 {
+	if c.Maximize {
+		return -fitness
+	}
+	return fitness
+}
+
+// objectiveTrajectory returns the trajectory with objective values
+func (c *MetricsCollector) objectiveTrajectory(trajectory []TrajectoryPoint) []TrajectoryPoint {
+	if !c.Maximize {
+		return trajectory
+	}
+	converted := make([]TrajectoryPoint, len(trajectory))
+	for i, p := range trajectory {
+		p.Fitness = -p.Fitness
+		converted[i] = p
+	}
+	return converted
+}
+
+// optimum returns the minimized fitness of the known optimum of an instance
+func (c *MetricsCollector) optimum(instanceName string) (int, bool) {
+	optimum, ok := c.Optima.Lookup(instanceName)
+	return c.objective(optimum), ok
+}
+
+// OptimumDistance returns the assignment distance of a run's solution to the
+// optimal permutation of its instance, see qap.AssignmentDistance
+func (c *MetricsCollector) OptimumDistance(run RunMetrics) (int, bool) {
+	optimal, ok := c.OptimalPermutations[run.InstanceName]
+	if !ok || len(optimal) != len(run.Solution) {
+		return 0, false
+	}
+	return qap.AssignmentDistance(run.Solution, optimal), true
+}
+
+// Reference returns the fitness that gaps on an instance are measured against:
+// the known optimum if available, otherwise the best final fitness of any run.
+func (c *MetricsCollector) Reference(instanceName string) int {
+	if optimum, ok := c.optimum(instanceName); ok {
+		return optimum
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	best := 0
+	found := false
+	for _, experiment := range c.Experiments[instanceName] {
+		for _, run := range experiment.Runs {
+			if !found || run.FinalFitness < best {
+				best = run.FinalFitness
+				found = true
+			}
+		}
+	}
+	return best
+}
+
+// ValidateRuns recomputes the fitness of every stored solution on an instance
+// and marks runs whose FinalFitness does not match. It returns the number of
+// invalid runs.
+func (c *MetricsCollector) ValidateRuns(instanceName string, fitness func(solution []int) (int, error)) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	invalid := 0
+	for _, experiment := range c.Experiments[instanceName] {
+		for i := range experiment.Runs {
+			run := &experiment.Runs[i]
+			recomputed, err := fitness(run.Solution)
+			switch {
+			case err != nil:
+				run.ValidationError = err.Error()
+			case recomputed != run.FinalFitness:
+				run.ValidationError = fmt.Sprintf("recorded fitness %d, recomputed %d", run.FinalFitness, recomputed)
+			default:
+				run.ValidationError = ""
+				continue
+			}
+			invalid++
+		}
+	}
+	return invalid
+}
+
+func validationStatus(run RunMetrics) string {
+	if run.ValidationError != "" {
+		return run.ValidationError
+	}
+	return "ok"
 }
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+)
+
+// OperatorStats aggregates the applications of one operator at one strength
+// in a solver choosing among several operators or neighborhoods
+type OperatorStats struct {
+	Name        string
+	Strength    int // neighborhood size of the application, 0 when the operator has none
+	Used        int
+	Accepted    int // applications whose result the solver kept
+	Improving   int // applications that improved the best fitness
+	Improvement int // total decrease of the best fitness contributed
+}
+
+// Operator records one application of an operator at a strength. accepted
+// tells whether the solver kept the result and improvement is the decrease
+// of the best fitness it brought, 0 for none.
+func (d *Diagnostics) Operator(name string, strength int, accepted bool, improvement int) {
+	if d == nil {
+		return
+	}
+	var stats *OperatorStats
+	for k := range d.operators {
+		if d.operators[k].Name == name && d.operators[k].Strength == strength {
+			stats = &d.operators[k]
+			break
+		}
+	}
+	if stats == nil {
+		d.operators = append(d.operators, OperatorStats{Name: name, Strength: strength})
+		stats = &d.operators[len(d.operators)-1]
+	}
+	stats.Used++
+	if accepted {
+		stats.Accepted++
+	}
+	if improvement > 0 {
+		stats.Improving++
+		stats.Improvement += improvement
+	}
+}
+
+// writeOperators writes prefix_operators.csv, one row per operator and
+// strength
+func (d *Diagnostics) writeOperators() error {
+	slices.SortFunc(d.operators, func(a, b OperatorStats) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Strength, b.Strength))
+	})
+	rows := [][]string{{"Operator", "Strength", "Used", "Accepted", "AcceptanceRate", "Improving", "Improvement", "MeanImprovement"}}
+	for _, o := range d.operators {
+		rows = append(rows, []string{
+			o.Name,
+			strconv.Itoa(o.Strength),
+			strconv.Itoa(o.Used),
+			strconv.Itoa(o.Accepted),
+			strconv.FormatFloat(rate(o.Accepted, o.Used), 'f', 4, 64),
+			strconv.Itoa(o.Improving),
+			strconv.Itoa(o.Improvement),
+			strconv.FormatFloat(float64(o.Improvement)/float64(o.Used), 'f', 2, 64),
+		})
+	}
+	return writeCSVRows(d.prefix+"_operators.csv", rows)
+}
@@ -0,0 +1,255 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// PairedComparison compares two solvers on one instance using runs paired by
+// seed. With common random numbers both runs of a pair start from the same
+// random state, so the paired differences have a much smaller variance than
+// the difference of two independent samples.
+type PairedComparison struct {
+	InstanceName string
+	SolverA      string
+	SolverB      string
+	Pairs        int
+	MeanDiff     float64 // mean of FinalFitness(A) - FinalFitness(B), negative favors A in either objective direction
+	StdDiff      float64
+	TStatistic   float64
+	TPValue      float64 // two-sided paired t-test
+	WilcoxonZ    float64 // signed-rank statistic, normal approximation
+	WilcoxonP    float64 // two-sided
+}
+
+// PairedComparisons compares every pair of solvers on every instance. Runs are
+// matched by seed, runs without a partner with the same seed are ignored.
+func (c *MetricsCollector) PairedComparisons() []PairedComparison {
+	var comparisons []PairedComparison
+	for instanceName, solvers := range c.Experiments {
+		names := make([]string, 0, len(solvers))
+		for name := range solvers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for a := 0; a < len(names); a++ {
+			for b := a + 1; b < len(names); b++ {
+				diffs := pairedDifferences(solvers[names[a]].Runs, solvers[names[b]].Runs)
+				if len(diffs) < 2 {
+					continue
+				}
+				comparisons = append(comparisons, comparePaired(instanceName, names[a], names[b], diffs))
+			}
+		}
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool {
+		if comparisons[i].InstanceName != comparisons[j].InstanceName {
+			return comparisons[i].InstanceName < comparisons[j].InstanceName
+		}
+		if comparisons[i].SolverA != comparisons[j].SolverA {
+			return comparisons[i].SolverA < comparisons[j].SolverA
+		}
+		return comparisons[i].SolverB < comparisons[j].SolverB
+	})
+	return comparisons
+}
+
+// pairedDifferences returns FinalFitness(a) - FinalFitness(b) for runs with equal seeds
+func pairedDifferences(a, b []RunMetrics) []float64 {
+	bySeed := make(map[int64]int, len(b))
+	for _, run := range b {
+		if run.Seed != 0 {
+			bySeed[run.Seed] = run.FinalFitness
+		}
+	}
+	var diffs []float64
+	for _, run := range a {
+		if fitness, ok := bySeed[run.Seed]; ok && run.Seed != 0 {
+			diffs = append(diffs, float64(run.FinalFitness-fitness))
+		}
+	}
+	return diffs
+}
+
+func comparePaired(instanceName, solverA, solverB string, diffs []float64) PairedComparison {
+	n := float64(len(diffs))
+	p := PairedComparison{InstanceName: instanceName, SolverA: solverA, SolverB: solverB, Pairs: len(diffs)}
+
+	for _, d := range diffs {
+		p.MeanDiff += d
+	}
+	p.MeanDiff /= n
+	for _, d := range diffs {
+		p.StdDiff += (d - p.MeanDiff) * (d - p.MeanDiff)
+	}
+	p.StdDiff = math.Sqrt(p.StdDiff / (n - 1))
+
+	switch {
+	case p.StdDiff > 0:
+		p.TStatistic = p.MeanDiff / (p.StdDiff / math.Sqrt(n))
+		p.TPValue = studentTwoSided(p.TStatistic, n-1)
+	case p.MeanDiff == 0:
+		p.TPValue = 1
+	default:
+		// Every pair differs by the same amount
+		p.TStatistic = math.Inf(int(math.Copysign(1, p.MeanDiff)))
+		p.TPValue = 0
+	}
+
+	p.WilcoxonZ, p.WilcoxonP = wilcoxonSignedRank(diffs)
+	return p
+}
+
+// wilcoxonSignedRank returns the z score and two-sided p-value of the
+// Wilcoxon signed-rank test with the normal approximation. Zero differences
+// are dropped and ties get their average rank.
+func wilcoxonSignedRank(diffs []float64) (float64, float64) {
+	var nonzero []float64
+	for _, d := range diffs {
+		if d != 0 {
+			nonzero = append(nonzero, d)
+		}
+	}
+	n := len(nonzero)
+	if n == 0 {
+		return 0, 1
+	}
+	sort.Slice(nonzero, func(i, j int) bool { return math.Abs(nonzero[i]) < math.Abs(nonzero[j]) })
+
+	wPlus := 0.0
+	tieCorrection := 0.0
+	for i := 0; i < n; {
+		j := i
+		for j < n && math.Abs(nonzero[j]) == math.Abs(nonzero[i]) {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // average of ranks i+1..j
+		for k := i; k < j; k++ {
+			if nonzero[k] > 0 {
+				wPlus += rank
+			}
+		}
+		t := float64(j - i)
+		tieCorrection += t*t*t - t
+		i = j
+	}
+
+	fn := float64(n)
+	mean := fn * (fn + 1) / 4
+	variance := fn*(fn+1)*(2*fn+1)/24 - tieCorrection/48
+	if variance <= 0 {
+		return 0, 1
+	}
+	z := (wPlus - mean) / math.Sqrt(variance)
+	return z, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// studentTwoSided returns P(|T| >= |t|) for Student's t with df degrees of freedom
+func studentTwoSided(t, df float64) float64 {
+	return regularizedIncompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// regularizedIncompleteBeta evaluates I_x(a, b) with the continued fraction
+// of Numerical Recipes (betacf)
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 3e-14
+		tiny          = 1e-300
+	)
+	qab, qap, qam := a+b, a+1, a-1
+	c, d := 1.0, 1-qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
+
+// SavePairedCSV writes the paired comparisons of all solver pairs to paired_<timestamp>.csv
+func (c *MetricsCollector) SavePairedCSV() error {
+	pairedPath := filepath.Join(c.OutputDir, fmt.Sprintf("paired_%s.csv", c.Timestamp))
+	pairedFile, err := os.Create(pairedPath)
+	if err != nil {
+		return err
+	}
+	defer pairedFile.Close()
+
+	writer := csv.NewWriter(pairedFile)
+
+	writer.Write([]string{
+		"Instance", "SolverA", "SolverB", "Pairs", "MeanDiff", "StdDiff",
+		"TStatistic", "TPValue", "WilcoxonZ", "WilcoxonPValue",
+	})
+	for _, p := range c.PairedComparisons() {
+		writer.Write([]string{
+			p.InstanceName, p.SolverA, p.SolverB, strconv.Itoa(p.Pairs),
+			c.Output.Float(p.MeanDiff),
+			c.Output.Float(p.StdDiff),
+			c.Output.Float(p.TStatistic),
+			c.Output.Float(p.TPValue),
+			c.Output.Float(p.WilcoxonZ),
+			c.Output.Float(p.WilcoxonP),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return pairedFile.Close()
+}
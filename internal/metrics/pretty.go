@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/qap"
+	"sort"
+	"strings"
+)
+
+// WritePrettySolution writes a solution for people: a header naming the
+// instance, the solver and the objective value, then one line per facility
+// such as "Assembly → Bay 3"
+func WritePrettySolution(w io.Writer, instanceName, solverName string, objective int, solution []int, names *qap.Names) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Instance:  %s\nSolver:    %s\nObjective: %d\n\n", instanceName, solverName, objective)
+	for _, line := range names.FormatAssignment(solution) {
+		b.WriteString(line + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// bestRun returns the run with the lowest final fitness on an instance
+func (c *MetricsCollector) bestRun(instanceName string) (RunMetrics, bool) {
+	var best RunMetrics
+	found := false
+	for _, experiment := range c.Experiments[instanceName] {
+		for _, run := range experiment.Runs {
+			if len(run.Solution) > 0 && (!found || run.FinalFitness < best.FinalFitness) {
+				best, found = run, true
+			}
+		}
+	}
+	return best, found
+}
+
+// instanceNames returns the instances with runs in sorted order
+func (c *MetricsCollector) instanceNames() []string {
+	names := make([]string, 0, len(c.Experiments))
+	for instanceName := range c.Experiments {
+		names = append(names, instanceName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SavePrettySolutions writes solution_pretty_<timestamp>.txt with the best
+// solution found on every instance, using Names where they fit its size
+func (c *MetricsCollector) SavePrettySolutions() error {
+	path := filepath.Join(c.OutputDir, fmt.Sprintf("solution_pretty_%s.txt", c.Timestamp))
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for k, instanceName := range c.instanceNames() {
+		best, ok := c.bestRun(instanceName)
+		if !ok {
+			continue
+		}
+		if k > 0 {
+			fmt.Fprintln(file)
+		}
+		solver := fmt.Sprintf("%s, run %d", best.SolverName, best.Run)
+		if err := WritePrettySolution(file, instanceName, solver, c.objective(best.FinalFitness), best.Solution, c.Names); err != nil {
+			return err
+		}
+	}
+	return file.Close()
+}
@@ -0,0 +1,232 @@
+package metrics
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Boxplot layout in SVG user units
+const (
+	boxplotRowHeight   = 28
+	boxplotLabelWidth  = 180
+	boxplotPlotWidth   = 520
+	boxplotAxisHeight  = 30
+	boxplotBoxFraction = 0.6 // share of the row height covered by the box
+)
+
+// InstanceFamily groups instances by the letters their file name starts with,
+// the QAPLIB naming convention: tai12a and tai256c belong to "tai". Names
+// without a leading letter form the family "other".
+func InstanceFamily(instanceName string) string {
+	base := path.Base(filepath.ToSlash(instanceName))
+	end := strings.IndexFunc(base, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(base)
+	}
+	if end == 0 {
+		return "other"
+	}
+	return strings.ToLower(base[:end])
+}
+
+// BoxplotStats summarizes a distribution by its quartiles. Whiskers reach the
+// most extreme values within 1.5 interquartile ranges of the box, values
+// beyond them are outliers.
+type BoxplotStats struct {
+	Count                   int
+	Q1, Median, Q3          float64
+	WhiskerLow, WhiskerHigh float64
+	Outliers                []float64
+}
+
+// NewBoxplotStats computes the boxplot of values, which must not be empty
+func NewBoxplotStats(values []float64) BoxplotStats {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	s := BoxplotStats{
+		Count:  len(sorted),
+		Q1:     quantile(sorted, 0.25),
+		Median: quantile(sorted, 0.5),
+		Q3:     quantile(sorted, 0.75),
+	}
+	iqr := s.Q3 - s.Q1
+	low, high := s.Q1-1.5*iqr, s.Q3+1.5*iqr
+	s.WhiskerLow, s.WhiskerHigh = s.Q1, s.Q3
+	for _, v := range sorted {
+		if v < low || v > high {
+			s.Outliers = append(s.Outliers, v)
+			continue
+		}
+		s.WhiskerLow = math.Min(s.WhiskerLow, v)
+		s.WhiskerHigh = math.Max(s.WhiskerHigh, v)
+	}
+	return s
+}
+
+// quantile interpolates linearly between the closest ranks of sorted values
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := min(lower+1, len(sorted)-1)
+	return sorted[lower] + (pos-float64(lower))*(sorted[upper]-sorted[lower])
+}
+
+// familyGaps returns the final gaps in percent of every run, keyed by
+// instance family and solver
+func (c *MetricsCollector) familyGaps() map[string]map[string][]float64 {
+	gaps := make(map[string]map[string][]float64)
+	for instanceName, solvers := range c.Experiments {
+		reference := c.Reference(instanceName)
+		family := InstanceFamily(instanceName)
+		if gaps[family] == nil {
+			gaps[family] = make(map[string][]float64)
+		}
+		for solverName, experiment := range solvers {
+			for _, run := range experiment.Runs {
+				gaps[family][solverName] = append(gaps[family][solverName], GapPercent(run.FinalFitness, reference))
+			}
+		}
+	}
+	return gaps
+}
+
+// SaveHTMLReport writes report_<timestamp>.html, a self-contained page headed
+// by the session name and note, with one SVG boxplot of the final gaps per instance family, one box per solver
+// pooling its runs on all instances of the family, followed by the summary table
+func (c *MetricsCollector) SaveHTMLReport() error {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	title := c.Session.Title("QAP experiment")
+	fmt.Fprintf(&b, "<title>%s %s</title>\n", html.EscapeString(title), html.EscapeString(c.Timestamp))
+	b.WriteString("<style>\n" +
+		"body { font-family: sans-serif; margin: 2em; }\n" +
+		"table { border-collapse: collapse; }\n" +
+		"th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: right; }\n" +
+		"th:nth-child(-n+2), td:nth-child(-n+2) { text-align: left; }\n" +
+		"</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&b, "<h1>%s %s</h1>\n", html.EscapeString(title), html.EscapeString(c.Timestamp))
+	if c.Session != nil && c.Session.Note != "" {
+		fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(c.Session.Note))
+	}
+
+	gaps := c.familyGaps()
+	families := make([]string, 0, len(gaps))
+	for family := range gaps {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+
+	b.WriteString("<h2>Final gap per instance family</h2>\n")
+	b.WriteString("<p>Gap of the final objective to the reference (optimum or best found) in percent, " +
+		"pooled over all runs on the instances of a family.</p>\n")
+	for _, family := range families {
+		fmt.Fprintf(&b, "<h3>%s</h3>\n", html.EscapeString(family))
+		writeBoxplotSVG(&b, gaps[family], c.Output)
+	}
+
+	b.WriteString("<h2>Summary</h2>\n<table>\n<tr><th>Instance</th><th>Solver</th><th>Runs</th>" +
+		"<th>Reference</th><th>BestFitness</th><th>MeanFitness</th><th>MeanGapPercent</th></tr>\n")
+	for _, s := range c.Summaries() {
+		fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td>%s</td><td>%s</td></tr>\n",
+			html.EscapeString(s.InstanceName), html.EscapeString(s.SolverName), s.Runs, c.objective(s.Reference),
+			c.objective(s.BestFitness), c.Output.Float(c.objectiveFloat(s.MeanFitness)), c.Output.Float(s.MeanGapPercent))
+	}
+	b.WriteString("</table>\n")
+
+	if c.Names != nil {
+		b.WriteString("<h2>Best solutions</h2>\n")
+		for _, instanceName := range c.instanceNames() {
+			best, ok := c.bestRun(instanceName)
+			if !ok {
+				continue
+			}
+			fmt.Fprintf(&b, "<details><summary>%s: %s run %d, objective %d</summary>\n<pre>",
+				html.EscapeString(instanceName), html.EscapeString(best.SolverName), best.Run, c.objective(best.FinalFitness))
+			for _, line := range c.Names.FormatAssignment(best.Solution) {
+				b.WriteString(html.EscapeString(line) + "\n")
+			}
+			b.WriteString("</pre></details>\n")
+		}
+	}
+	b.WriteString("</body>\n</html>\n")
+
+	reportPath := filepath.Join(c.OutputDir, fmt.Sprintf("report_%s.html", c.Timestamp))
+	return os.WriteFile(reportPath, []byte(b.String()), 0644)
+}
+
+// writeBoxplotSVG draws one horizontal box per solver on a shared gap axis
+func writeBoxplotSVG(b *strings.Builder, gaps map[string][]float64, output OutputOptions) {
+	solvers := make([]string, 0, len(gaps))
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for solver, values := range gaps {
+		if len(values) == 0 {
+			continue
+		}
+		solvers = append(solvers, solver)
+		for _, v := range values {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	if len(solvers) == 0 {
+		return
+	}
+	sort.Strings(solvers)
+
+	// The axis starts at 0 unless some gap is negative, e.g. with a wrong .sln file
+	lo = math.Min(lo, 0)
+	if hi-lo < 1e-9 {
+		hi = lo + 1
+	}
+	x := func(v float64) float64 {
+		return boxplotLabelWidth + (v-lo)/(hi-lo)*boxplotPlotWidth
+	}
+
+	width := boxplotLabelWidth + boxplotPlotWidth + 20
+	height := len(solvers)*boxplotRowHeight + boxplotAxisHeight
+	fmt.Fprintf(b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-size=\"12\">\n", width, height)
+
+	for row, solver := range solvers {
+		s := NewBoxplotStats(gaps[solver])
+		mid := float64(row*boxplotRowHeight) + boxplotRowHeight/2
+		half := boxplotRowHeight * boxplotBoxFraction / 2
+
+		fmt.Fprintf(b, "<text x=\"%d\" y=\"%.1f\" text-anchor=\"end\" dominant-baseline=\"middle\">%s</text>\n",
+			boxplotLabelWidth-8, mid, html.EscapeString(solver))
+		fmt.Fprintf(b, "<g stroke=\"#333\"><title>%s: median %s%%, n=%d</title>\n",
+			html.EscapeString(solver), output.Float(s.Median), s.Count)
+		fmt.Fprintf(b, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\"/>\n", x(s.WhiskerLow), mid, x(s.Q1), mid)
+		fmt.Fprintf(b, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\"/>\n", x(s.Q3), mid, x(s.WhiskerHigh), mid)
+		for _, w := range []float64{s.WhiskerLow, s.WhiskerHigh} {
+			fmt.Fprintf(b, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\"/>\n", x(w), mid-half/2, x(w), mid+half/2)
+		}
+		fmt.Fprintf(b, "<rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"#9ecae1\"/>\n",
+			x(s.Q1), mid-half, x(s.Q3)-x(s.Q1), 2*half)
+		fmt.Fprintf(b, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" stroke-width=\"2\"/>\n",
+			x(s.Median), mid-half, x(s.Median), mid+half)
+		for _, v := range s.Outliers {
+			fmt.Fprintf(b, "<circle cx=\"%.1f\" cy=\"%.1f\" r=\"2.5\" fill=\"none\"/>\n", x(v), mid)
+		}
+		b.WriteString("</g>\n")
+	}
+
+	// Gap axis with five ticks
+	axis := float64(len(solvers) * boxplotRowHeight)
+	fmt.Fprintf(b, "<line x1=\"%d\" y1=\"%.1f\" x2=\"%d\" y2=\"%.1f\" stroke=\"#333\"/>\n",
+		boxplotLabelWidth, axis, boxplotLabelWidth+boxplotPlotWidth, axis)
+	for k := 0; k <= 4; k++ {
+		v := lo + float64(k)/4*(hi-lo)
+		fmt.Fprintf(b, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" stroke=\"#333\"/>\n", x(v), axis, x(v), axis+4)
+		fmt.Fprintf(b, "<text x=\"%.1f\" y=\"%.1f\" text-anchor=\"middle\">%s%%</text>\n",
+			x(v), axis+16, strconv.FormatFloat(v, 'g', 3, 64))
+	}
+	b.WriteString("</svg>\n")
+}
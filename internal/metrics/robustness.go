@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// RobustnessStats describes how the final solution of a run performs on
+// instances with perturbed flows
+type RobustnessStats struct {
+	Scenarios          int
+	MeanFitness        float64
+	StdFitness         float64
+	WorstFitness       int
+	DegradationPercent float64 // mean scenario fitness relative to the nominal fitness
+}
+
+// NewRobustnessStats summarizes the fitness of a solution on every scenario
+func NewRobustnessStats(nominalFitness int, scenarioFitness []int) RobustnessStats {
+	stats := RobustnessStats{Scenarios: len(scenarioFitness)}
+	if len(scenarioFitness) == 0 {
+		return stats
+	}
+
+	stats.WorstFitness = scenarioFitness[0]
+	for _, f := range scenarioFitness {
+		stats.MeanFitness += float64(f)
+		stats.WorstFitness = max(stats.WorstFitness, f)
+	}
+	stats.MeanFitness /= float64(len(scenarioFitness))
+
+	for _, f := range scenarioFitness {
+		d := float64(f) - stats.MeanFitness
+		stats.StdFitness += d * d
+	}
+	stats.StdFitness = math.Sqrt(stats.StdFitness / float64(len(scenarioFitness)))
+
+	if nominalFitness != 0 {
+		stats.DegradationPercent = 100 * (stats.MeanFitness - float64(nominalFitness)) / math.Abs(float64(nominalFitness))
+	}
+	return stats
+}
+
+// SetRobustness attaches robustness statistics to a recorded run
+func (c *MetricsCollector) SetRobustness(instanceName, solverName string, run int, stats RobustnessStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	experiment, ok := c.Experiments[instanceName][solverName]
+	if !ok {
+		return
+	}
+	for i := range experiment.Runs {
+		if experiment.Runs[i].Run == run {
+			experiment.Runs[i].Robustness = &stats
+		}
+	}
+}
+
+// SaveRobustnessCSV writes one row per run that has robustness statistics
+func (c *MetricsCollector) SaveRobustnessCSV() error {
+	var rows [][]string
+	for instanceName, solvers := range c.Experiments {
+		for solverName, experiment := range solvers {
+			for _, run := range experiment.Runs {
+				r := run.Robustness
+				if r == nil {
+					continue
+				}
+				rows = append(rows, []string{
+					instanceName, solverName, strconv.Itoa(run.Run),
+					strconv.Itoa(c.objective(run.FinalFitness)),
+					strconv.Itoa(r.Scenarios),
+					c.Output.Float(c.objectiveFloat(r.MeanFitness)),
+					c.Output.Float(r.StdFitness),
+					strconv.Itoa(c.objective(r.WorstFitness)),
+					c.Output.Float(r.DegradationPercent),
+				})
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		for k := 0; k < 2; k++ {
+			if rows[i][k] != rows[j][k] {
+				return rows[i][k] < rows[j][k]
+			}
+		}
+		a, _ := strconv.Atoi(rows[i][2])
+		b, _ := strconv.Atoi(rows[j][2])
+		return a < b
+	})
+
+	path := filepath.Join(c.OutputDir, fmt.Sprintf("robustness_%s.csv", c.Timestamp))
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	writer.Write([]string{
+		"Instance", "Solver", "Run", "NominalFitness", "Scenarios",
+		"MeanScenarioFitness", "StdScenarioFitness", "WorstScenarioFitness", "DegradationPercent",
+	})
+	writer.WriteAll(rows)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// ScreeningResult is the rank of a solver on an instance after the short
+// screening runs of a two-phase experiment
+type ScreeningResult struct {
+	SolverSummary
+	Rank     int  // 1 for the smallest mean gap on the instance
+	Selected bool // the solver is evaluated again with full-length runs
+}
+
+// Screen ranks the solvers on every instance by their mean final gap, ties
+// broken by the best fitness, and selects the topK best of each instance
+func (c *MetricsCollector) Screen(topK int) []ScreeningResult {
+	summaries := c.Summaries()
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.InstanceName != b.InstanceName {
+			return a.InstanceName < b.InstanceName
+		}
+		if a.MeanGapPercent != b.MeanGapPercent {
+			return a.MeanGapPercent < b.MeanGapPercent
+		}
+		return a.BestFitness < b.BestFitness
+	})
+
+	results := make([]ScreeningResult, len(summaries))
+	rank := 0
+	for i, s := range summaries {
+		if i == 0 || s.InstanceName != summaries[i-1].InstanceName {
+			rank = 0
+		}
+		rank++
+		results[i] = ScreeningResult{SolverSummary: s, Rank: rank, Selected: rank <= topK}
+	}
+	return results
+}
+
+// SaveScreeningCSV writes the ranking of the screening phase to
+// screening_<timestamp>.csv
+func (c *MetricsCollector) SaveScreeningCSV(results []ScreeningResult) error {
+	path := filepath.Join(c.OutputDir, fmt.Sprintf("screening_%s.csv", c.Timestamp))
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	writer.Write([]string{
+		"Instance", "Solver", "Runs", "Reference", "BestFitness", "MeanGapPercent",
+		c.Output.TimeColumn("MeanTime"), "Rank", "Selected",
+	})
+	for _, r := range results {
+		writer.Write([]string{
+			r.InstanceName, r.SolverName, strconv.Itoa(r.Runs), strconv.Itoa(c.objective(r.Reference)),
+			strconv.Itoa(c.objective(r.BestFitness)),
+			c.Output.Float(r.MeanGapPercent),
+			c.Output.Duration(r.MeanTime),
+			strconv.Itoa(r.Rank),
+			strconv.FormatBool(r.Selected),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
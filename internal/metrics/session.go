@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Session describes an experiment in session_<timestamp>.json: what it was
+// run for, when and how. Many result directories stay apart by their names
+// and notes, see FindSessions.
+type Session struct {
+	Name     string     `json:"name,omitempty"`
+	Note     string     `json:"note,omitempty"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"` // nil while the experiment runs or after it was interrupted
+	Args     []string   `json:"args,omitempty"`     // the command line of the experiment
+	Solvers  []string   `json:"solvers,omitempty"`
+	Runs     int        `json:"runs"` // runs finished when the file was written
+}
+
+// Title is the name of the session, or the given fallback when it has none
+func (s *Session) Title(fallback string) string {
+	if s == nil || s.Name == "" {
+		return fallback
+	}
+	return s.Name
+}
+
+// sessionFile is the file name of the session of a timestamp
+func sessionFile(timestamp string) string {
+	return fmt.Sprintf("session_%s.json", timestamp)
+}
+
+// SaveSession writes the session of the collector, with the runs added so
+// far, to session_<timestamp>.json; without a session it does nothing
+func (c *MetricsCollector) SaveSession() error {
+	if c.Session == nil {
+		return nil
+	}
+	session := *c.Session
+	session.Runs = int(c.RunsAdded())
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.OutputDir, sessionFile(c.Timestamp)), append(data, '\n'), 0644)
+}
+
+// LoadSession reads a session file
+func LoadSession(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	session := &Session{}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return session, nil
+}
+
+// SessionOf returns the session written next to a results_<timestamp>.csv
+// file, or nil when there is none
+func SessionOf(resultsPath string) (*Session, error) {
+	base := strings.TrimSuffix(filepath.Base(resultsPath), ".csv")
+	stamp, ok := strings.CutPrefix(base, "results_")
+	if !ok {
+		return nil, nil
+	}
+	session, err := LoadSession(filepath.Join(filepath.Dir(resultsPath), sessionFile(stamp)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return session, err
+}
+
+// SessionEntry is a session file found by FindSessions
+type SessionEntry struct {
+	Path    string
+	Session *Session
+}
+
+// FindSessions returns the sessions in dir and its subdirectories, oldest
+// first. Unreadable session files are returned as errors after the others.
+func FindSessions(dir string) ([]SessionEntry, []error) {
+	var entries []SessionEntry
+	var errs []error
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() || !strings.HasPrefix(name, "session_") || !strings.HasSuffix(name, ".json") {
+			return nil
+		}
+		session, err := LoadSession(path)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		entries = append(entries, SessionEntry{Path: path, Session: session})
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Session.Started.Before(entries[j].Session.Started)
+	})
+	return entries, errs
+}
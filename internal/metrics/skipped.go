@@ -0,0 +1,60 @@
+package metrics
+
+// ReachedOptimum reports whether a run of a solver recorded so far on an
+// instance reached its known optimum. It is safe to call while runs execute.
+func (c *MetricsCollector) ReachedOptimum(instanceName, solverName string) bool {
+	optimum, ok := c.optimum(instanceName)
+	if !ok {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if experiment, ok := c.Experiments[instanceName][solverName]; ok {
+		for _, run := range experiment.Runs {
+			if run.FinalFitness <= optimum {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddSkippedRun counts a run of a solver on an instance that was not executed
+// because an earlier run reached the known optimum. The summary reports these
+// runs, they are missing from the results.
+func (c *MetricsCollector) AddSkippedRun(instanceName, solverName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.skipped == nil {
+		c.skipped = make(map[string]map[string]int)
+	}
+	if c.skipped[instanceName] == nil {
+		c.skipped[instanceName] = make(map[string]int)
+	}
+	c.skipped[instanceName][solverName]++
+}
+
+// SkippedRuns returns the number of runs of a solver on an instance skipped
+// at the optimum, see AddSkippedRun
+func (c *MetricsCollector) SkippedRuns(instanceName, solverName string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skipped[instanceName][solverName]
+}
+
+// TotalSkippedRuns returns the number of runs skipped at the optimum over all
+// instances and solvers
+func (c *MetricsCollector) TotalSkippedRuns() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	total := 0
+	for _, bySolver := range c.skipped {
+		for _, n := range bySolver {
+			total += n
+		}
+	}
+	return total
+}
@@ -0,0 +1,65 @@
+package metrics
+
+// Snapshot returns a copy of the collector holding the runs added so far.
+// The copy shares no maps or run lists with the collector, so it can be read
+// and saved while runs are still added; it is meant to be read only, the
+// slices inside the runs, such as Solution, are shared.
+func (c *MetricsCollector) Snapshot() *MetricsCollector {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := &MetricsCollector{
+		Experiments:         make(map[string]map[string]*ExperimentMetrics, len(c.Experiments)),
+		OutputDir:           c.OutputDir,
+		Output:              c.Output,
+		Optima:              c.Optima,
+		OptimalPermutations: c.OptimalPermutations,
+		Symmetries:          c.Symmetries,
+		Timestamp:           c.Timestamp,
+		Maximize:            c.Maximize,
+		Names:               c.Names,
+		Session:             c.Session,
+		ControlEvents:       append([]ControlEvent(nil), c.ControlEvents...),
+	}
+	for instanceName, bySolver := range c.Experiments {
+		copied := make(map[string]*ExperimentMetrics, len(bySolver))
+		for solverName, experiment := range bySolver {
+			copied[solverName] = &ExperimentMetrics{
+				InstanceName: experiment.InstanceName,
+				SolverName:   experiment.SolverName,
+				Runs:         append([]RunMetrics(nil), experiment.Runs...),
+			}
+			for _, run := range experiment.Runs {
+				snapshot.runsAdded.Add(1)
+				snapshot.evaluationsAdded.Add(int64(run.EvaluationsCount))
+			}
+		}
+		snapshot.Experiments[instanceName] = copied
+	}
+	if c.skipped != nil {
+		snapshot.skipped = make(map[string]map[string]int, len(c.skipped))
+		for instanceName, bySolver := range c.skipped {
+			snapshot.skipped[instanceName] = make(map[string]int, len(bySolver))
+			for solverName, n := range bySolver {
+				snapshot.skipped[instanceName][solverName] = n
+			}
+		}
+	}
+	return snapshot
+}
+
+// Flush writes the results and the summary of the runs added so far, and the
+// session with their count. It is safe to call while runs execute: the files
+// are written from a Snapshot and replaced by every later Flush and by the
+// final SaveToCSV and SaveSummaryCSV, so an interrupted experiment leaves its
+// partial results.
+func (c *MetricsCollector) Flush() error {
+	snapshot := c.Snapshot()
+	if err := snapshot.SaveToCSV(); err != nil {
+		return err
+	}
+	if err := snapshot.SaveSummaryCSV(); err != nil {
+		return err
+	}
+	return snapshot.SaveSession()
+}
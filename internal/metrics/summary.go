@@ -0,0 +1,187 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// GapPercent is the relative distance of fitness above the reference, in percent
+func GapPercent(fitness, reference int) float64 {
+	if reference == 0 {
+		return 100 * PrimalGap(fitness, reference)
+	}
+	return 100 * float64(fitness-reference) / math.Abs(float64(reference))
+}
+
+// SolverSummary aggregates all runs of one solver on one instance
+type SolverSummary struct {
+	InstanceName       string
+	SolverName         string
+	Runs               int
+	Reference          int
+	BestFitness        int
+	MeanFitness        float64
+	WorstFitness       int
+	MeanGapPercent     float64
+	MeanTime           time.Duration
+	MeanCPUTime        time.Duration
+	MeanSolverTime     time.Duration // MeanTime without the instrumentation overhead
+	MeanOverheadTime   time.Duration
+	MeanEvaluations    float64
+	MeanPrimalIntegral float64
+	MeanConvergenceAUC float64
+	DuplicateRuns      int // runs repeating the solution of an earlier run, see DuplicateRuns
+	SkippedRuns        int // runs not executed as an earlier one reached the optimum, see AddSkippedRun
+
+	// FitnessCIHalfWidth is the half-width of the 95% confidence interval of
+	// MeanFitness, infinite for a single run, see ConfidenceHalfWidth
+	FitnessCIHalfWidth float64
+
+	// MeanOptimumDistance is the mean assignment distance to the optimal
+	// permutation over the OptimumDistanceRuns runs that have one
+	MeanOptimumDistance float64
+	OptimumDistanceRuns int
+
+	// MeanBestAt holds the mean of every BestAt column over the runs that have
+	// a value, keyed by column name, as objective values
+	MeanBestAt map[string]float64
+
+	// MeanDerived holds the mean of every derived metric of the output options
+	MeanDerived map[string]float64
+}
+
+// Summaries aggregates the collected runs, sorted by instance and solver name
+func (c *MetricsCollector) Summaries() []SolverSummary {
+	var summaries []SolverSummary
+
+	for instanceName, solvers := range c.Experiments {
+		reference := c.Reference(instanceName)
+		for solverName, experiment := range solvers {
+			if len(experiment.Runs) == 0 {
+				continue
+			}
+			s := SolverSummary{
+				InstanceName: instanceName,
+				SolverName:   solverName,
+				Runs:         len(experiment.Runs),
+				Reference:    reference,
+				BestFitness:  experiment.Runs[0].FinalFitness,
+				WorstFitness: experiment.Runs[0].FinalFitness,
+
+				DuplicateRuns: c.DuplicateRuns(instanceName, solverName),
+				SkippedRuns:   c.SkippedRuns(instanceName, solverName),
+				MeanBestAt:    make(map[string]float64),
+				MeanDerived:   make(map[string]float64),
+			}
+			bestAtRuns := make(map[string]int)
+			finals := make([]int, 0, len(experiment.Runs))
+
+			for _, run := range experiment.Runs {
+				finals = append(finals, run.FinalFitness)
+				s.BestFitness = min(s.BestFitness, run.FinalFitness)
+				s.WorstFitness = max(s.WorstFitness, run.FinalFitness)
+				s.MeanFitness += float64(run.FinalFitness)
+				s.MeanGapPercent += GapPercent(run.FinalFitness, reference)
+				s.MeanTime += run.TimeElapsed
+				s.MeanCPUTime += run.CPUTime
+				s.MeanSolverTime += run.SolverTime()
+				s.MeanOverheadTime += run.OverheadTime
+				s.MeanEvaluations += float64(run.EvaluationsCount)
+				s.MeanPrimalIntegral += PrimalIntegral(run.Trajectory, reference, run.TimeElapsed)
+				s.MeanConvergenceAUC += ConvergenceAUC(run.Trajectory, reference, run.EvaluationsCount)
+				if distance, ok := c.OptimumDistance(run); ok {
+					s.MeanOptimumDistance += float64(distance)
+					s.OptimumDistanceRuns++
+				}
+				for _, percent := range AnytimePercents {
+					if fitness, ok := BestAtTime(run.Trajectory, run.TimeElapsed, percent); ok {
+						s.MeanBestAt[fmt.Sprintf("BestAt%dTime", percent)] += float64(c.objective(fitness))
+						bestAtRuns[fmt.Sprintf("BestAt%dTime", percent)]++
+					}
+					if fitness, ok := BestAtEvaluations(run.Trajectory, run.EvaluationsCount, percent); ok {
+						s.MeanBestAt[fmt.Sprintf("BestAt%dEvals", percent)] += float64(c.objective(fitness))
+						bestAtRuns[fmt.Sprintf("BestAt%dEvals", percent)]++
+					}
+				}
+				if len(c.Output.Derived) > 0 {
+					variables := c.derivedVariables(run, reference)
+					for _, m := range c.Output.Derived {
+						s.MeanDerived[m.Name] += m.Eval(variables)
+					}
+				}
+			}
+
+			n := float64(s.Runs)
+			s.MeanFitness /= n
+			s.FitnessCIHalfWidth = ConfidenceHalfWidth(finals, 0.95)
+			s.MeanGapPercent /= n
+			s.MeanTime /= time.Duration(s.Runs)
+			s.MeanCPUTime /= time.Duration(s.Runs)
+			s.MeanSolverTime /= time.Duration(s.Runs)
+			s.MeanOverheadTime /= time.Duration(s.Runs)
+			s.MeanEvaluations /= n
+			s.MeanPrimalIntegral /= n
+			s.MeanConvergenceAUC /= n
+			if s.OptimumDistanceRuns > 0 {
+				s.MeanOptimumDistance /= float64(s.OptimumDistanceRuns)
+			}
+			for name, runs := range bestAtRuns {
+				s.MeanBestAt[name] /= float64(runs)
+			}
+			for name := range s.MeanDerived {
+				s.MeanDerived[name] /= n
+			}
+
+			summaries = append(summaries, s)
+		}
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].InstanceName != summaries[j].InstanceName {
+			return summaries[i].InstanceName < summaries[j].InstanceName
+		}
+		return summaries[i].SolverName < summaries[j].SolverName
+	})
+	return summaries
+}
+
+// SaveSummaryCSV writes one row per instance and solver with aggregated statistics
+func (c *MetricsCollector) SaveSummaryCSV() error {
+	summaryPath := filepath.Join(c.OutputDir, fmt.Sprintf("summary_%s.csv", c.Timestamp))
+	summaryFile, err := os.Create(summaryPath)
+	if err != nil {
+		return err
+	}
+	defer summaryFile.Close()
+
+	writer := csv.NewWriter(summaryFile)
+
+	columns, err := c.Output.summaryTableColumns()
+	if err != nil {
+		return err
+	}
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.header(c.Output)
+	}
+	writer.Write(header)
+
+	for _, s := range c.Summaries() {
+		record := make([]string, len(columns))
+		for i, col := range columns {
+			record[i] = col.value(c, s)
+		}
+		writer.Write(record)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return summaryFile.Close()
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// SaveTidyCSV writes the results in long format, one row per instance,
+// solver, run and metric, ready for plotting with R/ggplot or pandas. Only
+// numeric metrics are included; solutions and trajectories stay in the
+// results file.
+func (c *MetricsCollector) SaveTidyCSV() error {
+	tidyPath := filepath.Join(c.OutputDir, fmt.Sprintf("tidy_%s.csv", c.Timestamp))
+	tidyFile, err := os.Create(tidyPath)
+	if err != nil {
+		return err
+	}
+	defer tidyFile.Close()
+
+	writer := csv.NewWriter(tidyFile)
+
+	writer.Write([]string{"Instance", "Solver", "Run", "Metric", "Value"})
+
+	instanceNames := make([]string, 0, len(c.Experiments))
+	for instanceName := range c.Experiments {
+		instanceNames = append(instanceNames, instanceName)
+	}
+	sort.Strings(instanceNames)
+
+	for _, instanceName := range instanceNames {
+		reference := c.Reference(instanceName)
+		solvers := c.Experiments[instanceName]
+		solverNames := make([]string, 0, len(solvers))
+		for solverName := range solvers {
+			solverNames = append(solverNames, solverName)
+		}
+		sort.Strings(solverNames)
+
+		for _, solverName := range solverNames {
+			runs := append([]RunMetrics(nil), solvers[solverName].Runs...)
+			sort.Slice(runs, func(i, j int) bool { return runs[i].Run < runs[j].Run })
+			for _, run := range runs {
+				prefix := []string{instanceName, solverName, strconv.Itoa(run.Run)}
+				for _, m := range c.tidyMetrics(run, reference) {
+					writer.Write(append(prefix, m[0], m[1]))
+				}
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return tidyFile.Close()
+}
+
+// tidyMetrics returns the (metric, value) pairs of a run
+func (c *MetricsCollector) tidyMetrics(run RunMetrics, reference int) [][2]string {
+	metrics := [][2]string{
+		{"InitialFitness", strconv.Itoa(c.objective(run.InitialFitness))},
+		{"FinalFitness", strconv.Itoa(c.objective(run.FinalFitness))},
+		{"GapPercent", c.Output.Float(GapPercent(run.FinalFitness, reference))},
+		{"QAPCost", strconv.Itoa(run.QAPCost)},
+		{"RelocationCost", strconv.Itoa(run.RelocationCost)},
+		{"ConstraintViolation", strconv.Itoa(run.ConstraintViolation)},
+		{c.Output.TimeColumn("Time"), c.Output.Duration(run.TimeElapsed)},
+		{c.Output.TimeColumn("CPUTime"), c.Output.Duration(run.CPUTime)},
+		{c.Output.TimeColumn("SolverTime"), c.Output.Duration(run.SolverTime())},
+		{c.Output.TimeColumn("OverheadTime"), c.Output.Duration(run.OverheadTime)},
+		{"Steps", strconv.Itoa(run.StepsCount)},
+		{"Evaluations", strconv.Itoa(run.EvaluationsCount)},
+		{"SolutionsChecked", strconv.Itoa(run.SolutionsChecked)},
+		{"AcceptedMoves", strconv.Itoa(run.AcceptedMoves)},
+		{"RejectedMoves", strconv.Itoa(run.RejectedMoves)},
+		{"ImprovingMoves", strconv.Itoa(run.ImprovingMoves)},
+		{"AllocatedBytes", strconv.FormatUint(run.AllocatedBytes, 10)},
+		{"Mallocs", strconv.FormatUint(run.Mallocs, 10)},
+		{"PeakHeapBytes", strconv.FormatUint(run.PeakHeapBytes, 10)},
+		{"PrimalIntegral", c.Output.Float(PrimalIntegral(run.Trajectory, reference, run.TimeElapsed))},
+		{"ConvergenceAUC", c.Output.Float(ConvergenceAUC(run.Trajectory, reference, run.EvaluationsCount))},
+	}
+	if distance, ok := c.OptimumDistance(run); ok {
+		metrics = append(metrics, [2]string{"OptimumDistance", strconv.Itoa(distance)})
+	}
+	for _, percent := range AnytimePercents {
+		if fitness, ok := BestAtTime(run.Trajectory, run.TimeElapsed, percent); ok {
+			metrics = append(metrics, [2]string{fmt.Sprintf("BestAt%dTime", percent), strconv.Itoa(c.objective(fitness))})
+		}
+		if fitness, ok := BestAtEvaluations(run.Trajectory, run.EvaluationsCount, percent); ok {
+			metrics = append(metrics, [2]string{fmt.Sprintf("BestAt%dEvals", percent), strconv.Itoa(c.objective(fitness))})
+		}
+	}
+	return metrics
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// TraceWriter writes every accepted move of a run to a CSV file.
+// A nil *TraceWriter is valid and discards all moves.
+type TraceWriter struct {
+	file   *os.File
+	buffer *bufio.Writer
+	writer *csv.Writer
+}
+
+// NewTraceWriter creates the trace file at path, including missing directories
+func NewTraceWriter(path string) (*TraceWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := bufio.NewWriter(file)
+	t := &TraceWriter{file: file, buffer: buffer, writer: csv.NewWriter(buffer)}
+	t.writer.Write([]string{"Iteration", "I", "J", "Delta", "Fitness", "Context"})
+	return t, nil
+}
+
+// Move records an accepted swap of positions i and j. Context carries solver
+// specific state such as the temperature or tabu tenure.
+func (t *TraceWriter) Move(iteration, i, j, delta, fitness int, context string) {
+	if t == nil {
+		return
+	}
+	t.writer.Write([]string{
+		strconv.Itoa(iteration),
+		strconv.Itoa(i),
+		strconv.Itoa(j),
+		strconv.Itoa(delta),
+		strconv.Itoa(fitness),
+		context,
+	})
+}
+
+// Close flushes the trace and closes the file
+func (t *TraceWriter) Close() error {
+	if t == nil {
+		return nil
+	}
+	t.writer.Flush()
+	if err := t.buffer.Flush(); err != nil {
+		t.file.Close()
+		return err
+	}
+	return t.file.Close()
+}
@@ -0,0 +1,58 @@
+package metrics
+
+// DefaultTrajectoryPoints is the default bound on the points stored per run
+const DefaultTrajectoryPoints = 1000
+
+// TrajectorySampler records a trajectory in bounded memory. It stores every
+// stride-th improvement; whenever the buffer is full every other point is
+// dropped and the stride doubles, so the kept points stay evenly spread over
+// the improvements, dense where the search improved often, and the first and
+// the latest point are always kept.
+type TrajectorySampler struct {
+	limit  int // maximum number of points, 0 for no limit
+	stride int
+	added  int
+	points []TrajectoryPoint
+	latest TrajectoryPoint
+}
+
+// NewTrajectorySampler creates a sampler keeping at most limit points, at
+// least two when limited; 0 keeps all points
+func NewTrajectorySampler(limit int) *TrajectorySampler {
+	if limit > 0 {
+		limit = max(limit, 2)
+	}
+	return &TrajectorySampler{limit: limit, stride: 1}
+}
+
+// Add records the next improvement
+func (s *TrajectorySampler) Add(p TrajectoryPoint) {
+	s.latest = p
+	s.added++
+	if (s.added-1)%s.stride != 0 {
+		return
+	}
+	s.points = append(s.points, p)
+
+	// One slot stays free for the latest point
+	if s.limit > 0 && len(s.points) > s.limit-1 {
+		kept := s.points[:0]
+		for i := 0; i < len(s.points); i += 2 {
+			kept = append(kept, s.points[i])
+		}
+		s.points = kept
+		s.stride *= 2
+	}
+}
+
+// Points returns the sampled trajectory ending with the latest improvement
+func (s *TrajectorySampler) Points() []TrajectoryPoint {
+	if s.added == 0 {
+		return nil
+	}
+	points := append([]TrajectoryPoint(nil), s.points...)
+	if points[len(points)-1] != s.latest {
+		points = append(points, s.latest)
+	}
+	return points
+}
@@ -0,0 +1,227 @@
+package qap
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FacilityGroup requires every pair of its facilities to be assigned to
+// locations at most MaxDistance apart
+type FacilityGroup struct {
+	Facilities  []int
+	MaxDistance int
+}
+
+// Constraints are side constraints on the assignment. With a positive Penalty
+// each unit of violation adds Penalty to the fitness; with zero Penalty the
+// constraints are only checked and reported.
+type Constraints struct {
+	Groups  []FacilityGroup
+	Penalty int
+
+	// Forbidden lists facility-location pairs that must not be assigned, see Forbid
+	Forbidden [][2]int
+	forbidden map[[2]int]bool
+}
+
+// ReadConstraints reads a constraints file. Each non-empty line that is not a
+// comment (#) holds one directive:
+//
+//	group <maxDistance> <facility> <facility> ...
+//	forbid <facility> <location>
+//	penalty <cost per unit of violation>
+//
+// Facilities and locations are 0-based.
+func ReadConstraints(filename string) (*Constraints, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	c := &Constraints{}
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		values := make([]int, len(fields)-1)
+		for i, f := range fields[1:] {
+			if values[i], err = strconv.Atoi(f); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid number %q", filename, lineNumber, f)
+			}
+		}
+
+		switch strings.ToLower(fields[0]) {
+		case "group":
+			if len(values) < 3 {
+				return nil, fmt.Errorf("%s:%d: group needs a distance and at least two facilities", filename, lineNumber)
+			}
+			c.Groups = append(c.Groups, FacilityGroup{MaxDistance: values[0], Facilities: values[1:]})
+		case "forbid":
+			if len(values) != 2 {
+				return nil, fmt.Errorf("%s:%d: forbid needs a facility and a location", filename, lineNumber)
+			}
+			c.Forbid(values[0], values[1])
+		case "penalty":
+			if len(values) != 1 || values[0] < 0 {
+				return nil, fmt.Errorf("%s:%d: penalty needs one non-negative value", filename, lineNumber)
+			}
+			c.Penalty = values[0]
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown directive %q", filename, lineNumber, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Forbid adds a facility-location pair that must not be assigned
+func (c *Constraints) Forbid(facility, location int) {
+	if c.forbidden == nil {
+		c.forbidden = make(map[[2]int]bool)
+	}
+	if !c.forbidden[[2]int{facility, location}] {
+		c.forbidden[[2]int{facility, location}] = true
+		c.Forbidden = append(c.Forbidden, [2]int{facility, location})
+	}
+}
+
+// IsForbidden reports whether facility must not be placed at location
+func (c *Constraints) IsForbidden(facility, location int) bool {
+	return c.forbidden[[2]int{facility, location}]
+}
+
+// HasForbidden reports whether any facility-location pair is forbidden
+func (c *Constraints) HasForbidden() bool {
+	return len(c.Forbidden) > 0
+}
+
+// Validate checks that all facilities and locations exist in an instance of the given size
+func (c *Constraints) Validate(size int) error {
+	for _, g := range c.Groups {
+		for _, f := range g.Facilities {
+			if f < 0 || f >= size {
+				return fmt.Errorf("group facility %d outside 0..%d", f, size-1)
+			}
+		}
+	}
+	for _, p := range c.Forbidden {
+		if p[0] < 0 || p[0] >= size || p[1] < 0 || p[1] >= size {
+			return fmt.Errorf("forbidden pair %d %d outside 0..%d", p[0], p[1], size-1)
+		}
+	}
+	return nil
+}
+
+// ForbiddenAssignments returns the number of facilities placed on a forbidden location
+func (c *Constraints) ForbiddenAssignments(solution []int) int {
+	count := 0
+	for _, p := range c.Forbidden {
+		if solution[p[0]] == p[1] {
+			count++
+		}
+	}
+	return count
+}
+
+// AllowsSwap reports whether swapping the locations of facilities i and j
+// keeps both of them off forbidden locations
+func (c *Constraints) AllowsSwap(solution []int, i, j int) bool {
+	return !c.IsForbidden(i, solution[j]) && !c.IsForbidden(j, solution[i])
+}
+
+// RepairForbidden moves facilities off forbidden locations by swaps that do
+// not create new forbidden assignments. It is best effort and returns whether
+// the solution ends up without forbidden assignments.
+func (c *Constraints) RepairForbidden(solution []int) bool {
+	feasible := true
+	for _, p := range c.Forbidden {
+		f := p[0]
+		if solution[f] != p[1] {
+			continue
+		}
+		repaired := false
+		for g := range solution {
+			if g != f && c.AllowsSwap(solution, f, g) {
+				solution[f], solution[g] = solution[g], solution[f]
+				repaired = true
+				break
+			}
+		}
+		feasible = feasible && repaired
+	}
+	return feasible
+}
+
+// Violation returns the total amount by which the solution exceeds the group
+// distance limits, summed over all facility pairs of every group, plus one
+// for every forbidden assignment. It is zero for feasible solutions.
+func (c *Constraints) Violation(instance *QAPInstance, solution []int) int {
+	violation := c.ForbiddenAssignments(solution)
+	for _, g := range c.Groups {
+		for a := 0; a < len(g.Facilities); a++ {
+			for b := a + 1; b < len(g.Facilities); b++ {
+				la, lb := solution[g.Facilities[a]], solution[g.Facilities[b]]
+				d := max(instance.DistanceMatrix[la][lb], instance.DistanceMatrix[lb][la])
+				if d > g.MaxDistance {
+					violation += d - g.MaxDistance
+				}
+			}
+		}
+	}
+	return violation
+}
+
+// Feasible reports whether the solution satisfies every constraint
+func (c *Constraints) Feasible(instance *QAPInstance, solution []int) bool {
+	return c.Violation(instance, solution) == 0
+}
+
+// penalty returns the fitness penalty of a solution
+func (c *Constraints) penalty(instance *QAPInstance, solution []int) int {
+	if c.Penalty == 0 {
+		return 0
+	}
+	return c.Penalty * c.Violation(instance, solution)
+}
+
+// AllowsLocation reports whether facility can be placed at location given the
+// facilities already placed (assigned[f] >= 0) without breaking a group limit
+// or using a forbidden location
+func (c *Constraints) AllowsLocation(instance *QAPInstance, assigned []int, facility, location int) bool {
+	if c.IsForbidden(facility, location) {
+		return false
+	}
+	for _, g := range c.Groups {
+		member := false
+		for _, f := range g.Facilities {
+			if f == facility {
+				member = true
+				break
+			}
+		}
+		if !member {
+			continue
+		}
+		for _, f := range g.Facilities {
+			if f == facility || assigned[f] < 0 {
+				continue
+			}
+			l := assigned[f]
+			if max(instance.DistanceMatrix[location][l], instance.DistanceMatrix[l][location]) > g.MaxDistance {
+				return false
+			}
+		}
+	}
+	return true
+}
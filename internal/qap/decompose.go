@@ -0,0 +1,81 @@
+package qap
+
+import "sort"
+
+// FlowComponents splits the facilities into the connected components of the
+// flow graph, where two facilities are adjacent when flow goes between them
+// in either direction. No flow connects two components, so their costs only
+// depend on their own locations. Facilities without any flow, self-flow
+// included, are returned separately as free: they cost nothing wherever they
+// are, like the dummies of a padded instance. Components are sorted by
+// decreasing size, then by their first facility.
+func FlowComponents(instance *QAPInstance) (components [][]int, free []int) {
+	n := instance.Size
+	flow := instance.FlowMatrix
+	component := make([]int, n)
+	for i := range component {
+		component[i] = -1
+	}
+
+	for start := 0; start < n; start++ {
+		if component[start] >= 0 {
+			continue
+		}
+		if isFree(flow, start) {
+			free = append(free, start)
+			continue
+		}
+		id := len(components)
+		component[start] = id
+		members := []int{start}
+		for k := 0; k < len(members); k++ {
+			i := members[k]
+			for j := 0; j < n; j++ {
+				if component[j] < 0 && j != i && (flow[i][j] != 0 || flow[j][i] != 0) {
+					component[j] = id
+					members = append(members, j)
+				}
+			}
+		}
+		sort.Ints(members)
+		components = append(components, members)
+	}
+
+	sort.SliceStable(components, func(a, b int) bool { return len(components[a]) > len(components[b]) })
+	return components, free
+}
+
+// isFree reports whether facility i has no flow at all
+func isFree(flow [][]int, i int) bool {
+	for j := range flow {
+		if flow[i][j] != 0 || flow[j][i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SubInstance returns the instance of the given facilities placed on the
+// given locations, facility k of the sub-instance being facilities[k] and
+// location k being locations[k]. With more locations than facilities it is
+// padded, see Pad. Relocation, constraints and uncertain flows are not
+// carried over.
+func SubInstance(instance *QAPInstance, facilities, locations []int) *QAPInstance {
+	flow := make([][]int, len(facilities))
+	for a, fa := range facilities {
+		flow[a] = make([]int, len(facilities))
+		for b, fb := range facilities {
+			flow[a][b] = instance.FlowMatrix[fa][fb]
+		}
+	}
+	distance := make([][]int, len(locations))
+	for a, la := range locations {
+		distance[a] = make([]int, len(locations))
+		for b, lb := range locations {
+			distance[a][b] = instance.DistanceMatrix[la][lb]
+		}
+	}
+	sub := &QAPInstance{Size: len(facilities), FlowMatrix: flow, DistanceMatrix: distance, Maximize: instance.Maximize}
+	sub.Pad()
+	return sub
+}
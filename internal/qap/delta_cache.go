@@ -0,0 +1,73 @@
+package qap
+
+// DeltaCache keeps the swap delta of every pair of positions of a solution.
+// After a swap only the pairs sharing a position with it need an O(n)
+// evaluation, the others are updated in O(1) (Taillard 1991), so keeping the
+// whole neighborhood current costs O(n^2) per move instead of O(n^3). It
+// applies to the plain QAP cost, see CanCacheDeltas.
+type DeltaCache struct {
+	instance *QAPInstance
+	solution []int
+	deltas   []int // deltas[i*n+j] for i < j
+}
+
+// CanCacheDeltas reports whether the swap deltas of instance can be cached:
+// relocation, constraint and stochastic terms change with every move
+func CanCacheDeltas(instance *QAPInstance) bool {
+	return !instance.hasExtraTerms()
+}
+
+// NewDeltaCache evaluates every swap of solution. The cache keeps solution
+// and swaps it in place, see Swap; changing it otherwise needs a Reset.
+func NewDeltaCache(instance *QAPInstance, solution []int) *DeltaCache {
+	c := &DeltaCache{instance: instance, solution: solution, deltas: make([]int, instance.Size*instance.Size)}
+	c.Reset()
+	return c
+}
+
+// Reset evaluates every swap of the solution again
+func (c *DeltaCache) Reset() {
+	n := c.instance.Size
+	for i := 0; i < n-1; i++ {
+		for j := i + 1; j < n; j++ {
+			c.deltas[i*n+j] = SwapDelta(c.instance, c.solution, i, j)
+		}
+	}
+}
+
+// Delta returns the fitness change of swapping positions i and j, like
+// SwapDelta
+func (c *DeltaCache) Delta(i, j int) int {
+	if i > j {
+		i, j = j, i
+	}
+	if i == j {
+		return 0
+	}
+	return c.deltas[i*c.instance.Size+j]
+}
+
+// Swap exchanges positions r and s of the solution and updates the deltas
+func (c *DeltaCache) Swap(r, s int) {
+	if r == s {
+		return
+	}
+	n := c.instance.Size
+	p := c.solution
+	p[r], p[s] = p[s], p[r]
+
+	a, b := c.instance.FlowMatrix, c.instance.DistanceMatrix
+	sense := c.instance.Sense()
+	pr, ps := p[r], p[s]
+	for u := 0; u < n-1; u++ {
+		for v := u + 1; v < n; v++ {
+			if u == r || u == s || v == r || v == s {
+				c.deltas[u*n+v] = SwapDelta(c.instance, p, u, v)
+				continue
+			}
+			pu, pv := p[u], p[v]
+			c.deltas[u*n+v] += sense * ((a[r][u]-a[r][v]+a[s][v]-a[s][u])*(b[ps][pu]-b[ps][pv]+b[pr][pv]-b[pr][pu]) +
+				(a[u][r]-a[v][r]+a[v][s]-a[u][s])*(b[pu][ps]-b[pv][ps]+b[pv][pr]-b[pu][pr]))
+		}
+	}
+}
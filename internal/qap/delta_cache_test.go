@@ -0,0 +1,83 @@
+package qap
+
+import (
+	"fmt"
+	"math/rand"
+	"qap_solver/internal/qaptest"
+	"qap_solver/pkg"
+	"testing"
+)
+
+// TestDeltaCache compares every cached delta with SwapDelta after each of a
+// sequence of random swaps, on every qaptest.Variants instance
+func TestDeltaCache(t *testing.T) {
+	for _, n := range []int{2, 3, 7, 16, 33} {
+		for _, variant := range qaptest.Variants {
+			t.Run(fmt.Sprintf("n=%d %s", n, variant.Name), func(t *testing.T) {
+				rng := rand.New(rand.NewSource(int64(n)))
+				flow, distance := variant.Matrices(rng, n)
+				instance := &QAPInstance{Size: n, FlowMatrix: flow, DistanceMatrix: distance, Maximize: variant.Maximize}
+				instance.Pad()
+				size := instance.Size
+				solution := rng.Perm(size)
+				cache := NewDeltaCache(instance, solution)
+				for move := 0; move < 2*size; move++ {
+					cache.Swap(pkg.RandomDistinctPair(rng, size))
+					for i := 0; i < size; i++ {
+						for j := 0; j < size; j++ {
+							if got, want := cache.Delta(i, j), SwapDelta(instance, solution, i, j); got != want {
+								t.Fatalf("after %d moves the cached delta of swap (%d, %d) is %d, SwapDelta gives %d",
+									move+1, i, j, got, want)
+							}
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+// deltaSink keeps the benchmarked deltas from being optimized away
+var deltaSink int
+
+// BenchmarkDeltaCache times a steepest descent iteration that evaluates the
+// whole swap neighborhood with SwapDelta against one reading and updating a
+// DeltaCache. Moves are random so the solution keeps changing.
+func BenchmarkDeltaCache(b *testing.B) {
+	for _, n := range []int{100, 256, 500} {
+		rng := rand.New(rand.NewSource(1))
+		instance := &QAPInstance{Size: n, FlowMatrix: qaptest.Matrix(rng, n), DistanceMatrix: qaptest.Matrix(rng, n)}
+
+		b.Run(fmt.Sprintf("n=%d/recompute", n), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			solution := rng.Perm(n)
+			b.ResetTimer()
+			for k := 0; k < b.N; k++ {
+				best := 0
+				for i := 0; i < n-1; i++ {
+					for j := i + 1; j < n; j++ {
+						best = min(best, SwapDelta(instance, solution, i, j))
+					}
+				}
+				deltaSink += best
+				r, s := pkg.RandomDistinctPair(rng, n)
+				solution[r], solution[s] = solution[s], solution[r]
+			}
+		})
+		b.Run(fmt.Sprintf("n=%d/cached", n), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			cache := NewDeltaCache(instance, rng.Perm(n))
+			b.ResetTimer()
+			for k := 0; k < b.N; k++ {
+				best := 0
+				for i := 0; i < n-1; i++ {
+					for j := i + 1; j < n; j++ {
+						best = min(best, cache.Delta(i, j))
+					}
+				}
+				deltaSink += best
+				cache.Swap(pkg.RandomDistinctPair(rng, n))
+			}
+		})
+	}
+}
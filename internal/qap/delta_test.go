@@ -0,0 +1,47 @@
+package qap
+
+import (
+	"fmt"
+	"math/rand"
+	"qap_solver/internal/qaptest"
+	"qap_solver/pkg"
+	"testing"
+)
+
+// TestSwapDeltaMatchesFullEvaluation compares SwapDelta with the difference
+// of two full evaluations for every swap of a solution, after each of a
+// sequence of random swaps, on every qaptest.Variants instance: these cover
+// the asymmetric cases the O(n) formula must get right.
+func TestSwapDeltaMatchesFullEvaluation(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 7, 16, 33} {
+		for _, variant := range qaptest.Variants {
+			t.Run(fmt.Sprintf("n=%d %s", n, variant.Name), func(t *testing.T) {
+				rng := rand.New(rand.NewSource(int64(n)))
+				flow, distance := variant.Matrices(rng, n)
+				instance := &QAPInstance{Size: n, FlowMatrix: flow, DistanceMatrix: distance, Maximize: variant.Maximize}
+				instance.Pad()
+				size := instance.Size
+				solution := rng.Perm(size)
+				for move := 0; move <= size; move++ {
+					before := CalculateFitness(instance, solution)
+					for i := 0; i < size; i++ {
+						for j := 0; j < size; j++ {
+							got := SwapDelta(instance, solution, i, j)
+							solution[i], solution[j] = solution[j], solution[i]
+							want := CalculateFitness(instance, solution) - before
+							solution[i], solution[j] = solution[j], solution[i]
+							if got != want {
+								t.Fatalf("after %d moves SwapDelta of swap (%d, %d) is %d, a full evaluation gives %d",
+									move, i, j, got, want)
+							}
+						}
+					}
+					if size > 1 {
+						r, s := pkg.RandomDistinctPair(rng, size)
+						solution[r], solution[s] = solution[s], solution[r]
+					}
+				}
+			})
+		}
+	}
+}
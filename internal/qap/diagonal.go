@@ -0,0 +1,46 @@
+package qap
+
+// Diagonal counts the nonzero diagonal entries of an instance: self-flows of
+// a facility and distances of a location to itself
+type Diagonal struct {
+	Flow     int
+	Distance int
+}
+
+// Nonzero reports whether either matrix has a nonzero diagonal entry
+func (d Diagonal) Nonzero() bool {
+	return d.Flow > 0 || d.Distance > 0
+}
+
+// AffectsCost reports whether the diagonals change the cost: the term
+// flow[i][i]*distance[p[i]][p[i]] needs nonzero entries on both diagonals.
+// Otherwise zeroing them leaves the cost of every solution unchanged.
+func (d Diagonal) AffectsCost() bool {
+	return d.Flow > 0 && d.Distance > 0
+}
+
+// Diagonal counts the nonzero diagonal entries of the instance. The cost,
+// SwapDelta and the construction heuristics include them as given.
+func (instance *QAPInstance) Diagonal() Diagonal {
+	var d Diagonal
+	for i := 0; i < instance.Size; i++ {
+		if instance.FlowMatrix[i][i] != 0 {
+			d.Flow++
+		}
+		if instance.DistanceMatrix[i][i] != 0 {
+			d.Distance++
+		}
+	}
+	return d
+}
+
+// ZeroDiagonals clears the diagonals of both matrices and returns the
+// nonzero entries it found
+func (instance *QAPInstance) ZeroDiagonals() Diagonal {
+	d := instance.Diagonal()
+	for i := 0; i < instance.Size; i++ {
+		instance.FlowMatrix[i][i] = 0
+		instance.DistanceMatrix[i][i] = 0
+	}
+	return d
+}
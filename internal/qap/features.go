@@ -0,0 +1,58 @@
+package qap
+
+import "math"
+
+// Features are structural properties of an instance that correlate with how
+// hard it is for local search
+type Features struct {
+	Size int
+
+	// Dominance is 100 * standard deviation / mean of the off-diagonal
+	// entries, as used to classify QAPLIB instances. High flow dominance means
+	// a few facility pairs carry most of the flow (structured, real-life
+	// instances), low values are typical of uniformly random instances.
+	FlowDominance     float64
+	DistanceDominance float64
+
+	// Sparsity is the fraction of zero off-diagonal entries
+	FlowSparsity     float64
+	DistanceSparsity float64
+}
+
+// ComputeFeatures measures the features of an instance
+func ComputeFeatures(instance *QAPInstance) Features {
+	f := Features{Size: instance.Size}
+	f.FlowDominance, f.FlowSparsity = matrixStats(instance.FlowMatrix)
+	f.DistanceDominance, f.DistanceSparsity = matrixStats(instance.DistanceMatrix)
+	return f
+}
+
+// matrixStats returns the dominance and sparsity of the off-diagonal entries
+func matrixStats(m [][]int) (float64, float64) {
+	var sum, sumSquares float64
+	count, zeros := 0, 0
+	for i, row := range m {
+		for j, v := range row {
+			if i == j {
+				continue
+			}
+			sum += float64(v)
+			sumSquares += float64(v) * float64(v)
+			count++
+			if v == 0 {
+				zeros++
+			}
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+
+	mean := sum / float64(count)
+	sparsity := float64(zeros) / float64(count)
+	if mean == 0 {
+		return 0, sparsity
+	}
+	std := math.Sqrt(max(sumSquares/float64(count)-mean*mean, 0))
+	return 100 * std / mean, sparsity
+}
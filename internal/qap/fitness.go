@@ -1,14 +1,35 @@
 package qap
 
+// CalculateFitness returns the value solvers minimize: the QAP cost (negated
+// for maximization instances) plus the relocation cost and constraint penalty.
+// With uncertain flows the QAP cost is their expected or percentile cost.
 func CalculateFitness(instance *QAPInstance, solution []int) int {
-	size := instance.Size
-	totalCost := 0
+	if !instance.hasExtraTerms() {
+		return instance.Sense() * QAPCost(instance, solution)
+	}
 
-	for i := 0; i < size; i++ {
-		for j := 0; j < size; j++ {
-			totalCost += instance.FlowMatrix[i][j] * instance.DistanceMatrix[solution[i]][solution[j]]
-		}
+	var fitness int
+	if instance.Stochastic != nil {
+		fitness = instance.Sense() * instance.Stochastic.Cost(instance, solution)
+	} else {
+		fitness = instance.Sense() * QAPCost(instance, solution)
+	}
+	if instance.Relocation != nil {
+		fitness += weightedRelocationCost(instance, solution)
+	}
+	if instance.Constraints != nil {
+		fitness += instance.Constraints.penalty(instance, solution)
 	}
+	return fitness
+}
+
+// hasExtraTerms reports whether the fitness contains more than the QAP cost
+func (instance *QAPInstance) hasExtraTerms() bool {
+	return instance.Relocation != nil || instance.Constraints != nil || instance.Stochastic != nil
+}
 
-	return totalCost
+// QAPCost returns the plain QAP objective, ignoring any relocation term
+func QAPCost(instance *QAPInstance, solution []int) int {
+	// Dummy facilities have no flows, only the real ones add to the cost
+	return blockedCost(instance.FlowMatrix, instance.DistanceMatrix, solution[:instance.FacilityCount()])
 }
@@ -0,0 +1,220 @@
+package qap
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Instance families of Generate
+const (
+	FamilyUniform   = "uniform"   // uniform flows and distances, like Taillard's "a" instances
+	FamilyGrid      = "grid"      // Manhattan distances on a grid, uniform flows, like nug and sko
+	FamilyClustered = "clustered" // clustered Euclidean distances and sparse heavy-tailed flows, like Taillard's "b" instances
+	FamilyLIPA      = "lipa"      // instances with an optimum known by construction, in the spirit of Li and Pardalos
+)
+
+// Families lists the instance families of Generate
+var Families = []string{FamilyUniform, FamilyGrid, FamilyClustered, FamilyLIPA}
+
+// GeneratorOptions configure Generate
+type GeneratorOptions struct {
+	Family   string
+	Size     int
+	MaxValue int   // largest flow and uniform distance value, default 100
+	Rows     int   // grid rows, 0 picks the most square grid with at least Size cells
+	Clusters int   // clusters of the clustered family, 0 picks about sqrt(Size)/2
+	Seed     int64 // seed of the random generator, equal seeds give equal instances
+}
+
+// GeneratedInstance is an instance of Generate with its optimum when the
+// family knows it
+type GeneratedInstance struct {
+	Instance *QAPInstance
+	Optimum  *SolutionFile // nil unless the family constructs a known optimum
+}
+
+// Generate builds a random instance of a structured family. All matrices are
+// symmetric with zero diagonals.
+func Generate(opts GeneratorOptions) (GeneratedInstance, error) {
+	if opts.Size < 2 {
+		return GeneratedInstance{}, fmt.Errorf("size must be at least 2, got %d", opts.Size)
+	}
+	if opts.MaxValue <= 0 {
+		opts.MaxValue = 100
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+	n := opts.Size
+
+	switch strings.ToLower(opts.Family) {
+	case FamilyUniform:
+		return GeneratedInstance{Instance: &QAPInstance{
+			Size:           n,
+			FlowMatrix:     symmetricMatrix(n, func(i, j int) int { return rng.Intn(opts.MaxValue + 1) }),
+			DistanceMatrix: symmetricMatrix(n, func(i, j int) int { return rng.Intn(opts.MaxValue + 1) }),
+		}}, nil
+
+	case FamilyGrid:
+		if opts.Rows < 0 || opts.Rows > n {
+			return GeneratedInstance{}, fmt.Errorf("grid rows must be between 0 and the size, got %d", opts.Rows)
+		}
+		return GeneratedInstance{Instance: &QAPInstance{
+			Size:           n,
+			FlowMatrix:     symmetricMatrix(n, func(i, j int) int { return rng.Intn(opts.MaxValue + 1) }),
+			DistanceMatrix: GridDistances(n, opts.Rows),
+		}}, nil
+
+	case FamilyClustered:
+		return GeneratedInstance{Instance: &QAPInstance{
+			Size:           n,
+			FlowMatrix:     heavyTailedFlows(n, opts.MaxValue, rng),
+			DistanceMatrix: clusteredDistances(n, opts.Clusters, rng),
+		}}, nil
+
+	case FamilyLIPA:
+		return knownOptimumInstance(n, opts.MaxValue, rng), nil
+	}
+	return GeneratedInstance{}, fmt.Errorf("unknown family %q, use one of %v", opts.Family, Families)
+}
+
+// GridDistances places n locations row by row on a grid and returns their
+// Manhattan distances. With rows 0 the grid is the most square one with at
+// least n cells.
+func GridDistances(n, rows int) [][]int {
+	if rows <= 0 {
+		rows = int(math.Sqrt(float64(n)))
+	}
+	cols := (n + rows - 1) / rows
+	return symmetricMatrix(n, func(i, j int) int {
+		return abs(i/cols-j/cols) + abs(i%cols-j%cols)
+	})
+}
+
+// clusteredDistances scatters locations normally around cluster centers in
+// a 100x100 square and returns their rounded Euclidean distances
+func clusteredDistances(n, clusters int, rng *rand.Rand) [][]int {
+	if clusters <= 0 {
+		clusters = max(1, int(math.Sqrt(float64(n))/2))
+	}
+	centers := make([][2]float64, clusters)
+	for c := range centers {
+		centers[c] = [2]float64{rng.Float64() * 100, rng.Float64() * 100}
+	}
+	points := make([][2]float64, n)
+	for i := range points {
+		center := centers[rng.Intn(clusters)]
+		points[i] = [2]float64{center[0] + rng.NormFloat64()*5, center[1] + rng.NormFloat64()*5}
+	}
+	return symmetricMatrix(n, func(i, j int) int {
+		return int(math.Round(math.Hypot(points[i][0]-points[j][0], points[i][1]-points[j][1])))
+	})
+}
+
+// heavyTailedFlows returns sparse flows: about half of the pairs exchange
+// nothing, the others exchange values spread evenly on a log scale up to max
+func heavyTailedFlows(n, maxValue int, rng *rand.Rand) [][]int {
+	return symmetricMatrix(n, func(i, j int) int {
+		if rng.Intn(2) == 0 {
+			return 0
+		}
+		return int(math.Pow(float64(maxValue), rng.Float64()))
+	})
+}
+
+// knownOptimumInstance draws random distances and gives the pairs of
+// facilities flows that decrease with the distance of their locations under
+// a hidden permutation. Every permutation uses the same multiset of
+// distances, so by the rearrangement inequality no assignment can pair the
+// flows with smaller distances than the hidden one, which is thus optimal.
+func knownOptimumInstance(n, maxValue int, rng *rand.Rand) GeneratedInstance {
+	distances := symmetricMatrix(n, func(i, j int) int { return 1 + rng.Intn(maxValue) })
+
+	type pair struct{ i, j int }
+	pairs := make([]pair, 0, n*(n-1)/2)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			pairs = append(pairs, pair{i, j})
+		}
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		return distances[pairs[a].i][pairs[a].j] < distances[pairs[b].i][pairs[b].j]
+	})
+	flows := make([]int, len(pairs))
+	for k := range flows {
+		flows[k] = rng.Intn(maxValue + 1)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(flows)))
+
+	// Facility f is the one assigned to location optimum[f]
+	optimum := rng.Perm(n)
+	facility := make([]int, n)
+	for f, location := range optimum {
+		facility[location] = f
+	}
+	flowMatrix := make([][]int, n)
+	for i := range flowMatrix {
+		flowMatrix[i] = make([]int, n)
+	}
+	for k, p := range pairs {
+		a, b := facility[p.i], facility[p.j]
+		flowMatrix[a][b], flowMatrix[b][a] = flows[k], flows[k]
+	}
+
+	instance := &QAPInstance{Size: n, FlowMatrix: flowMatrix, DistanceMatrix: distances}
+	return GeneratedInstance{
+		Instance: instance,
+		Optimum:  &SolutionFile{Size: n, Value: QAPCost(instance, optimum), Permutation: optimum},
+	}
+}
+
+// symmetricMatrix fills the upper triangle with value and mirrors it, the
+// diagonal stays zero
+func symmetricMatrix(n int, value func(i, j int) int) [][]int {
+	m := make([][]int, n)
+	for i := range m {
+		m[i] = make([]int, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			m[i][j] = value(i, j)
+			m[j][i] = m[i][j]
+		}
+	}
+	return m
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// WriteInstance writes an instance in the QAPLIB layout read by ReadInstance
+func WriteInstance(filename string, instance *QAPInstance) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d\n\n", instance.Size)
+	for k, matrix := range [][][]int{instance.FlowMatrix, instance.DistanceMatrix} {
+		if k > 0 {
+			b.WriteString("\n")
+		}
+		for _, row := range matrix {
+			for j, v := range row {
+				if j > 0 {
+					b.WriteString(" ")
+				}
+				b.WriteString(strconv.Itoa(v))
+			}
+			b.WriteString("\n")
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, []byte(b.String()), 0644)
+}
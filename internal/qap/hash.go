@@ -0,0 +1,32 @@
+package qap
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// ContentHash returns a SHA-256 hash of the size, facility count, objective
+// sense and matrices of instance as a hex string. Instances with the same
+// hash have the same plain QAP cost for every solution, whatever their file
+// name or format; relocation, constraint and stochastic terms are not
+// covered.
+func ContentHash(instance *QAPInstance) string {
+	hash := sha256.New()
+	buf := make([]byte, 8)
+	write := func(v int) {
+		binary.LittleEndian.PutUint64(buf, uint64(v))
+		hash.Write(buf)
+	}
+	write(instance.Size)
+	write(instance.FacilityCount())
+	write(instance.Sense())
+	for _, matrix := range [][][]int{instance.FlowMatrix, instance.DistanceMatrix} {
+		for _, row := range matrix {
+			for _, v := range row {
+				write(v)
+			}
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -1,6 +1,10 @@
 package qap
 
 import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -10,51 +14,404 @@ type QAPInstance struct {
 	Size           int
 	FlowMatrix     [][]int
 	DistanceMatrix [][]int
+
+	// Facilities is set for rectangular instances with fewer facilities than
+	// locations, see Pad; 0 means Size facilities
+	Facilities int
+
+	// Relocation is set for re-layout problems, see SetRelocation
+	Relocation *Relocation
+
+	// Constraints are optional side constraints, see ReadConstraints
+	Constraints *Constraints
+
+	// Stochastic holds flow scenarios when flows are uncertain, see
+	// SetFlowUncertainty
+	Stochastic *StochasticFlows
+
+	// Maximize marks instances whose QAP objective is maximized, such as an
+	// adjacency reward. Fitness values are minimized either way, see Objective.
+	Maximize bool
 }
 
+// IsInstanceFile reports whether name has a known instance extension
+// (.dat, .qap or one registered with RegisterReader), optionally gzip
+// compressed
+func IsInstanceFile(name string) bool {
+	if _, ok := readerFor(name); ok {
+		return true
+	}
+	name = strings.TrimSuffix(name, ".gz")
+	return strings.HasSuffix(name, ".dat") || strings.HasSuffix(name, ".qap")
+}
+
+// DetectTriangular makes ReadInstance accept symmetric matrices given as
+// their upper triangle, with or without the diagonal, and mirror them into
+// full matrices. The layout is only tried when the file has fewer values
+// than two full matrices need; the row lengths then tell it apart, a
+// triangle's rows shrinking by one value per row. A matrix that does not
+// read as a triangle is read in full, so wrapped rows of full matrices
+// are never mistaken for one.
+var DetectTriangular = true
 
+// ReadInstance reads an instance in QAPLIB format: the size, the flow matrix
+// and the distance matrix, read as a stream of values regardless of line
+// breaks, so rows wrapped over several lines, several rows on one line and
+// any number of blank lines between them are all accepted. A truncated file
+// is reported with the matrix it ends in. A rectangular instance gives the
+// number of facilities n and of locations m > n on the first line, followed
+// by the n x n flow matrix and the m x m distance matrix; it is padded to
+// size m, see Pad. Comment lines starting with # or c are ignored, as is the
+// rest of a line after #. Files ending in .gz are decompressed
+// transparently. See DetectTriangular for triangular input. Files with an
+// extension registered with RegisterReader are parsed by that reader instead.
 func ReadInstance(filename string) (*QAPInstance, error) {
-	data, err := os.ReadFile(filename)
+	data, err := readFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	parse := ParseInstance
+	if reader, ok := readerFor(filename); ok {
+		// A registered reader may return a rectangular instance unpadded
+		parse = func(data []byte) (*QAPInstance, error) {
+			instance, err := reader(data)
+			if err != nil {
+				return nil, err
+			}
+			if instance == nil {
+				return nil, fmt.Errorf("reader returned no instance")
+			}
+			instance.Pad()
+			return instance, nil
+		}
+	}
+	instance, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return instance, nil
+}
+
+// ParseInstance reads an instance in QAPLIB format from memory, for callers
+// without a file system such as the WebAssembly build. The file is read as
+// a stream of values: the size, then the values of the flow matrix and of
+// the distance matrix row by row, however they are spread over lines and
+// blank lines. Only triangular matrices, see DetectTriangular, need one row
+// per line, as their layout is told apart by the row lengths.
+func ParseInstance(data []byte) (*QAPInstance, error) {
+	values, err := valueTokens(string(data))
 	if err != nil {
 		return nil, err
 	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("missing size")
+	}
+	size := values[0].value
+	if size < 1 {
+		return nil, fmt.Errorf("line %d: invalid size %d", values[0].line, size)
+	}
 
-	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
-	size, _ := strconv.Atoi(strings.TrimSpace(lines[0]))
+	// A second value on the size line, larger than the size and alone with
+	// it, may count the locations of a rectangular instance
+	if len(values) > 1 && values[1].line == values[0].line && values[1].value > size &&
+		(len(values) == 2 || values[2].line != values[0].line) {
+		r := &valueReader{values: values, pos: 2}
+		instance, rectangularErr := r.instance(size, values[1].value)
+		if rectangularErr == nil {
+			return instance, nil
+		}
+		// Otherwise the value may start the flow matrix of a square instance
+		r = &valueReader{values: values, pos: 1}
+		if instance, err := r.instance(size, size); err == nil {
+			return instance, nil
+		}
+		return nil, fmt.Errorf("%d facilities on %d locations: %v", size, values[1].value, rectangularErr)
+	}
+	r := &valueReader{values: values, pos: 1}
+	return r.instance(size, size)
+}
 
-	flowMatrix := make([][]int, size)
-	distMatrix := make([][]int, size)
+// valueToken is a value of an instance file and the line it is on
+type valueToken struct {
+	value int
+	line  int // counting from 1
+}
 
-	// Initialize matrices
-	for i := 0; i < size; i++ {
-		flowMatrix[i] = make([]int, size)
-		distMatrix[i] = make([]int, size)
+// valueTokens splits an instance file into its values, leaving out blank
+// lines, comment lines and trailing # comments
+func valueTokens(text string) ([]valueToken, error) {
+	var values []valueToken
+	for _, line := range valueLines(text) {
+		for _, field := range line.fields {
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid value %q", line.number, field)
+			}
+			values = append(values, valueToken{value, line.number})
+		}
 	}
+	return values, nil
+}
 
-	// Parse flow matrix (starts at line 2)
-	lineIndex := 2
-	for i := 0; i < size; i++ {
-		flowMatrix[i] = parseLine(lines[lineIndex+i])
+// valueLine is a line of an instance file that holds values
+type valueLine struct {
+	number int // counting from 1
+	fields []string
+}
+
+// valueLines splits an instance file into its lines holding values, leaving
+// out blank lines, comment lines and trailing # comments
+func valueLines(text string) []valueLine {
+	var lines []valueLine
+	for i, line := range strings.Split(text, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(strings.ToLower(fields[0]), "c") {
+			continue
+		}
+		lines = append(lines, valueLine{i + 1, fields})
 	}
+	return lines
+}
 
-	// Parse distance matrix (starts after flow matrix)
-	lineIndex = 3 + size
-	for i := 0; i < size; i++ {
-		distMatrix[i] = parseLine(lines[lineIndex+i])
+// Pad turns an instance whose distance matrix has more locations than its
+// flow matrix has facilities into a square one: dummy facilities without
+// flows are added until every location has one, and Facilities keeps the
+// number of real facilities. Solutions then stay permutations of Size, the
+// locations of the dummies being the unused ones, and swapping a facility
+// with a dummy moves it to an empty location. Pad does nothing when the
+// matrices have the same size.
+func (instance *QAPInstance) Pad() {
+	n, m := len(instance.FlowMatrix), len(instance.DistanceMatrix)
+	if m <= n {
+		return
 	}
+	padded := make([][]int, m)
+	for i := range padded {
+		padded[i] = make([]int, m)
+		if i < n {
+			copy(padded[i], instance.FlowMatrix[i])
+		}
+	}
+	instance.FlowMatrix = padded
+	instance.Size = m
+	instance.Facilities = n
+}
 
-	return &QAPInstance{
-		Size:           size,
-		FlowMatrix:     flowMatrix,
-		DistanceMatrix: distMatrix,
-	}, nil
+// FacilityCount returns the number of real facilities, the first positions of
+// a solution; the remaining positions hold the dummies of a padded instance
+func (instance *QAPInstance) FacilityCount() int {
+	if instance.Facilities > 0 {
+		return instance.Facilities
+	}
+	return instance.Size
 }
 
-func parseLine(line string) []int {
-	parts := strings.Fields(line)
-	result := make([]int, len(parts))
-	for i, v := range parts {
-		result[i], _ = strconv.Atoi(v)
+// IsDummy reports whether position i of a solution holds a dummy facility,
+// whose location is unused
+func (instance *QAPInstance) IsDummy(i int) bool {
+	return i >= instance.FacilityCount()
+}
+
+// valueReader reads the matrices of an instance from its values
+type valueReader struct {
+	values     []valueToken
+	pos        int  // next value to read
+	triangular bool // whether matrices may be upper triangles
+}
+
+// instance reads a facilities x facilities flow matrix and a locations x
+// locations distance matrix, padding the instance when there are more
+// locations. Values may follow on later lines, e.g. a solution appended to
+// the file, but not on the line the distance matrix ends on: that means the
+// matrices have more values than the size allows.
+func (r *valueReader) instance(facilities, locations int) (*QAPInstance, error) {
+	// Two full matrices leave no room for a triangle's layout to matter
+	r.triangular = DetectTriangular && len(r.values)-r.pos < facilities*facilities+locations*locations
+	flowMatrix, err := r.matrix("flow", facilities)
+	if err != nil {
+		return nil, err
+	}
+	distMatrix, err := r.matrix("distance", locations)
+	if err != nil {
+		return nil, err
+	}
+	if r.pos < len(r.values) && r.pos > 0 && r.values[r.pos].line == r.values[r.pos-1].line {
+		return nil, fmt.Errorf("line %d: the distance matrix ends within the line, %d values follow it; "+
+			"expected %d x %d and %d x %d matrices", r.values[r.pos].line, len(r.values)-r.pos,
+			facilities, facilities, locations, locations)
 	}
-	return result
+	instance := &QAPInstance{Size: facilities, FlowMatrix: flowMatrix, DistanceMatrix: distMatrix}
+	instance.Pad()
+	return instance, nil
+}
+
+// lineLength counts the values from pos to the end of its line
+func (r *valueReader) lineLength(pos int) int {
+	k := pos
+	for k < len(r.values) && r.values[k].line == r.values[pos].line {
+		k++
+	}
+	return k - pos
+}
+
+// matrix reads a size x size matrix. When triangular, rows that shrink by
+// one value per line are an upper triangle, with or without the diagonal,
+// mirrored into a symmetric matrix; any other matrix, or one whose rows
+// stop shrinking, is read as size*size values regardless of line breaks.
+func (r *valueReader) matrix(name string, size int) ([][]int, error) {
+	if r.pos >= len(r.values) {
+		return nil, fmt.Errorf("%s matrix is missing, the file ends after line %d", name, r.lastLine())
+	}
+
+	// Row lengths of the triangles: n, n-1, ..., 1 with the diagonal and
+	// n-1, n-2, ..., 1 without
+	offset := -1
+	if r.triangular && size > 1 {
+		first := r.lineLength(r.pos)
+		switch {
+		case first == size-1:
+			offset = 1
+		case first == size && r.pos+first < len(r.values) && r.lineLength(r.pos+first) == size-1:
+			offset = 0
+		}
+	}
+	if offset < 0 {
+		return r.fullMatrix(name, size)
+	}
+	start := r.pos
+	matrix, triangleErr := r.triangle(name, size, offset)
+	if triangleErr == nil {
+		return matrix, nil
+	}
+	r.pos = start
+	if matrix, err := r.fullMatrix(name, size); err == nil {
+		return matrix, nil
+	}
+	return nil, triangleErr
+}
+
+// triangle reads the upper triangle of a symmetric size x size matrix, one
+// row per line, starting offset values right of the diagonal
+func (r *valueReader) triangle(name string, size, offset int) ([][]int, error) {
+	matrix := newMatrix(size)
+	for i := 0; i < size-offset; i++ {
+		if r.pos >= len(r.values) {
+			return nil, fmt.Errorf("%s matrix is truncated: the upper triangle has %d of %d rows, the file ends after line %d",
+				name, i, size-offset, r.lastLine())
+		}
+		line, expected := r.values[r.pos].line, size-i-offset
+		if length := r.lineLength(r.pos); length != expected {
+			return nil, fmt.Errorf("line %d: row %d of the %s matrix's upper triangle has %d values, expected %d",
+				line, i+1, name, length, expected)
+		}
+		for k := 0; k < expected; k++ {
+			j := i + offset + k
+			matrix[i][j], matrix[j][i] = r.values[r.pos].value, r.values[r.pos].value
+			r.pos++
+		}
+	}
+	return matrix, nil
+}
+
+// fullMatrix reads size*size values row by row
+func (r *valueReader) fullMatrix(name string, size int) ([][]int, error) {
+	if left := len(r.values) - r.pos; left < size*size {
+		return nil, fmt.Errorf("%s matrix is truncated: %d of %d values, the file ends after line %d",
+			name, left, size*size, r.lastLine())
+	}
+	matrix := newMatrix(size)
+	for i := range matrix {
+		for j := range matrix[i] {
+			matrix[i][j] = r.values[r.pos].value
+			r.pos++
+		}
+	}
+	return matrix, nil
+}
+
+// newMatrix returns a size x size matrix of zeros
+func newMatrix(size int) [][]int {
+	matrix := make([][]int, size)
+	for i := range matrix {
+		matrix[i] = make([]int, size)
+	}
+	return matrix
+}
+
+// lastLine is the line of the last value
+func (r *valueReader) lastLine() int {
+	if len(r.values) == 0 {
+		return 0
+	}
+	return r.values[len(r.values)-1].line
+}
+
+// ReadInstanceSize returns the size stored in the first value line of an
+// instance file without reading its matrices, the number of facilities for
+// rectangular instances. Files read by a registered reader are read in full.
+func ReadInstanceSize(filename string) (int, error) {
+	if _, ok := readerFor(filename); ok {
+		instance, err := ReadInstance(filename)
+		if err != nil {
+			return 0, err
+		}
+		return instance.FacilityCount(), nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(filename, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return 0, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	// Comment lines may precede the size, see ReadInstance
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		lines := valueLines(scanner.Text())
+		if len(lines) == 0 {
+			continue
+		}
+		size, err := strconv.Atoi(lines[0].fields[0])
+		if err != nil {
+			return 0, fmt.Errorf("%s: invalid size %q", filename, lines[0].fields[0])
+		}
+		return size, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%s is empty", filename)
+}
+
+// readFile returns the contents of filename, decompressing .gz files
+func readFile(filename string) ([]byte, error) {
+	if !strings.HasSuffix(filename, ".gz") {
+		return os.ReadFile(filename)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
 }
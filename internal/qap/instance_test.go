@@ -0,0 +1,150 @@
+package qap
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseInstance reads the same 4 x 4 instance in every layout the
+// parser accepts and checks the errors of broken files
+func TestParseInstance(t *testing.T) {
+	flow := [][]int{{0, 1, 2, 3}, {1, 0, 4, 5}, {2, 4, 0, 6}, {3, 5, 6, 0}}
+	distance := [][]int{{0, 7, 8, 9}, {10, 0, 11, 12}, {13, 14, 0, 15}, {16, 17, 18, 0}}
+	tests := []struct {
+		name string
+		text string
+		err  string // a substring of the error, empty when the file is valid
+	}{
+		{"one row per line", `4
+
+0 1 2 3
+1 0 4 5
+2 4 0 6
+3 5 6 0
+
+0 7 8 9
+10 0 11 12
+13 14 0 15
+16 17 18 0
+`, ""},
+		{"no blank lines", "4\n0 1 2 3\n1 0 4 5\n2 4 0 6\n3 5 6 0\n0 7 8 9\n10 0 11 12\n13 14 0 15\n16 17 18 0\n", ""},
+		{"varying blank lines", "\n\n4\n\n\n\n0 1 2 3\n1 0 4 5\n\n2 4 0 6\n3 5 6 0\n\n\n\n\n0 7 8 9\n10 0 11 12\n13 14 0 15\n\n16 17 18 0\n\n", ""},
+		{"rows wrapped 3+1", `4
+0 1 2
+3
+1 0 4
+5
+2 4 0
+6
+3 5 6
+0
+0 7 8
+9
+10 0 11
+12
+13 14 0
+15
+16 17 18
+0
+`, ""},
+		{"several rows per line", "4\n0 1 2 3 1 0 4 5\n2 4 0 6 3 5 6 0\n0 7 8 9 10 0 11 12 13 14 0 15 16 17 18 0\n", ""},
+		{"all on one line", "4 0 1 2 3 1 0 4 5 2 4 0 6 3 5 6 0 0 7 8 9 10 0 11 12 13 14 0 15 16 17 18 0", ""},
+		{"comments", `# a comment line
+c another one
+4 # the size
+0 1 2 3
+1 0 4 5 # trailing comment
+2 4 0 6
+3 5 6 0
+# between the matrices
+0 7 8 9
+10 0 11 12
+13 14 0 15
+16 17 18 0
+`, ""},
+		{"upper triangle with diagonal", `4
+0 1 2 3
+0 4 5
+0 6
+0
+
+0 7 8 9
+10 0 11 12
+13 14 0 15
+16 17 18 0
+`, ""},
+		{"upper triangle without diagonal", `4
+1 2 3
+4 5
+6
+
+0 7 8 9
+10 0 11 12
+13 14 0 15
+16 17 18 0
+`, ""},
+		{"solution appended", "4\n0 1 2 3\n1 0 4 5\n2 4 0 6\n3 5 6 0\n0 7 8 9\n10 0 11 12\n13 14 0 15\n16 17 18 0\n1 2 3 4\n", ""},
+		{"empty", "", "missing size"},
+		{"invalid size", "0\n", "invalid size 0"},
+		{"invalid value", "4\n0 1 x 3\n", `line 2: invalid value "x"`},
+		{"missing flow matrix", "4\n", "flow matrix is missing"},
+		{"truncated flow matrix", "4\n0 1 2 3\n1 0 4 5\n2 4 0 6\n3 5 6\n", "flow matrix is truncated: 15 of 16 values"},
+		{"missing distance matrix", "4\n0 1 2 3\n1 0 4 5\n2 4 0 6\n3 5 6 0\n", "distance matrix is missing"},
+		{"truncated distance matrix", "4\n0 1 2 3\n1 0 4 5\n2 4 0 6\n3 5 6 0\n0 7 8 9\n10 0 11 12\n13 14 0 15\n16 17 18\n",
+			"distance matrix is truncated: 15 of 16 values, the file ends after line 9"},
+		{"truncated triangle", "4\n1 2 3\n4 5\n", "flow matrix is truncated: the upper triangle has 2 of 3 rows"},
+		{"too many values", "4\n0 1 2 3\n1 0 4 5\n2 4 0 6\n3 5 6 0\n0 7 8 9\n10 0 11 12\n13 14 0 15\n16 17 18 0 1\n",
+			"the distance matrix ends within the line"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			instance, err := ParseInstance([]byte(test.text))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want one containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if instance.Size != 4 || !reflect.DeepEqual(instance.FlowMatrix, flow) {
+				t.Errorf("flow matrix of size %d is %v, want %v", instance.Size, instance.FlowMatrix, flow)
+			}
+			if !reflect.DeepEqual(instance.DistanceMatrix, distance) {
+				t.Errorf("distance matrix is %v, want %v", instance.DistanceMatrix, distance)
+			}
+		})
+	}
+}
+
+// TestParseInstanceWithoutTriangles checks that wrapped full matrices read
+// the same with DetectTriangular off, and that triangles are then rejected
+func TestParseInstanceWithoutTriangles(t *testing.T) {
+	defer func(detect bool) { DetectTriangular = detect }(DetectTriangular)
+	DetectTriangular = false
+
+	wrapped := "4\n0 1 2\n3\n1 0 4\n5\n2 4 0\n6\n3 5 6\n0\n0 7 8\n9\n10 0 11\n12\n13 14 0\n15\n16 17 18\n0\n"
+	if _, err := ParseInstance([]byte(wrapped)); err != nil {
+		t.Errorf("wrapped rows: %v", err)
+	}
+	triangle := "4\n1 2 3\n4 5\n6\n0 7 8 9\n10 0 11 12\n13 14 0 15\n16 17 18 0\n"
+	if _, err := ParseInstance([]byte(triangle)); err == nil {
+		t.Error("an upper triangle was read with DetectTriangular off")
+	}
+}
+
+// TestParseRectangularInstance reads 2 facilities on 3 locations and pads it
+func TestParseRectangularInstance(t *testing.T) {
+	instance, err := ParseInstance([]byte("2 3\n0 5\n5 0\n\n0 1 2\n1 0 3\n2 3 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Size != 3 || instance.FacilityCount() != 2 {
+		t.Fatalf("size %d with %d facilities, want 3 with 2", instance.Size, instance.FacilityCount())
+	}
+	if want := [][]int{{0, 5, 0}, {5, 0, 0}, {0, 0, 0}}; !reflect.DeepEqual(instance.FlowMatrix, want) {
+		t.Errorf("padded flow matrix is %v, want %v", instance.FlowMatrix, want)
+	}
+}
@@ -0,0 +1,25 @@
+package qap
+
+// fitnessBlock is the tile width of the blocked fitness kernel. The distance
+// rows of a tile of facilities stay in cache while the tile is swept over all
+// blocks of the permutation.
+const fitnessBlock = 64
+
+// blockedCost computes sum_ij flow[i][j] * distance[p[i]][p[j]] tile by tile
+// over i and j. The inner loop over a row segment is rowSegmentCost, which is
+// selected at build time (see kernel_generic.go and kernel_unrolled.go).
+func blockedCost(flow, distance [][]int, solution []int) int {
+	n := len(solution)
+	total := 0
+	for ib := 0; ib < n; ib += fitnessBlock {
+		ie := min(ib+fitnessBlock, n)
+		for jb := 0; jb < n; jb += fitnessBlock {
+			je := min(jb+fitnessBlock, n)
+			perm := solution[jb:je]
+			for i := ib; i < ie; i++ {
+				total += rowSegmentCost(flow[i][jb:je], distance[solution[i]], perm)
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,16 @@
+//go:build !qap_unrolled
+
+package qap
+
+// KernelVariant names the inner loop compiled into the fitness kernel
+const KernelVariant = "generic"
+
+// rowSegmentCost returns sum_k flow[k] * distance[perm[k]]
+func rowSegmentCost(flow, distance, perm []int) int {
+	flow = flow[:len(perm)]
+	total := 0
+	for k, p := range perm {
+		total += flow[k] * distance[p]
+	}
+	return total
+}
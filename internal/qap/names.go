@@ -0,0 +1,89 @@
+package qap
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Names maps facility and location indices to names for human-readable
+// output. Either list may be empty, missing names fall back to "Facility 3"
+// or "Location 3", counting from 1.
+type Names struct {
+	Facilities []string
+	Locations  []string
+}
+
+// ReadNames reads a name file: one name per line in index order. Blank lines
+// and lines starting with # are skipped, so a name cannot be empty.
+func ReadNames(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, scanner.Err()
+}
+
+// Fits reports whether every given list names exactly the facilities or the
+// locations of an instance, see QAPInstance.FacilityCount
+func (n *Names) Fits(facilities, locations int) bool {
+	return n != nil && (len(n.Facilities) == 0 || len(n.Facilities) == facilities) &&
+		(len(n.Locations) == 0 || len(n.Locations) == locations)
+}
+
+// Validate checks that the name lists match the facilities and locations of
+// an instance
+func (n *Names) Validate(facilities, locations int) error {
+	if !n.Fits(facilities, locations) {
+		return fmt.Errorf("name files list %d facilities and %d locations, instance has %d facilities and %d locations",
+			len(n.Facilities), len(n.Locations), facilities, locations)
+	}
+	return nil
+}
+
+// Facility returns the name of facility i
+func (n *Names) Facility(i int) string {
+	if n != nil && i < len(n.Facilities) {
+		return n.Facilities[i]
+	}
+	return fmt.Sprintf("Facility %d", i+1)
+}
+
+// Location returns the name of location j
+func (n *Names) Location(j int) string {
+	if n != nil && j < len(n.Locations) {
+		return n.Locations[j]
+	}
+	return fmt.Sprintf("Location %d", j+1)
+}
+
+// FormatAssignment returns one line per facility, "Assembly → Bay 3". Fewer
+// facility names than positions name the real facilities of a padded
+// instance, the dummies on the unused locations are left out. Names that do
+// not fit the solution size are ignored.
+func (n *Names) FormatAssignment(solution []int) []string {
+	facilities := len(solution)
+	if n != nil && len(n.Facilities) > 0 && len(n.Facilities) < facilities {
+		facilities = len(n.Facilities)
+	}
+	if !n.Fits(facilities, len(solution)) {
+		n, facilities = nil, len(solution)
+	}
+	lines := make([]string, facilities)
+	for facility, location := range solution[:facilities] {
+		lines[facility] = n.Facility(facility) + " → " + n.Location(location)
+	}
+	return lines
+}
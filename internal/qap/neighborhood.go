@@ -0,0 +1,46 @@
+package qap
+
+// SwapNeighbor is the solution reached from another one by swapping the
+// locations of positions I < J, with its fitness change as computed three
+// ways. They agree for a correct delta evaluation.
+type SwapNeighbor struct {
+	I, J        int
+	Delta       int // SwapDelta
+	CachedDelta int // DeltaCache, Delta itself when the deltas cannot be cached
+	FullDelta   int // difference of two full CalculateFitness evaluations
+}
+
+// Consistent reports whether the three fitness changes agree
+func (n SwapNeighbor) Consistent() bool {
+	return n.Delta == n.FullDelta && n.CachedDelta == n.FullDelta
+}
+
+// SwapNeighborhood enumerates the n(n-1)/2 swap neighbors of solution in
+// order of I, then J. Every neighbor is evaluated in full, so it costs
+// O(n^4) and is meant for inspection and for checking delta evaluations on
+// small instances. The solution is not modified.
+func SwapNeighborhood(instance *QAPInstance, solution []int) []SwapNeighbor {
+	n := len(solution)
+	current := append([]int(nil), solution...)
+	fitness := CalculateFitness(instance, current)
+	var cache *DeltaCache
+	if CanCacheDeltas(instance) {
+		cache = NewDeltaCache(instance, append([]int(nil), solution...))
+	}
+
+	neighbors := make([]SwapNeighbor, 0, n*(n-1)/2)
+	for i := 0; i < n-1; i++ {
+		for j := i + 1; j < n; j++ {
+			neighbor := SwapNeighbor{I: i, J: j, Delta: SwapDelta(instance, current, i, j)}
+			neighbor.CachedDelta = neighbor.Delta
+			if cache != nil {
+				neighbor.CachedDelta = cache.Delta(i, j)
+			}
+			current[i], current[j] = current[j], current[i]
+			neighbor.FullDelta = CalculateFitness(instance, current) - fitness
+			current[i], current[j] = current[j], current[i]
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+	return neighbors
+}
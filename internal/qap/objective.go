@@ -0,0 +1,17 @@
+package qap
+
+// Sense is 1 for minimization and -1 for maximization instances
+func (instance *QAPInstance) Sense() int {
+	if instance.Maximize {
+		return -1
+	}
+	return 1
+}
+
+// Objective converts a fitness value into the objective value of the
+// instance. Solvers always minimize fitness: for maximization instances the
+// fitness is the negated QAP objective plus any relocation cost and penalty,
+// so the objective is the reward minus those costs.
+func (instance *QAPInstance) Objective(fitness int) int {
+	return instance.Sense() * fitness
+}
@@ -2,60 +2,108 @@ package qap
 
 import (
 	"bufio"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
 )
 
-// OptimalSolutions maps instance names to their optimal fitness values
+// OptimalSolutions maps instance names to their optimal fitness values. Keys
+// are lower-case and without extension; files in subdirectories are stored
+// under their slash-separated path relative to the scanned directory as well
+// as under their base name.
 type OptimalSolutions map[string]int
 
+// LoadOptimalSolutions reads the .sln files in instancesDir and all of its
+// subdirectories. The first line of a .sln file holds the instance size and
+// the optimal fitness. When several subdirectories contain a file with the
+// same base name, the base name refers to the first one found and the others
+// are reachable by their relative path.
 func LoadOptimalSolutions(instancesDir string) (OptimalSolutions, error) {
 	solutions := make(OptimalSolutions)
 
-	entries, err := os.ReadDir(instancesDir)
-	if err != nil {
-		return nil, err
-	}
+	err := filepath.WalkDir(instancesDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if filePath == instancesDir {
+				return err
+			}
+			return nil // Skip unreadable subdirectories
+		}
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".sln") {
+			return nil
+		}
 
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sln") {
-			filePath := filepath.Join(instancesDir, entry.Name())
+		value, ok := readOptimalValue(filePath)
+		if !ok {
+			return nil // Skip files that can't be read
+		}
 
-			file, err := os.Open(filePath)
-			if err != nil {
-				continue // Skip files that can't be opened
-			}
-			defer file.Close()
-
-			scanner := bufio.NewScanner(file)
-			if scanner.Scan() { // Read the first line
-				parts := strings.Fields(scanner.Text())
-				if len(parts) >= 2 {
-					_, valueStr := parts[0], parts[1] // Ignore size, extract optimal value
-					value, err := strconv.Atoi(valueStr)
-					if err == nil {
-						instanceName := strings.TrimSuffix(entry.Name(), ".sln")
-						solutions[instanceName] = value
-					}
-				}
+		relative, err := filepath.Rel(instancesDir, filePath)
+		if err != nil {
+			relative = entry.Name()
+		}
+		key := solutionKey(relative)
+		solutions[key] = value
+		if base := path.Base(key); base != key {
+			if _, exists := solutions[base]; !exists {
+				solutions[base] = value
 			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return solutions, nil
 }
 
-func (o OptimalSolutions) GetOptimalSolution(instanceName string) int {
-	// Extract base instance name without path and extension
-	base := instanceName
-	if idx := strings.LastIndex(base, "/"); idx >= 0 {
-		base = base[idx+1:]
+// readOptimalValue returns the optimal fitness from the first line of a .sln file
+func readOptimalValue(filePath string) (int, bool) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() { // Read the first line
+		return 0, false
 	}
-	if idx := strings.LastIndex(base, "."); idx >= 0 {
-		base = base[:idx]
+	parts := strings.Fields(scanner.Text())
+	if len(parts) < 2 {
+		return 0, false
 	}
+	value, err := strconv.Atoi(parts[1]) // Ignore size, extract optimal value
+	return value, err == nil
+}
 
-	return o[base]
+// solutionKey normalizes a file or instance name: slash-separated, lower-case,
+// without a .gz suffix and without extension
+func solutionKey(name string) string {
+	key := strings.ToLower(filepath.ToSlash(name))
+	key = strings.TrimSuffix(key, ".gz")
+	if ext := path.Ext(key); ext != "" {
+		key = strings.TrimSuffix(key, ext)
+	}
+	return key
+}
+
+func (o OptimalSolutions) GetOptimalSolution(instanceName string) int {
+	value, _ := o.Lookup(instanceName)
+	return value
+}
+
+// Lookup returns the optimal fitness for an instance and whether it is known.
+// The name may be a base name or a path relative to the instance directory;
+// a path is matched against the .sln file in the same subdirectory first.
+func (o OptimalSolutions) Lookup(instanceName string) (int, bool) {
+	key := solutionKey(instanceName)
+	if value, ok := o[key]; ok {
+		return value, true
+	}
+	value, ok := o[path.Base(key)]
+	return value, ok
 }
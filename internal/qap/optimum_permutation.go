@@ -0,0 +1,108 @@
+package qap
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Encodings of a permutation. QAPLIB .sln files list the location of every
+// facility, but some published solutions list the facility of every location.
+const (
+	EncodingFacility = "facility" // solution[i] is the location of facility i
+	EncodingLocation = "location" // solution[k] is the facility at location k
+)
+
+// OptimalPermutations maps instance names to the content of their .sln
+// files, keyed like OptimalSolutions
+type OptimalPermutations map[string]SolutionFile
+
+// LoadOptimalPermutations reads the .sln files in instancesDir and all of its
+// subdirectories, skipping files whose permutation does not parse
+func LoadOptimalPermutations(instancesDir string) (OptimalPermutations, error) {
+	permutations := make(OptimalPermutations)
+
+	err := filepath.WalkDir(instancesDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if filePath == instancesDir {
+				return err
+			}
+			return nil
+		}
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".sln") {
+			return nil
+		}
+
+		s, err := ReadSolutionFile(filePath)
+		if err != nil {
+			return nil
+		}
+
+		relative, err := filepath.Rel(instancesDir, filePath)
+		if err != nil {
+			relative = entry.Name()
+		}
+		key := solutionKey(relative)
+		permutations[key] = s
+		if base := path.Base(key); base != key {
+			if _, exists := permutations[base]; !exists {
+				permutations[base] = s
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return permutations, nil
+}
+
+// Lookup returns the .sln content of an instance, see OptimalSolutions.Lookup
+func (o OptimalPermutations) Lookup(instanceName string) (SolutionFile, bool) {
+	key := solutionKey(instanceName)
+	if s, ok := o[key]; ok {
+		return s, true
+	}
+	s, ok := o[path.Base(key)]
+	return s, ok
+}
+
+// InversePermutation returns the permutation mapping every value of p back to
+// its index, converting between the two encodings
+func InversePermutation(p []int) []int {
+	inverse := make([]int, len(p))
+	for i, v := range p {
+		inverse[v] = i
+	}
+	return inverse
+}
+
+// AssignmentDistance is the number of facilities assigned to different
+// locations in a and b, from 0 for equal solutions to their size
+func AssignmentDistance(a, b []int) int {
+	distance := 0
+	for i := range a {
+		if a[i] != b[i] {
+			distance++
+		}
+	}
+	return distance
+}
+
+// OrientSolution returns the permutation of a solution file in the facility
+// encoding together with the encoding the file used: the one whose QAP cost
+// reproduces the file's value, the facility encoding when both do. It fails
+// when neither does, e.g. for an instance that was modified after loading.
+func OrientSolution(instance *QAPInstance, s SolutionFile) ([]int, string, bool) {
+	if len(s.Permutation) != instance.Size {
+		return nil, "", false
+	}
+	if QAPCost(instance, s.Permutation) == s.Value {
+		return s.Permutation, EncodingFacility, true
+	}
+	if inverse := InversePermutation(s.Permutation); QAPCost(instance, inverse) == s.Value {
+		return inverse, EncodingLocation, true
+	}
+	return nil, "", false
+}
@@ -0,0 +1,29 @@
+package qap
+
+import (
+	"math"
+	"math/rand"
+)
+
+// PerturbFlow returns a copy of the instance whose flows are each scaled by an
+// independent uniform factor in [1-noise, 1+noise]. The distance matrix,
+// relocation settings, constraints and objective direction are shared with the original.
+func PerturbFlow(instance *QAPInstance, noise float64, rng *rand.Rand) *QAPInstance {
+	flow := make([][]int, instance.Size)
+	for i, row := range instance.FlowMatrix {
+		flow[i] = make([]int, len(row))
+		for j, v := range row {
+			factor := 1 + noise*(2*rng.Float64()-1)
+			flow[i][j] = int(math.Round(float64(v) * factor))
+		}
+	}
+
+	return &QAPInstance{
+		Size:           instance.Size,
+		FlowMatrix:     flow,
+		DistanceMatrix: instance.DistanceMatrix,
+		Relocation:     instance.Relocation,
+		Constraints:    instance.Constraints,
+		Maximize:       instance.Maximize,
+	}
+}
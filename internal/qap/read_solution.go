@@ -0,0 +1,104 @@
+package qap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ReadSolution reads a permutation for an instance of the given size produced
+// by any tool. Accepted formats are
+//
+//	.sln files       size, value and the 1-based locations
+//	JSON             an array, or an object with a "solution", "permutation"
+//	                 or "assignment" array
+//	plain text       values separated by spaces, commas or newlines
+//
+// JSON and plain text values may be 0-based or 1-based, as in ReadAssignment.
+// A plain text file holding size+2 values starting with size is read as a
+// .sln file without the extension.
+func ReadSolution(filename string, size int) ([]int, error) {
+	if strings.EqualFold(filepath.Ext(strings.TrimSuffix(filename, ".gz")), ".sln") {
+		s, err := ReadSolutionFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSolutionSize(filename, s.Permutation, size); err != nil {
+			return nil, err
+		}
+		return s.Permutation, nil
+	}
+
+	data, err := readFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []int
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		if values, err = parseJSONSolution(trimmed); err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+	} else {
+		for _, f := range strings.Fields(strings.ReplaceAll(string(data), ",", " ")) {
+			v, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid value %q", filename, f)
+			}
+			values = append(values, v)
+		}
+		if len(values) == size+2 && values[0] == size {
+			s, err := ReadSolutionFile(filename)
+			if err != nil {
+				return nil, err
+			}
+			return s.Permutation, nil
+		}
+	}
+
+	if !slices.Contains(values, 0) {
+		for i := range values {
+			values[i]--
+		}
+	}
+	if err := checkSolutionSize(filename, values, size); err != nil {
+		return nil, err
+	}
+	if err := ValidatePermutation(values); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return values, nil
+}
+
+// parseJSONSolution decodes a JSON array or an object holding one
+func parseJSONSolution(data []byte) ([]int, error) {
+	var values []int
+	if data[0] == '[' {
+		err := json.Unmarshal(data, &values)
+		return values, err
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"solution", "permutation", "assignment"} {
+		if raw, ok := object[key]; ok {
+			err := json.Unmarshal(raw, &values)
+			return values, err
+		}
+	}
+	return nil, fmt.Errorf("no \"solution\", \"permutation\" or \"assignment\" key")
+}
+
+func checkSolutionSize(filename string, solution []int, size int) error {
+	if len(solution) != size {
+		return fmt.Errorf("%s: %d locations, instance size is %d", filename, len(solution), size)
+	}
+	return nil
+}
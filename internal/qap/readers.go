@@ -0,0 +1,54 @@
+package qap
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// InstanceReader parses the contents of an instance file, already
+// decompressed when the file ends in .gz
+type InstanceReader func(data []byte) (*QAPInstance, error)
+
+// Registry of instance readers by file extension
+var (
+	readersMu sync.RWMutex
+	readers   = make(map[string]InstanceReader)
+)
+
+// RegisterReader makes ReadInstance parse files ending in extension, e.g.
+// ".json", with reader instead of the QAPLIB format. Extensions are matched
+// case-insensitively, with or without a trailing .gz, and a later
+// registration replaces an earlier one. Custom formats are added from an
+// init function in a separate file of the main package, without touching
+// this package; the experiment file finder then picks the files up as well,
+// see IsInstanceFile.
+func RegisterReader(extension string, reader InstanceReader) error {
+	extension = strings.ToLower(strings.TrimSpace(extension))
+	if !strings.HasPrefix(extension, ".") {
+		extension = "." + extension
+	}
+	if extension == "." || extension == ".gz" || strings.ContainsAny(extension, `/\`) {
+		return fmt.Errorf("invalid instance extension %q", extension)
+	}
+	if reader == nil {
+		return fmt.Errorf("nil reader for instance extension %q", extension)
+	}
+	readersMu.Lock()
+	defer readersMu.Unlock()
+	readers[extension] = reader
+	return nil
+}
+
+// readerFor returns the reader registered for the extension of name, if any
+func readerFor(name string) (InstanceReader, bool) {
+	extension := strings.ToLower(filepath.Ext(strings.TrimSuffix(name, ".gz")))
+	if extension == "" {
+		return nil, false
+	}
+	readersMu.RLock()
+	defer readersMu.RUnlock()
+	reader, ok := readers[extension]
+	return reader, ok
+}
@@ -0,0 +1,134 @@
+package qap
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Relocation turns an instance into a re-layout problem: besides the QAP cost,
+// moving a facility away from its current location costs Cost[from][to].
+// The objective becomes QAP cost + Weight * relocation cost.
+type Relocation struct {
+	Current []int   // current location of each facility
+	Cost    [][]int // Cost[from][to] of moving a facility between locations
+	Weight  float64
+}
+
+// SetRelocation attaches a current layout to the instance. A nil cost matrix
+// means relocation is priced by the instance distance matrix.
+func (instance *QAPInstance) SetRelocation(current []int, cost [][]int, weight float64) error {
+	if len(current) != instance.Size {
+		return fmt.Errorf("current layout has %d entries, instance size is %d", len(current), instance.Size)
+	}
+	if err := ValidatePermutation(current); err != nil {
+		return fmt.Errorf("invalid current layout: %v", err)
+	}
+	if cost == nil {
+		cost = instance.DistanceMatrix
+	}
+	if len(cost) != instance.Size {
+		return fmt.Errorf("relocation matrix has %d rows, instance size is %d", len(cost), instance.Size)
+	}
+	for i, row := range cost {
+		if len(row) != instance.Size {
+			return fmt.Errorf("relocation matrix row %d has %d columns, instance size is %d", i, len(row), instance.Size)
+		}
+	}
+
+	instance.Relocation = &Relocation{
+		Current: current,
+		Cost:    cost,
+		Weight:  weight,
+	}
+	return nil
+}
+
+// RelocationCost returns the unweighted cost of moving from the current layout to solution
+func RelocationCost(instance *QAPInstance, solution []int) int {
+	r := instance.Relocation
+	if r == nil {
+		return 0
+	}
+
+	cost := 0
+	for facility, location := range solution {
+		cost += r.Cost[r.Current[facility]][location]
+	}
+	return cost
+}
+
+func weightedRelocationCost(instance *QAPInstance, solution []int) int {
+	return int(math.Round(instance.Relocation.Weight * float64(RelocationCost(instance, solution))))
+}
+
+// ValidatePermutation checks that solution contains every index 0..n-1 exactly once
+func ValidatePermutation(solution []int) error {
+	seen := make([]bool, len(solution))
+	for i, v := range solution {
+		if v < 0 || v >= len(solution) {
+			return fmt.Errorf("position %d holds %d, outside 0..%d", i, v, len(solution)-1)
+		}
+		if seen[v] {
+			return fmt.Errorf("value %d appears more than once", v)
+		}
+		seen[v] = true
+	}
+	return nil
+}
+
+// ReadAssignment reads a whitespace separated permutation. Values may be 0-based
+// or 1-based (as in QAPLIB .sln files); 1-based input is converted to 0-based.
+func ReadAssignment(filename string) ([]int, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(data))
+	assignment := make([]int, len(fields))
+	hasZero := false
+	for i, f := range fields {
+		v, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid value %q", filename, f)
+		}
+		assignment[i] = v
+		if v == 0 {
+			hasZero = true
+		}
+	}
+
+	if !hasZero {
+		for i := range assignment {
+			assignment[i]--
+		}
+	}
+	return assignment, nil
+}
+
+// ReadMatrix reads a square matrix of whitespace separated integers, one row per line
+func ReadMatrix(filename string) ([][]int, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var matrix [][]int
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		row := make([]int, len(fields))
+		for j, field := range fields {
+			if row[j], err = strconv.Atoi(field); err != nil {
+				return nil, fmt.Errorf("%s: line %d: invalid value %q", filename, i+1, field)
+			}
+		}
+		matrix = append(matrix, row)
+	}
+	return matrix, nil
+}
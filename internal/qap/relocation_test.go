@@ -0,0 +1,45 @@
+package qap
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestReadMatrix reads a matrix with blank lines and checks that values
+// which are not integers are reported with their line
+func TestReadMatrix(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want [][]int
+		err  string // a substring of the error, empty when the file is valid
+	}{
+		{"valid", "1 2\n\n3  4\n", [][]int{{1, 2}, {3, 4}}, ""},
+		{"word", "1 2\n3 x\n", nil, `line 2: invalid value "x"`},
+		{"fraction", "\n1.5 2\n3 4\n", nil, `line 2: invalid value "1.5"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "matrix.txt")
+			if err := os.WriteFile(path, []byte(test.text), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			matrix, err := ReadMatrix(path)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want one containing %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(matrix, test.want) {
+				t.Errorf("got %v, want %v", matrix, test.want)
+			}
+		})
+	}
+}
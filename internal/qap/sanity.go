@@ -0,0 +1,72 @@
+package qap
+
+import (
+	"fmt"
+	"math"
+)
+
+// overflowHeadroom is the factor by which the largest possible cost must
+// stay below math.MaxInt: swap deltas, penalties and sums of costs are
+// computed in int as well
+const overflowHeadroom = 16
+
+// CheckSanity looks for structural problems of an instance that readers
+// accept but that make results unreliable: matrices that are not n x n,
+// negative entries, which usually come from a broken export and make gaps
+// meaningless once costs turn negative, and entries so large that a cost
+// could overflow. It returns one description per problem, none for a sane
+// instance.
+func CheckSanity(instance *QAPInstance) []string {
+	var problems []string
+	for _, m := range []struct {
+		name   string
+		matrix [][]int
+	}{{"flow", instance.FlowMatrix}, {"distance", instance.DistanceMatrix}} {
+		if len(m.matrix) != instance.Size {
+			problems = append(problems, fmt.Sprintf("%s matrix has %d rows, expected %d", m.name, len(m.matrix), instance.Size))
+			continue
+		}
+		for i, row := range m.matrix {
+			if len(row) != instance.Size {
+				problems = append(problems, fmt.Sprintf("%s matrix row %d has %d values, expected %d", m.name, i+1, len(row), instance.Size))
+				break
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+
+	flowNegative, flowSum, flowMax := absoluteStats(instance.FlowMatrix)
+	distanceNegative, distanceSum, distanceMax := absoluteStats(instance.DistanceMatrix)
+	if flowNegative > 0 {
+		problems = append(problems, fmt.Sprintf("flow matrix has %d negative entries", flowNegative))
+	}
+	if distanceNegative > 0 {
+		problems = append(problems, fmt.Sprintf("distance matrix has %d negative entries", distanceNegative))
+	}
+	// Every cost is a sum of n^2 products, each bounded by the entries of
+	// one matrix times the largest entry of the other
+	bound := math.Min(flowSum*distanceMax, distanceSum*flowMax)
+	if bound > math.MaxInt/overflowHeadroom {
+		problems = append(problems, fmt.Sprintf("costs up to %.3g risk integer overflow, the limit is %.3g",
+			bound, float64(math.MaxInt/overflowHeadroom)))
+	}
+	return problems
+}
+
+// absoluteStats counts the negative entries of a matrix and returns the sum
+// and the largest of the absolute values, as floats so they cannot overflow
+func absoluteStats(m [][]int) (negative int, sum, largest float64) {
+	for _, row := range m {
+		for _, v := range row {
+			if v < 0 {
+				negative++
+			}
+			a := math.Abs(float64(v))
+			sum += a
+			largest = math.Max(largest, a)
+		}
+	}
+	return negative, sum, largest
+}
@@ -0,0 +1,96 @@
+package qap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// slnValuesPerLine is how many locations a line of a written .sln file holds
+const slnValuesPerLine = 15
+
+// SolutionFile is the content of a QAPLIB .sln file: the instance size, the
+// objective value and the location of every facility
+type SolutionFile struct {
+	Size        int
+	Value       int
+	Permutation []int // zero-based, the file stores locations starting at 1
+}
+
+// ReadSolutionFile parses a .sln file. The permutation may span several lines
+// and its values may be separated by commas, as in some QAPLIB files.
+func ReadSolutionFile(filename string) (SolutionFile, error) {
+	data, err := readFile(filename)
+	if err != nil {
+		return SolutionFile{}, err
+	}
+
+	fields := strings.Fields(strings.ReplaceAll(string(data), ",", " "))
+	if len(fields) < 2 {
+		return SolutionFile{}, fmt.Errorf("%s: missing size and value", filename)
+	}
+	values := make([]int, len(fields))
+	for i, f := range fields {
+		if values[i], err = strconv.Atoi(f); err != nil {
+			return SolutionFile{}, fmt.Errorf("%s: invalid value %q", filename, f)
+		}
+	}
+
+	s := SolutionFile{Size: values[0], Value: values[1]}
+	if len(values)-2 != s.Size {
+		return SolutionFile{}, fmt.Errorf("%s: size %d but %d locations", filename, s.Size, len(values)-2)
+	}
+	s.Permutation = make([]int, s.Size)
+	for i, location := range values[2:] {
+		s.Permutation[i] = location - 1
+	}
+	if err := ValidatePermutation(s.Permutation); err != nil {
+		return SolutionFile{}, fmt.Errorf("%s: %v", filename, err)
+	}
+	return s, nil
+}
+
+// WriteSolutionFile writes a solution in the QAPLIB .sln layout and reads the
+// file back, failing if the reader does not reproduce the solution
+func WriteSolutionFile(filename string, s SolutionFile) error {
+	if len(s.Permutation) != s.Size {
+		return fmt.Errorf("size %d but %d locations", s.Size, len(s.Permutation))
+	}
+	if err := ValidatePermutation(s.Permutation); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%6d %9d\n", s.Size, s.Value)
+	for i, location := range s.Permutation {
+		if i%slnValuesPerLine == 0 {
+			b.WriteString("  ")
+		}
+		fmt.Fprintf(&b, "%4d", location+1)
+		if (i+1)%slnValuesPerLine == 0 || i == len(s.Permutation)-1 {
+			b.WriteString("\n")
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filename, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+
+	read, err := ReadSolutionFile(filename)
+	if err != nil {
+		return fmt.Errorf("written file does not parse: %v", err)
+	}
+	if read.Size != s.Size || read.Value != s.Value || !slices.Equal(read.Permutation, s.Permutation) {
+		return fmt.Errorf("%s does not round-trip: read size %d, value %d", filename, read.Size, read.Value)
+	}
+	if value, ok := readOptimalValue(filename); !ok || value != s.Value {
+		return fmt.Errorf("%s does not round-trip: optimum reader gets %d", filename, value)
+	}
+	return nil
+}
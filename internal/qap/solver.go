@@ -1,5 +0,0 @@
-package qap
-
-type Solver interface {
-    Solve(instance *QAPInstance) []int
-}
@@ -0,0 +1,131 @@
+package qap
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Evaluation modes of uncertain flows, see FlowUncertainty
+const (
+	StochasticExpected   = "expected"   // mean cost over the scenarios
+	StochasticPercentile = "percentile" // cost not exceeded in Percentile percent of the scenarios
+)
+
+// FlowUncertainty describes flows given as distributions: every flow is
+// normally distributed with the instance flow as mean and Std as standard
+// deviation, truncated at zero. Fitness is evaluated over Samples scenarios
+// drawn once per instance from Seed, so every solution faces the same
+// scenarios and fitness values stay reproducible.
+type FlowUncertainty struct {
+	Std        [][]int
+	Mode       string  // StochasticExpected or StochasticPercentile
+	Percentile float64 // in (0, 100], used by StochasticPercentile
+	Samples    int
+	Seed       int64
+}
+
+// Validate checks the settings and that the deviation matrix fits an instance of the given size
+func (u *FlowUncertainty) Validate(size int) error {
+	switch u.Mode {
+	case StochasticExpected, StochasticPercentile:
+	default:
+		return fmt.Errorf("unknown stochastic mode %q, use %s or %s", u.Mode, StochasticExpected, StochasticPercentile)
+	}
+	if u.Percentile <= 0 || u.Percentile > 100 {
+		return fmt.Errorf("percentile %g outside (0, 100]", u.Percentile)
+	}
+	if u.Samples <= 0 {
+		return fmt.Errorf("need at least one scenario, got %d", u.Samples)
+	}
+	if len(u.Std) != size {
+		return fmt.Errorf("flow deviation matrix has %d rows, instance size is %d", len(u.Std), size)
+	}
+	for i, row := range u.Std {
+		if len(row) != size {
+			return fmt.Errorf("flow deviation matrix row %d has %d columns, instance size is %d", i, len(row), size)
+		}
+		for _, v := range row {
+			if v < 0 {
+				return fmt.Errorf("flow deviation matrix row %d holds negative value %d", i, v)
+			}
+		}
+	}
+	return nil
+}
+
+// StochasticFlows holds the flow scenarios of an instance with uncertain flows
+type StochasticFlows struct {
+	Mode       string
+	Percentile float64
+	Scenarios  [][][]int
+	total      [][]int // sum of the scenario flows, the expected cost is linear in it
+}
+
+// SetFlowUncertainty draws the flow scenarios of the instance. From then on
+// CalculateFitness uses the expected or percentile cost over the scenarios in
+// place of the QAP cost, while QAPCost keeps using the mean flows.
+func (instance *QAPInstance) SetFlowUncertainty(u *FlowUncertainty) error {
+	if err := u.Validate(instance.Size); err != nil {
+		return err
+	}
+
+	n := instance.Size
+	rng := rand.New(rand.NewSource(u.Seed))
+	s := &StochasticFlows{
+		Mode:       u.Mode,
+		Percentile: u.Percentile,
+		Scenarios:  make([][][]int, u.Samples),
+		total:      make([][]int, n),
+	}
+	for i := range s.total {
+		s.total[i] = make([]int, n)
+	}
+	for k := range s.Scenarios {
+		flow := make([][]int, n)
+		for i := range flow {
+			flow[i] = make([]int, n)
+			for j := range flow[i] {
+				mean := float64(instance.FlowMatrix[i][j])
+				sample := mean
+				if u.Std[i][j] > 0 {
+					sample = math.Max(0, math.Round(mean+float64(u.Std[i][j])*rng.NormFloat64()))
+				}
+				flow[i][j] = int(sample)
+				s.total[i][j] += flow[i][j]
+			}
+		}
+		s.Scenarios[k] = flow
+	}
+	instance.Stochastic = s
+	return nil
+}
+
+// ScenarioCosts returns the QAP cost of solution in every flow scenario
+func (s *StochasticFlows) ScenarioCosts(instance *QAPInstance, solution []int) []int {
+	costs := make([]int, len(s.Scenarios))
+	for k, flow := range s.Scenarios {
+		costs[k] = blockedCost(flow, instance.DistanceMatrix, solution[:instance.Size])
+	}
+	return costs
+}
+
+// Cost returns the expected or percentile QAP cost of solution. For
+// maximization instances the percentile applies to the reward from below, so
+// a percentile of 90 is the reward reached in 90 percent of the scenarios.
+func (s *StochasticFlows) Cost(instance *QAPInstance, solution []int) int {
+	if s.Mode == StochasticExpected {
+		total := blockedCost(s.total, instance.DistanceMatrix, solution[:instance.Size])
+		return int(math.Round(float64(total) / float64(len(s.Scenarios))))
+	}
+
+	costs := s.ScenarioCosts(instance, solution)
+	sort.Ints(costs)
+	p := s.Percentile
+	if instance.Maximize {
+		p = 100 - p
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(costs))))
+	return costs[min(max(rank, 1), len(costs))-1]
+}
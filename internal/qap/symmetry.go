@@ -0,0 +1,157 @@
+package qap
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Symmetry search bounds: a matrix with more automorphisms than
+// maxAutomorphisms, or whose search visits more than automorphismNodes
+// partial assignments, is treated as having none. Canonical forms then tell
+// fewer solutions apart as duplicates but stay consistent.
+const (
+	maxAutomorphisms  = 64
+	automorphismNodes = 100000
+)
+
+// Symmetries are the automorphisms of an instance: permutations σ of the
+// locations with D[σ(a)][σ(b)] = D[a][b] for all a, b, e.g. the rotations
+// and reflections of a square grid, and permutations τ of the facilities
+// with F[τ(i)][τ(j)] = F[i][j]. Every solution p has the same cost as
+// σ∘p∘τ, so solutions are compared by their canonical form, the smallest of
+// these copies. A nil *Symmetries has only the identity.
+type Symmetries struct {
+	locations  [][]int
+	facilities [][]int
+}
+
+// FindSymmetries returns the automorphisms of the flow and distance
+// matrices, nil when both have only the identity or when the instance has
+// relocation, constraint or stochastic terms, which depend on the actual
+// assignments
+func FindSymmetries(instance *QAPInstance) *Symmetries {
+	if instance.hasExtraTerms() {
+		return nil
+	}
+	s := &Symmetries{
+		locations:  automorphisms(instance.DistanceMatrix),
+		facilities: automorphisms(instance.FlowMatrix),
+	}
+	if s.Count() == 1 {
+		return nil
+	}
+	return s
+}
+
+// Count returns the number of copies of every solution with the same cost,
+// the solution itself included
+func (s *Symmetries) Count() int {
+	if s == nil {
+		return 1
+	}
+	return len(s.locations) * len(s.facilities)
+}
+
+// Canonical returns the lexicographically smallest copy σ∘p∘τ of solution.
+// Two solutions are symmetric duplicates exactly when their canonical forms
+// are equal. The solution is not modified; solutions of another size are
+// returned as a copy.
+func (s *Symmetries) Canonical(solution []int) []int {
+	best := slices.Clone(solution)
+	if s == nil || len(solution) != len(s.locations[0]) {
+		return best
+	}
+	candidate := make([]int, len(solution))
+	for _, sigma := range s.locations {
+		for _, tau := range s.facilities {
+			for i := range candidate {
+				candidate[i] = sigma[solution[tau[i]]]
+			}
+			if slices.Compare(candidate, best) < 0 {
+				copy(best, candidate)
+			}
+		}
+	}
+	return best
+}
+
+// Equivalent reports whether a and b are copies of each other under the
+// symmetries
+func (s *Symmetries) Equivalent(a, b []int) bool {
+	if s == nil {
+		return slices.Equal(a, b)
+	}
+	return slices.Equal(s.Canonical(a), s.Canonical(b))
+}
+
+// automorphisms enumerates the permutations σ with m[σ(a)][σ(b)] = m[a][b]
+// by backtracking. Only indices with the same diagonal entry and the same
+// sorted row and column can map to each other. When the search exceeds its
+// bounds only the identity is returned.
+func automorphisms(m [][]int) [][]int {
+	n := len(m)
+	identity := make([]int, n)
+	for i := range identity {
+		identity[i] = i
+	}
+
+	class := make([]int, n)
+	classes := make(map[string]int)
+	for a := 0; a < n; a++ {
+		row, column := slices.Clone(m[a]), make([]int, n)
+		for b := 0; b < n; b++ {
+			column[b] = m[b][a]
+		}
+		slices.Sort(row)
+		slices.Sort(column)
+		key := fmt.Sprint(m[a][a], row, column)
+		if _, ok := classes[key]; !ok {
+			classes[key] = len(classes)
+		}
+		class[a] = classes[key]
+	}
+	if len(classes) == n {
+		return [][]int{identity}
+	}
+
+	var found [][]int
+	perm := make([]int, n)
+	used := make([]bool, n)
+	nodes := 0
+	overflow := false
+	var search func(a int)
+	search = func(a int) {
+		if a == n {
+			if len(found) == maxAutomorphisms {
+				overflow = true
+				return
+			}
+			found = append(found, slices.Clone(perm))
+			return
+		}
+		for b := 0; b < n && !overflow; b++ {
+			if used[b] || class[b] != class[a] {
+				continue
+			}
+			if nodes++; nodes > automorphismNodes {
+				overflow = true
+				return
+			}
+			consistent := true
+			for c := 0; c < a && consistent; c++ {
+				consistent = m[perm[c]][b] == m[c][a] && m[b][perm[c]] == m[a][c]
+			}
+			if !consistent {
+				continue
+			}
+			perm[a], used[b] = b, true
+			search(a + 1)
+			used[b] = false
+		}
+	}
+	search(0)
+	if overflow || len(found) == 0 {
+		return [][]int{identity}
+	}
+	return found
+}
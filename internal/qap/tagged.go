@@ -0,0 +1,244 @@
+package qap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TaggedExtension is the file extension of the tagged matrix format
+const TaggedExtension = ".qapt"
+
+func init() {
+	RegisterReader(TaggedExtension, ParseTagged)
+}
+
+// ParseTagged reads an instance in the tagged matrix format, an interchange
+// format for instances written by scripts. Every part is introduced by a
+// keyword, matched case-insensitively:
+//
+//	# comment
+//	SIZE 12            (or SIZE <facilities> <locations> when rectangular)
+//	MAXIMIZE           (optional, the objective is maximized)
+//	FLOW               (dense: one matrix row per line)
+//	0 3 ...
+//	DIST SPARSE        (sparse: "row column value" triplets, numbered from 1)
+//	1 2 5
+//	END                (optional)
+//
+// A section header may add SYMMETRIC after SPARSE, every triplet then sets
+// both (i, j) and (j, i). Entries missing from a sparse section are zero, an
+// entry given twice is an error. DISTANCE is accepted for DIST. The flow
+// matrix has the size of the facilities and the distance matrix that of the
+// locations; rectangular instances are padded by ReadInstance.
+func ParseTagged(data []byte) (*QAPInstance, error) {
+	var instance *QAPInstance
+	facilities, locations := 0, 0
+
+	// The section being read: its matrix, size and layout
+	var matrix [][]int
+	var section string
+	sparse, symmetric := false, false
+	row := 0
+	seen := make(map[[2]int]bool)
+	finish := func() error {
+		if matrix != nil && !sparse && row < len(matrix) {
+			return fmt.Errorf("%s section has %d rows, expected %d", section, row, len(matrix))
+		}
+		return nil
+	}
+
+	for _, line := range valueLines(string(data)) {
+		keyword := strings.ToUpper(line.fields[0])
+		switch keyword {
+		case "SIZE":
+			if instance != nil {
+				return nil, fmt.Errorf("line %d: SIZE given twice", line.number)
+			}
+			sizes := make([]int, len(line.fields)-1)
+			for k, field := range line.fields[1:] {
+				value, err := strconv.Atoi(field)
+				if err != nil || value < 1 {
+					return nil, fmt.Errorf("line %d: invalid size %q", line.number, field)
+				}
+				sizes[k] = value
+			}
+			switch len(sizes) {
+			case 1:
+				facilities, locations = sizes[0], sizes[0]
+			case 2:
+				facilities, locations = sizes[0], sizes[1]
+				if facilities > locations {
+					return nil, fmt.Errorf("line %d: %d facilities do not fit on %d locations", line.number, facilities, locations)
+				}
+			default:
+				return nil, fmt.Errorf("line %d: expected SIZE <n> or SIZE <facilities> <locations>", line.number)
+			}
+			instance = &QAPInstance{Size: facilities}
+			continue
+		case "MAXIMIZE":
+			if instance == nil {
+				return nil, fmt.Errorf("line %d: MAXIMIZE before SIZE", line.number)
+			}
+			instance.Maximize = true
+			continue
+		case "FLOW", "DIST", "DISTANCE":
+			if instance == nil {
+				return nil, fmt.Errorf("line %d: %s before SIZE", line.number, keyword)
+			}
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			section = "FLOW"
+			size, target := facilities, &instance.FlowMatrix
+			if keyword != "FLOW" {
+				section, size, target = "DIST", locations, &instance.DistanceMatrix
+			}
+			if *target != nil {
+				return nil, fmt.Errorf("line %d: %s section given twice", line.number, section)
+			}
+			sparse, symmetric = false, false
+			for _, modifier := range line.fields[1:] {
+				switch strings.ToUpper(modifier) {
+				case "DENSE":
+				case "SPARSE":
+					sparse = true
+				case "SYMMETRIC":
+					symmetric = true
+				default:
+					return nil, fmt.Errorf("line %d: unknown %s modifier %q, expected DENSE, SPARSE or SYMMETRIC", line.number, section, modifier)
+				}
+			}
+			if symmetric && !sparse {
+				return nil, fmt.Errorf("line %d: SYMMETRIC applies to SPARSE sections only", line.number)
+			}
+			*target = make([][]int, size)
+			for i := range *target {
+				(*target)[i] = make([]int, size)
+			}
+			matrix, row = *target, 0
+			clear(seen)
+			continue
+		case "END":
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			matrix = nil
+			continue
+		}
+
+		if matrix == nil {
+			return nil, fmt.Errorf("line %d: values outside a FLOW or DIST section", line.number)
+		}
+		values := make([]int, len(line.fields))
+		for k, field := range line.fields {
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid value %q", line.number, field)
+			}
+			values[k] = value
+		}
+		n := len(matrix)
+		if !sparse {
+			if row == n {
+				return nil, fmt.Errorf("line %d: %s section has more than %d rows", line.number, section, n)
+			}
+			if len(values) != n {
+				return nil, fmt.Errorf("line %d: %s row %d has %d values, expected %d", line.number, section, row+1, len(values), n)
+			}
+			copy(matrix[row], values)
+			row++
+			continue
+		}
+		if len(values) != 3 {
+			return nil, fmt.Errorf("line %d: expected a \"row column value\" triplet in the sparse %s section", line.number, section)
+		}
+		i, j := values[0]-1, values[1]-1
+		if i < 0 || i >= n || j < 0 || j >= n {
+			return nil, fmt.Errorf("line %d: %s entry (%d, %d) outside the %dx%d matrix", line.number, section, values[0], values[1], n, n)
+		}
+		entries := [][2]int{{i, j}}
+		if symmetric && i != j {
+			entries = append(entries, [2]int{j, i})
+		}
+		for _, entry := range entries {
+			if seen[entry] {
+				return nil, fmt.Errorf("line %d: %s entry (%d, %d) given twice", line.number, section, entry[0]+1, entry[1]+1)
+			}
+			seen[entry] = true
+			matrix[entry[0]][entry[1]] = values[2]
+		}
+	}
+
+	if instance == nil {
+		return nil, fmt.Errorf("missing SIZE")
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	if instance.FlowMatrix == nil || instance.DistanceMatrix == nil {
+		return nil, fmt.Errorf("missing FLOW or DIST section")
+	}
+	return instance, nil
+}
+
+// WriteTagged writes an instance in the tagged matrix format read by
+// ParseTagged. Matrices that are mostly zero are written as sparse
+// triplets, the others row by row; a padded instance is written with its
+// real facilities only.
+func WriteTagged(filename string, instance *QAPInstance) error {
+	var b strings.Builder
+	facilities := instance.FacilityCount()
+	if facilities < instance.Size {
+		fmt.Fprintf(&b, "SIZE %d %d\n", facilities, instance.Size)
+	} else {
+		fmt.Fprintf(&b, "SIZE %d\n", instance.Size)
+	}
+	if instance.Maximize {
+		b.WriteString("MAXIMIZE\n")
+	}
+	writeTaggedSection(&b, "FLOW", instance.FlowMatrix, facilities)
+	writeTaggedSection(&b, "DIST", instance.DistanceMatrix, instance.Size)
+	b.WriteString("END\n")
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, []byte(b.String()), 0644)
+}
+
+// writeTaggedSection writes the leading n x n block of matrix, as triplets
+// when fewer than a third of its entries are nonzero
+func writeTaggedSection(b *strings.Builder, section string, matrix [][]int, n int) {
+	nonzero := 0
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if matrix[i][j] != 0 {
+				nonzero++
+			}
+		}
+	}
+	if 3*nonzero < n*n {
+		fmt.Fprintf(b, "%s SPARSE\n", section)
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if matrix[i][j] != 0 {
+					fmt.Fprintf(b, "%d %d %d\n", i+1, j+1, matrix[i][j])
+				}
+			}
+		}
+		return
+	}
+	b.WriteString(section + "\n")
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if j > 0 {
+				b.WriteString(" ")
+			}
+			b.WriteString(strconv.Itoa(matrix[i][j]))
+		}
+		b.WriteString("\n")
+	}
+}
@@ -0,0 +1,115 @@
+// Package qaptest builds the random matrices of the instances used by tests
+// and benchmarks. It does not import qap, so the tests of package qap can
+// use it too; the other packages wrap the matrices in a qap.QAPInstance.
+package qaptest
+
+import "math/rand"
+
+// MaxValue bounds the entries of the random matrices, except the heavy
+// entries of SparseMatrix
+const MaxValue = 100
+
+// Matrix returns an asymmetric n x n matrix of values in [0, MaxValue),
+// with a nonzero diagonal
+func Matrix(rng *rand.Rand, n int) [][]int {
+	m := make([][]int, n)
+	for i := range m {
+		m[i] = make([]int, n)
+		for j := range m[i] {
+			m[i][j] = rng.Intn(MaxValue)
+		}
+	}
+	return m
+}
+
+// SymmetricMatrix returns a symmetric n x n matrix of values in
+// [0, MaxValue) with a zero diagonal
+func SymmetricMatrix(rng *rand.Rand, n int) [][]int {
+	m := make([][]int, n)
+	for i := range m {
+		m[i] = make([]int, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			m[i][j] = rng.Intn(MaxValue)
+			m[j][i] = m[i][j]
+		}
+	}
+	return m
+}
+
+// OneWayMatrix is a Matrix with every off-diagonal pair used in one
+// direction only: m[i][j] > 0 implies m[j][i] = 0
+func OneWayMatrix(rng *rand.Rand, n int) [][]int {
+	m := Matrix(rng, n)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if rng.Intn(2) == 0 {
+				m[i][j] = 0
+			} else {
+				m[j][i] = 0
+			}
+		}
+	}
+	return m
+}
+
+// SparseMatrix is mostly zero with a few heavy entries in either direction,
+// like the flows of the tai*b instances
+func SparseMatrix(rng *rand.Rand, n int) [][]int {
+	m := make([][]int, n)
+	for i := range m {
+		m[i] = make([]int, n)
+		for j := range m[i] {
+			if rng.Intn(5) == 0 {
+				m[i][j] = rng.Intn(10000)
+			}
+		}
+	}
+	return m
+}
+
+// ZeroDiagonal clears the diagonal of m and returns it
+func ZeroDiagonal(m [][]int) [][]int {
+	for i := range m {
+		m[i][i] = 0
+	}
+	return m
+}
+
+// Variant is a kind of random instance that incremental evaluations must
+// get right
+type Variant struct {
+	Name     string
+	Flow     func(rng *rand.Rand, n int) [][]int
+	Distance func(rng *rand.Rand, n int) [][]int
+	Maximize bool
+	Padded   bool // more locations than facilities, see Locations
+}
+
+// Variants covers both matrices asymmetric, only one of them, one-way and
+// sparse flows, all with nonzero diagonals, minimized and maximized, square
+// and padded
+var Variants = []Variant{
+	{Name: "asymmetric", Flow: Matrix, Distance: Matrix},
+	{Name: "asymmetric flows", Flow: Matrix, Distance: SymmetricMatrix},
+	{Name: "asymmetric distances", Flow: SymmetricMatrix, Distance: Matrix},
+	{Name: "one-way flows", Flow: OneWayMatrix, Distance: Matrix},
+	{Name: "sparse flows", Flow: SparseMatrix, Distance: Matrix},
+	{Name: "max", Flow: Matrix, Distance: Matrix, Maximize: true},
+	{Name: "padded", Flow: Matrix, Distance: Matrix, Padded: true},
+}
+
+// Locations returns the number of locations of a variant with n facilities
+func (v Variant) Locations(n int) int {
+	if v.Padded {
+		return n + n/4 + 1
+	}
+	return n
+}
+
+// Matrices returns the n x n flow matrix and the distance matrix over
+// Locations(n) locations of a variant
+func (v Variant) Matrices(rng *rand.Rand, n int) (flow, distance [][]int) {
+	return v.Flow(rng, n), v.Distance(rng, v.Locations(n))
+}
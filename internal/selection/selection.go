@@ -0,0 +1,174 @@
+// Package selection implements parent selection for population solvers.
+// Fitness values are minimized as everywhere in the solvers, so lower values
+// are selected more often. Scalings turn fitness values into selection
+// weights, samplers draw individuals by weight.
+package selection
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Bounds of the linear ranking pressure, see RankWeights
+const (
+	MinRankPressure = 1.0
+	MaxRankPressure = 2.0
+)
+
+// RouletteWeights scales fitness proportionally with windowing: the weight
+// of an individual is how much better it is than the worst one, plus the
+// mean of those distances so that the worst one keeps a chance. Equal
+// fitness values get equal weights.
+func RouletteWeights(fitness []int) []float64 {
+	weights := make([]float64, len(fitness))
+	if len(fitness) == 0 {
+		return weights
+	}
+	worst := fitness[0]
+	for _, f := range fitness {
+		worst = max(worst, f)
+	}
+	total := 0.0
+	for i, f := range fitness {
+		weights[i] = float64(worst - f)
+		total += weights[i]
+	}
+	offset := total / float64(len(fitness))
+	if offset == 0 {
+		offset = 1
+	}
+	for i := range weights {
+		weights[i] += offset
+	}
+	return weights
+}
+
+// RankWeights implements linear ranking: the best individual has weight
+// pressure, the worst 2-pressure, and the ranks in between are spaced
+// evenly. Pressure is clamped to [MinRankPressure, MaxRankPressure], 1 selects
+// uniformly. Tied individuals share the mean weight of their ranks.
+func RankWeights(fitness []int, pressure float64) []float64 {
+	pressure = math.Max(MinRankPressure, math.Min(MaxRankPressure, pressure))
+	n := len(fitness)
+	if n == 1 {
+		return []float64{1}
+	}
+	return byRank(fitness, func(rank int) float64 {
+		// rank 0 is the best individual
+		return pressure - 2*(pressure-1)*float64(rank)/float64(n-1)
+	})
+}
+
+// TournamentProbabilities returns the exact probability of every individual
+// to win one tournament of the given size, whose contestants are drawn with
+// replacement. Larger tournaments raise the selection pressure.
+func TournamentProbabilities(fitness []int, size int) []float64 {
+	size = max(size, 1)
+	n := float64(len(fitness))
+	return byRank(fitness, func(rank int) float64 {
+		// The winner has this rank when all contestants rank at least as
+		// low and not all of them rank lower
+		return (math.Pow(n-float64(rank), float64(size)) - math.Pow(n-float64(rank)-1, float64(size))) /
+			math.Pow(n, float64(size))
+	})
+}
+
+// byRank assigns every individual the value of its rank, best first, and
+// gives tied individuals the mean value of their ranks
+func byRank(fitness []int, value func(rank int) float64) []float64 {
+	order := make([]int, len(fitness))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return fitness[order[a]] < fitness[order[b]] })
+
+	values := make([]float64, len(fitness))
+	for start := 0; start < len(order); {
+		end := start
+		sum := 0.0
+		for end < len(order) && fitness[order[end]] == fitness[order[start]] {
+			sum += value(end)
+			end++
+		}
+		for k := start; k < end; k++ {
+			values[order[k]] = sum / float64(end-start)
+		}
+		start = end
+	}
+	return values
+}
+
+// Probabilities normalizes weights to selection probabilities of one draw
+func Probabilities(weights []float64) []float64 {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	probabilities := make([]float64, len(weights))
+	for i, w := range weights {
+		probabilities[i] = w / total
+	}
+	return probabilities
+}
+
+// Roulette draws count individuals independently with probabilities
+// proportional to their weights
+func Roulette(rng *rand.Rand, weights []float64, count int) []int {
+	cumulative := cumulativeWeights(weights)
+	total := cumulative[len(cumulative)-1]
+	selected := make([]int, count)
+	for k := range selected {
+		selected[k] = pointer(cumulative, rng.Float64()*total)
+	}
+	return selected
+}
+
+// StochasticUniversal draws count individuals with a single spin of a wheel
+// with count evenly spaced pointers. Every individual is selected its
+// expected number of times rounded up or down, so the spread is minimal.
+func StochasticUniversal(rng *rand.Rand, weights []float64, count int) []int {
+	cumulative := cumulativeWeights(weights)
+	total := cumulative[len(cumulative)-1]
+	step := total / float64(count)
+	start := rng.Float64() * step
+	selected := make([]int, count)
+	for k := range selected {
+		selected[k] = pointer(cumulative, start+float64(k)*step)
+	}
+	return selected
+}
+
+// Tournament draws count winners of tournaments of the given size, see
+// TournamentProbabilities
+func Tournament(rng *rand.Rand, fitness []int, size, count int) []int {
+	size = max(size, 1)
+	selected := make([]int, count)
+	for k := range selected {
+		winner := rng.Intn(len(fitness))
+		for c := 1; c < size; c++ {
+			if contestant := rng.Intn(len(fitness)); fitness[contestant] < fitness[winner] {
+				winner = contestant
+			}
+		}
+		selected[k] = winner
+	}
+	return selected
+}
+
+func cumulativeWeights(weights []float64) []float64 {
+	cumulative := make([]float64, len(weights))
+	total := 0.0
+	for i, w := range weights {
+		total += w
+		cumulative[i] = total
+	}
+	return cumulative
+}
+
+// pointer returns the individual whose slice of the wheel contains
+// position; slices are half-open, [cumulative[i-1], cumulative[i])
+func pointer(cumulative []float64, position float64) int {
+	i := sort.Search(len(cumulative), func(i int) bool { return cumulative[i] > position })
+	return min(i, len(cumulative)-1)
+}
@@ -0,0 +1,112 @@
+package selection
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testFitness has ties and a spread of values, minimized
+var testFitness = []int{50, 10, 30, 10, 80, 20}
+
+// chiSquareCritical is the 0.999 quantile of the chi-square distribution
+// with len(testFitness)-1 = 5 degrees of freedom. With a fixed seed the tests
+// are deterministic; the bound only decides whether the frequencies fit.
+const chiSquareCritical = 20.52
+
+const draws = 200000
+
+// chiSquare compares the counts of draws with the expected probabilities
+func chiSquare(t *testing.T, selected []int, probabilities []float64) {
+	t.Helper()
+	counts := make([]int, len(probabilities))
+	for _, i := range selected {
+		counts[i]++
+	}
+	statistic := 0.0
+	for i, p := range probabilities {
+		expected := p * float64(len(selected))
+		if expected == 0 {
+			if counts[i] > 0 {
+				t.Fatalf("individual %d has probability 0 but was selected %d times", i, counts[i])
+			}
+			continue
+		}
+		statistic += (float64(counts[i]) - expected) * (float64(counts[i]) - expected) / expected
+	}
+	if statistic > chiSquareCritical {
+		t.Fatalf("chi-square %.2f exceeds %.2f: counts %v, probabilities %v", statistic, chiSquareCritical, counts, probabilities)
+	}
+}
+
+func TestRouletteMatchesProbabilities(t *testing.T) {
+	for _, scaling := range []struct {
+		name    string
+		weights []float64
+	}{
+		{"fitness", RouletteWeights(testFitness)},
+		{"rank 1.0", RankWeights(testFitness, 1.0)},
+		{"rank 1.7", RankWeights(testFitness, 1.7)},
+		{"rank 2.0", RankWeights(testFitness, 2.0)},
+	} {
+		t.Run(scaling.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			chiSquare(t, Roulette(rng, scaling.weights, draws), Probabilities(scaling.weights))
+		})
+	}
+}
+
+func TestTournamentMatchesProbabilities(t *testing.T) {
+	for _, size := range []int{1, 2, 3, 5} {
+		t.Run(fmt.Sprintf("size=%d", size), func(t *testing.T) {
+			probabilities := TournamentProbabilities(testFitness, size)
+			sum := 0.0
+			for _, p := range probabilities {
+				sum += p
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Fatalf("tournament probabilities sum to %v", sum)
+			}
+			rng := rand.New(rand.NewSource(1))
+			chiSquare(t, Tournament(rng, testFitness, size, draws), probabilities)
+		})
+	}
+}
+
+func TestStochasticUniversalMatchesProbabilities(t *testing.T) {
+	weights := RankWeights(testFitness, 1.5)
+	rng := rand.New(rand.NewSource(1))
+	var selected []int
+	for spin := 0; spin < draws/10; spin++ {
+		selected = append(selected, StochasticUniversal(rng, weights, 10)...)
+	}
+	chiSquare(t, selected, Probabilities(weights))
+}
+
+// TestStochasticUniversalSpread checks the property that sets the sampler
+// apart from roulette: in every spin each individual is selected its
+// expected number of times rounded down or up
+func TestStochasticUniversalSpread(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, weights := range [][]float64{RouletteWeights(testFitness), RankWeights(testFitness, 2.0), {1, 1, 1}, {5, 0, 1, 3}} {
+		probabilities := Probabilities(weights)
+		for _, count := range []int{1, 2, 5, 6, 13, 100} {
+			for spin := 0; spin < 1000; spin++ {
+				counts := make([]int, len(weights))
+				for _, i := range StochasticUniversal(rng, weights, count) {
+					counts[i]++
+				}
+				for i, p := range probabilities {
+					expected := p * float64(count)
+					// Tolerance for the rounding of the pointer positions
+					low, high := math.Floor(expected+1e-9), math.Ceil(expected-1e-9)
+					if float64(counts[i]) < low || float64(counts[i]) > high {
+						t.Fatalf("weights %v, %d pointers: individual %d selected %d times, expected %.3f",
+							weights, count, i, counts[i], expected)
+					}
+				}
+			}
+		}
+	}
+}
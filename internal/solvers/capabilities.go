@@ -0,0 +1,27 @@
+package solvers
+
+// Capabilities lists the optional features a solver supports, so callers can
+// adapt to it and warn instead of silently ignoring a requested feature
+type Capabilities struct {
+	SupportsMetrics      bool // SolveWithMetrics records runs and honors SolveOptions
+	SupportsWarmStart    bool // the search can start from a given solution
+	SupportsCancellation bool // a run stops early, keeping its best solution, when its limits are hit
+	SupportsTimeBudget   bool // SolveOptions.TimeLimit ends a run once it is exceeded
+	SupportsSwapRadius   bool // swap neighborhoods honor SolveOptions.SwapRadius
+}
+
+// CapableSolver is implemented by solvers that declare their capabilities
+type CapableSolver interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the declared capabilities of a solver. Solvers that
+// declare none are assumed to support metrics only if they implement
+// SolveWithMetrics.
+func CapabilitiesOf(solver Solver) Capabilities {
+	if c, ok := solver.(CapableSolver); ok {
+		return c.Capabilities()
+	}
+	_, ok := solver.(metricsSolver)
+	return Capabilities{SupportsMetrics: ok}
+}
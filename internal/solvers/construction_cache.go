@@ -0,0 +1,119 @@
+package solvers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"sync"
+)
+
+// ConstructionCache keeps the solutions of deterministic constructions, such
+// as those of clusterinit and faqinit, so repeated experiments on the same
+// instance skip them. Every instance and construction has a JSON file in the
+// cache directory holding the content hash of the instance it was built
+// for, see qap.ContentHash; a file whose hash differs from the instance's,
+// because the instance file changed, is ignored and rewritten. Entries are
+// kept in memory too, so the runs of an experiment read a file at most once.
+// A ConstructionCache is safe for concurrent use, a nil one caches nothing.
+type ConstructionCache struct {
+	dir string
+
+	mu                        sync.Mutex
+	memory                    map[string]cachedConstruction // by file name
+	hits, misses, invalidated int
+}
+
+// cachedConstruction is the content of a cache file
+type cachedConstruction struct {
+	Instance     string `json:"instance"`
+	Hash         string `json:"hash"`
+	Construction string `json:"construction"`
+	Solution     []int  `json:"solution"`
+}
+
+// NewConstructionCache caches constructions in dir, which is created with
+// the first entry
+func NewConstructionCache(dir string) *ConstructionCache {
+	return &ConstructionCache{dir: dir, memory: make(map[string]cachedConstruction)}
+}
+
+// Dir returns the cache directory
+func (c *ConstructionCache) Dir() string {
+	return c.dir
+}
+
+// Stats returns how many constructions were found in the cache, how many
+// were built, and how many of those replaced an entry of a changed instance
+func (c *ConstructionCache) Stats() (hits, misses, invalidated int) {
+	if c == nil {
+		return 0, 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses, c.invalidated
+}
+
+// get returns a copy of the cached construction of the instance, counting a
+// miss when there is none for its hash
+func (c *ConstructionCache) get(instanceName, hash, construction string, size int) ([]int, bool) {
+	name := cacheFileName(instanceName, hash, construction)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.memory[name]
+	if !ok {
+		if data, err := os.ReadFile(filepath.Join(c.dir, name)); err == nil && json.Unmarshal(data, &entry) == nil {
+			ok = true
+		}
+	}
+	switch {
+	case ok && entry.Hash == hash && len(entry.Solution) == size && qap.ValidatePermutation(entry.Solution) == nil:
+		c.memory[name] = entry
+		c.hits++
+		return append([]int(nil), entry.Solution...), true
+	case ok:
+		c.invalidated++
+	}
+	c.misses++
+	return nil, false
+}
+
+// put stores a construction. A file that cannot be written only costs the
+// next experiment the construction, so write errors are dropped.
+func (c *ConstructionCache) put(instanceName, hash, construction string, solution []int) {
+	name := cacheFileName(instanceName, hash, construction)
+	entry := cachedConstruction{Instance: instanceName, Hash: hash, Construction: construction, Solution: append([]int(nil), solution...)}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.memory[name] = entry
+
+	data, err := json.Marshal(entry)
+	if err != nil || os.MkdirAll(c.dir, 0755) != nil {
+		return
+	}
+	// Written under another name first, so readers never see half a file
+	temp, err := os.CreateTemp(c.dir, name+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err != nil || closeErr != nil {
+		os.Remove(temp.Name())
+		return
+	}
+	if os.Rename(temp.Name(), filepath.Join(c.dir, name)) != nil {
+		os.Remove(temp.Name())
+	}
+}
+
+// cacheFileName names the file of a construction by the sanitized instance
+// name, or by the start of its hash for unnamed instances. Instances whose
+// names sanitize alike share a file; the hash check keeps them apart.
+func cacheFileName(instanceName, hash, construction string) string {
+	if instanceName == "" {
+		instanceName = hash[:16]
+	}
+	return metrics.InstanceFileStem(instanceName) + "_" + metrics.SanitizeFilename(construction) + ".json"
+}
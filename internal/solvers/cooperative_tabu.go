@@ -0,0 +1,404 @@
+package solvers
+
+import (
+	"fmt"
+	"math/rand"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"qap_solver/pkg"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stopPollInterval is how often the coordinating goroutine asks the run
+// tracker whether the workers have to stop
+const stopPollInterval = 10 * time.Millisecond
+
+// CooperativeTabuSolver runs several tabu searches in parallel from different
+// random starts. Every Sync iterations a worker offers its best solution to a
+// shared elite pool and continues from the pool's best solution if that is
+// better than its own. Workers exchange solutions asynchronously, there is no
+// barrier between them.
+type CooperativeTabuSolver struct {
+	P       int // a worker stops after P*n iterations without improving its best
+	Workers int
+	Sync    int    // iterations between two exchanges with the elite pool
+	TabuOn  string // attribute made tabu after a move, see TabuOnAssignments
+}
+
+func NewCooperativeTabuSolver(p, workers, sync int, tabuOn string) *CooperativeTabuSolver {
+	return &CooperativeTabuSolver{P: p, Workers: workers, Sync: sync, TabuOn: tabuOn}
+}
+
+func (s *CooperativeTabuSolver) Name() string {
+	return "CooperativeTabu"
+}
+
+func (s *CooperativeTabuSolver) Description() string {
+	return fmt.Sprintf("Cooperative parallel Tabu Search, %d workers exchanging solutions every %d iterations (tabu on %s)",
+		s.Workers, s.Sync, s.TabuOn)
+}
+
+func (s *CooperativeTabuSolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsWarmStart: true, SupportsCancellation: true, SupportsTimeBudget: true, SupportsSwapRadius: true}
+}
+
+func (s *CooperativeTabuSolver) Solve(instance *qap.QAPInstance) SolverResult {
+	return s.SolveWithMetrics(instance, nil, "", 0, SolveOptions{})
+}
+
+func (s *CooperativeTabuSolver) SolveWithMetrics(
+	instance *qap.QAPInstance,
+	metricsCollector *metrics.MetricsCollector,
+	instanceName string,
+	runNumber int,
+	opts SolveOptions,
+) SolverResult {
+	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
+
+	n := instance.Size
+	pool := newElitePool(s.Workers, tracker)
+
+	// Starting solutions are generated up front so the initial fitness is known
+	workers := make([]*tabuWorker, s.Workers)
+	initialFitness := 0
+	for w := range workers {
+		workers[w] = newTabuWorker(w, instance, tracker, pool, s.TabuOn)
+		if w == 0 || workers[w].currentFitness < initialFitness {
+			initialFitness = workers[w].currentFitness
+		}
+	}
+	pool.best = initialFitness
+	tracker.improved(0, initialFitness)
+	tracker.tenure(n / 2)
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for _, worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker.run(s.P*n, s.Sync, &stop)
+		}()
+	}
+
+	// The tracker is not safe for concurrent use: workers reach it through
+	// the pool under its lock, and this goroutine takes the same lock to
+	// publish their progress, poll the tracker and tell the workers to stop
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	ticker := time.NewTicker(stopPollInterval)
+	for waiting := true; waiting; {
+		select {
+		case <-done:
+			waiting = false
+		case <-ticker.C:
+			pool.mu.Lock()
+			tracker.progress(int(pool.evaluations.Load()), int(pool.moves.Load()))
+			stopping := tracker.shouldStop()
+			pool.mu.Unlock()
+			if stopping {
+				stop.Store(true)
+			}
+		}
+	}
+	ticker.Stop()
+
+	totalSteps := 0
+	totalEvaluations := 0
+	for _, worker := range workers {
+		totalSteps += worker.steps
+		totalEvaluations += worker.evaluations
+		tracker.counts.add(worker.counts)
+		tracker.addCPUTime(worker.cpuTime)
+	}
+	tracker.artifact("elite_pool.csv", pool.rows)
+	tracker.artifact("workers.csv", func() [][]string {
+		rows := [][]string{{"Worker", "CurrentFitness", "BestFitness", "BestSolution"}}
+		for _, worker := range workers {
+			rows = append(rows, []string{strconv.Itoa(worker.id), strconv.Itoa(instance.Objective(worker.currentFitness)),
+				strconv.Itoa(instance.Objective(worker.bestFitness)), fmt.Sprint(worker.best)})
+		}
+		return rows
+	})
+	best, bestFitness := pool.bestSolution()
+
+	elapsedTime := time.Since(startTime)
+
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     bestFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalEvaluations,
+		Solution:         best,
+	})
+
+	return SolverResult{
+		Solution: best,
+		Fitness:  bestFitness,
+	}
+}
+
+// elitePool holds the best distinct solutions found by all workers,
+// symmetric copies of a pool solution are not distinct, see
+// qap.Symmetries. It also owns the run tracker's trajectory and trace while
+// workers run.
+type elitePool struct {
+	mu          sync.Mutex
+	capacity    int
+	solutions   [][]int
+	canonical   [][]int // canonical forms of solutions
+	fitness     []int
+	symmetries  *qap.Symmetries
+	best        int
+	evaluations atomic.Int64 // evaluations of all workers, for the trajectory
+	moves       atomic.Int64 // moves of all workers, for the stop condition
+	tracker     *runTracker
+}
+
+func newElitePool(capacity int, tracker *runTracker) *elitePool {
+	p := &elitePool{capacity: max(capacity, 1), tracker: tracker}
+	// A custom fitness need not share the symmetries of the matrices
+	if tracker.fitnessFunc == nil {
+		p.symmetries = qap.FindSymmetries(tracker.instance)
+	}
+	return p
+}
+
+// exchange offers a worker's best solution and returns a copy of the best
+// pool solution if it is better than the offered one, nil otherwise
+func (p *elitePool) exchange(solution []int, fitness int) ([]int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.offer(solution, fitness)
+	if len(p.solutions) > 0 && p.fitness[0] < fitness {
+		return append([]int(nil), p.solutions[0]...), p.fitness[0]
+	}
+	return nil, 0
+}
+
+// offer inserts a solution keeping the pool sorted by fitness. Callers hold mu.
+func (p *elitePool) offer(solution []int, fitness int) {
+	if len(p.solutions) == p.capacity && fitness >= p.fitness[len(p.fitness)-1] {
+		return
+	}
+	canonical := p.symmetries.Canonical(solution)
+	for k := range p.solutions {
+		if p.fitness[k] == fitness && equalSolutions(p.canonical[k], canonical) {
+			return
+		}
+	}
+
+	k := sort.SearchInts(p.fitness, fitness)
+	p.solutions = append(p.solutions, nil)
+	p.canonical = append(p.canonical, nil)
+	p.fitness = append(p.fitness, 0)
+	copy(p.solutions[k+1:], p.solutions[k:])
+	copy(p.canonical[k+1:], p.canonical[k:])
+	copy(p.fitness[k+1:], p.fitness[k:])
+	p.solutions[k] = append([]int(nil), solution...)
+	p.canonical[k] = canonical
+	p.fitness[k] = fitness
+
+	if len(p.solutions) > p.capacity {
+		p.solutions = p.solutions[:p.capacity]
+		p.canonical = p.canonical[:p.capacity]
+		p.fitness = p.fitness[:p.capacity]
+	}
+}
+
+// improved records a new best of a worker. A new global best enters the pool
+// and the trajectory right away, other solutions wait for the next exchange.
+func (p *elitePool) improved(solution []int, fitness int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if fitness < p.best {
+		p.offer(solution, fitness)
+		p.best = fitness
+		p.tracker.improved(int(p.evaluations.Load()), fitness)
+	}
+}
+
+// move forwards an accepted move of a worker to the trace
+func (p *elitePool) move(iteration, i, j, delta, fitness int, context string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tracker.move(iteration, i, j, delta, fitness, context)
+}
+
+func (p *elitePool) bestSolution() ([]int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]int(nil), p.solutions[0]...), p.fitness[0]
+}
+
+// rows lists the pool solutions best first, for the run artifacts
+func (p *elitePool) rows() [][]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rows := [][]string{{"Rank", "Fitness", "Solution"}}
+	for k, solution := range p.solutions {
+		rows = append(rows, []string{strconv.Itoa(k + 1), strconv.Itoa(p.tracker.instance.Objective(p.fitness[k])), fmt.Sprint(solution)})
+	}
+	return rows
+}
+
+func equalSolutions(a, b []int) bool {
+	for k := range a {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// tabuWorker is one search thread of the cooperative tabu search. Its moves
+// are evaluated incrementally, the neighborhood is sampled like in
+// TabuSearchSolver.
+type tabuWorker struct {
+	id       int
+	instance *qap.QAPInstance
+	tracker  *runTracker
+	pool     *elitePool
+	rng      *rand.Rand
+
+	current        []int
+	currentFitness int
+	best           []int
+	bestFitness    int
+	tabuList       *tabuMemory
+
+	steps       int
+	evaluations int
+	counts      moveCounts // merged into the run tracker once the worker is done
+	cpuTime     time.Duration
+}
+
+func newTabuWorker(id int, instance *qap.QAPInstance, tracker *runTracker, pool *elitePool, tabuOn string) *tabuWorker {
+	n := instance.Size
+	w := &tabuWorker{
+		id:       id,
+		instance: instance,
+		tracker:  tracker,
+		pool:     pool,
+		rng:      rand.New(rand.NewSource(tracker.rng.Int63())),
+		current:  RandomSolutionFrom(tracker.rng, n),
+		tabuList: newTabuMemory(tabuOn, n),
+	}
+	// The first worker continues from the warm start, the others diversify
+	if id == 0 && len(tracker.warmStart) == n {
+		copy(w.current, tracker.warmStart)
+	}
+	tracker.repair(w.current)
+	w.currentFitness = tracker.fitness(w.current)
+	w.best = append([]int(nil), w.current...)
+	w.bestFitness = w.currentFitness
+	pool.offer(w.best, w.bestFitness)
+	return w
+}
+
+// run searches until maxNoImprovement iterations pass without a new best or
+// stop is set
+func (w *tabuWorker) run(maxNoImprovement, syncInterval int, stop *atomic.Bool) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	cpuStart, _ := pkg.ThreadCPUTime()
+
+	n := w.instance.Size
+	tabuTenure := n / 2
+	possibleSwaps := allSwaps(n)
+	sampleSize := min(max(len(possibleSwaps)/5, 1), len(possibleSwaps))
+
+	noImprovementCounter := 0
+	for iteration := 1; noImprovementCounter < maxNoImprovement && !stop.Load(); iteration++ {
+		if iteration%syncInterval == 0 {
+			if solution, fitness := w.pool.exchange(w.best, w.bestFitness); solution != nil {
+				copy(w.current, solution)
+				copy(w.best, solution)
+				w.currentFitness = fitness
+				w.bestFitness = fitness
+				w.tabuList.clear()
+				noImprovementCounter = 0
+				w.tracker.restarted(fmt.Sprintf("worker %d continues from the elite solution %d", w.id, w.instance.Objective(fitness)))
+			}
+		}
+
+		var candidateMoves []move
+		w.rng.Shuffle(len(possibleSwaps), func(i, j int) {
+			possibleSwaps[i], possibleSwaps[j] = possibleSwaps[j], possibleSwaps[i]
+		})
+		for _, sw := range possibleSwaps[:sampleSize] {
+			i, j := sw[0], sw[1]
+			if !w.tracker.allows(w.current, i, j) {
+				continue
+			}
+
+			newFitness := w.currentFitness + w.tracker.delta(w.current, i, j)
+			w.evaluations++
+
+			isTabu := w.tabuList.isTabu(w.current, i, j, iteration)
+			aspiration := newFitness < w.bestFitness
+
+			candidateMoves = append(candidateMoves, move{i, j, newFitness, isTabu, aspiration})
+		}
+		w.pool.evaluations.Add(int64(len(candidateMoves)))
+		if len(candidateMoves) == 0 {
+			noImprovementCounter++
+			continue
+		}
+
+		sort.Slice(candidateMoves, func(a, b int) bool {
+			return candidateMoves[a].newFitness < candidateMoves[b].newFitness
+		})
+		candidateMoves = candidateMoves[:max(len(candidateMoves)/5, 1)]
+
+		chosen := candidateMoves[0]
+		for _, m := range candidateMoves {
+			if !m.isTabu || m.aspiration {
+				chosen = m
+				break
+			}
+			w.counts.reject()
+		}
+
+		i, j := chosen.i, chosen.j
+		if w.tracker.tracing() {
+			w.pool.move(iteration, i, j, chosen.newFitness-w.currentFitness, chosen.newFitness,
+				fmt.Sprintf("worker=%d tabu=%t aspiration=%t", w.id, chosen.isTabu, chosen.aspiration))
+		}
+		w.tabuList.add(w.current, i, j, iteration+tabuTenure)
+		w.current[i], w.current[j] = w.current[j], w.current[i]
+		w.counts.accept(chosen.newFitness - w.currentFitness)
+		w.currentFitness = chosen.newFitness
+
+		w.steps++
+		w.pool.moves.Add(1)
+
+		if w.currentFitness < w.bestFitness {
+			copy(w.best, w.current)
+			w.bestFitness = w.currentFitness
+			w.pool.improved(w.best, w.bestFitness)
+			noImprovementCounter = 0
+		} else {
+			noImprovementCounter++
+		}
+	}
+
+	if cpuEnd, ok := pkg.ThreadCPUTime(); ok {
+		w.cpuTime = cpuEnd - cpuStart
+	}
+}
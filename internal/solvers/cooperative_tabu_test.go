@@ -0,0 +1,33 @@
+package solvers
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCooperativeTabuStopCondition checks that the coordinator publishes the
+// workers' progress to the stop condition while they improve the pool's
+// best. The workers never give up on their own, so only the condition ends
+// the run before its time limit. Run it with -race to cover the tracker
+// shared by the coordinator and the workers.
+func TestCooperativeTabuStopCondition(t *testing.T) {
+	for _, condition := range []string{"evals > 20000", "moves > 500", "stall > 5000 || fitness < 0"} {
+		t.Run(condition, func(t *testing.T) {
+			stop, err := ParseStopCondition(condition)
+			if err != nil {
+				t.Fatal(err)
+			}
+			const timeLimit = 30 * time.Second
+			solver := NewCooperativeTabuSolver(1<<20, 4, 10, TabuOnAssignments)
+			start := time.Now()
+			result := solver.SolveWithMetrics(randomTestInstance(30, 1), nil, "", 0,
+				SolveOptions{Seed: 1, Stop: stop, TimeLimit: timeLimit})
+			if elapsed := time.Since(start); elapsed >= timeLimit {
+				t.Fatalf("the run took %v, the stop condition never fired", elapsed)
+			}
+			if len(result.Solution) != 30 {
+				t.Fatalf("got a solution of size %d, want 30", len(result.Solution))
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package solvers
+
+import (
+	"fmt"
+	"qap_solver/internal/qap"
+	"sort"
+)
+
+const (
+	// cyclicBeamWidth is how many partial paths per start facility the
+	// improvement graph search extends at each length
+	cyclicBeamWidth = 10
+	// cyclicCandidates is how many cycles with the best estimated gain are
+	// evaluated exactly per step
+	cyclicCandidates = 20
+)
+
+// cycleCandidate is a cyclic exchange: facility cycle[k] moves to the
+// location of cycle[k+1] and the last facility to the location of the first
+type cycleCandidate struct {
+	cycle    []int
+	estimate int
+}
+
+// polishCyclic applies improving cyclic exchanges of 3 to maxLength
+// facilities to solution until none is found or the run has to stop, and
+// returns the new fitness. It is a deep neighborhood meant for polishing near
+// optimal solutions, every step costs O(n^3).
+func polishCyclic(tracker *runTracker, instance *qap.QAPInstance, solution []int, fitness, maxLength int, steps, evaluations *int) int {
+	if maxLength < 3 || instance.Size < 3 {
+		return fitness
+	}
+	for !tracker.shouldStop() {
+		tracker.progress(*evaluations, *steps)
+		cycle, delta := findCyclicExchange(tracker, instance, solution, maxLength, evaluations)
+		if cycle == nil {
+			break
+		}
+
+		*steps++
+		running := fitness
+		for k := 0; k+1 < len(cycle); k++ {
+			i, j := cycle[k], cycle[k+1]
+			if tracker.tracing() {
+				swapDelta := tracker.delta(solution, i, j)
+				running += swapDelta
+				tracker.move(*steps, i, j, swapDelta, running, fmt.Sprintf("cycle=%d", len(cycle)))
+			}
+			solution[i], solution[j] = solution[j], solution[i]
+		}
+		fitness += delta
+		tracker.counts.accept(delta)
+		tracker.improved(*evaluations, fitness)
+	}
+	return fitness
+}
+
+// findCyclicExchange searches the improvement graph of solution for the best
+// improving cyclic exchange. An arc i→j moves facility i to the location of
+// facility j, its cost is the change of the interactions of i with all
+// facilities left in place. The cost of a cycle estimates its fitness change
+// without the interactions among the moved facilities, so a beam search over
+// the graph collects the cycles with the lowest estimates and the most
+// promising ones are evaluated exactly. It returns nil when none improves.
+func findCyclicExchange(tracker *runTracker, instance *qap.QAPInstance, solution []int, maxLength int, evaluations *int) ([]int, int) {
+	arcs := improvementGraph(instance, solution)
+	n := instance.Size
+
+	var candidates []cycleCandidate
+	for start := 0; start < n; start++ {
+		// Cycles are searched from their smallest facility only, so each is
+		// found once
+		beam := []cycleCandidate{{cycle: []int{start}}}
+		for length := 2; length <= maxLength && len(beam) > 0; length++ {
+			var next []cycleCandidate
+			for _, path := range beam {
+				last := path.cycle[len(path.cycle)-1]
+				for j := start + 1; j < n; j++ {
+					if containsFacility(path.cycle, j) {
+						continue
+					}
+					extended := cycleCandidate{
+						cycle:    append(append(make([]int, 0, length), path.cycle...), j),
+						estimate: path.estimate + arcs[last][j],
+					}
+					if length >= 3 && extended.estimate+arcs[j][start] < 0 {
+						candidates = append(candidates, cycleCandidate{
+							cycle:    extended.cycle,
+							estimate: extended.estimate + arcs[j][start],
+						})
+					}
+					next = append(next, extended)
+				}
+			}
+			sort.SliceStable(next, func(a, b int) bool { return next[a].estimate < next[b].estimate })
+			beam = next[:min(len(next), cyclicBeamWidth)]
+		}
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].estimate < candidates[b].estimate })
+	var best []int
+	bestDelta := 0
+	work := make([]int, n)
+	for _, c := range candidates[:min(len(candidates), cyclicCandidates)] {
+		copy(work, solution)
+		delta, ok := cycleDelta(tracker, work, c.cycle)
+		*evaluations++
+		if ok && delta < bestDelta {
+			best, bestDelta = c.cycle, delta
+		}
+	}
+	return best, bestDelta
+}
+
+// cycleDelta applies a cyclic exchange to work as a chain of swaps, facility
+// 0 with 1, 1 with 2 and so on, and returns the exact fitness change. It
+// fails when the tracker does not allow one of the swaps.
+func cycleDelta(tracker *runTracker, work []int, cycle []int) (int, bool) {
+	delta := 0
+	for k := 0; k+1 < len(cycle); k++ {
+		i, j := cycle[k], cycle[k+1]
+		if !tracker.allows(work, i, j) {
+			return 0, false
+		}
+		delta += tracker.delta(work, i, j)
+		work[i], work[j] = work[j], work[i]
+	}
+	return delta, true
+}
+
+// improvementGraph returns the arc costs of the improvement graph, arcs[i][j]
+// is the change of the cost of facility i's flows when it moves to the
+// location of facility j and all other facilities stay
+func improvementGraph(instance *qap.QAPInstance, solution []int) [][]int {
+	n := instance.Size
+	a, b := instance.FlowMatrix, instance.DistanceMatrix
+	sense := instance.Sense()
+
+	// cost of facility i placed at location l
+	placed := func(i, l int) int {
+		cost := a[i][i] * b[l][l]
+		for k := 0; k < n; k++ {
+			if k != i {
+				pk := solution[k]
+				cost += a[i][k]*b[l][pk] + a[k][i]*b[pk][l]
+			}
+		}
+		return cost
+	}
+
+	arcs := make([][]int, n)
+	for i := range arcs {
+		arcs[i] = make([]int, n)
+		current := placed(i, solution[i])
+		for j := 0; j < n; j++ {
+			if j != i {
+				arcs[i][j] = sense * (placed(i, solution[j]) - current)
+			}
+		}
+	}
+	return arcs
+}
+
+func containsFacility(cycle []int, facility int) bool {
+	for _, f := range cycle {
+		if f == facility {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package solvers
+
+import (
+	"fmt"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"time"
+)
+
+// minComponentTimeLimit is the time limit of a component solved after the
+// run's time limit has passed, see DecomposeSolver
+const minComponentTimeLimit = time.Millisecond
+
+// DecomposeSolver exploits instances whose flow graph falls apart, such as
+// many esc and chr instances: facilities without any flow are left out, they
+// cost nothing wherever they go, and every connected component of the
+// remaining flow graph is solved by the inner solver as a sub-instance.
+// With a single component it gets all locations, the unused ones padded
+// with dummies, so the decomposition loses nothing. Several components get
+// disjoint location clusters of their size, grown by distance with the
+// largest component first; a best-improvement swap descent on the whole
+// instance then lets them trade locations. Instances that do not decompose,
+// or whose fitness has relocation, constraint or stochastic terms or is
+// replaced by a custom Fitness, are solved by the inner solver as a whole.
+type DecomposeSolver struct {
+	Inner         Solver
+	MaxIterations int // swap descent steps after recombining several components
+}
+
+func NewDecomposeSolver(inner Solver, maxIterations int) *DecomposeSolver {
+	return &DecomposeSolver{Inner: inner, MaxIterations: maxIterations}
+}
+
+func (s *DecomposeSolver) Name() string {
+	return "Decompose(" + s.Inner.Name() + ")"
+}
+
+func (s *DecomposeSolver) Description() string {
+	return fmt.Sprintf("Flow graph decomposition solving each component with %s (%d descent iterations)",
+		s.Inner.Name(), s.MaxIterations)
+}
+
+func (s *DecomposeSolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsCancellation: true, SupportsTimeBudget: true, SupportsSwapRadius: true}
+}
+
+// Deterministic reports whether the inner solver is, the decomposition has
+// no random choices
+func (s *DecomposeSolver) Deterministic() bool {
+	return IsDeterministic(s.Inner)
+}
+
+func (s *DecomposeSolver) Solve(instance *qap.QAPInstance) SolverResult {
+	return s.SolveWithMetrics(instance, nil, "", 0, SolveOptions{})
+}
+
+func (s *DecomposeSolver) SolveWithMetrics(
+	instance *qap.QAPInstance,
+	metricsCollector *metrics.MetricsCollector,
+	instanceName string,
+	runNumber int,
+	opts SolveOptions,
+) SolverResult {
+	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
+	n := instance.Size
+
+	components, free := qap.FlowComponents(instance)
+	decomposable := instance.Relocation == nil && instance.Constraints == nil && instance.Stochastic == nil &&
+		opts.Fitness == nil && (len(components) > 1 || len(free) > 0) && len(components) > 0
+
+	var current []int
+	totalSteps, totalEvaluations := 0, 0
+	segment := func(sub *qap.QAPInstance, limit time.Duration) metrics.RunMetrics {
+		run := runSegment(s.Inner, sub, instanceName, SolveOptions{
+			MemoryLimit: opts.MemoryLimit,
+			TimeLimit:   limit,
+			Forbidden:   opts.Forbidden,
+			Fitness:     opts.Fitness,
+			Delta:       opts.Delta,
+			SwapRadius:  opts.SwapRadius,
+			Seed:        tracker.rng.Int63(),
+		})
+		totalSteps += run.StepsCount
+		totalEvaluations += run.EvaluationsCount
+		tracker.counts.add(moveCounts{run.AcceptedMoves, run.RejectedMoves, run.ImprovingMoves})
+		tracker.addOverhead(run.OverheadTime)
+		return run
+	}
+
+	if !decomposable {
+		current = segment(instance, opts.TimeLimit).Solution
+	} else {
+		current = make([]int, n)
+		locationSets := componentLocations(instance, components)
+		remaining := 0
+		for _, component := range components {
+			remaining += len(component)
+		}
+		used := make([]bool, n)
+		for c, component := range components {
+			// Components share the time limit by size. Every component needs
+			// a solution, so those left after the earlier ones overran the
+			// limit still get a minimal one rather than none, which would
+			// mean no limit at all.
+			limit := time.Duration(0)
+			if opts.TimeLimit > 0 {
+				limit = (opts.TimeLimit - time.Since(startTime)) * time.Duration(len(component)) / time.Duration(remaining)
+				limit = max(limit, minComponentTimeLimit)
+				remaining -= len(component)
+			}
+			run := segment(qap.SubInstance(instance, component, locationSets[c]), limit)
+			for k, facility := range component {
+				current[facility] = locationSets[c][run.Solution[k]]
+				used[current[facility]] = true
+			}
+		}
+		// Free facilities take the locations left over
+		next := 0
+		for _, facility := range free {
+			for used[next] {
+				next++
+			}
+			current[facility] = next
+			used[next] = true
+		}
+	}
+
+	currentFitness := tracker.fitness(current)
+	initialFitness := currentFitness
+	totalEvaluations++
+	tracker.improved(totalEvaluations, currentFitness)
+
+	// Components placed on separate clusters may gain from trading locations
+	for iter := 0; decomposable && len(components) > 1 && iter < s.MaxIterations && !tracker.shouldStop(); iter++ {
+		tracker.progress(totalEvaluations, totalSteps)
+		bestDelta, bestI, bestJ := 0, -1, -1
+		for i := 0; i < n-1; i++ {
+			for j := i + 1; j < n; j++ {
+				if !tracker.allows(current, i, j) {
+					continue
+				}
+				delta := tracker.delta(current, i, j)
+				totalEvaluations++
+				if delta < bestDelta {
+					bestDelta, bestI, bestJ = delta, i, j
+				}
+			}
+		}
+		if bestI < 0 {
+			break
+		}
+
+		tracker.move(iter, bestI, bestJ, bestDelta, currentFitness+bestDelta, "")
+		current[bestI], current[bestJ] = current[bestJ], current[bestI]
+		currentFitness += bestDelta
+		totalSteps++
+		tracker.counts.accept(bestDelta)
+		tracker.improved(totalEvaluations, currentFitness)
+	}
+
+	elapsedTime := time.Since(startTime)
+
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     currentFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalEvaluations,
+		Solution:         current,
+	})
+
+	return SolverResult{
+		Solution: current,
+		Fitness:  currentFitness,
+	}
+}
+
+// componentLocations returns the locations of every flow component: all
+// locations for a single component, otherwise disjoint clusters of the
+// component sizes. Each cluster starts from the free location closest to the
+// other free ones and grows by the location closest to its members, or the
+// farthest for maximization instances.
+func componentLocations(instance *qap.QAPInstance, components [][]int) [][]int {
+	n := instance.Size
+	if len(components) == 1 {
+		all := make([]int, n)
+		for l := range all {
+			all[l] = l
+		}
+		return [][]int{all}
+	}
+
+	distance := instance.DistanceMatrix
+	sense := float64(instance.Sense())
+	free := make([]bool, n)
+	for l := range free {
+		free[l] = true
+	}
+	locations := make([][]int, len(components))
+	for c, component := range components {
+		locations[c] = growCluster(free, len(component),
+			func(candidate int) float64 {
+				total := 0
+				for m, ok := range free {
+					if ok {
+						total += distance[candidate][m] + distance[m][candidate]
+					}
+				}
+				return sense * float64(total)
+			},
+			func(candidate int, members []int) float64 {
+				total := 0
+				for _, m := range members {
+					total += distance[candidate][m] + distance[m][candidate]
+				}
+				return sense * float64(total)
+			})
+	}
+	return locations
+}
@@ -0,0 +1,61 @@
+package solvers
+
+import (
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"testing"
+	"time"
+)
+
+// overrunSolver ignores its time limit and sleeps past it, recording the
+// limits it was given
+type overrunSolver struct {
+	sleep  time.Duration
+	limits []time.Duration
+}
+
+func (s *overrunSolver) Name() string        { return "Overrun" }
+func (s *overrunSolver) Description() string { return "sleeps past its time limit" }
+
+func (s *overrunSolver) Solve(instance *qap.QAPInstance) SolverResult {
+	return s.SolveWithMetrics(instance, nil, "", 0, SolveOptions{})
+}
+
+func (s *overrunSolver) SolveWithMetrics(instance *qap.QAPInstance, _ *metrics.MetricsCollector, _ string, _ int, opts SolveOptions) SolverResult {
+	s.limits = append(s.limits, opts.TimeLimit)
+	time.Sleep(s.sleep)
+	solution := make([]int, instance.Size)
+	for i := range solution {
+		solution[i] = i
+	}
+	return SolverResult{Solution: solution, Fitness: qap.CalculateFitness(instance, solution)}
+}
+
+// TestDecomposeOverrunComponent checks that the components solved after an
+// earlier one overran the time limit still get a positive limit
+func TestDecomposeOverrunComponent(t *testing.T) {
+	// Two components, {0, 1} and {2, 3, 4}
+	flow := [][]int{
+		{0, 3, 0, 0, 0},
+		{3, 0, 0, 0, 0},
+		{0, 0, 0, 2, 1},
+		{0, 0, 2, 0, 4},
+		{0, 0, 1, 4, 0},
+	}
+	instance := &qap.QAPInstance{Size: 5, FlowMatrix: flow, DistanceMatrix: randomTestInstance(5, 1).DistanceMatrix}
+	inner := &overrunSolver{sleep: 30 * time.Millisecond}
+	result := NewDecomposeSolver(inner, 10).SolveWithMetrics(instance, nil, "", 0,
+		SolveOptions{Seed: 1, TimeLimit: 20 * time.Millisecond})
+
+	if len(inner.limits) != 2 {
+		t.Fatalf("the inner solver ran %d times, want once per component", len(inner.limits))
+	}
+	for c, limit := range inner.limits {
+		if limit <= 0 {
+			t.Errorf("component %d got the time limit %v, want a positive one", c, limit)
+		}
+	}
+	if err := qap.ValidatePermutation(result.Solution); err != nil {
+		t.Errorf("solution %v: %v", result.Solution, err)
+	}
+}
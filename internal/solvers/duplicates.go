@@ -0,0 +1,31 @@
+package solvers
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DeduplicateSolvers drops solvers configured exactly like an earlier one,
+// e.g. a configuration listed twice, and returns the kept solvers and for
+// every dropped position the position of the solver it repeats. Solvers that
+// share a name but differ in their configuration are an error, their runs
+// would be counted together in the results; labels tell them apart.
+func DeduplicateSolvers(list []Solver) ([]Solver, map[int]int, error) {
+	var unique []Solver
+	kept := make(map[string]int) // name to position in list
+	dropped := make(map[int]int)
+	for i, solver := range list {
+		earlier, seen := kept[solver.Name()]
+		if !seen {
+			kept[solver.Name()] = i
+			unique = append(unique, solver)
+			continue
+		}
+		if !reflect.DeepEqual(list[earlier], solver) {
+			return nil, nil, fmt.Errorf("solvers %d and %d are both named %s but configured differently, "+
+				"give them distinct names with @label=", earlier+1, i+1, solver.Name())
+		}
+		dropped[i] = earlier
+	}
+	return unique, dropped, nil
+}
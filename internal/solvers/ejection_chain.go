@@ -0,0 +1,165 @@
+package solvers
+
+import (
+	"fmt"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"time"
+)
+
+// EjectionChainSolver is a variable-depth local search. Each step builds a
+// chain of correlated swaps: the facility displaced by one swap is the one
+// moved by the next. The best prefix of the chain is applied when it improves,
+// so moves that need a few worsening swaps first can still be found.
+type EjectionChainSolver struct {
+	Depth         int
+	MaxIterations int
+}
+
+func NewEjectionChainSolver(depth, maxIterations int) *EjectionChainSolver {
+	return &EjectionChainSolver{
+		Depth:         depth,
+		MaxIterations: maxIterations,
+	}
+}
+
+func (s *EjectionChainSolver) Name() string {
+	return "EjectionChain"
+}
+
+func (s *EjectionChainSolver) Description() string {
+	return fmt.Sprintf("Variable-depth ejection chain search (depth %d)", s.Depth)
+}
+
+func (s *EjectionChainSolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsWarmStart: true, SupportsCancellation: true, SupportsTimeBudget: true, SupportsSwapRadius: true}
+}
+
+func (s *EjectionChainSolver) Solve(instance *qap.QAPInstance) SolverResult {
+	return s.SolveWithMetrics(instance, nil, "", 0, SolveOptions{})
+}
+
+func (s *EjectionChainSolver) SolveWithMetrics(
+	instance *qap.QAPInstance,
+	metricsCollector *metrics.MetricsCollector,
+	instanceName string,
+	runNumber int,
+	opts SolveOptions,
+) SolverResult {
+	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
+
+	n := instance.Size
+	current := tracker.initialSolution(n)
+	tracker.repair(current)
+	currentFitness := tracker.fitness(current)
+	initialFitness := currentFitness
+	tracker.improved(0, initialFitness)
+
+	totalSteps := 0
+	totalEvaluations := 0
+
+	for iter := 0; iter < s.MaxIterations && !tracker.shouldStop(); iter++ {
+		tracker.progress(totalEvaluations, totalSteps)
+		chain, gain, evaluations := s.buildChain(instance, current, tracker)
+		totalEvaluations += evaluations
+
+		// No prefix of the chain improves: current is a local optimum
+		if len(chain) == 0 {
+			break
+		}
+
+		running := currentFitness
+		for k, link := range chain {
+			running += link.delta
+			if tracker.tracing() {
+				tracker.move(iter, link.i, link.j, link.delta, running, fmt.Sprintf("depth=%d", k+1))
+			}
+			current[link.i], current[link.j] = current[link.j], current[link.i]
+		}
+		currentFitness += gain
+		totalSteps++
+		tracker.counts.accept(gain)
+		tracker.improved(totalEvaluations, currentFitness)
+	}
+
+	elapsedTime := time.Since(startTime)
+
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     currentFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalEvaluations,
+		Solution:         current,
+	})
+
+	return SolverResult{
+		Solution: current,
+		Fitness:  currentFitness,
+	}
+}
+
+// chainLink is a swap of an ejection chain and its fitness change, given
+// the swaps before it in the chain
+type chainLink struct {
+	i, j  int
+	delta int
+}
+
+// buildChain greedily builds a chain of up to Depth swaps on a copy of solution
+// and returns its best improving prefix together with the total fitness change.
+// Swaps the tracker does not allow are never part of a chain.
+func (s *EjectionChainSolver) buildChain(instance *qap.QAPInstance, solution []int, tracker *runTracker) ([]chainLink, int, int) {
+	n := instance.Size
+	work := make([]int, n)
+	copy(work, solution)
+	locked := make([]bool, n)
+
+	var chain []chainLink
+	evaluations := 0
+	cumulative, bestCumulative, bestLength := 0, 0, 0
+	ejected := -1
+
+	for depth := 0; depth < s.Depth; depth++ {
+		best := chainLink{i: -1}
+
+		// The first swap may use any pair, later swaps continue from the
+		// facility ejected by the previous one
+		for i := 0; i < n; i++ {
+			if locked[i] || (ejected >= 0 && i != ejected) {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				if j == i || locked[j] || (ejected < 0 && j < i) || !tracker.allows(work, i, j) {
+					continue
+				}
+				delta := tracker.delta(work, i, j)
+				evaluations++
+				if best.i < 0 || delta < best.delta {
+					best = chainLink{i: i, j: j, delta: delta}
+				}
+			}
+		}
+		if best.i < 0 {
+			break
+		}
+
+		work[best.i], work[best.j] = work[best.j], work[best.i]
+		locked[best.i] = true
+		ejected = best.j
+		cumulative += best.delta
+		chain = append(chain, best)
+
+		if cumulative < bestCumulative {
+			bestCumulative = cumulative
+			bestLength = len(chain)
+		}
+	}
+
+	return chain[:bestLength], bestCumulative, evaluations
+}
@@ -0,0 +1,58 @@
+package solvers
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"strconv"
+	"testing"
+)
+
+// TestEjectionChainTrace replays the trace of a run from its warm start and
+// checks that every link of a chain logs its own delta and the fitness
+// reached after it
+func TestEjectionChainTrace(t *testing.T) {
+	instance := randomTestInstance(20, 1)
+	path := filepath.Join(t.TempDir(), "trace.csv")
+	trace, err := metrics.NewTraceWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	solution := RandomSolution(instance.Size)
+	start := append([]int(nil), solution...)
+	NewEjectionChainSolver(5, 100).SolveWithMetrics(instance, nil, "", 0,
+		SolveOptions{Seed: 1, Start: start, Trace: trace})
+	if err := trace.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) < 3 {
+		t.Fatalf("the trace has %d moves, want a few", len(rows)-1)
+	}
+	fitness := qap.CalculateFitness(instance, solution)
+	for _, row := range rows[1:] {
+		var values [5]int
+		for k := range values {
+			values[k], _ = strconv.Atoi(row[k])
+		}
+		i, j, delta, logged := values[1], values[2], values[3], values[4]
+		solution[i], solution[j] = solution[j], solution[i]
+		after := qap.CalculateFitness(instance, solution)
+		if delta != after-fitness || logged != after {
+			t.Fatalf("trace row %v: swap (%d, %d) changes the fitness from %d to %d, logged delta %d and fitness %d",
+				row, i, j, fitness, after, delta, logged)
+		}
+		fitness = after
+	}
+}
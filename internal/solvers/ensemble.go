@@ -0,0 +1,144 @@
+package solvers
+
+import (
+	"fmt"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"strings"
+	"time"
+)
+
+// EnsembleSolver runs its members in turn, each for a slice of time, and
+// passes the incumbent between them: every slice starts a member from the
+// best solution found so far. With a run time limit the slices continue
+// round-robin until it is used up, the last slice being cut short; without
+// one the ensemble stops after Rounds rounds. Members without warm start
+// support restart from scratch, but their result still counts.
+type EnsembleSolver struct {
+	Members []Solver
+	Slice   time.Duration
+	Rounds  int // rounds through all members when the run has no time limit
+}
+
+func NewEnsembleSolver(members []Solver, slice time.Duration, rounds int) *EnsembleSolver {
+	return &EnsembleSolver{
+		Members: members,
+		Slice:   slice,
+		Rounds:  rounds,
+	}
+}
+
+func (s *EnsembleSolver) Name() string {
+	names := make([]string, len(s.Members))
+	for i, member := range s.Members {
+		names[i] = member.Name()
+	}
+	return "Ensemble(" + strings.Join(names, "+") + ")"
+}
+
+func (s *EnsembleSolver) Description() string {
+	return fmt.Sprintf("Ensemble of %d solvers in slices of %v", len(s.Members), s.Slice)
+}
+
+func (s *EnsembleSolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsWarmStart: true, SupportsCancellation: true, SupportsTimeBudget: true, SupportsSwapRadius: true}
+}
+
+func (s *EnsembleSolver) Solve(instance *qap.QAPInstance) SolverResult {
+	return s.SolveWithMetrics(instance, nil, "", 0, SolveOptions{})
+}
+
+func (s *EnsembleSolver) SolveWithMetrics(
+	instance *qap.QAPInstance,
+	metricsCollector *metrics.MetricsCollector,
+	instanceName string,
+	runNumber int,
+	opts SolveOptions,
+) SolverResult {
+	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
+
+	n := instance.Size
+	best := tracker.initialSolution(n)
+	tracker.repair(best)
+	bestFitness := tracker.fitness(best)
+	initialFitness := bestFitness
+	tracker.improved(0, initialFitness)
+
+	totalSteps := 0
+	totalEvaluations := 0
+	slices := s.Rounds * len(s.Members)
+	for k := 0; (opts.TimeLimit > 0 || k < slices) && !tracker.shouldStop(); k++ {
+		tracker.progress(totalEvaluations, totalSteps)
+
+		limit := s.Slice
+		if opts.TimeLimit > 0 {
+			limit = min(limit, opts.TimeLimit-time.Since(startTime))
+		}
+		if limit <= 0 {
+			break
+		}
+
+		// Members record into a private collector so their counts can be
+		// added to the ensemble's run
+		member := s.Members[k%len(s.Members)]
+		run := runSegment(member, instance, instanceName, SolveOptions{
+			MemoryLimit: opts.MemoryLimit,
+			TimeLimit:   limit,
+			Forbidden:   opts.Forbidden,
+			Fitness:     opts.Fitness,
+			Delta:       opts.Delta,
+			SwapRadius:  opts.SwapRadius,
+			Seed:        tracker.rng.Int63(),
+			Start:       best,
+		})
+		totalSteps += run.StepsCount
+		totalEvaluations += run.EvaluationsCount
+		tracker.counts.add(moveCounts{run.AcceptedMoves, run.RejectedMoves, run.ImprovingMoves})
+		tracker.addOverhead(run.OverheadTime)
+
+		if run.FinalFitness < bestFitness {
+			copy(best, run.Solution)
+			bestFitness = run.FinalFitness
+			tracker.improved(totalEvaluations, bestFitness)
+		}
+	}
+
+	elapsedTime := time.Since(startTime)
+
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     bestFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalEvaluations,
+		Solution:         best,
+	})
+
+	return SolverResult{
+		Solution: best,
+		Fitness:  bestFitness,
+	}
+}
+
+// runSegment runs a solver for one part of a run, such as an ensemble slice
+// or a restart segment, and returns its run metrics. The instance name lets
+// solvers with params=auto find their profile. Solvers without
+// SolveWithMetrics only report their result.
+func runSegment(solver Solver, instance *qap.QAPInstance, instanceName string, opts SolveOptions) metrics.RunMetrics {
+	inner, ok := solver.(metricsSolver)
+	if !ok {
+		result := solver.Solve(instance)
+		return metrics.RunMetrics{FinalFitness: result.Fitness, Solution: result.Solution}
+	}
+	collector := &metrics.MetricsCollector{Experiments: make(map[string]map[string]*metrics.ExperimentMetrics)}
+	result := inner.SolveWithMetrics(instance, collector, instanceName, 1, opts)
+	for _, experiment := range collector.Experiments[instanceName] {
+		return experiment.Runs[0]
+	}
+	return metrics.RunMetrics{FinalFitness: result.Fitness, Solution: result.Solution}
+}
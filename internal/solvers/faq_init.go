@@ -0,0 +1,315 @@
+package solvers
+
+import (
+	"fmt"
+	"math"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"time"
+)
+
+// FAQInitSolver builds a solution from the continuous relaxation of the QAP
+// (the FAQ method of Vogelstein et al.): the permutation matrix is relaxed to
+// a doubly stochastic matrix, Frank-Wolfe steps descend on the relaxed cost
+// starting from the barycenter, and the result is projected back to the
+// nearest permutation with the Hungarian method. Every Frank-Wolfe direction
+// is a permutation too; the best of these and the projection is kept. The
+// construction can be refined with a best-improvement swap descent, but it is
+// mainly a starting point for local search on large instances, where it is
+// far better than a random permutation.
+type FAQInitSolver struct {
+	Iterations    int // Frank-Wolfe steps
+	MaxIterations int // refinement steps, 0 returns the construction unrefined
+}
+
+func NewFAQInitSolver(iterations, maxIterations int) *FAQInitSolver {
+	return &FAQInitSolver{Iterations: iterations, MaxIterations: maxIterations}
+}
+
+func (s *FAQInitSolver) Name() string {
+	return "FAQInit"
+}
+
+func (s *FAQInitSolver) Description() string {
+	return fmt.Sprintf("Frank-Wolfe on the doubly stochastic relaxation, projected by the Hungarian method (%d iterations, %d refinement steps)",
+		s.Iterations, s.MaxIterations)
+}
+
+func (s *FAQInitSolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsCancellation: true, SupportsTimeBudget: true, SupportsSwapRadius: true}
+}
+
+// Deterministic reports true, the relaxation starts from the barycenter and
+// has no random choices
+func (s *FAQInitSolver) Deterministic() bool {
+	return true
+}
+
+func (s *FAQInitSolver) Solve(instance *qap.QAPInstance) SolverResult {
+	return s.SolveWithMetrics(instance, nil, "", 0, SolveOptions{})
+}
+
+func (s *FAQInitSolver) SolveWithMetrics(
+	instance *qap.QAPInstance,
+	metricsCollector *metrics.MetricsCollector,
+	instanceName string,
+	runNumber int,
+	opts SolveOptions,
+) SolverResult {
+	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
+
+	n := instance.Size
+	var current []int
+	currentFitness := 0
+	totalEvaluations := 0
+	consider := func(candidate []int) {
+		tracker.repair(candidate)
+		fitness := tracker.fitness(candidate)
+		totalEvaluations++
+		if current == nil || fitness < currentFitness {
+			current, currentFitness = candidate, fitness
+		}
+	}
+	cached := tracker.construction(instanceName, fmt.Sprintf("faqinit_iters%d", s.Iterations), func() []int {
+		faqConstruction(instance, s.Iterations, tracker, consider)
+		return current
+	})
+	if current == nil {
+		// Taken from the construction cache, only the final permutation is evaluated
+		consider(cached)
+	}
+	initialFitness := currentFitness
+	tracker.improved(totalEvaluations, currentFitness)
+
+	// Best-improvement swap descent
+	totalSteps := 0
+	for iter := 0; iter < s.MaxIterations && !tracker.shouldStop(); iter++ {
+		tracker.progress(totalEvaluations, totalSteps)
+		bestDelta, bestI, bestJ := 0, -1, -1
+		for i := 0; i < n-1; i++ {
+			for j := i + 1; j < n; j++ {
+				if !tracker.allows(current, i, j) {
+					continue
+				}
+				delta := tracker.delta(current, i, j)
+				totalEvaluations++
+				if delta < bestDelta {
+					bestDelta, bestI, bestJ = delta, i, j
+				}
+			}
+		}
+		if bestI < 0 {
+			break
+		}
+
+		tracker.move(iter, bestI, bestJ, bestDelta, currentFitness+bestDelta, "")
+		current[bestI], current[bestJ] = current[bestJ], current[bestI]
+		currentFitness += bestDelta
+		totalSteps++
+		tracker.counts.accept(bestDelta)
+		tracker.improved(totalEvaluations, currentFitness)
+	}
+
+	elapsedTime := time.Since(startTime)
+
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     currentFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalEvaluations,
+		Solution:         current,
+	})
+
+	return SolverResult{
+		Solution: current,
+		Fitness:  currentFitness,
+	}
+}
+
+// faqConstruction runs Frank-Wolfe on the relaxed cost <F, P D P^T> over
+// doubly stochastic P, where P[facility][location] is the share of a
+// facility on a location. Every permutation it visits, the directions and
+// the final projection, is passed to consider as solution[facility] =
+// location. The relaxation covers the QAP cost only, relocation costs and
+// constraints count when consider evaluates the permutations.
+func faqConstruction(instance *qap.QAPInstance, iterations int, tracker *runTracker, consider func([]int)) {
+	n := instance.Size
+	sense := float64(instance.Sense())
+	flow := make([][]float64, n)
+	distance := make([][]float64, n)
+	p := make([][]float64, n)
+	for i := 0; i < n; i++ {
+		flow[i] = make([]float64, n)
+		distance[i] = make([]float64, n)
+		p[i] = make([]float64, n)
+		for j := 0; j < n; j++ {
+			flow[i][j] = sense * float64(instance.FlowMatrix[i][j])
+			distance[i][j] = float64(instance.DistanceMatrix[i][j])
+			p[i][j] = 1 / float64(n)
+		}
+	}
+
+	// cost is <F, P D P^T>, kept up to date along the line searches
+	pd := multiplyMatrices(p, distance)
+	cost := 0.0
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			pdp := 0.0
+			for l := 0; l < n; l++ {
+				pdp += pd[i][l] * p[j][l]
+			}
+			cost += flow[i][j] * pdp
+		}
+	}
+
+	for iter := 0; iter < iterations && !tracker.shouldStop(); iter++ {
+		// Gradient F P D^T + F^T P D
+		pd = multiplyMatrices(p, distance)
+		pdt := make([][]float64, n) // P D^T
+		for i := 0; i < n; i++ {
+			pdt[i] = make([]float64, n)
+			for l, share := range p[i] {
+				if share == 0 {
+					continue
+				}
+				for k := 0; k < n; k++ {
+					pdt[i][k] += share * distance[k][l]
+				}
+			}
+		}
+		gradient := multiplyMatrices(flow, pdt)
+		for k := 0; k < n; k++ {
+			for i := 0; i < n; i++ {
+				if flow[k][i] == 0 {
+					continue
+				}
+				for j := 0; j < n; j++ {
+					gradient[i][j] += flow[k][i] * pd[k][j]
+				}
+			}
+		}
+
+		// The direction is the permutation minimizing the linearized cost
+		q := linearAssignment(gradient)
+		consider(append([]int(nil), q...))
+
+		// Exact line search on P + a(Q - P), the cost is quadratic in a
+		var pdq, qdp, qdq float64 // <F, P D Q^T>, <F, Q D P^T>, <F, Q D Q^T>
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				pdq += flow[i][j] * pd[i][q[j]]
+				qdp += flow[i][j] * pdt[j][q[i]]
+				qdq += flow[i][j] * distance[q[i]][q[j]]
+			}
+		}
+		linear := pdq + qdp - 2*cost
+		quadratic := qdq - pdq - qdp + cost
+		step := 1.0
+		if quadratic > 0 {
+			step = math.Min(math.Max(-linear/(2*quadratic), 0), 1)
+		} else if linear+quadratic >= 0 {
+			step = 0
+		}
+		if step == 0 {
+			break // P minimizes the relaxation along every direction
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				p[i][j] *= 1 - step
+			}
+			p[i][q[i]] += step
+		}
+		cost += step*linear + step*step*quadratic
+	}
+
+	// The nearest permutation maximizes <P, Q>
+	negated := make([][]float64, n)
+	for i := range negated {
+		negated[i] = make([]float64, n)
+		for j := range negated[i] {
+			negated[i][j] = -p[i][j]
+		}
+	}
+	consider(linearAssignment(negated))
+}
+
+// multiplyMatrices returns the product a b of square matrices
+func multiplyMatrices(a, b [][]float64) [][]float64 {
+	n := len(a)
+	product := make([][]float64, n)
+	for i := 0; i < n; i++ {
+		product[i] = make([]float64, n)
+		for k, v := range a[i] {
+			if v == 0 {
+				continue
+			}
+			for j, w := range b[k] {
+				product[i][j] += v * w
+			}
+		}
+	}
+	return product
+}
+
+// linearAssignment solves the linear assignment problem with the Hungarian
+// method in O(n^3): it returns the permutation assignment[row] = column with
+// the lowest total cost.
+func linearAssignment(cost [][]float64) []int {
+	n := len(cost)
+	// Potentials and matching use 1-based indices, index 0 is the free column
+	u := make([]float64, n+1)
+	v := make([]float64, n+1)
+	match := make([]int, n+1) // row matched to each column
+	way := make([]int, n+1)
+	for row := 1; row <= n; row++ {
+		match[0] = row
+		column := 0
+		minimum := make([]float64, n+1)
+		used := make([]bool, n+1)
+		for j := range minimum {
+			minimum[j] = math.Inf(1)
+		}
+		for match[column] != 0 {
+			used[column] = true
+			i, delta, next := match[column], math.Inf(1), 0
+			for j := 1; j <= n; j++ {
+				if used[j] {
+					continue
+				}
+				if reduced := cost[i-1][j-1] - u[i] - v[j]; reduced < minimum[j] {
+					minimum[j], way[j] = reduced, column
+				}
+				if minimum[j] < delta {
+					delta, next = minimum[j], j
+				}
+			}
+			for j := 0; j <= n; j++ {
+				if used[j] {
+					u[match[j]] += delta
+					v[j] -= delta
+				} else {
+					minimum[j] -= delta
+				}
+			}
+			column = next
+		}
+		// Augment along the alternating path
+		for column != 0 {
+			previous := way[column]
+			match[column] = match[previous]
+			column = previous
+		}
+	}
+
+	assignment := make([]int, n)
+	for j := 1; j <= n; j++ {
+		assignment[match[j]-1] = j - 1
+	}
+	return assignment
+}
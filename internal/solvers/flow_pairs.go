@@ -0,0 +1,63 @@
+package solvers
+
+import (
+	"math/rand"
+	"qap_solver/internal/qap"
+	"sort"
+)
+
+// Neighbor selection biases for simulated annealing
+const (
+	BiasUniform  = "uniform"  // every swap is equally likely
+	BiasFlow     = "flow"     // prefer swaps of facilities with high flow interaction
+	BiasAdaptive = "adaptive" // start with the flow bias and move to uniform as the search cools
+)
+
+// flowBiasFraction is the share of all facility pairs kept in the flow-ranked list
+const flowBiasFraction = 0.2
+
+// flowBiasProbability is how often the flow bias draws from the ranked list
+// instead of picking a uniform swap, which keeps every swap reachable
+const flowBiasProbability = 0.8
+
+// flowRankedPairs lists facility pairs ordered by their flow interaction
+// F[i][j] + F[j][i], strongest first. Swapping such facilities changes the
+// fitness the most, so they are the impactful moves early in a search.
+type flowRankedPairs struct {
+	pairs [][2]int
+}
+
+func newFlowRankedPairs(instance *qap.QAPInstance) *flowRankedPairs {
+	n := instance.FacilityCount()
+	var pairs [][2]int
+	for _, p := range allSwaps(instance.Size) {
+		// Swaps of two dummy facilities change nothing
+		if !instance.IsDummy(p[0]) || !instance.IsDummy(p[1]) {
+			pairs = append(pairs, p)
+		}
+	}
+	interaction := func(p [2]int) int {
+		return instance.FlowMatrix[p[0]][p[1]] + instance.FlowMatrix[p[1]][p[0]]
+	}
+	sort.SliceStable(pairs, func(a, b int) bool {
+		return interaction(pairs[a]) > interaction(pairs[b])
+	})
+
+	keep := max(int(float64(len(pairs))*flowBiasFraction), min(n, len(pairs)))
+	return &flowRankedPairs{pairs: pairs[:keep]}
+}
+
+// pick returns a pair of distinct positions. With probability
+// flowBias*flowBiasProbability it comes from the ranked list, otherwise it is
+// uniform, see randomSwap. A nil rng uses the global math/rand source.
+func (f *flowRankedPairs) pick(rng *rand.Rand, instance *qap.QAPInstance, flowBias float64) (int, int) {
+	float64n, intn := rand.Float64, rand.Intn
+	if rng != nil {
+		float64n, intn = rng.Float64, rng.Intn
+	}
+	if flowBias > 0 && len(f.pairs) > 0 && float64n() < flowBias*flowBiasProbability {
+		p := f.pairs[intn(len(f.pairs))]
+		return p[0], p[1]
+	}
+	return randomSwap(rng, instance)
+}
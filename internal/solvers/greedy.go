@@ -26,6 +26,10 @@ func (s *GreedySolver) Description() string {
 	return fmt.Sprintf("Greedy search")
 }
 
+func (s *GreedySolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsWarmStart: true, SupportsCancellation: true, SupportsTimeBudget: true, SupportsSwapRadius: true}
+}
+
 func (s *GreedySolver) Solve(instance *qap.QAPInstance) SolverResult {
 	currentSolution := RandomSolution(instance.Size)
 	currentFitness := qap.CalculateFitness(instance, currentSolution)
@@ -62,12 +66,15 @@ func (s *GreedySolver) SolveWithMetrics(
 	metricsCollector *metrics.MetricsCollector,
 	instanceName string,
 	runNumber int,
+	opts SolveOptions,
 ) SolverResult {
 	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
 
 	// Initial values for solution and fitness
-	currentSolution := RandomSolution(instance.Size)
-	currentFitness := qap.CalculateFitness(instance, currentSolution)
+	currentSolution := tracker.initialSolution(instance.Size)
+	tracker.repair(currentSolution)
+	currentFitness := tracker.fitness(currentSolution)
 
 	// Metrics counters
 	totalSteps := 0
@@ -81,26 +88,37 @@ func (s *GreedySolver) SolveWithMetrics(
 	initialSolution = make([]int, len(currentSolution))
 	copy(initialSolution, currentSolution)
 	initialFitness = currentFitness
+	tracker.improved(0, initialFitness)
 
 	// Start the greedy search iterations until no improvement
 	for iter := 0; iter < s.MaxIterations; iter++ {
+		if tracker.shouldStop() {
+			break
+		}
+		tracker.progress(totalEvaluations, totalSteps)
 		improved := false
 
 		// Try to improve the current solution by checking neighbors
 		for i := 0; i < instance.Size-1; i++ {
 			for j := i + 1; j < instance.Size; j++ {
+				if !tracker.allows(currentSolution, i, j) {
+					continue
+				}
 				newSolution := make([]int, instance.Size)
 				copy(newSolution, currentSolution)
 				newSolution[i], newSolution[j] = newSolution[j], newSolution[i]
-				newFitness := qap.CalculateFitness(instance, newSolution)
+				newFitness := tracker.fitness(newSolution)
 
 				totalEvaluations++
 				totalSolutionsChecked++
 
 				// If a better solution is found, accept it
 				if newFitness < currentFitness {
+					tracker.move(iter, i, j, newFitness-currentFitness, newFitness, "")
+					tracker.counts.accept(newFitness - currentFitness)
 					copy(currentSolution, newSolution)
 					currentFitness = newFitness
+					tracker.improved(totalEvaluations, currentFitness)
 					improved = true
 					break
 				}
@@ -122,20 +140,18 @@ func (s *GreedySolver) SolveWithMetrics(
 	elapsedTime := time.Since(startTime)
 
 	// Record metrics if the collector is provided
-	if metricsCollector != nil {
-		metricsCollector.AddRunMetrics(metrics.RunMetrics{
-			InstanceName:     instanceName,
-			SolverName:       s.Name(),
-			Run:              runNumber,
-			InitialFitness:   initialFitness,
-			FinalFitness:     currentFitness,
-			TimeElapsed:      elapsedTime,
-			StepsCount:       totalSteps,
-			EvaluationsCount: totalEvaluations,
-			SolutionsChecked: totalSolutionsChecked,
-			Solution:         currentSolution,
-		})
-	}
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     currentFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalSolutionsChecked,
+		Solution:         currentSolution,
+	})
 
 	// Return the result
 	return SolverResult{
@@ -1,17 +1,28 @@
 package solvers
 
 import (
+	"fmt"
+	"math/rand"
 	"qap_solver/internal/metrics"
 	"qap_solver/internal/qap"
 	"sort"
 	"time"
 )
 
-type GreedyConstructionSolver struct{}
+// GreedyConstructionSolver builds Starts solutions greedily and returns the
+// best. Ties between facilities and between locations are broken at random,
+// so repeated runs explore different constructions. With RCL above 0 every
+// facility is placed GRASP-style on a random location of the restricted
+// candidate list: the free locations whose incremental cost is within RCL of
+// the range between the cheapest and the most expensive one.
+type GreedyConstructionSolver struct {
+	Starts int     // constructions per run, at least 1
+	RCL    float64 // candidate list width in [0, 1], 0 = cheapest locations only
+}
 
 // NewGreedyConstructionSolver creates a new instance of the greedy heuristic solver
-func NewGreedyConstructionSolver() *GreedyConstructionSolver {
-	return &GreedyConstructionSolver{}
+func NewGreedyConstructionSolver(starts int, rcl float64) *GreedyConstructionSolver {
+	return &GreedyConstructionSolver{Starts: max(starts, 1), RCL: rcl}
 }
 
 func (s *GreedyConstructionSolver) Name() string {
@@ -19,13 +30,23 @@ func (s *GreedyConstructionSolver) Name() string {
 }
 
 func (s *GreedyConstructionSolver) Description() string {
-	return "Greedy heuristic for Quadratic Assignment Problem (QAP)"
+	return fmt.Sprintf("Greedy heuristic for Quadratic Assignment Problem (QAP), best of %d constructions with candidate list width %.2f", s.Starts, s.RCL)
+}
+
+// Capabilities reports that a run stops between constructions when its
+// limits are hit; a single construction cannot be stopped early
+func (s *GreedyConstructionSolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsCancellation: true, SupportsTimeBudget: true}
+}
+
+// Deterministic reports true without a candidate list: runs then differ only
+// in how ties are broken, so repeated solutions are expected
+func (s *GreedyConstructionSolver) Deterministic() bool {
+	return s.RCL == 0
 }
 
 func (s *GreedyConstructionSolver) Solve(instance *qap.QAPInstance) SolverResult {
-	solution := greedyConstruction(instance, nil)
-	fitness := qap.CalculateFitness(instance, solution)
-	return SolverResult{Solution: solution, Fitness: fitness}
+	return s.SolveWithMetrics(instance, nil, "", 0, SolveOptions{})
 }
 
 func (s *GreedyConstructionSolver) SolveWithMetrics(
@@ -33,74 +54,137 @@ func (s *GreedyConstructionSolver) SolveWithMetrics(
 	metricsCollector *metrics.MetricsCollector,
 	instanceName string,
 	runNumber int,
+	opts SolveOptions,
 ) SolverResult {
 	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
 	totalSteps := 0
 	totalEvaluations := 0
-	solution := greedyConstruction(instance, &totalSteps)
-	fitness := qap.CalculateFitness(instance, solution)
-	totalEvaluations++
+	var solution []int
+	fitness, initialFitness := 0, 0
+	for start := 0; start < s.Starts && (start == 0 || !tracker.shouldStop()); start++ {
+		tracker.progress(totalEvaluations, totalSteps)
+		candidate := greedyConstruction(instance, tracker.rng, s.RCL, &totalSteps)
+		candidateFitness := tracker.fitness(candidate)
+		totalEvaluations++
+		if start == 0 {
+			initialFitness = candidateFitness
+		}
+		if solution == nil || candidateFitness < fitness {
+			solution, fitness = candidate, candidateFitness
+			tracker.improved(totalEvaluations, fitness)
+		}
+	}
 
 	elapsedTime := time.Since(startTime)
 
-	if metricsCollector != nil {
-		metricsCollector.AddRunMetrics(metrics.RunMetrics{
-			InstanceName:     instanceName,
-			SolverName:       s.Name(),
-			Run:              runNumber,
-			InitialFitness:   fitness,
-			FinalFitness:     fitness,
-			TimeElapsed:      elapsedTime,
-			StepsCount:       totalSteps,
-			EvaluationsCount: totalEvaluations,
-			SolutionsChecked: totalSteps,
-			Solution:         solution,
-		})
-	}
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     fitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalSteps,
+		Solution:         solution,
+	})
 
 	return SolverResult{Solution: solution, Fitness: fitness}
 }
 
-func greedyConstruction(instance *qap.QAPInstance, stepsCounter *int) []int {
+// greedyConstruction places facilities in order of decreasing total flow, each
+// on a free location chosen at random among those whose incremental cost is
+// within rcl of the cost range, see GreedyConstructionSolver; rcl 0 picks
+// among the cheapest. Ties in the facility order are broken at random as
+// well. With constraints the location must keep the facility's groups within
+// their distance limits; when no free location does, any free location is a
+// candidate and the violation is left to the fitness penalty. The dummy
+// facilities of a padded instance take the locations left over.
+func greedyConstruction(instance *qap.QAPInstance, rng *rand.Rand, rcl float64, stepsCounter *int) []int {
+	return guidedConstruction(instance, rng, rcl, nil, stepsCounter)
+}
+
+// guidedConstruction is greedyConstruction with penalty, when set, added to
+// the incremental cost of every facility-location pair
+func guidedConstruction(instance *qap.QAPInstance, rng *rand.Rand, rcl float64,
+	penalty func(facility, location int) int, stepsCounter *int) []int {
 	size := instance.Size
-	unassignedFacilities := make([]int, size)
+	unassignedFacilities := rng.Perm(instance.FacilityCount())
 	unassignedLocations := make([]int, size)
 	assigned := make([][2]int, 0, size)
 
+	// placement[f] is the location of facility f, -1 while unassigned
+	placement := make([]int, size)
+
 	for i := 0; i < size; i++ {
-		unassignedFacilities[i] = i
 		unassignedLocations[i] = i
+		placement[i] = -1
 	}
 
-	sort.Slice(unassignedFacilities, func(i, j int) bool {
+	// The shuffled order decides between facilities of equal flow
+	sort.SliceStable(unassignedFacilities, func(i, j int) bool {
 		return facilityFlowSum(instance, unassignedFacilities[i]) > facilityFlowSum(instance, unassignedFacilities[j])
 	})
 
-	for len(unassignedFacilities) > 0 {
-		facility := unassignedFacilities[len(unassignedFacilities)-1]
-		unassignedFacilities = unassignedFacilities[:len(unassignedFacilities)-1]
+	costs := make([]int, size)
+	var candidates []int
+	for _, facility := range unassignedFacilities {
+		allowed := unassignedLocations
+		if instance.Constraints != nil {
+			allowed = nil
+			for _, location := range unassignedLocations {
+				if instance.Constraints.AllowsLocation(instance, placement, facility, location) {
+					allowed = append(allowed, location)
+				}
+			}
+			if len(allowed) == 0 {
+				allowed = unassignedLocations
+			}
+		}
 
-		sort.Slice(unassignedLocations, func(i, j int) bool {
-			return calculateIncrementalCost(instance, facility, unassignedLocations[i], assigned) <
-				calculateIncrementalCost(instance, facility, unassignedLocations[j], assigned)
-		})
+		lowest, highest := 0, 0
+		for k, location := range allowed {
+			costs[k] = calculateIncrementalCost(instance, facility, location, assigned)
+			if penalty != nil {
+				costs[k] += penalty(facility, location)
+			}
+			if k == 0 || costs[k] < lowest {
+				lowest = costs[k]
+			}
+			if k == 0 || costs[k] > highest {
+				highest = costs[k]
+			}
+		}
+		threshold := float64(lowest) + rcl*float64(highest-lowest)
+		candidates = candidates[:0]
+		for k, location := range allowed {
+			if float64(costs[k]) <= threshold {
+				candidates = append(candidates, location)
+			}
+		}
 
-		location := unassignedLocations[len(unassignedLocations)-1]
-		unassignedLocations = unassignedLocations[:len(unassignedLocations)-1]
+		location := candidates[rng.Intn(len(candidates))]
+		for k, free := range unassignedLocations {
+			if free == location {
+				unassignedLocations = append(unassignedLocations[:k], unassignedLocations[k+1:]...)
+				break
+			}
+		}
 
 		assigned = append(assigned, [2]int{facility, location})
+		placement[facility] = location
 
 		if stepsCounter != nil {
 			*stepsCounter++
 		}
 	}
 
-	solution := make([]int, size)
-	for _, pair := range assigned {
-		solution[pair[1]] = pair[0]
+	for facility := instance.FacilityCount(); facility < size; facility++ {
+		placement[facility] = unassignedLocations[facility-instance.FacilityCount()]
 	}
-
-	return solution
+	return placement
 }
 
 func facilityFlowSum(instance *qap.QAPInstance, facility int) int {
@@ -111,12 +195,16 @@ func facilityFlowSum(instance *qap.QAPInstance, facility int) int {
 	return sum
 }
 
+// calculateIncrementalCost returns the cost added by placing facility on
+// location next to the assigned pairs, including its self-flow term when the
+// diagonals are nonzero, negated for maximization instances so that lower is
+// better either way
 func calculateIncrementalCost(instance *qap.QAPInstance, facility, location int, assigned [][2]int) int {
-	cost := 0
+	cost := instance.FlowMatrix[facility][facility] * instance.DistanceMatrix[location][location]
 	for _, pair := range assigned {
 		f, l := pair[0], pair[1]
 		cost += instance.FlowMatrix[facility][f] * instance.DistanceMatrix[location][l]
 		cost += instance.FlowMatrix[f][facility] * instance.DistanceMatrix[l][location]
 	}
-	return cost
+	return instance.Sense() * cost
 }
@@ -0,0 +1,168 @@
+package solvers
+
+import (
+	"fmt"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"time"
+)
+
+// IteratedLocalSearchSolver alternates a best-improvement swap descent with a
+// perturbation of the best solution found so far, see Perturbation. The
+// perturbed solution is kept when its local optimum is not worse. When
+// MaxStrength exceeds Strength the search works like a basic variable
+// neighborhood search: the strength grows by one after every iteration
+// without improvement and falls back to Strength after an improvement.
+// Perturbation may name several operators joined by +, one of them is then
+// drawn at random for every iteration; diagnostics report how each of them
+// fared at each strength.
+type IteratedLocalSearchSolver struct {
+	Perturbation  string
+	Strength      int // Cayley distance of a perturbation, 0 uses an eighth of the size
+	MaxStrength   int
+	MaxIterations int
+	Polish        int // longest cyclic exchange polishing the best solution, 0 disables it
+}
+
+func NewIteratedLocalSearchSolver(perturbation string, strength, maxStrength, maxIterations, polish int) *IteratedLocalSearchSolver {
+	return &IteratedLocalSearchSolver{
+		Perturbation:  perturbation,
+		Strength:      strength,
+		MaxStrength:   maxStrength,
+		MaxIterations: maxIterations,
+		Polish:        polish,
+	}
+}
+
+func (s *IteratedLocalSearchSolver) Name() string {
+	return "IteratedLocalSearch"
+}
+
+func (s *IteratedLocalSearchSolver) Description() string {
+	if s.MaxStrength > s.Strength {
+		return fmt.Sprintf("Iterated local search with %s perturbations of strength %d to %d", s.Perturbation, s.Strength, s.MaxStrength)
+	}
+	return fmt.Sprintf("Iterated local search with %s perturbations", s.Perturbation)
+}
+
+func (s *IteratedLocalSearchSolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsWarmStart: true, SupportsCancellation: true, SupportsTimeBudget: true, SupportsSwapRadius: true}
+}
+
+func (s *IteratedLocalSearchSolver) Solve(instance *qap.QAPInstance) SolverResult {
+	return s.SolveWithMetrics(instance, nil, "", 0, SolveOptions{})
+}
+
+func (s *IteratedLocalSearchSolver) SolveWithMetrics(
+	instance *qap.QAPInstance,
+	metricsCollector *metrics.MetricsCollector,
+	instanceName string,
+	runNumber int,
+	opts SolveOptions,
+) SolverResult {
+	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
+	operators, names, err := PerturbationsByNames(s.Perturbation)
+	if err != nil {
+		operators, names = []Perturbation{PerturbBySwaps}, []string{PerturbSwaps}
+	}
+
+	n := instance.Size
+	minStrength := s.Strength
+	if minStrength <= 0 {
+		minStrength = max(n/8, 2)
+	}
+	maxStrength := max(s.MaxStrength, minStrength)
+	strength := minStrength
+
+	best := tracker.initialSolution(n)
+	tracker.repair(best)
+	bestFitness := tracker.fitness(best)
+	initialFitness := bestFitness
+	tracker.improved(0, initialFitness)
+
+	totalSteps := 0
+	totalEvaluations := 0
+	if bestFitness = s.descend(tracker, best, bestFitness, &totalSteps, &totalEvaluations); bestFitness < initialFitness {
+		tracker.improved(totalEvaluations, bestFitness)
+	}
+
+	candidate := make([]int, n)
+	for iter := 0; iter < s.MaxIterations && !tracker.shouldStop(); iter++ {
+		tracker.progress(totalEvaluations, totalSteps)
+		copy(candidate, best)
+		operator := 0
+		if len(operators) > 1 {
+			operator = tracker.rng.Intn(len(operators))
+		}
+		operators[operator](tracker.rng, candidate, strength)
+		tracker.repair(candidate)
+		candidateFitness := tracker.fitness(candidate)
+		totalEvaluations++
+		candidateFitness = s.descend(tracker, candidate, candidateFitness, &totalSteps, &totalEvaluations)
+		tracker.operatorApplied(names[operator], strength, candidateFitness <= bestFitness, max(bestFitness-candidateFitness, 0))
+
+		switch {
+		case candidateFitness < bestFitness:
+			strength = minStrength
+			tracker.improved(totalEvaluations, candidateFitness)
+			fallthrough
+		case candidateFitness == bestFitness:
+			copy(best, candidate)
+			bestFitness = candidateFitness
+		default:
+			strength = min(strength+1, maxStrength)
+		}
+	}
+	bestFitness = polishCyclic(tracker, instance, best, bestFitness, s.Polish, &totalSteps, &totalEvaluations)
+
+	elapsedTime := time.Since(startTime)
+
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     bestFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalEvaluations,
+		Solution:         best,
+	})
+
+	return SolverResult{
+		Solution: best,
+		Fitness:  bestFitness,
+	}
+}
+
+// descend applies the best improving swap until none is left and returns the
+// fitness of the local optimum
+func (s *IteratedLocalSearchSolver) descend(tracker *runTracker, solution []int, fitness int, steps, evaluations *int) int {
+	n := len(solution)
+	for !tracker.shouldStop() {
+		bestDelta, bestI, bestJ := 0, -1, -1
+		for i := 0; i < n-1; i++ {
+			for j := i + 1; j < n; j++ {
+				if !tracker.allows(solution, i, j) {
+					continue
+				}
+				delta := tracker.delta(solution, i, j)
+				*evaluations++
+				if delta < bestDelta {
+					bestDelta, bestI, bestJ = delta, i, j
+				}
+			}
+		}
+		if bestI < 0 {
+			break
+		}
+		*steps++
+		tracker.move(*steps, bestI, bestJ, bestDelta, fitness+bestDelta, "")
+		solution[bestI], solution[bestJ] = solution[bestJ], solution[bestI]
+		fitness += bestDelta
+		tracker.counts.accept(bestDelta)
+	}
+	return fitness
+}
@@ -0,0 +1,45 @@
+package solvers
+
+import (
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+)
+
+// labeledSolver wraps a solver configured with "@label=..." so that the
+// label is used in place of the solver name in logs, files and results
+type labeledSolver struct {
+	Solver
+	label string
+}
+
+type metricsSolver interface {
+	SolveWithMetrics(instance *qap.QAPInstance, metricsCollector *metrics.MetricsCollector,
+		instanceName string, runNumber int, opts SolveOptions) SolverResult
+}
+
+func (s *labeledSolver) Name() string {
+	return s.label
+}
+
+func (s *labeledSolver) Deterministic() bool {
+	return IsDeterministic(s.Solver)
+}
+
+func (s *labeledSolver) Capabilities() Capabilities {
+	return CapabilitiesOf(s.Solver)
+}
+
+func (s *labeledSolver) SolveWithMetrics(
+	instance *qap.QAPInstance,
+	metricsCollector *metrics.MetricsCollector,
+	instanceName string,
+	runNumber int,
+	opts SolveOptions,
+) SolverResult {
+	inner, ok := s.Solver.(metricsSolver)
+	if !ok {
+		return s.Solver.Solve(instance)
+	}
+	opts.Label = s.label
+	return inner.SolveWithMetrics(instance, metricsCollector, instanceName, runNumber, opts)
+}
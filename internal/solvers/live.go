@@ -0,0 +1,56 @@
+package solvers
+
+import "expvar"
+
+// Live counters published with expvar as the map "qap_solver", served at
+// /debug/vars when the program listens on -debug-addr. Counters sum over all
+// runs of the process, including parallel ones. Runs publish them at the
+// memory sample interval and when they end, so the solver loops only write
+// plain fields of their tracker.
+var (
+	liveRuns        = new(expvar.Int)   // runs in progress
+	liveEvaluations = new(expvar.Int)   // solutions or moves evaluated
+	liveMoves       = new(expvar.Int)   // accepted moves
+	liveRestarts    = new(expvar.Int)   // searches continued from another solution, e.g. an elite one
+	liveTemperature = new(expvar.Float) // current temperature of the last annealing run that reported one
+	liveTenure      = new(expvar.Int)   // tabu tenure of the last tabu search run that reported one
+)
+
+func init() {
+	vars := expvar.NewMap("qap_solver")
+	vars.Set("runs_active", liveRuns)
+	vars.Set("evaluations", liveEvaluations)
+	vars.Set("moves_accepted", liveMoves)
+	vars.Set("restarts", liveRestarts)
+	vars.Set("temperature", liveTemperature)
+	vars.Set("tabu_tenure", liveTenure)
+}
+
+// progress reports the evaluations and accepted moves of the run so far. It
+// only stores them, they are published by the next memory sample.
+func (t *runTracker) progress(evaluations, moves int) {
+	t.evaluations, t.moves = evaluations, moves
+}
+
+// temperature reports the current temperature of an annealing run
+func (t *runTracker) temperature(T float64) {
+	t.currentTemperature, t.hasTemperature = T, true
+}
+
+// tenure reports the tabu tenure of a tabu search run
+func (t *runTracker) tenure(tenure int) {
+	t.currentTenure = tenure
+}
+
+// publish adds the progress since the last call to the live counters
+func (t *runTracker) publish() {
+	liveEvaluations.Add(int64(t.evaluations - t.publishedEvaluations))
+	liveMoves.Add(int64(t.moves - t.publishedMoves))
+	t.publishedEvaluations, t.publishedMoves = t.evaluations, t.moves
+	if t.hasTemperature {
+		liveTemperature.Set(t.currentTemperature)
+	}
+	if t.currentTenure > 0 {
+		liveTenure.Set(int64(t.currentTenure))
+	}
+}
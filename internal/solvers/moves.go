@@ -0,0 +1,43 @@
+package solvers
+
+import (
+	"math/rand"
+	"qap_solver/internal/qap"
+	"qap_solver/pkg"
+)
+
+// ApplyMove swaps positions i and j of solution in place and returns the
+// fitness after the swap, updated from fitness with the swap delta instead of
+// a full evaluation. Together with RevertMove it lets a search try a neighbor
+// without copying the permutation.
+func ApplyMove(instance *qap.QAPInstance, solution []int, fitness, i, j int) int {
+	delta := qap.SwapDelta(instance, solution, i, j)
+	solution[i], solution[j] = solution[j], solution[i]
+	return fitness + delta
+}
+
+// RevertMove undoes ApplyMove(instance, solution, fitness, i, j); the fitness
+// before the move is the one ApplyMove was given
+func RevertMove(solution []int, i, j int) {
+	solution[i], solution[j] = solution[j], solution[i]
+}
+
+// applyMove is ApplyMove with the fitness of the run, see runTracker.delta
+func (t *runTracker) applyMove(solution []int, fitness, i, j int) int {
+	delta := t.delta(solution, i, j)
+	solution[i], solution[j] = solution[j], solution[i]
+	return fitness + delta
+}
+
+// randomSwap returns two distinct positions of a solution of instance drawn
+// uniformly among the swaps that can change it: in a padded instance at
+// least one of them holds a real facility, so the swap moves it, possibly to
+// an empty location. A nil rng uses the global math/rand source.
+func randomSwap(rng *rand.Rand, instance *qap.QAPInstance) (int, int) {
+	for {
+		i, j := pkg.RandomDistinctPair(rng, instance.Size)
+		if !instance.IsDummy(i) || !instance.IsDummy(j) {
+			return i, j
+		}
+	}
+}
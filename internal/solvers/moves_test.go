@@ -0,0 +1,73 @@
+package solvers
+
+import (
+	"fmt"
+	"math/rand"
+	"qap_solver/internal/qap"
+	"qap_solver/internal/qaptest"
+	"qap_solver/pkg"
+	"testing"
+)
+
+var moveBenchmarkSizes = []int{50, 100, 256}
+
+// moveSink keeps the benchmarked fitness from being optimized away
+var moveSink int
+
+// randomTestInstance has asymmetric random matrices with zero diagonals
+func randomTestInstance(n int, seed int64) *qap.QAPInstance {
+	rng := rand.New(rand.NewSource(seed))
+	return &qap.QAPInstance{Size: n, FlowMatrix: qaptest.ZeroDiagonal(qaptest.Matrix(rng, n)),
+		DistanceMatrix: qaptest.ZeroDiagonal(qaptest.Matrix(rng, n))}
+}
+
+// BenchmarkCopyMoves times the former inner loop of simulated annealing and
+// random walk: copy the permutation, swap two positions, evaluate the
+// neighbor in full and accept half of the moves
+func BenchmarkCopyMoves(b *testing.B) {
+	for _, n := range moveBenchmarkSizes {
+		instance := randomTestInstance(n, 1)
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			current := rng.Perm(n)
+			fitness := qap.CalculateFitness(instance, current)
+			b.ResetTimer()
+			for k := 0; k < b.N; k++ {
+				i, j := pkg.RandomDistinctPair(rng, n)
+				neighbor := make([]int, n)
+				copy(neighbor, current)
+				neighbor[i], neighbor[j] = neighbor[j], neighbor[i]
+				newFitness := qap.CalculateFitness(instance, neighbor)
+				if rng.Intn(2) == 0 {
+					copy(current, neighbor)
+					fitness = newFitness
+				}
+			}
+			moveSink = fitness
+		})
+	}
+}
+
+// BenchmarkInPlaceMoves times the same iteration with ApplyMove and
+// RevertMove in place
+func BenchmarkInPlaceMoves(b *testing.B) {
+	for _, n := range moveBenchmarkSizes {
+		instance := randomTestInstance(n, 1)
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			current := rng.Perm(n)
+			fitness := qap.CalculateFitness(instance, current)
+			b.ResetTimer()
+			for k := 0; k < b.N; k++ {
+				i, j := pkg.RandomDistinctPair(rng, n)
+				newFitness := ApplyMove(instance, current, fitness, i, j)
+				if rng.Intn(2) == 0 {
+					fitness = newFitness
+				} else {
+					RevertMove(current, i, j)
+				}
+			}
+			moveSink = fitness
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package solvers
+
+import (
+	"fmt"
+	"log"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"time"
+)
+
+// Termination reasons recorded in RunMetrics.TerminationReason
+const (
+	TerminationCompleted   = "completed"
+	TerminationMemoryLimit = "memory_limit"
+	TerminationTimeLimit   = "time_limit"
+	TerminationStop        = "stop_condition"
+)
+
+// Strategies for forbidden facility-location pairs
+const (
+	ForbiddenPenalty = "penalty" // forbidden assignments are allowed but penalized in the fitness
+	ForbiddenHard    = "hard"    // neighborhoods skip moves that create forbidden assignments
+)
+
+// ValidateForbiddenStrategy checks a strategy name, "" selects ForbiddenPenalty
+func ValidateForbiddenStrategy(strategy string) error {
+	switch strategy {
+	case "", ForbiddenPenalty, ForbiddenHard:
+		return nil
+	default:
+		return fmt.Errorf("unknown forbidden strategy %q, use %s or %s", strategy, ForbiddenPenalty, ForbiddenHard)
+	}
+}
+
+// FitnessFunc evaluates a solution, lower is better. Custom functions usually
+// wrap qap.CalculateFitness, which already respects the objective direction of
+// the instance, and add domain-specific penalty terms.
+type FitnessFunc func(instance *qap.QAPInstance, solution []int) int
+
+// DeltaFunc returns the fitness change of swapping positions i and j of
+// solution without modifying it, like qap.SwapDelta
+type DeltaFunc func(instance *qap.QAPInstance, solution []int, i, j int) int
+
+// SolveOptions holds per-run settings passed from the experiment runner to a solver
+type SolveOptions struct {
+	// MemoryLimit is a soft limit (in bytes) on the heap of the process, which
+	// runs executed in parallel share. When a run finds it exceeded, it stops
+	// early and keeps its best solution. Zero disables the limit.
+	MemoryLimit uint64
+
+	// TimeLimit stops the run early with its best solution once it has run
+	// this long. Zero disables the limit.
+	TimeLimit time.Duration
+
+	// Stop ends the run early with its best solution once the condition
+	// holds, see StopCondition. Its gap refers to Optimum when HasOptimum is set.
+	Stop       *StopCondition
+	Optimum    int // best known fitness in minimized form, see qap.QAPInstance.Sense
+	HasOptimum bool
+
+	// Trace receives every accepted move when set
+	Trace *metrics.TraceWriter
+
+	// Diagnostics collects tabu frequencies and annealing temperatures when set
+	Diagnostics *metrics.Diagnostics
+
+	// Artifacts receives algorithm-specific dumps of the run when set, such
+	// as the final tabu list and residence frequencies of tabu search
+	Artifacts *metrics.Artifacts
+
+	// Log receives the solver's internal progress when set, as much of it as
+	// Verbosity asks for, see VerbosityQuiet
+	Log       *log.Logger
+	Verbosity int
+
+	// Constructions caches the solutions of deterministic constructions
+	// across runs and experiments when set, see ConstructionCache
+	Constructions *ConstructionCache
+
+	// TrajectoryPoints bounds the convergence trajectory stored for the run,
+	// longer trajectories are thinned, see metrics.TrajectorySampler. Zero
+	// keeps every improvement.
+	TrajectoryPoints int
+
+	// Label replaces the solver name in the recorded metrics when set
+	Label string
+
+	// Forbidden selects how forbidden facility-location pairs of the instance
+	// constraints are handled, ForbiddenPenalty when empty
+	Forbidden string
+
+	// Fitness replaces qap.CalculateFitness when set. Delta is the matching
+	// incremental evaluation; without it swaps are evaluated in full with
+	// Fitness. Both may be called concurrently by parallel solvers.
+	Fitness FitnessFunc
+	Delta   DeltaFunc
+
+	// Seed initializes the random generator of the run. Runs with the same
+	// seed draw the same random numbers, which pairs them for comparisons
+	// (common random numbers). Zero draws a fresh seed.
+	Seed int64
+
+	// Start is the initial solution of solvers supporting warm starts, see
+	// Capabilities.SupportsWarmStart; nil or a permutation of another size
+	// starts from a random solution. Solvers copy it before searching.
+	Start []int
+
+	// SwapRadius restricts swap neighborhoods to spatially local moves: two
+	// facilities may only swap when their locations lie at most this far
+	// apart in the distance matrix. Zero allows every swap.
+	SwapRadius int
+}
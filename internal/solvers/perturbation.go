@@ -0,0 +1,135 @@
+package solvers
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Perturbation operators, selected by name in iterated local search
+const (
+	PerturbSwaps    = "swaps"    // k chained transpositions of k+1 random positions
+	PerturbScramble = "scramble" // a random cyclic shuffle of a segment of k+1 positions
+	PerturbReverse  = "reverse"  // reversal of a segment of 2k or 2k+1 positions
+)
+
+// Perturbation changes solution in place so that its Cayley distance to the
+// original is exactly strength, the number of transpositions separating
+// them. Strengths beyond what the size allows are clamped. It returns the
+// distance applied.
+type Perturbation func(rng *rand.Rand, solution []int, strength int) int
+
+var perturbations = map[string]Perturbation{
+	PerturbSwaps:    PerturbBySwaps,
+	PerturbScramble: PerturbByScramble,
+	PerturbReverse:  PerturbByReversal,
+}
+
+// PerturbationByName returns the operator registered under name
+func PerturbationByName(name string) (Perturbation, error) {
+	if p, ok := perturbations[name]; ok {
+		return p, nil
+	}
+	names := make([]string, 0, len(perturbations))
+	for name := range perturbations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown perturbation %q, use one of %v", name, names)
+}
+
+// PerturbationsByNames returns the operators of a list of names joined by
+// +, e.g. "swaps+reverse", in the order given
+func PerturbationsByNames(spec string) ([]Perturbation, []string, error) {
+	names := strings.Split(spec, "+")
+	operators := make([]Perturbation, len(names))
+	for k, name := range names {
+		p, err := PerturbationByName(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, other := range names[:k] {
+			if other == name {
+				return nil, nil, fmt.Errorf("perturbation %q given twice", name)
+			}
+		}
+		operators[k] = p
+	}
+	return operators, names, nil
+}
+
+// PerturbBySwaps swaps k+1 distinct random positions along a chain, p0 with
+// p1, p1 with p2 and so on. The result is a single (k+1)-cycle, so unlike k
+// independent random swaps no two transpositions can cancel out.
+func PerturbBySwaps(rng *rand.Rand, solution []int, strength int) int {
+	k := min(strength, len(solution)-1)
+	if k <= 0 {
+		return 0
+	}
+	positions := rng.Perm(len(solution))[:k+1]
+	for m := 0; m < k; m++ {
+		a, b := positions[m], positions[m+1]
+		solution[a], solution[b] = solution[b], solution[a]
+	}
+	return k
+}
+
+// PerturbByScramble shuffles a random segment of k+1 consecutive positions
+// with Sattolo's algorithm, which draws a uniformly random single cycle, so
+// every position of the segment changes and the distance is exactly k
+func PerturbByScramble(rng *rand.Rand, solution []int, strength int) int {
+	k := min(strength, len(solution)-1)
+	if k <= 0 {
+		return 0
+	}
+	start := rng.Intn(len(solution) - k)
+	segment := solution[start : start+k+1]
+	for i := len(segment) - 1; i > 0; i-- {
+		j := rng.Intn(i)
+		segment[i], segment[j] = segment[j], segment[i]
+	}
+	return k
+}
+
+// PerturbByReversal reverses a random segment. A segment of length 2k or
+// 2k+1 reverses as k disjoint transpositions, the middle element of an odd
+// segment stays in place.
+func PerturbByReversal(rng *rand.Rand, solution []int, strength int) int {
+	k := min(strength, len(solution)/2)
+	if k <= 0 {
+		return 0
+	}
+	length := 2 * k
+	if length < len(solution) && rng.Intn(2) == 1 {
+		length++
+	}
+	start := rng.Intn(len(solution) - length + 1)
+	for i, j := start, start+length-1; i < j; i, j = i+1, j-1 {
+		solution[i], solution[j] = solution[j], solution[i]
+	}
+	return k
+}
+
+// CayleyDistance is the minimum number of transpositions turning permutation
+// a into b: the size minus the number of cycles of the permutation mapping
+// the positions of a onto those of b
+func CayleyDistance(a, b []int) int {
+	n := len(a)
+	position := make([]int, n)
+	for i, v := range b {
+		position[v] = i
+	}
+	visited := make([]bool, n)
+	cycles := 0
+	for i := 0; i < n; i++ {
+		if visited[i] {
+			continue
+		}
+		cycles++
+		for j := i; !visited[j]; j = position[a[j]] {
+			visited[j] = true
+		}
+	}
+	return n - cycles
+}
@@ -0,0 +1,72 @@
+package solvers
+
+import (
+	"fmt"
+	"math/rand"
+	"qap_solver/internal/qap"
+	"testing"
+)
+
+// TestPerturbationDistance checks that every operator moves a solution by
+// exactly the strength it returns, in transpositions, and that the strength
+// is clamped to what the size allows
+func TestPerturbationDistance(t *testing.T) {
+	operators := []struct {
+		name  string
+		apply Perturbation
+		limit func(n int) int
+	}{
+		{PerturbSwaps, PerturbBySwaps, func(n int) int { return n - 1 }},
+		{PerturbScramble, PerturbByScramble, func(n int) int { return n - 1 }},
+		{PerturbReverse, PerturbByReversal, func(n int) int { return n / 2 }},
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, op := range operators {
+		for _, n := range []int{1, 2, 3, 8, 25} {
+			for _, strength := range []int{0, 1, n / 2, n + 3} {
+				t.Run(fmt.Sprintf("%s/n=%d/strength=%d", op.name, n, strength), func(t *testing.T) {
+					want := max(min(strength, op.limit(n)), 0)
+					for trial := 0; trial < 20; trial++ {
+						before := rng.Perm(n)
+						after := append([]int(nil), before...)
+						got := op.apply(rng, after, strength)
+						if err := qap.ValidatePermutation(after); err != nil {
+							t.Fatalf("result %v is not a permutation: %v", after, err)
+						}
+						if got != want {
+							t.Fatalf("returned distance %d, want %d", got, want)
+						}
+						if distance := CayleyDistance(before, after); distance != got {
+							t.Fatalf("Cayley distance of %v to %v is %d, the operator returned %d", before, after, distance, got)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestCayleyDistance checks the distance on permutations with a known
+// cycle structure
+func TestCayleyDistance(t *testing.T) {
+	cases := []struct {
+		a, b []int
+		want int
+	}{
+		{[]int{}, []int{}, 0},
+		{[]int{0, 1, 2, 3}, []int{0, 1, 2, 3}, 0},
+		{[]int{0, 1, 2, 3}, []int{1, 0, 2, 3}, 1},
+		{[]int{0, 1, 2, 3}, []int{1, 0, 3, 2}, 2},
+		{[]int{0, 1, 2, 3}, []int{1, 2, 3, 0}, 3},
+		{[]int{3, 1, 0, 2}, []int{3, 1, 0, 2}, 0},
+		{[]int{3, 1, 0, 2}, []int{1, 3, 0, 2}, 1},
+	}
+	for _, c := range cases {
+		if got := CayleyDistance(c.a, c.b); got != c.want {
+			t.Errorf("CayleyDistance(%v, %v) = %d, want %d", c.a, c.b, got, c.want)
+		}
+		if got := CayleyDistance(c.b, c.a); got != c.want {
+			t.Errorf("CayleyDistance(%v, %v) = %d, want %d", c.b, c.a, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package solvers
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"qap_solver/internal/qap"
+	"strconv"
+	"strings"
+)
+
+// Strategies creating the first generation of population solvers, see PopulationInit
+const (
+	InitRandom     = "random"     // uniform random permutations
+	InitHeuristic  = "heuristic"  // GRASP constructions, see greedyConstruction
+	InitOpposition = "opposition" // the better of a random permutation and its opposite
+	InitDiverse    = "diverse"    // greedy constructions avoiding the assignments of earlier ones
+)
+
+// heuristicInitRCL is the candidate list width of heuristic individuals,
+// wide enough that they differ from each other
+const heuristicInitRCL = 0.25
+
+// InitPart is a strategy creating a fraction of the population
+type InitPart struct {
+	Strategy string
+	Fraction float64 // in (0, 1]
+}
+
+// PopulationInit creates the first generation of the genetic, memetic and
+// scatter search solvers. Every part creates its fraction of the population,
+// rounded to the nearest individual, and random permutations fill the rest.
+// It is configured with init=<spec>, see ParsePopulationInit.
+type PopulationInit struct {
+	Parts []InitPart
+}
+
+// ParsePopulationInit parses the init argument of population solvers: a
+// single strategy, which creates the whole population, or mixed: followed by
+// fractions of strategies joined by +, e.g. mixed:0.2heuristic or
+// mixed:0.2heuristic+0.3opposition, where random permutations make up the
+// rest. The strategies are random, heuristic, opposition and diverse.
+func ParsePopulationInit(spec string) (*PopulationInit, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	mix, mixed := strings.CutPrefix(spec, "mixed:")
+	if !mixed {
+		if err := validateInitStrategy(spec); err != nil {
+			return nil, err
+		}
+		return &PopulationInit{Parts: []InitPart{{Strategy: spec, Fraction: 1}}}, nil
+	}
+
+	init := &PopulationInit{}
+	total := 0.0
+	for _, term := range strings.Split(mix, "+") {
+		term = strings.TrimSpace(term)
+		split := strings.IndexFunc(term, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+		if split <= 0 {
+			return nil, fmt.Errorf("invalid init term %q, expected a fraction and a strategy, e.g. 0.2heuristic", term)
+		}
+		fraction, err := strconv.ParseFloat(term[:split], 64)
+		if err != nil || fraction <= 0 || fraction > 1 {
+			return nil, fmt.Errorf("invalid init fraction %q, expected a number in (0, 1]", term[:split])
+		}
+		strategy := term[split:]
+		if err := validateInitStrategy(strategy); err != nil {
+			return nil, err
+		}
+		for _, part := range init.Parts {
+			if part.Strategy == strategy {
+				return nil, fmt.Errorf("init strategy %s appears twice", strategy)
+			}
+		}
+		init.Parts = append(init.Parts, InitPart{Strategy: strategy, Fraction: fraction})
+		total += fraction
+	}
+	if total > 1+1e-9 {
+		return nil, fmt.Errorf("init fractions add up to %.3g, more than the whole population", total)
+	}
+	return init, nil
+}
+
+func validateInitStrategy(strategy string) error {
+	switch strategy {
+	case InitRandom, InitHeuristic, InitOpposition, InitDiverse:
+		return nil
+	default:
+		return fmt.Errorf("unknown init strategy %q (expected %s, %s, %s or %s)",
+			strategy, InitRandom, InitHeuristic, InitOpposition, InitDiverse)
+	}
+}
+
+// String returns the spec the initialization was parsed from, in canonical form
+func (p *PopulationInit) String() string {
+	if len(p.Parts) == 1 && p.Parts[0].Fraction == 1 {
+		return p.Parts[0].Strategy
+	}
+	terms := make([]string, len(p.Parts))
+	for k, part := range p.Parts {
+		terms[k] = strconv.FormatFloat(part.Fraction, 'g', -1, 64) + part.Strategy
+	}
+	return "mixed:" + strings.Join(terms, "+")
+}
+
+// Initialize creates size individuals for instance. Opposition-based
+// individuals are compared with fitness, typically the run tracker's, which
+// is called twice for each of them. Callers repair the individuals when the
+// instance has constraints.
+func (p *PopulationInit) Initialize(instance *qap.QAPInstance, rng *rand.Rand, size int, fitness func([]int) int) [][]int {
+	population := make([][]int, 0, size)
+	for _, part := range p.Parts {
+		count := min(int(math.Round(part.Fraction*float64(size))), size-len(population))
+		switch part.Strategy {
+		case InitHeuristic:
+			for k := 0; k < count; k++ {
+				population = append(population, greedyConstruction(instance, rng, heuristicInitRCL, nil))
+			}
+		case InitOpposition:
+			for k := 0; k < count; k++ {
+				population = append(population, oppositionIndividual(instance, rng, fitness))
+			}
+		case InitDiverse:
+			population = append(population, diverseConstructions(instance, rng, count)...)
+		default:
+			for k := 0; k < count; k++ {
+				population = append(population, RandomSolutionFrom(rng, instance.Size))
+			}
+		}
+	}
+	for len(population) < size {
+		population = append(population, RandomSolutionFrom(rng, instance.Size))
+	}
+	return population
+}
+
+// oppositionIndividual applies opposition-based learning to permutations: the
+// opposite of a random permutation puts every facility on the mirrored
+// location n-1-p[i], and the better of the two is kept
+func oppositionIndividual(instance *qap.QAPInstance, rng *rand.Rand, fitness func([]int) int) []int {
+	n := instance.Size
+	solution := RandomSolutionFrom(rng, n)
+	opposite := make([]int, n)
+	for i, location := range solution {
+		opposite[i] = n - 1 - location
+	}
+	if fitness(opposite) < fitness(solution) {
+		return opposite
+	}
+	return solution
+}
+
+// diverseConstructions builds count greedy constructions in turn, each one
+// paying a penalty for the facility-location assignments the earlier ones
+// made. The penalty of one earlier use is about the cost of an average
+// placement, so later constructions spread over the assignments the greedy
+// rule ranks next.
+func diverseConstructions(instance *qap.QAPInstance, rng *rand.Rand, count int) [][]int {
+	n := instance.Size
+	totalFlow, totalDistance := 0, 0
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			totalFlow += instance.FlowMatrix[i][j]
+			totalDistance += instance.DistanceMatrix[i][j]
+		}
+	}
+	unit := max(1, int(float64(totalFlow)*float64(totalDistance)/math.Pow(float64(max(n, 1)), 3)))
+
+	usage := make([][]int, n)
+	for i := range usage {
+		usage[i] = make([]int, n)
+	}
+	penalty := func(facility, location int) int {
+		return usage[facility][location] * unit
+	}
+
+	constructions := make([][]int, 0, count)
+	for k := 0; k < count; k++ {
+		solution := guidedConstruction(instance, rng, 0, penalty, nil)
+		for facility, location := range solution {
+			usage[facility][location]++
+		}
+		constructions = append(constructions, solution)
+	}
+	return constructions
+}
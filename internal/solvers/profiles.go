@@ -0,0 +1,192 @@
+package solvers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ParamsAuto is the solver argument "params=auto": the parameters come from
+// the profile matching the instance, see Profiles.Lookup
+const ParamsAuto = "params=auto"
+
+// AnyFamily matches instances of every family in a Profile
+const AnyFamily = "*"
+
+// Profile holds the recommended parameters of a solver type for the instances
+// of a family within a size range, usually learned by the "tune" subcommand
+type Profile struct {
+	Solver  string  `json:"solver"`           // solver type, e.g. simanneal
+	Family  string  `json:"family"`           // instance family, see metrics.InstanceFamily, or AnyFamily
+	MinSize int     `json:"min_size"`         // smallest instance size the profile applies to
+	MaxSize int     `json:"max_size"`         // largest instance size, 0 for no bound
+	Params  string  `json:"params"`           // solver arguments, e.g. "alpha=0.99,p=5"
+	MeanGap float64 `json:"mean_gap_percent"` // mean gap of the tuning runs with Params
+	Runs    int     `json:"runs"`             // tuning runs behind MeanGap
+}
+
+func (p Profile) fits(size int) bool {
+	return size >= p.MinSize && (p.MaxSize == 0 || size <= p.MaxSize)
+}
+
+// distance is how far size lies outside the size range of the profile
+func (p Profile) distance(size int) int {
+	switch {
+	case size < p.MinSize:
+		return p.MinSize - size
+	case p.MaxSize > 0 && size > p.MaxSize:
+		return size - p.MaxSize
+	}
+	return 0
+}
+
+// Profiles is a store of parameter profiles kept in a JSON file
+type Profiles struct {
+	Entries []Profile `json:"profiles"`
+}
+
+// LoadProfiles reads a profile store. A missing file gives an empty store.
+func LoadProfiles(path string) (*Profiles, error) {
+	p := &Profiles{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+// Save writes the store sorted by solver, family and size
+func (p *Profiles) Save(path string) error {
+	sort.Slice(p.Entries, func(i, j int) bool {
+		a, b := p.Entries[i], p.Entries[j]
+		if a.Solver != b.Solver {
+			return a.Solver < b.Solver
+		}
+		if a.Family != b.Family {
+			return a.Family < b.Family
+		}
+		return a.MinSize < b.MinSize
+	})
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Set adds a profile, replacing the one of the same solver, family and size range
+func (p *Profiles) Set(profile Profile) {
+	for i, e := range p.Entries {
+		if e.Solver == profile.Solver && e.Family == profile.Family &&
+			e.MinSize == profile.MinSize && e.MaxSize == profile.MaxSize {
+			p.Entries[i] = profile
+			return
+		}
+	}
+	p.Entries = append(p.Entries, profile)
+}
+
+// Lookup returns the profile of a solver type for an instance. Profiles of
+// the instance's family come first, then those for AnyFamily; among them the
+// one whose size range contains size or lies closest to it wins.
+func (p *Profiles) Lookup(solverType, family string, size int) (Profile, bool) {
+	if p == nil {
+		return Profile{}, false
+	}
+	for _, f := range []string{strings.ToLower(family), AnyFamily} {
+		best, found := Profile{}, false
+		for _, e := range p.Entries {
+			if e.Solver != solverType || strings.ToLower(e.Family) != f {
+				continue
+			}
+			if !found || e.distance(size) < best.distance(size) {
+				best, found = e, true
+			}
+		}
+		if found {
+			return best, true
+		}
+	}
+	return Profile{}, false
+}
+
+// profiledSolver is a solver configured with params=auto: every run uses a
+// solver created with the parameters of the profile matching its instance,
+// followed by the explicitly given arguments, which take precedence
+type profiledSolver struct {
+	Solver   // created without profile parameters, names the solver
+	create   func(args []string) (Solver, error)
+	profiles *Profiles
+	solver   string   // solver type
+	args     []string // explicit arguments besides params=auto
+
+	mu      sync.Mutex
+	created map[string]Solver // by profile parameters
+}
+
+// resolve returns the solver for an instance
+func (s *profiledSolver) resolve(instance *qap.QAPInstance, instanceName string) Solver {
+	profile, ok := s.profiles.Lookup(s.solver, metrics.InstanceFamily(instanceName), instance.Size)
+	if !ok {
+		return s.Solver
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if solver, ok := s.created[profile.Params]; ok {
+		return solver
+	}
+	var args []string
+	if profile.Params != "" {
+		args = strings.Split(profile.Params, ",")
+	}
+	solver, err := s.create(append(args, s.args...))
+	if err != nil {
+		// Profiles of a former version may hold rejected values
+		solver = s.Solver
+	}
+	if s.created == nil {
+		s.created = make(map[string]Solver)
+	}
+	s.created[profile.Params] = solver
+	return solver
+}
+
+// Solve uses the profile of the instance size, the instance name is unknown
+func (s *profiledSolver) Solve(instance *qap.QAPInstance) SolverResult {
+	return s.resolve(instance, "").Solve(instance)
+}
+
+func (s *profiledSolver) Deterministic() bool {
+	return IsDeterministic(s.Solver)
+}
+
+func (s *profiledSolver) Capabilities() Capabilities {
+	return CapabilitiesOf(s.Solver)
+}
+
+func (s *profiledSolver) SolveWithMetrics(
+	instance *qap.QAPInstance,
+	metricsCollector *metrics.MetricsCollector,
+	instanceName string,
+	runNumber int,
+	opts SolveOptions,
+) SolverResult {
+	solver := s.resolve(instance, instanceName)
+	inner, ok := solver.(metricsSolver)
+	if !ok {
+		return solver.Solve(instance)
+	}
+	return inner.SolveWithMetrics(instance, metricsCollector, instanceName, runNumber, opts)
+}
@@ -2,6 +2,7 @@ package solvers
 
 import (
 	"fmt"
+	"math/rand"
 	"qap_solver/internal/metrics"
 	"qap_solver/internal/qap"
 	"qap_solver/pkg"
@@ -27,15 +28,21 @@ func (s *RandomSolver) Description() string {
 	return fmt.Sprintf("Random solution generator (%d iterations)", s.Iterations)
 }
 
+func (s *RandomSolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsCancellation: true, SupportsTimeBudget: true}
+}
+
 func (s *RandomSolver) Solve(instance *qap.QAPInstance) SolverResult {
 	bestSolution := make([]int, instance.Size)
-	bestFitness := -1
+	bestFitness := 0
 
+	// Every permutation is drawn into the same buffer
+	solution := make([]int, instance.Size)
 	for i := 0; i < s.Iterations; i++ {
-		solution := RandomSolution(instance.Size)
+		RandomSolutionInto(nil, solution)
 		fitness := qap.CalculateFitness(instance, solution)
 
-		if bestFitness == -1 || fitness < bestFitness {
+		if i == 0 || fitness < bestFitness {
 			copy(bestSolution, solution)
 			bestFitness = fitness
 		}
@@ -52,11 +59,13 @@ func (s *RandomSolver) SolveWithMetrics(
 	metricsCollector *metrics.MetricsCollector,
 	instanceName string,
 	runNumber int,
-	) SolverResult {
+	opts SolveOptions,
+) SolverResult {
 	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
 
 	bestSolution := make([]int, instance.Size)
-	bestFitness := -1
+	bestFitness := 0
 
 	totalSteps := 0
 	totalEvaluations := 0
@@ -65,9 +74,17 @@ func (s *RandomSolver) SolveWithMetrics(
 	var initialSolution []int
 	var initialFitness int
 
+	// Every permutation is drawn into the same buffer
+	solution := make([]int, instance.Size)
 	for i := 0; i < s.Iterations; i++ {
-		solution := RandomSolution(instance.Size)
-		fitness := qap.CalculateFitness(instance, solution)
+		if i > 0 && tracker.shouldStop() {
+			break
+		}
+		tracker.progress(totalEvaluations, totalSteps)
+
+		RandomSolutionInto(tracker.rng, solution)
+		tracker.repair(solution)
+		fitness := tracker.fitness(solution)
 
 		if i == 0 {
 			// record initial solution
@@ -80,28 +97,27 @@ func (s *RandomSolver) SolveWithMetrics(
 		totalEvaluations += 1
 		totalSolutionsChecked += 1
 
-		if bestFitness == -1 || fitness < bestFitness {
+		if i == 0 || fitness < bestFitness {
 			copy(bestSolution, solution)
 			bestFitness = fitness
+			tracker.improved(totalEvaluations, bestFitness)
 		}
 	}
 
 	elapsedTime := time.Since(startTime)
 
-	if metricsCollector != nil {
-		metricsCollector.AddRunMetrics(metrics.RunMetrics{
-			InstanceName:     instanceName,
-			SolverName:       s.Name(),
-			Run:              runNumber,
-			InitialFitness:   initialFitness,
-			FinalFitness:     bestFitness,
-			TimeElapsed:      elapsedTime,
-			StepsCount:       totalSteps,
-			EvaluationsCount: totalEvaluations,
-			SolutionsChecked: totalSolutionsChecked,
-			Solution:         bestSolution,
-		})
-	}
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     bestFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalSolutionsChecked,
+		Solution:         bestSolution,
+	})
 
 	return SolverResult{
 		Solution: bestSolution,
@@ -110,10 +126,37 @@ func (s *RandomSolver) SolveWithMetrics(
 }
 
 func RandomSolution(size int) []int {
-	solution := make([]int, size)
+	return RandomSolutionFrom(nil, size)
+}
+
+// RandomSolutionFrom returns a random permutation drawn from rng.
+// A nil rng uses the global math/rand source.
+func RandomSolutionFrom(rng *rand.Rand, size int) []int {
+	if rng == nil {
+		solution := make([]int, size)
+		for i := range solution {
+			solution[i] = i
+		}
+		pkg.ShuffleSlice(solution)
+		return solution
+	}
+	return rng.Perm(size)
+}
+
+// RandomSolutionInto fills solution with a random permutation drawn from rng
+// without allocating. It draws the same permutation as rng.Perm, so seeded
+// runs match RandomSolutionFrom. A nil rng uses the global math/rand source.
+func RandomSolutionInto(rng *rand.Rand, solution []int) {
+	if rng == nil {
+		for i := range solution {
+			solution[i] = i
+		}
+		pkg.ShuffleSlice(solution)
+		return
+	}
 	for i := range solution {
-		solution[i] = i
+		j := rng.Intn(i + 1)
+		solution[i] = solution[j]
+		solution[j] = i
 	}
-	pkg.ShuffleSlice(solution)
-	return solution
 }
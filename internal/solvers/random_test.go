@@ -0,0 +1,32 @@
+package solvers
+
+import (
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"testing"
+)
+
+// TestRandomSolversKeepBestOfMinusOne runs the random solvers on a maximized
+// instance whose best solution has fitness -1, the negated objective 1. The
+// other solution, fitness 0, must never replace it.
+func TestRandomSolversKeepBestOfMinusOne(t *testing.T) {
+	instance := &qap.QAPInstance{Size: 2, Maximize: true,
+		FlowMatrix:     [][]int{{0, 1}, {0, 0}},
+		DistanceMatrix: [][]int{{0, 1}, {0, 0}}}
+	if got := qap.CalculateFitness(instance, []int{0, 1}); got != -1 {
+		t.Fatalf("the identity has fitness %d, want -1", got)
+	}
+	type metricsSolver interface {
+		Solver
+		SolveWithMetrics(*qap.QAPInstance, *metrics.MetricsCollector, string, int, SolveOptions) SolverResult
+	}
+	for _, solver := range []metricsSolver{NewRandomSolver(50), NewRandomWalkSolver(50)} {
+		for seed := int64(1); seed <= 5; seed++ {
+			result := solver.SolveWithMetrics(instance, nil, "", 0, SolveOptions{Seed: seed})
+			if result.Fitness != -1 || result.Solution[0] != 0 {
+				t.Errorf("%s with seed %d returned %v with fitness %d, want [0 1] with -1",
+					solver.Name(), seed, result.Solution, result.Fitness)
+			}
+		}
+	}
+}
@@ -2,7 +2,6 @@ package solvers
 
 import (
 	"fmt"
-	"math/rand"
 	"qap_solver/internal/metrics"
 	"qap_solver/internal/qap"
 	"time"
@@ -27,26 +26,23 @@ func (s *RandomWalkSolver) Description() string {
 	return fmt.Sprintf("Random walk search with max iterations: %d", s.MaxIterations)
 }
 
-func (s *RandomWalkSolver) Solve(instance *qap.QAPInstance) SolverResult {
-	bestSolution := make([]int, instance.Size)
-	bestFitness := -1
+func (s *RandomWalkSolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsWarmStart: true, SupportsCancellation: true, SupportsTimeBudget: true, SupportsSwapRadius: true}
+}
 
+func (s *RandomWalkSolver) Solve(instance *qap.QAPInstance) SolverResult {
 	currentSolution := RandomSolution(instance.Size)
 	currentFitness := qap.CalculateFitness(instance, currentSolution)
+	bestSolution := append([]int(nil), currentSolution...)
+	bestFitness := currentFitness
 
 	for iter := 0; iter < s.MaxIterations; iter++ {
-		i, j := rand.Intn(instance.Size), 1+rand.Intn(instance.Size-2)
-		j = (i + j) % instance.Size
+		i, j := randomSwap(nil, instance)
 
-		newSolution := make([]int, instance.Size)
-		copy(newSolution, currentSolution)
-		newSolution[i], newSolution[j] = newSolution[j], newSolution[i]
-		newFitness := qap.CalculateFitness(instance, newSolution)
+		// Every move is accepted, so it is applied in place
+		currentFitness = ApplyMove(instance, currentSolution, currentFitness, i, j)
 
-		copy(currentSolution, newSolution)
-		currentFitness = newFitness
-
-		if bestFitness == -1 || currentFitness < bestFitness {
+		if currentFitness < bestFitness {
 			copy(bestSolution, currentSolution)
 			bestFitness = currentFitness
 		}
@@ -61,15 +57,18 @@ func (s *RandomWalkSolver) SolveWithMetrics(
 	metricsCollector *metrics.MetricsCollector,
 	instanceName string,
 	runNumber int,
+	opts SolveOptions,
 ) SolverResult {
 	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
 
-	// Initial values for solution and fitness
-	bestSolution := make([]int, instance.Size)
-	bestFitness := -1
+	currentSolution := tracker.initialSolution(instance.Size)
+	tracker.repair(currentSolution)
+	currentFitness := tracker.fitness(currentSolution)
 
-	currentSolution := RandomSolution(instance.Size)
-	currentFitness := qap.CalculateFitness(instance, currentSolution)
+	// The walk starts at the best solution so far
+	bestSolution := append([]int(nil), currentSolution...)
+	bestFitness := currentFitness
 
 	// Metrics counters
 	totalSteps := 0
@@ -83,30 +82,37 @@ func (s *RandomWalkSolver) SolveWithMetrics(
 	initialSolution = make([]int, len(currentSolution))
 	copy(initialSolution, currentSolution)
 	initialFitness = currentFitness
+	tracker.improved(0, initialFitness)
 
 	// Start the random walk search
 	for iter := 0; iter < s.MaxIterations; iter++ {
+		if tracker.shouldStop() {
+			break
+		}
+		tracker.progress(totalEvaluations, totalSteps)
+
 		// Randomly select two indices i and j
-		i, j := rand.Intn(instance.Size), 1+rand.Intn(instance.Size-2)
-		j = (i + j) % instance.Size
+		i, j := randomSwap(tracker.rng, instance)
+		if !tracker.allows(currentSolution, i, j) {
+			continue
+		}
 
-		// Generate a new solution by swapping i and j
-		newSolution := make([]int, instance.Size)
-		copy(newSolution, currentSolution)
-		newSolution[i], newSolution[j] = newSolution[j], newSolution[i]
-		newFitness := qap.CalculateFitness(instance, newSolution)
+		// Move to the neighbor by swapping i and j in place
+		newFitness := tracker.applyMove(currentSolution, currentFitness, i, j)
 
 		totalEvaluations++
 		totalSolutionsChecked++
 
 		// Accept the new solution
-		copy(currentSolution, newSolution)
+		tracker.move(iter, i, j, newFitness-currentFitness, newFitness, "")
+		tracker.counts.accept(newFitness - currentFitness)
 		currentFitness = newFitness
 
 		// If the new solution is better, update the best solution
-		if bestFitness == -1 || currentFitness < bestFitness {
+		if currentFitness < bestFitness {
 			copy(bestSolution, currentSolution)
 			bestFitness = currentFitness
+			tracker.improved(totalEvaluations, bestFitness)
 		}
 
 		totalSteps++
@@ -116,20 +122,18 @@ func (s *RandomWalkSolver) SolveWithMetrics(
 	elapsedTime := time.Since(startTime)
 
 	// Record metrics if the collector is provided
-	if metricsCollector != nil {
-		metricsCollector.AddRunMetrics(metrics.RunMetrics{
-			InstanceName:     instanceName,
-			SolverName:       s.Name(),
-			Run:              runNumber,
-			InitialFitness:   initialFitness,
-			FinalFitness:     bestFitness,
-			TimeElapsed:      elapsedTime,
-			StepsCount:       totalSteps,
-			EvaluationsCount: totalEvaluations,
-			SolutionsChecked: totalSolutionsChecked,
-			Solution:         bestSolution,
-		})
-	}
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     bestFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalSolutionsChecked,
+		Solution:         bestSolution,
+	})
 
 	// Return the result
 	return SolverResult{
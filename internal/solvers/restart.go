@@ -0,0 +1,213 @@
+package solvers
+
+import (
+	"fmt"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// What a restart continues from, see RestartPolicy.Keep
+const (
+	RestartKeepBest = "best" // the best solution of the run so far
+	RestartKeepNone = "none" // a random solution
+)
+
+// defaultRestarts bounds the restarts of a run when the policy does not and
+// the run has no time limit or stop condition that would end it
+const defaultRestarts = 10
+
+// RestartPolicy restarts a trajectory solver when its search stagnates. The
+// run is split into segments, each a run of the solver ended by When, e.g.
+// "stall>50000" or "idle>2s || evals>1e6", or by the solver itself. The next
+// segment starts from the best solution so far or from scratch, disturbed by
+// Perturb random swaps. Policies are written as
+//
+//	when=stall>50000,keep=best,perturb=5,max=10
+//
+// and attached to solvers by the restart.<solver> keys of the configuration
+// file, see SolverFactory.SetRestartPolicies.
+type RestartPolicy struct {
+	When    *StopCondition // ends a segment, its variables count from the segment start
+	Keep    string         // RestartKeepBest or RestartKeepNone
+	Perturb int            // random swaps applied to the restart solution
+	Max     int            // restarts per run, 0 = until the run's time limit or stop condition
+}
+
+// ParseRestartPolicy parses a policy, see RestartPolicy. keep defaults to
+// best and max to 10.
+func ParseRestartPolicy(spec string) (*RestartPolicy, error) {
+	policy := &RestartPolicy{Keep: RestartKeepBest, Max: defaultRestarts}
+	for _, field := range strings.Split(spec, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(field, "=")
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid restart setting %q, expected key=value", field)
+		}
+		switch key {
+		case "when":
+			when, err := ParseStopCondition(value)
+			if err != nil {
+				return nil, err
+			}
+			policy.When = when
+		case "keep":
+			value = strings.ToLower(value)
+			if value != RestartKeepBest && value != RestartKeepNone {
+				return nil, fmt.Errorf("invalid keep %q, use %s or %s", value, RestartKeepBest, RestartKeepNone)
+			}
+			policy.Keep = value
+		case "perturb", "max":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s %q, expected a count of 0 or more", key, value)
+			}
+			if key == "perturb" {
+				policy.Perturb = n
+			} else {
+				policy.Max = n
+			}
+		default:
+			return nil, fmt.Errorf("unknown restart setting %q, use when, keep, perturb or max", key)
+		}
+	}
+	if policy.When == nil {
+		return nil, fmt.Errorf("restart policy %q needs when=<condition>", spec)
+	}
+	return policy, nil
+}
+
+func (p *RestartPolicy) String() string {
+	return fmt.Sprintf("when=%s,keep=%s,perturb=%d,max=%d", p.When, p.Keep, p.Perturb, p.Max)
+}
+
+// WithRestarts wraps a solver so its runs follow a restart policy. The solver
+// must stop early on a condition, and keep=best needs warm starts.
+func WithRestarts(solver Solver, policy *RestartPolicy) (Solver, error) {
+	capabilities := CapabilitiesOf(solver)
+	if !capabilities.SupportsMetrics || !capabilities.SupportsCancellation {
+		return nil, fmt.Errorf("%s cannot stop a search early, restart policies do not apply", solver.Name())
+	}
+	if policy.Keep == RestartKeepBest && !capabilities.SupportsWarmStart {
+		return nil, fmt.Errorf("%s cannot continue from a given solution, use keep=%s", solver.Name(), RestartKeepNone)
+	}
+	return &restartSolver{Solver: solver, policy: policy}, nil
+}
+
+// restartSolver runs a solver in segments, see RestartPolicy
+type restartSolver struct {
+	Solver
+	policy *RestartPolicy
+}
+
+func (s *restartSolver) Description() string {
+	return fmt.Sprintf("%s, restarted with %s", s.Solver.Description(), s.policy)
+}
+
+func (s *restartSolver) Capabilities() Capabilities {
+	return CapabilitiesOf(s.Solver)
+}
+
+func (s *restartSolver) Solve(instance *qap.QAPInstance) SolverResult {
+	return s.SolveWithMetrics(instance, nil, "", 0, SolveOptions{})
+}
+
+// SolveWithMetrics records the segments as one run. The run's own time limit
+// is shared by the segments; its stop condition is checked between them.
+func (s *restartSolver) SolveWithMetrics(
+	instance *qap.QAPInstance,
+	metricsCollector *metrics.MetricsCollector,
+	instanceName string,
+	runNumber int,
+	opts SolveOptions,
+) SolverResult {
+	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
+
+	restarts := s.policy.Max
+	if restarts == 0 && opts.TimeLimit <= 0 && opts.Stop == nil {
+		restarts = defaultRestarts
+	}
+
+	var best []int
+	bestFitness, initialFitness := 0, 0
+	totalSteps := 0
+	totalEvaluations := 0
+	for segment := 0; (restarts == 0 || segment <= restarts) && !tracker.shouldStop(); segment++ {
+		segmentOpts := opts
+		segmentOpts.Stop = s.policy.When
+		segmentOpts.Seed = tracker.rng.Int63()
+		segmentOpts.Label = ""
+		segmentOpts.Start = nil
+		if opts.TimeLimit > 0 {
+			// Measured like the tracker, so the last segment ends the run at its limit
+			segmentOpts.TimeLimit = opts.TimeLimit - time.Since(tracker.start)
+			if segmentOpts.TimeLimit <= 0 {
+				break
+			}
+		}
+		if segment == 0 {
+			segmentOpts.Start = opts.Start
+		} else {
+			segmentOpts.Start = s.restartSolution(tracker, best)
+			tracker.restarted(fmt.Sprintf("segment %d starts with keep=%s, best so far %d",
+				segment+1, s.policy.Keep, instance.Objective(bestFitness)))
+		}
+
+		run := runSegment(s.Solver, instance, instanceName, segmentOpts)
+		totalSteps += run.StepsCount
+		totalEvaluations += run.EvaluationsCount
+		tracker.counts.add(moveCounts{run.AcceptedMoves, run.RejectedMoves, run.ImprovingMoves})
+		tracker.addOverhead(run.OverheadTime)
+		if segment == 0 {
+			initialFitness = run.InitialFitness
+		}
+		if best == nil || run.FinalFitness < bestFitness {
+			best = append([]int(nil), run.Solution...)
+			bestFitness = run.FinalFitness
+			tracker.improved(totalEvaluations, bestFitness)
+		}
+		tracker.progress(totalEvaluations, totalSteps)
+	}
+
+	elapsedTime := time.Since(startTime)
+
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     bestFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalEvaluations,
+		Solution:         best,
+	})
+
+	return SolverResult{
+		Solution: best,
+		Fitness:  bestFitness,
+	}
+}
+
+// restartSolution returns the start of the next segment: the best solution
+// or, with keep=none, a random one, disturbed by the policy's random swaps
+func (s *restartSolver) restartSolution(tracker *runTracker, best []int) []int {
+	n := len(best)
+	start := append([]int(nil), best...)
+	if s.policy.Keep == RestartKeepNone {
+		start = tracker.rng.Perm(n)
+	}
+	for k := 0; k < s.policy.Perturb && n > 1; k++ {
+		i, j := randomSwap(tracker.rng, tracker.instance)
+		start[i], start[j] = start[j], start[i]
+	}
+	tracker.repair(start)
+	return start
+}
@@ -0,0 +1,466 @@
+package solvers
+
+import (
+	"log"
+	"math/rand"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"qap_solver/pkg"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// memorySampleInterval is the minimum time between two memory samples.
+// runtime.ReadMemStats stops the world, so it must not run every iteration.
+const memorySampleInterval = 20 * time.Millisecond
+
+// runTracker follows a single SolveWithMetrics call. It accounts for the
+// memory allocated by the process during the run, parallel runs included,
+// records the convergence trajectory and tells the solver loop when to stop
+// early. All instrumentation of a run goes through it, so the time it takes
+// can be told apart from the search, see overhead.
+type runTracker struct {
+	instance    *qap.QAPInstance
+	trace       *metrics.TraceWriter
+	diagnostics *metrics.Diagnostics
+	artifacts   *metrics.Artifacts
+	label       string
+	start       time.Time
+	trajectory  *metrics.TrajectorySampler
+	memoryLimit uint64
+	timeLimit   time.Duration
+	baseline    runtime.MemStats
+	peakHeap    uint64
+	lastSample  time.Time
+	reason      string
+	cpuStart    time.Duration
+	cpuTime     time.Duration
+	extraCPU    time.Duration
+
+	// hardForbidden is set when moves creating forbidden assignments are rejected
+	hardForbidden bool
+
+	// radius bounds the distance between the locations of swapped facilities, see SolveOptions.SwapRadius
+	radius int
+
+	// rng is the random generator of the run, seeded with seed
+	rng  *rand.Rand
+	seed int64
+
+	// warmStart is the initial solution, see SolveOptions.Start
+	warmStart []int
+
+	// log and verbosity of the solver's progress lines, see SolveOptions.Log
+	log        *log.Logger
+	verbosity  int
+	lastLogged time.Time
+
+	// constructions caches deterministic constructions, see SolveOptions.Constructions
+	constructions *ConstructionCache
+
+	// custom fitness and delta evaluation, nil for the standard ones
+	fitnessFunc FitnessFunc
+	deltaFunc   DeltaFunc
+
+	// user-defined stopping condition and the state it needs
+	stop         *StopCondition
+	optimum      int
+	hasOptimum   bool
+	bestFitness  int
+	hasBest      bool
+	improvedAt   int // evaluations at the last improvement
+	improvedTime time.Time
+
+	// progress reported for the live counters, see publish
+	evaluations, moves                   int
+	publishedEvaluations, publishedMoves int
+	currentTemperature                   float64
+	hasTemperature                       bool
+	currentTenure                        int
+
+	// acceptance decisions of the run, see moveCounts
+	counts moveCounts
+
+	// overhead is the time in nanoseconds spent on instrumentation: setting up
+	// the tracker, the trajectory, traces, diagnostics, memory samples and live
+	// counters. Workers of parallel solvers add to it too, hence atomic.
+	overhead atomic.Int64
+}
+
+// moveCounts tallies the acceptance decisions of a search. Accepted moves
+// are the steps taken, improving ones lower the current fitness. Rejected
+// moves were evaluated and turned down by an acceptance rule: the Metropolis
+// criterion of annealing or the tabu status of tabu search. Local searches
+// only consider improving moves and reject none.
+type moveCounts struct {
+	accepted, rejected, improving int
+}
+
+// accept counts a step changing the current fitness by delta
+func (c *moveCounts) accept(delta int) {
+	c.accepted++
+	if delta < 0 {
+		c.improving++
+	}
+}
+
+// reject counts a move turned down by the acceptance rule
+func (c *moveCounts) reject() {
+	c.rejected++
+}
+
+// add merges the counts of a worker
+func (c *moveCounts) add(other moveCounts) {
+	c.accepted += other.accepted
+	c.rejected += other.rejected
+	c.improving += other.improving
+}
+
+func newRunTracker(instance *qap.QAPInstance, opts SolveOptions) *runTracker {
+	setup := time.Now()
+	t := &runTracker{
+		instance:      instance,
+		trace:         opts.Trace,
+		diagnostics:   opts.Diagnostics,
+		artifacts:     opts.Artifacts,
+		label:         opts.Label,
+		memoryLimit:   opts.MemoryLimit,
+		timeLimit:     opts.TimeLimit,
+		fitnessFunc:   opts.Fitness,
+		deltaFunc:     opts.Delta,
+		seed:          opts.Seed,
+		warmStart:     opts.Start,
+		constructions: opts.Constructions,
+		log:           opts.Log,
+		verbosity:     opts.Verbosity,
+		radius:        opts.SwapRadius,
+		trajectory:    metrics.NewTrajectorySampler(opts.TrajectoryPoints),
+		stop:          opts.Stop,
+		optimum:       opts.Optimum,
+		hasOptimum:    opts.HasOptimum,
+	}
+	if t.seed == 0 {
+		t.seed = rand.Int63()
+	}
+	t.rng = rand.New(rand.NewSource(t.seed))
+	t.hardForbidden = opts.Forbidden == ForbiddenHard &&
+		instance.Constraints != nil && instance.Constraints.HasForbidden()
+	runtime.ReadMemStats(&t.baseline)
+	t.peakHeap = t.baseline.HeapAlloc
+
+	// Keep the run on one OS thread so its thread CPU time can be measured
+	// even when other runs execute in parallel
+	runtime.LockOSThread()
+	t.cpuStart, _ = pkg.ThreadCPUTime()
+	liveRuns.Add(1)
+	t.start = time.Now()
+	t.lastSample = t.start
+	t.improvedTime = t.start
+	t.instrumented(setup)
+	return t
+}
+
+// instrumented adds the time since start to the instrumentation overhead
+func (t *runTracker) instrumented(start time.Time) {
+	t.overhead.Add(int64(time.Since(start)))
+}
+
+// addOverhead adds the instrumentation overhead of a nested run, such as an
+// ensemble slice
+func (t *runTracker) addOverhead(d time.Duration) {
+	t.overhead.Add(int64(d))
+}
+
+// initialSolution returns a copy of the warm start solution, or a random
+// solution of size n without one
+func (t *runTracker) initialSolution(n int) []int {
+	if len(t.warmStart) != n {
+		return RandomSolutionFrom(t.rng, n)
+	}
+	return append([]int(nil), t.warmStart...)
+}
+
+// improved records a new best fitness found after the given number of evaluations
+func (t *runTracker) improved(evaluations, fitness int) {
+	defer t.instrumented(time.Now())
+	t.bestFitness, t.hasBest = fitness, true
+	t.improvedAt, t.improvedTime = evaluations, time.Now()
+	t.trajectory.Add(metrics.TrajectoryPoint{
+		Evaluations: evaluations,
+		Elapsed:     time.Since(t.start),
+		Fitness:     fitness,
+	})
+	t.logf(VerbosityImprovements, "best %d after %d evaluations, %v", t.instance.Objective(fitness), evaluations,
+		time.Since(t.start).Round(time.Millisecond))
+}
+
+// tracing reports whether moves are traced, so solvers can skip building
+// the trace context otherwise
+func (t *runTracker) tracing() bool {
+	return t.trace != nil
+}
+
+// move forwards an accepted swap of positions i and j to the trace, if any
+func (t *runTracker) move(iteration, i, j, delta, fitness int, context string) {
+	if t.trace == nil {
+		return
+	}
+	defer t.instrumented(time.Now())
+	t.trace.Move(iteration, i, j, delta, fitness, context)
+}
+
+// annealed forwards an annealing step to the diagnostics, if any
+func (t *runTracker) annealed(iteration int, temperature float64, worse, accepted bool) {
+	if t.diagnostics == nil {
+		return
+	}
+	defer t.instrumented(time.Now())
+	t.diagnostics.Anneal(iteration, temperature, worse, accepted)
+}
+
+// tabuAssigned forwards a tabu assignment to the diagnostics, if any
+func (t *runTracker) tabuAssigned(n, facility, location int) {
+	if t.diagnostics == nil {
+		return
+	}
+	defer t.instrumented(time.Now())
+	t.diagnostics.TabuAssignment(n, facility, location)
+}
+
+// tabuBlocked forwards a move blocked by its tabu status to the diagnostics, if any
+func (t *runTracker) tabuBlocked(n, facility, location int, aspiration bool) {
+	if t.diagnostics == nil {
+		return
+	}
+	defer t.instrumented(time.Now())
+	t.diagnostics.TabuBlocked(n, facility, location, aspiration)
+}
+
+// operatorApplied forwards an application of one of several operators to the
+// diagnostics, if any
+func (t *runTracker) operatorApplied(name string, strength int, accepted bool, improvement int) {
+	if t.diagnostics == nil {
+		return
+	}
+	defer t.instrumented(time.Now())
+	t.diagnostics.Operator(name, strength, accepted, improvement)
+}
+
+// artifact writes a CSV artifact of the run built by rows, if artifacts are collected
+func (t *runTracker) artifact(name string, rows func() [][]string) {
+	if t.artifacts == nil {
+		return
+	}
+	defer t.instrumented(time.Now())
+	t.artifacts.WriteCSV(name, rows())
+}
+
+// artifactMatrix writes a table of counts as an artifact of the run, if artifacts are collected
+func (t *runTracker) artifactMatrix(name, rowLabel, colLabel string, matrix [][]int) {
+	if t.artifacts == nil {
+		return
+	}
+	defer t.instrumented(time.Now())
+	t.artifacts.WriteMatrix(name, rowLabel, colLabel, matrix)
+}
+
+// construction returns the solution of a deterministic construction named
+// key from the construction cache, or builds it and adds it to the cache. The
+// cache only applies to the plain QAP cost, and constructions cut short by
+// the stopping criteria are not stored.
+func (t *runTracker) construction(instanceName, key string, build func() []int) []int {
+	if t.constructions == nil || t.fitnessFunc != nil || !qap.CanCacheDeltas(t.instance) {
+		return build()
+	}
+	hash := qap.ContentHash(t.instance)
+	solution, ok := t.constructions.get(instanceName, hash, key, t.instance.Size)
+	if ok {
+		return solution
+	}
+	solution = build()
+	if t.reason == "" {
+		t.constructions.put(instanceName, hash, key, solution)
+	}
+	return solution
+}
+
+// fitness evaluates a solution with the custom fitness function, if any
+func (t *runTracker) fitness(solution []int) int {
+	if t.fitnessFunc != nil {
+		return t.fitnessFunc(t.instance, solution)
+	}
+	return qap.CalculateFitness(t.instance, solution)
+}
+
+// delta returns the fitness change of swapping positions i and j. A custom
+// fitness without a delta hook falls back to two full evaluations.
+func (t *runTracker) delta(solution []int, i, j int) int {
+	switch {
+	case t.deltaFunc != nil:
+		return t.deltaFunc(t.instance, solution, i, j)
+	case t.fitnessFunc != nil:
+		before := t.fitnessFunc(t.instance, solution)
+		solution[i], solution[j] = solution[j], solution[i]
+		after := t.fitnessFunc(t.instance, solution)
+		solution[i], solution[j] = solution[j], solution[i]
+		return after - before
+	default:
+		return qap.SwapDelta(t.instance, solution, i, j)
+	}
+}
+
+// allows reports whether swapping positions i and j may be evaluated. Swaps
+// of two dummy facilities of a padded instance change nothing and are
+// skipped. With hard forbidden constraints swaps that create a forbidden
+// assignment are skipped, with a swap radius swaps between locations farther
+// apart, in either direction of an asymmetric distance matrix.
+func (t *runTracker) allows(solution []int, i, j int) bool {
+	if t.instance.IsDummy(i) && t.instance.IsDummy(j) {
+		return false
+	}
+	if t.radius > 0 {
+		a, b := solution[i], solution[j]
+		if max(t.instance.DistanceMatrix[a][b], t.instance.DistanceMatrix[b][a]) > t.radius {
+			return false
+		}
+	}
+	return !t.hardForbidden || t.instance.Constraints.AllowsSwap(solution, i, j)
+}
+
+// repair moves facilities of a starting solution off forbidden locations
+// when forbidden constraints are hard, so the search starts feasible
+func (t *runTracker) repair(solution []int) {
+	if t.hardForbidden {
+		t.instance.Constraints.RepairForbidden(solution)
+	}
+}
+
+// addCPUTime adds the CPU time of helper threads, such as parallel workers,
+// to the CPU time of the run
+func (t *runTracker) addCPUTime(d time.Duration) {
+	t.extraCPU += d
+}
+
+// shouldStop reports whether the run has to terminate early.
+// Solvers call it once per iteration of their main loop.
+func (t *runTracker) shouldStop() bool {
+	if t.reason != "" {
+		return true
+	}
+	if t.timeLimit > 0 && time.Since(t.start) > t.timeLimit {
+		t.reason = TerminationTimeLimit
+		return true
+	}
+	if t.stop != nil && t.stop.Eval(t.stopState()) {
+		t.reason = TerminationStop
+		return true
+	}
+	if time.Since(t.lastSample) < memorySampleInterval {
+		return false
+	}
+	t.lastSample = time.Now()
+	defer t.instrumented(t.lastSample)
+	t.publish()
+	t.logSample()
+
+	heap := t.sampleHeap()
+	if t.memoryLimit > 0 && heap > t.memoryLimit {
+		// The limit is soft: collect garbage first and only abort
+		// when the live heap is still over the limit.
+		runtime.GC()
+		if t.sampleHeap() > t.memoryLimit {
+			t.reason = TerminationMemoryLimit
+			return true
+		}
+	}
+	return false
+}
+
+// stopState collects the variables of the stopping condition
+func (t *runTracker) stopState() StopState {
+	now := time.Now()
+	s := StopState{
+		Evaluations: t.evaluations,
+		Moves:       t.moves,
+		Elapsed:     now.Sub(t.start),
+		Idle:        now.Sub(t.improvedTime),
+		Stall:       max(t.evaluations-t.improvedAt, 0),
+		HasFitness:  t.hasBest,
+		HasGap:      t.hasBest && t.hasOptimum,
+	}
+	if t.hasBest {
+		s.Fitness = t.instance.Objective(t.bestFitness)
+	}
+	if s.HasGap {
+		s.Gap = metrics.GapPercent(t.bestFitness, t.optimum)
+	}
+	return s
+}
+
+func (t *runTracker) sampleHeap() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	if ms.HeapAlloc > t.peakHeap {
+		t.peakHeap = ms.HeapAlloc
+	}
+	return ms.HeapAlloc
+}
+
+// record completes the run metrics with memory accounting, the termination
+// reason, the trajectory and the objective breakdown, then adds them to the collector
+func (t *runTracker) record(collector *metrics.MetricsCollector, m metrics.RunMetrics) {
+	if cpuEnd, ok := pkg.ThreadCPUTime(); ok {
+		t.cpuTime = cpuEnd - t.cpuStart + t.extraCPU
+	}
+	runtime.UnlockOSThread()
+
+	// The final counts also cover solvers that never report progress
+	t.evaluations, t.moves = max(t.evaluations, m.EvaluationsCount), max(t.moves, m.StepsCount)
+	t.publish()
+	liveRuns.Add(-1)
+
+	if collector == nil {
+		return
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	if ms.HeapAlloc > t.peakHeap {
+		t.peakHeap = ms.HeapAlloc
+	}
+
+	m.AllocatedBytes = ms.TotalAlloc - t.baseline.TotalAlloc
+	m.Mallocs = ms.Mallocs - t.baseline.Mallocs
+	m.PeakHeapBytes = t.peakHeap
+	m.CPUTime = t.cpuTime
+	m.OverheadTime = time.Duration(t.overhead.Load())
+	m.TerminationReason = TerminationCompleted
+	if t.reason != "" {
+		m.TerminationReason = t.reason
+	}
+
+	m.Seed = t.seed
+	m.AcceptedMoves = t.counts.accepted
+	m.RejectedMoves = t.counts.rejected
+	m.ImprovingMoves = t.counts.improving
+	m.Trajectory = t.trajectory.Points()
+	m.Artifacts = t.artifacts.Path()
+	if t.label != "" {
+		m.SolverName = t.label
+	}
+
+	m.QAPCost = m.FinalFitness
+	if t.instance.Relocation != nil || t.instance.Constraints != nil || t.instance.Stochastic != nil ||
+		t.instance.Maximize || t.fitnessFunc != nil {
+		m.QAPCost = qap.QAPCost(t.instance, m.Solution)
+	}
+	if t.instance.Relocation != nil {
+		m.RelocationCost = qap.RelocationCost(t.instance, m.Solution)
+	}
+	if t.instance.Constraints != nil {
+		m.ConstraintViolation = t.instance.Constraints.Violation(t.instance, m.Solution)
+	}
+
+	collector.AddRunMetrics(m)
+}
@@ -1,10 +1,12 @@
 package solvers
 
 import (
+	"fmt"
 	"math"
 	"math/rand"
 	"qap_solver/internal/metrics"
 	"qap_solver/internal/qap"
+	"qap_solver/pkg"
 	"time"
 )
 
@@ -12,13 +14,15 @@ type SimulatedAnnealingSolver struct {
 	Alpha          float64
 	P              int
 	AcceptanceProb float64
+	Bias           string // neighbor selection: BiasUniform, BiasFlow or BiasAdaptive
 }
 
-func NewSimulatedAnnealingSolver(alpha float64, p int, acceptanceProb float64) *SimulatedAnnealingSolver {
+func NewSimulatedAnnealingSolver(alpha float64, p int, acceptanceProb float64, bias string) *SimulatedAnnealingSolver {
 	return &SimulatedAnnealingSolver{
 		Alpha:          alpha,
 		P:              p,
 		AcceptanceProb: acceptanceProb,
+		Bias:           bias,
 	}
 }
 
@@ -27,7 +31,11 @@ func (s *SimulatedAnnealingSolver) Name() string {
 }
 
 func (s *SimulatedAnnealingSolver) Description() string {
-	return "Simulated Annealing with adaptive initial temperature and cooling schedule"
+	return fmt.Sprintf("Simulated Annealing with adaptive initial temperature and cooling schedule (%s neighbors)", s.Bias)
+}
+
+func (s *SimulatedAnnealingSolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsWarmStart: true, SupportsCancellation: true, SupportsTimeBudget: true, SupportsSwapRadius: true}
 }
 
 func (s *SimulatedAnnealingSolver) Solve(instance *qap.QAPInstance) SolverResult {
@@ -42,25 +50,23 @@ func (s *SimulatedAnnealingSolver) Solve(instance *qap.QAPInstance) SolverResult
 	bestFitness := currentFitness
 
 	// Estimate average delta for worse moves to set initial temperature
-	T := s.estimateInitialTemperature(instance, current, currentFitness)
+	T := s.estimateInitialTemperature(nil, func(solution []int) int {
+		return qap.CalculateFitness(instance, solution)
+	}, n, current, currentFitness)
+	T0 := T
+	pairs := newFlowRankedPairs(instance)
 
 	minTemp := -1.0 / math.Log(s.AcceptanceProb)
 	noImprovementCounter := 0
 	maxNoImprovement := s.P * Lk
 
 	for T > minTemp || noImprovementCounter < maxNoImprovement {
-		i1, i2 := rand.Intn(n), 1+rand.Intn(n-2)
-		i1 = (i1 + i2) % n
+		i1, i2 := pairs.pick(nil, instance, s.flowBias(T, T0, minTemp))
 
-		neighbor := make([]int, n)
-		copy(neighbor, current)
-		neighbor[i1], neighbor[i2] = neighbor[i2], neighbor[i1]
-
-		newFitness := qap.CalculateFitness(instance, neighbor)
+		newFitness := ApplyMove(instance, current, currentFitness, i1, i2)
 		delta := float64(newFitness - currentFitness)
 
 		if delta < 0 || (rand.Float64() < math.Exp(-delta/T) && delta != 0) {
-			copy(current, neighbor)
 			currentFitness = newFitness
 
 			if currentFitness < bestFitness {
@@ -69,6 +75,7 @@ func (s *SimulatedAnnealingSolver) Solve(instance *qap.QAPInstance) SolverResult
 				noImprovementCounter = 0
 			}
 		} else {
+			RevertMove(current, i1, i2)
 			noImprovementCounter += 1
 		}
 		T *= s.Alpha
@@ -85,24 +92,30 @@ func (s *SimulatedAnnealingSolver) SolveWithMetrics(
 	metricsCollector *metrics.MetricsCollector,
 	instanceName string,
 	runNumber int,
+	opts SolveOptions,
 ) SolverResult {
 	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
 
 	n := instance.Size
 	Lk := n * (n - 1) / 2
 
-	current := RandomSolution(n)
+	current := tracker.initialSolution(n)
+	tracker.repair(current)
 	best := make([]int, n)
 	copy(best, current)
 
-	currentFitness := qap.CalculateFitness(instance, current)
+	currentFitness := tracker.fitness(current)
 	bestFitness := currentFitness
 
 	initialSolution := make([]int, n)
 	copy(initialSolution, current)
 	initialFitness := currentFitness
+	tracker.improved(0, initialFitness)
 
-	T := s.estimateInitialTemperature(instance, current, currentFitness)
+	T := s.estimateInitialTemperature(tracker.rng, tracker.fitness, n, current, currentFitness)
+	T0 := T
+	pairs := newFlowRankedPairs(instance)
 	minTemp := -1.0 / math.Log(s.AcceptanceProb)
 
 	noImprovementCounter := 0
@@ -113,30 +126,47 @@ func (s *SimulatedAnnealingSolver) SolveWithMetrics(
 	totalSolutionsChecked := 0
 
 	for T > minTemp || noImprovementCounter < maxNoImprovement {
-		i1, i2 := rand.Intn(n), 1+rand.Intn(n-2)
-		i1 = (i1 + i2) % n
-
-		neighbor := make([]int, n)
-		copy(neighbor, current)
-		neighbor[i1], neighbor[i2] = neighbor[i2], neighbor[i1]
+		if tracker.shouldStop() {
+			break
+		}
+		tracker.progress(totalEvaluations, totalSteps)
+		tracker.temperature(T)
+
+		i1, i2 := pairs.pick(tracker.rng, instance, s.flowBias(T, T0, minTemp))
+		if !tracker.allows(current, i1, i2) {
+			// A rejected move still cools the search so the loop terminates
+			noImprovementCounter++
+			T *= s.Alpha
+			continue
+		}
 
-		newFitness := qap.CalculateFitness(instance, neighbor)
+		// The move is applied in place and reverted when rejected
+		newFitness := tracker.applyMove(current, currentFitness, i1, i2)
 		totalEvaluations++
 		totalSolutionsChecked++
 
 		delta := float64(newFitness - currentFitness)
 
-		if delta < 0 || (rand.Float64() < math.Exp(-delta/T) && delta != 0) {
+		accepted := delta < 0 || (tracker.rng.Float64() < math.Exp(-delta/T) && delta != 0)
+		tracker.annealed(totalEvaluations, T, delta > 0, accepted)
+		if accepted {
 			totalSteps++
-			copy(current, neighbor)
+			tracker.counts.accept(newFitness - currentFitness)
+			if tracker.tracing() {
+				tracker.move(totalEvaluations, i1, i2, newFitness-currentFitness, newFitness,
+					fmt.Sprintf("temperature=%.4f", T))
+			}
 			currentFitness = newFitness
 
 			if currentFitness < bestFitness {
 				copy(best, current)
 				bestFitness = currentFitness
+				tracker.improved(totalEvaluations, bestFitness)
 				noImprovementCounter = 0
 			}
 		} else {
+			RevertMove(current, i1, i2)
+			tracker.counts.reject()
 			noImprovementCounter += 1
 		}
 
@@ -145,20 +175,18 @@ func (s *SimulatedAnnealingSolver) SolveWithMetrics(
 
 	elapsedTime := time.Since(startTime)
 
-	if metricsCollector != nil {
-		metricsCollector.AddRunMetrics(metrics.RunMetrics{
-			InstanceName:     instanceName,
-			SolverName:       s.Name(),
-			Run:              runNumber,
-			InitialFitness:   initialFitness,
-			FinalFitness:     bestFitness,
-			TimeElapsed:      elapsedTime,
-			StepsCount:       totalSteps,
-			EvaluationsCount: totalEvaluations,
-			SolutionsChecked: totalSolutionsChecked,
-			Solution:         best,
-		})
-	}
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     bestFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalSolutionsChecked,
+		Solution:         best,
+	})
 
 	return SolverResult{
 		Solution: best,
@@ -166,20 +194,34 @@ func (s *SimulatedAnnealingSolver) SolveWithMetrics(
 	}
 }
 
-func (s *SimulatedAnnealingSolver) estimateInitialTemperature(instance *qap.QAPInstance, sol []int, fitness int) float64 {
-	n := instance.Size
+// flowBias returns how strongly neighbor selection favors flow-ranked pairs at
+// temperature T. The adaptive bias falls from 1 to 0 on a logarithmic scale as
+// T cools from T0 to minTemp.
+func (s *SimulatedAnnealingSolver) flowBias(T, T0, minTemp float64) float64 {
+	switch s.Bias {
+	case BiasFlow:
+		return 1
+	case BiasAdaptive:
+		if T0 <= minTemp || T <= minTemp {
+			return 0
+		}
+		return math.Log(T/minTemp) / math.Log(T0/minTemp)
+	default:
+		return 0
+	}
+}
+
+func (s *SimulatedAnnealingSolver) estimateInitialTemperature(rng *rand.Rand, fitnessOf func([]int) int, n int, sol []int, fitness int) float64 {
 	numSamples := 100
 	var totalDelta float64
 	count := 0
 
 	for i := 0; i < numSamples; i++ {
-		i1, i2 := rand.Intn(n), 1+rand.Intn(n-2)
-		i1 = (i1 + i2) % n
+		i1, i2 := pkg.RandomDistinctPair(rng, n)
 
-		neighbor := make([]int, n)
-		copy(neighbor, sol)
-		neighbor[i1], neighbor[i2] = neighbor[i2], neighbor[i1]
-		newFitness := qap.CalculateFitness(instance, neighbor)
+		sol[i1], sol[i2] = sol[i2], sol[i1]
+		newFitness := fitnessOf(sol)
+		RevertMove(sol, i1, i2)
 		delta := float64(newFitness - fitness)
 		if delta > 0 {
 			totalDelta += delta
@@ -6,7 +6,7 @@ import (
 
 type SolverResult struct {
 	Solution []int
-	Fitness  int
+	Fitness  int // minimized fitness, see qap.QAPInstance.Objective for the objective value
 }
 
 // Solver interface defines the contract that all solvers must implement
@@ -20,3 +20,15 @@ type Solver interface {
 	// Description returns a description of the solver
 	Description() string
 }
+
+// DeterministicSolver is implemented by solvers that return the same solution
+// on every run, so identical solutions across runs are expected from them
+type DeterministicSolver interface {
+	Deterministic() bool
+}
+
+// IsDeterministic reports whether a solver declares itself deterministic
+func IsDeterministic(solver Solver) bool {
+	d, ok := solver.(DeterministicSolver)
+	return ok && d.Deterministic()
+}
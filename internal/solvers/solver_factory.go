@@ -2,14 +2,25 @@ package solvers
 
 import (
 	"fmt"
+	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // SolverFactory creates solver instances based on configuration strings
 type SolverFactory struct {
 	// Registry of available solvers
 	solverCreators map[string]func(args []string) (Solver, error)
+
+	// profiles supply the parameters of solvers configured with params=auto
+	profiles *Profiles
+
+	// restarts are the restart policies by solver type or label, see SetRestartPolicies
+	restarts map[string]*RestartPolicy
+
+	// strict rejects parameters that would be ignored, see SetStrict
+	strict bool
 }
 
 // NewSolverFactory creates a new factory with registered solvers
@@ -26,6 +37,13 @@ func NewSolverFactory() *SolverFactory {
 	factory.Register("heuristic", factory.createHeuristicSolver)
 	factory.Register("simanneal", factory.createSimulatedAnnealingSolver)
 	factory.Register("tabu", factory.createTabuSearchSolver)
+	factory.Register("ejection", factory.createEjectionChainSolver)
+	factory.Register("ctabu", factory.createCooperativeTabuSolver)
+	factory.Register("clusterinit", factory.createClusterInitSolver)
+	factory.Register("faqinit", factory.createFAQInitSolver)
+	factory.Register("ils", factory.createIteratedLocalSearchSolver)
+	factory.Register("ensemble", factory.createEnsembleSolver)
+	factory.Register("decompose", factory.createDecomposeSolver)
 
 	return factory
 }
@@ -35,9 +53,54 @@ func (f *SolverFactory) Register(name string, creator func(args []string) (Solve
 	f.solverCreators[strings.ToLower(name)] = creator
 }
 
+// SetStrict makes Create reject solver parameters it would otherwise ignore:
+// unknown keys, arguments without a value and values out of range, which
+// otherwise keep their defaults
+func (f *SolverFactory) SetStrict(strict bool) {
+	f.strict = strict
+}
+
+// ignored reports a solver argument without effect. It is an error in strict
+// mode, otherwise the argument is skipped and the default stays in place.
+func (f *SolverFactory) ignored(arg, reason string) error {
+	if !f.strict {
+		return nil
+	}
+	return fmt.Errorf("parameter %q would be ignored: %s", arg, reason)
+}
+
+// SetProfiles sets the parameter profiles used by solvers configured with
+// params=auto
+func (f *SolverFactory) SetProfiles(profiles *Profiles) {
+	f.profiles = profiles
+}
+
+// SetRestartPolicies attaches restart policies to the solvers created
+// afterwards. Policies are keyed by solver type, e.g. "tabu", or by label;
+// a label takes precedence over the type of the labeled solver.
+func (f *SolverFactory) SetRestartPolicies(policies map[string]*RestartPolicy) {
+	f.restarts = make(map[string]*RestartPolicy, len(policies))
+	for key, policy := range policies {
+		f.restarts[strings.ToLower(strings.TrimSpace(key))] = policy
+	}
+}
+
 // Create instantiates a solver based on a configuration string
-// Format: "solverName:param1=value1,param2=value2,..."
+// Format: "solverName:param1=value1,param2=value2,...@label=customName"
+// The optional label replaces the solver name in all output. With the
+// argument params=auto the other parameters come from the profile matching
+// each instance, see Profiles.
 func (f *SolverFactory) Create(config string) (Solver, error) {
+	config, options, hasOptions := strings.Cut(config, "@")
+	label := ""
+	if hasOptions {
+		key, value, _ := strings.Cut(options, "=")
+		if strings.ToLower(key) != "label" || value == "" {
+			return nil, fmt.Errorf("invalid solver option: @%s (expected @label=name)", options)
+		}
+		label = value
+	}
+
 	parts := strings.SplitN(config, ":", 2)
 	solverType := strings.ToLower(parts[0])
 
@@ -52,20 +115,68 @@ func (f *SolverFactory) Create(config string) (Solver, error) {
 		args = strings.Split(parts[1], ",")
 	}
 
-	return creator(args)
+	// The arguments of ensemble members include their own radius
+	radius := 0
+	if solverType != "ensemble" {
+		var err error
+		if args, radius, err = parseSwapRadius(args); err != nil {
+			return nil, err
+		}
+	}
+
+	var explicit []string
+	auto := false
+	for _, arg := range args {
+		if strings.EqualFold(strings.ReplaceAll(arg, " ", ""), ParamsAuto) {
+			auto = true
+		} else {
+			explicit = append(explicit, arg)
+		}
+	}
+
+	solver, err := creator(explicit)
+	if err == nil && auto {
+		solver = &profiledSolver{Solver: solver, create: creator, profiles: f.profiles, solver: solverType, args: explicit}
+	}
+	if err == nil && radius > 0 {
+		if !CapabilitiesOf(solver).SupportsSwapRadius {
+			return nil, fmt.Errorf("%s does not search swap neighborhoods, radius does not apply", solver.Name())
+		}
+		solver = &radiusSolver{Solver: solver, radius: radius}
+	}
+	if err == nil {
+		policy, ok := f.restarts[strings.ToLower(label)]
+		if !ok {
+			policy, ok = f.restarts[solverType]
+		}
+		if ok {
+			solver, err = WithRestarts(solver, policy)
+		}
+	}
+	if err != nil || label == "" {
+		return solver, err
+	}
+	return &labeledSolver{Solver: solver, label: label}, nil
 }
 
 func (f *SolverFactory) ListAvailable() []string {
 	var result []string
 
-	result = append(result, "Available solvers:")
+	result = append(result, "Available solvers (params=auto takes the parameters from the tuned profiles, see tune; radius=R restricts swaps to locations at most R apart):")
 	result = append(result, "  random:iterations=1000 - Random solution generator with 1000 iterations")
 	result = append(result, "  greedy:maxIter=10000 - Greedy search with max iterations")
-	result = append(result, "  steepest:maxIter=10000 - Steepest ascent search with max iterations")
+	result = append(result, "  steepest:maxIter=10000,polish=0 - Steepest ascent search with max iterations, polish=k finishes with cyclic exchanges of up to k facilities")
 	result = append(result, "  randomwalk:maxIter=10000 - Random walk search with max iterations 10000")
-	result = append(result, "  heuristic:maxIter=10000 - Heuristic search with max iterations 1000")
-	result = append(result, "  simanneal:alpha=0.9,p=10,acceptance=0.01 - Simulated Annealing with cooling schedule")
-	result = append(result, "  tabu:p=10 - Tabu Search with elite list and aspiration criteria")
+	result = append(result, "  heuristic:starts=1,rcl=0 - Greedy construction with random tie-breaking, best of starts constructions, rcl>0 picks GRASP-style among locations within that fraction of the cost range")
+	result = append(result, "  simanneal:alpha=0.9,p=10,acceptance=0.01,bias=uniform - Simulated Annealing with cooling schedule, bias=uniform|flow|adaptive")
+	result = append(result, "  tabu:p=10,tabuon=assignments,polish=0 - Tabu Search with elite list and aspiration criteria, tabuon=assignments|pairs|facilities, polish=k as for steepest")
+	result = append(result, "  ejection:depth=5,maxIter=10000 - Variable-depth ejection chain search")
+	result = append(result, "  ctabu:workers=8,sync=5000,p=10,tabuon=assignments - Cooperative parallel Tabu Search exchanging solutions every sync iterations (workers default to the CPU count)")
+	result = append(result, "  clusterinit:clusters=0,maxIter=1000 - Flow/distance clustering construction refined by swap descent (clusters=0 uses sqrt(n), maxIter=0 skips refinement)")
+	result = append(result, "  faqinit:iters=30,maxIter=0 - Frank-Wolfe on the doubly stochastic relaxation projected by the Hungarian method, a start for local search (maxIter>0 refines it by swap descent)")
+	result = append(result, "  ensemble:members=tabu|simanneal|ils,slice=10s,rounds=3 - Round-robin of the member solvers in time slices, each continuing from the best solution so far (members separated by |, rounds apply without a time limit)")
+	result = append(result, "  decompose:solver=tabu,maxIter=1000 - Solve every connected component of the flow graph separately with the given solver and recombine, for sparse instances such as esc and chr (maxIter bounds the swap descent after recombining)")
+	result = append(result, "  ils:perturb=swaps,strength=0,maxStrength=0,maxIter=1000,polish=0 - Iterated local search, perturb=swaps|scramble|reverse or several joined by + with strength in transpositions (0 = n/8), maxStrength > strength varies it like VNS, polish=k as for steepest")
 
 	return result
 }
@@ -83,6 +194,9 @@ func (f *SolverFactory) createRandomSolver(args []string) (Solver, error) {
 	for _, arg := range args {
 		parts := strings.SplitN(arg, "=", 2)
 		if len(parts) != 2 {
+			if err := f.ignored(arg, "expected key=value"); err != nil {
+				return nil, err
+			}
 			continue
 		}
 
@@ -92,7 +206,11 @@ func (f *SolverFactory) createRandomSolver(args []string) (Solver, error) {
 		if key == "iterations" {
 			if i, err := strconv.Atoi(value); err == nil && i > 0 {
 				iterations = i
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
 			}
+		} else if err := f.ignored(arg, "unknown parameter"); err != nil {
+			return nil, err
 		}
 	}
 
@@ -105,6 +223,9 @@ func (f *SolverFactory) createGreedySolver(args []string) (Solver, error) {
 	for _, arg := range args {
 		parts := strings.SplitN(arg, "=", 2)
 		if len(parts) != 2 {
+			if err := f.ignored(arg, "expected key=value"); err != nil {
+				return nil, err
+			}
 			continue
 		}
 		key := strings.ToLower(parts[0])
@@ -113,6 +234,12 @@ func (f *SolverFactory) createGreedySolver(args []string) (Solver, error) {
 		case "maxiter":
 			if i, err := strconv.Atoi(value); err == nil && i > 0 {
 				maxIterations = i
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
+			}
+		default:
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
 			}
 		}
 	}
@@ -121,10 +248,14 @@ func (f *SolverFactory) createGreedySolver(args []string) (Solver, error) {
 
 func (f *SolverFactory) createSteepestSolver(args []string) (Solver, error) {
 	maxIterations := 10000
+	polish := 0
 
 	for _, arg := range args {
 		parts := strings.SplitN(arg, "=", 2)
 		if len(parts) != 2 {
+			if err := f.ignored(arg, "expected key=value"); err != nil {
+				return nil, err
+			}
 			continue
 		}
 		key := strings.ToLower(parts[0])
@@ -133,10 +264,22 @@ func (f *SolverFactory) createSteepestSolver(args []string) (Solver, error) {
 		case "maxiter":
 			if i, err := strconv.Atoi(value); err == nil && i > 0 {
 				maxIterations = i
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
+			}
+		case "polish":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				polish = v
+			} else if err := f.ignored(arg, "expected a non-negative integer"); err != nil {
+				return nil, err
+			}
+		default:
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
 			}
 		}
 	}
-	return NewSteepestSolver(maxIterations), nil
+	return NewSteepestSolver(maxIterations, polish), nil
 }
 
 func (f *SolverFactory) createRandomWalkSolver(args []string) (Solver, error) {
@@ -145,6 +288,9 @@ func (f *SolverFactory) createRandomWalkSolver(args []string) (Solver, error) {
 	for _, arg := range args {
 		parts := strings.SplitN(arg, "=", 2)
 		if len(parts) != 2 {
+			if err := f.ignored(arg, "expected key=value"); err != nil {
+				return nil, err
+			}
 			continue
 		}
 		key := strings.ToLower(parts[0])
@@ -153,6 +299,12 @@ func (f *SolverFactory) createRandomWalkSolver(args []string) (Solver, error) {
 		case "maxiter":
 			if i, err := strconv.Atoi(value); err == nil && i > 0 {
 				maxIterations = i
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
+			}
+		default:
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
 			}
 		}
 	}
@@ -160,17 +312,53 @@ func (f *SolverFactory) createRandomWalkSolver(args []string) (Solver, error) {
 }
 
 func (f *SolverFactory) createHeuristicSolver(args []string) (Solver, error) {
-	return NewGreedyConstructionSolver(), nil
+	starts := 1
+	rcl := 0.0
+
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			if err := f.ignored(arg, "expected key=value"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key := strings.ToLower(parts[0])
+		value := parts[1]
+		switch key {
+		case "starts":
+			if v, err := strconv.Atoi(value); err == nil && v > 0 {
+				starts = v
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
+			}
+		case "rcl":
+			if v, err := strconv.ParseFloat(value, 64); err == nil && v >= 0 && v <= 1 {
+				rcl = v
+			} else if err := f.ignored(arg, "expected a number from 0 to 1"); err != nil {
+				return nil, err
+			}
+		default:
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return NewGreedyConstructionSolver(starts, rcl), nil
 }
 
 func (f *SolverFactory) createSimulatedAnnealingSolver(args []string) (Solver, error) {
 	alpha := 0.98
 	p := 10
 	acceptanceProb := 0.01
+	bias := BiasUniform
 
 	for _, arg := range args {
 		parts := strings.SplitN(arg, "=", 2)
 		if len(parts) != 2 {
+			if err := f.ignored(arg, "expected key=value"); err != nil {
+				return nil, err
+			}
 			continue
 		}
 		key := strings.ToLower(parts[0])
@@ -179,35 +367,396 @@ func (f *SolverFactory) createSimulatedAnnealingSolver(args []string) (Solver, e
 		case "alpha":
 			if a, err := strconv.ParseFloat(value, 64); err == nil && a > 0 && a < 1 {
 				alpha = a
+			} else if err := f.ignored(arg, "expected a number between 0 and 1"); err != nil {
+				return nil, err
 			}
 		case "p":
 			if v, err := strconv.Atoi(value); err == nil && v > 0 {
 				p = v
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
 			}
 		case "acceptance":
 			if ap, err := strconv.ParseFloat(value, 64); err == nil && ap > 0 && ap < 1 {
 				acceptanceProb = ap
+			} else if err := f.ignored(arg, "expected a number between 0 and 1"); err != nil {
+				return nil, err
+			}
+		case "bias":
+			switch strings.ToLower(value) {
+			case BiasUniform, BiasFlow, BiasAdaptive:
+				bias = strings.ToLower(value)
+			default:
+				return nil, fmt.Errorf("unknown bias %q (expected uniform, flow or adaptive)", value)
+			}
+		default:
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
 			}
 		}
 	}
-	return NewSimulatedAnnealingSolver(alpha, p, acceptanceProb), nil
+	return NewSimulatedAnnealingSolver(alpha, p, acceptanceProb, bias), nil
 }
 
 func (f *SolverFactory) createTabuSearchSolver(args []string) (Solver, error) {
 	p := 10 // default value
+	tabuOn := TabuOnAssignments
+	polish := 0
 
 	for _, arg := range args {
 		parts := strings.SplitN(arg, "=", 2)
 		if len(parts) != 2 {
+			if err := f.ignored(arg, "expected key=value"); err != nil {
+				return nil, err
+			}
 			continue
 		}
 		key := strings.ToLower(parts[0])
 		value := parts[1]
-		if key == "p" {
+		switch key {
+		case "p":
 			if val, err := strconv.Atoi(value); err == nil && val > 0 {
 				p = val
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
+			}
+		case "tabuon":
+			tabuOn = strings.ToLower(value)
+			if err := ValidateTabuOn(tabuOn); err != nil {
+				return nil, err
+			}
+		case "polish":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				polish = v
+			} else if err := f.ignored(arg, "expected a non-negative integer"); err != nil {
+				return nil, err
+			}
+		default:
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return NewTabuSearchSolver(p, tabuOn, polish), nil
+}
+
+func (f *SolverFactory) createEjectionChainSolver(args []string) (Solver, error) {
+	depth := 5
+	maxIterations := 10000
+
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			if err := f.ignored(arg, "expected key=value"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key := strings.ToLower(parts[0])
+		value := parts[1]
+		switch key {
+		case "depth":
+			if v, err := strconv.Atoi(value); err == nil && v > 0 {
+				depth = v
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
+			}
+		case "maxiter":
+			if v, err := strconv.Atoi(value); err == nil && v > 0 {
+				maxIterations = v
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
+			}
+		default:
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
 			}
 		}
 	}
-	return NewTabuSearchSolver(p), nil
+	return NewEjectionChainSolver(depth, maxIterations), nil
+}
+
+func (f *SolverFactory) createCooperativeTabuSolver(args []string) (Solver, error) {
+	p := 10
+	workers := runtime.NumCPU()
+	sync := 5000
+	tabuOn := TabuOnAssignments
+
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			if err := f.ignored(arg, "expected key=value"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key := strings.ToLower(parts[0])
+		value := parts[1]
+		switch key {
+		case "p":
+			if v, err := strconv.Atoi(value); err == nil && v > 0 {
+				p = v
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
+			}
+		case "workers":
+			if v, err := strconv.Atoi(value); err == nil && v > 0 {
+				workers = v
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
+			}
+		case "sync":
+			if v, err := strconv.Atoi(value); err == nil && v > 0 {
+				sync = v
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
+			}
+		case "tabuon":
+			tabuOn = strings.ToLower(value)
+			if err := ValidateTabuOn(tabuOn); err != nil {
+				return nil, err
+			}
+		default:
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return NewCooperativeTabuSolver(p, workers, sync, tabuOn), nil
+}
+
+func (f *SolverFactory) createClusterInitSolver(args []string) (Solver, error) {
+	clusters := 0
+	maxIterations := 1000
+
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			if err := f.ignored(arg, "expected key=value"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key := strings.ToLower(parts[0])
+		value := parts[1]
+		switch key {
+		case "clusters":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				clusters = v
+			} else if err := f.ignored(arg, "expected a non-negative integer"); err != nil {
+				return nil, err
+			}
+		case "maxiter":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				maxIterations = v
+			} else if err := f.ignored(arg, "expected a non-negative integer"); err != nil {
+				return nil, err
+			}
+		default:
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return NewClusterInitSolver(clusters, maxIterations), nil
+}
+
+func (f *SolverFactory) createFAQInitSolver(args []string) (Solver, error) {
+	iterations := 30
+	maxIterations := 0
+
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			if err := f.ignored(arg, "expected key=value"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key := strings.ToLower(parts[0])
+		value := parts[1]
+		switch key {
+		case "iters":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				iterations = v
+			} else if err := f.ignored(arg, "expected a non-negative integer"); err != nil {
+				return nil, err
+			}
+		case "maxiter":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				maxIterations = v
+			} else if err := f.ignored(arg, "expected a non-negative integer"); err != nil {
+				return nil, err
+			}
+		default:
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return NewFAQInitSolver(iterations, maxIterations), nil
+}
+
+func (f *SolverFactory) createIteratedLocalSearchSolver(args []string) (Solver, error) {
+	perturbation := PerturbSwaps
+	strength := 0
+	maxStrength := 0
+	maxIterations := 1000
+	polish := 0
+
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			if err := f.ignored(arg, "expected key=value"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		key := strings.ToLower(parts[0])
+		value := parts[1]
+		switch key {
+		case "perturb":
+			perturbation = strings.ToLower(value)
+			if _, _, err := PerturbationsByNames(perturbation); err != nil {
+				return nil, err
+			}
+		case "strength":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				strength = v
+			} else if err := f.ignored(arg, "expected a non-negative integer"); err != nil {
+				return nil, err
+			}
+		case "maxstrength":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				maxStrength = v
+			} else if err := f.ignored(arg, "expected a non-negative integer"); err != nil {
+				return nil, err
+			}
+		case "maxiter":
+			if v, err := strconv.Atoi(value); err == nil && v > 0 {
+				maxIterations = v
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
+			}
+		case "polish":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				polish = v
+			} else if err := f.ignored(arg, "expected a non-negative integer"); err != nil {
+				return nil, err
+			}
+		default:
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return NewIteratedLocalSearchSolver(perturbation, strength, maxStrength, maxIterations, polish), nil
+}
+
+// createEnsembleSolver parses members=a|b|c,slice=10s,rounds=3. The arguments
+// of a member may contain commas, so everything up to the next ensemble
+// argument belongs to the members list.
+func (f *SolverFactory) createEnsembleSolver(args []string) (Solver, error) {
+	var memberList []string
+	slice := 10 * time.Second
+	rounds := 3
+
+	current := ""
+	for _, arg := range args {
+		key, value, _ := strings.Cut(arg, "=")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "members":
+			memberList = append(memberList, value)
+			current = "members"
+			continue
+		case "slice":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid ensemble slice %q", value)
+			}
+			slice = d
+		case "rounds":
+			if v, err := strconv.Atoi(value); err == nil && v > 0 {
+				rounds = v
+			} else if err := f.ignored(arg, "expected a positive integer"); err != nil {
+				return nil, err
+			}
+		default:
+			if current == "members" {
+				memberList[len(memberList)-1] += "," + arg
+				continue
+			}
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
+			}
+		}
+		current = ""
+	}
+	if len(memberList) == 0 {
+		return nil, fmt.Errorf("ensemble needs members, e.g. members=tabu|simanneal")
+	}
+
+	var members []Solver
+	for _, config := range strings.Split(strings.Join(memberList, "|"), "|") {
+		if config = strings.TrimSpace(config); config == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(config), "ensemble") {
+			return nil, fmt.Errorf("ensembles cannot be nested")
+		}
+		member, err := f.Create(config)
+		if err != nil {
+			return nil, fmt.Errorf("ensemble member %q: %v", config, err)
+		}
+		members = append(members, member)
+	}
+	if len(members) < 2 {
+		return nil, fmt.Errorf("ensemble needs at least two members")
+	}
+	return NewEnsembleSolver(members, slice, rounds), nil
+}
+
+// createDecomposeSolver parses solver=config,maxIter=1000. The arguments of
+// the inner solver may contain commas, so everything up to the next
+// decompose argument belongs to it.
+func (f *SolverFactory) createDecomposeSolver(args []string) (Solver, error) {
+	innerConfig := ""
+	maxIterations := 1000
+
+	current := ""
+	for _, arg := range args {
+		key, value, _ := strings.Cut(arg, "=")
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "solver":
+			innerConfig = value
+			current = "solver"
+			continue
+		case "maxiter":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				maxIterations = v
+			} else if err := f.ignored(arg, "expected a non-negative integer"); err != nil {
+				return nil, err
+			}
+		default:
+			if current == "solver" {
+				innerConfig += "," + arg
+				continue
+			}
+			if err := f.ignored(arg, "unknown parameter"); err != nil {
+				return nil, err
+			}
+		}
+		current = ""
+	}
+	if innerConfig = strings.TrimSpace(innerConfig); innerConfig == "" {
+		return nil, fmt.Errorf("decompose needs an inner solver, e.g. solver=tabu")
+	}
+	if strings.HasPrefix(strings.ToLower(innerConfig), "decompose") {
+		return nil, fmt.Errorf("decompose cannot be nested")
+	}
+	inner, err := f.Create(innerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("decompose solver %q: %v", innerConfig, err)
+	}
+	return NewDecomposeSolver(inner, maxIterations), nil
 }
@@ -10,11 +10,13 @@ import (
 type SteepestSolver struct {
 	MaxIterations  int
 	RandomRestarts int
+	Polish         int // longest cyclic exchange polishing the local optimum, 0 disables it
 }
 
-func NewSteepestSolver(maxIterations int) *SteepestSolver {
+func NewSteepestSolver(maxIterations, polish int) *SteepestSolver {
 	return &SteepestSolver{
 		MaxIterations: maxIterations,
+		Polish:        polish,
 	}
 }
 
@@ -26,10 +28,32 @@ func (s *SteepestSolver) Description() string {
 	return fmt.Sprintf("Steepest search")
 }
 
+func (s *SteepestSolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsWarmStart: true, SupportsCancellation: true, SupportsTimeBudget: true, SupportsSwapRadius: true}
+}
+
 func (s *SteepestSolver) Solve(instance *qap.QAPInstance) SolverResult {
 
 	currentSolution := RandomSolution(instance.Size)
 	currentFitness := qap.CalculateFitness(instance, currentSolution)
+	if qap.CanCacheDeltas(instance) {
+		cache := qap.NewDeltaCache(instance, currentSolution)
+		for {
+			bestDelta, bestI, bestJ := 0, -1, -1
+			for i := 0; i < instance.Size-1; i++ {
+				for j := i + 1; j < instance.Size; j++ {
+					if delta := cache.Delta(i, j); delta < bestDelta {
+						bestDelta, bestI, bestJ = delta, i, j
+					}
+				}
+			}
+			if bestI < 0 {
+				return SolverResult{Solution: currentSolution, Fitness: currentFitness}
+			}
+			cache.Swap(bestI, bestJ)
+			currentFitness += bestDelta
+		}
+	}
 
 	for {
 		bestNeighbor := make([]int, instance.Size)
@@ -64,12 +88,15 @@ func (s *SteepestSolver) SolveWithMetrics(
 	metricsCollector *metrics.MetricsCollector,
 	instanceName string,
 	runNumber int,
+	opts SolveOptions,
 ) SolverResult {
 	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
 
 	// Initial values for solution and fitness
-	currentSolution := RandomSolution(instance.Size)
-	currentFitness := qap.CalculateFitness(instance, currentSolution)
+	currentSolution := tracker.initialSolution(instance.Size)
+	tracker.repair(currentSolution)
+	currentFitness := tracker.fitness(currentSolution)
 
 	// Metrics counters
 	totalSteps := 0
@@ -83,28 +110,44 @@ func (s *SteepestSolver) SolveWithMetrics(
 	initialSolution = make([]int, len(currentSolution))
 	copy(initialSolution, currentSolution)
 	initialFitness = currentFitness
+	tracker.improved(0, initialFitness)
+
+	// With the plain QAP cost the deltas of all swaps are cached and only
+	// the entries a move affects are updated, see qap.DeltaCache
+	var cache *qap.DeltaCache
+	if tracker.fitnessFunc == nil && tracker.deltaFunc == nil && qap.CanCacheDeltas(instance) {
+		cache = qap.NewDeltaCache(instance, currentSolution)
+	}
 
 	// Start the steepest descent iterations
-	for {
-		bestNeighbor := make([]int, instance.Size)
-		copy(bestNeighbor, currentSolution)
+	for !tracker.shouldStop() {
+		tracker.progress(totalEvaluations, totalSteps)
 		bestNeighborFitness := currentFitness
+		bestI, bestJ := -1, -1
 
 		// Check all possible neighbors
 		for i := 0; i < instance.Size-1; i++ {
 			for j := i + 1; j < instance.Size; j++ {
-				newSolution := make([]int, instance.Size)
-				copy(newSolution, currentSolution)
-				newSolution[i], newSolution[j] = newSolution[j], newSolution[i]
-				newFitness := qap.CalculateFitness(instance, newSolution)
+				if !tracker.allows(currentSolution, i, j) {
+					continue
+				}
+				var newFitness int
+				if cache != nil {
+					newFitness = currentFitness + cache.Delta(i, j)
+				} else {
+					newSolution := make([]int, instance.Size)
+					copy(newSolution, currentSolution)
+					newSolution[i], newSolution[j] = newSolution[j], newSolution[i]
+					newFitness = tracker.fitness(newSolution)
+				}
 
 				totalEvaluations++
 				totalSolutionsChecked++
 
 				// Update the best neighbor if a better fitness is found
 				if newFitness < bestNeighborFitness {
-					copy(bestNeighbor, newSolution)
 					bestNeighborFitness = newFitness
+					bestI, bestJ = i, j
 				}
 			}
 		}
@@ -113,32 +156,38 @@ func (s *SteepestSolver) SolveWithMetrics(
 
 		// If a better solution was found, accept it
 		if bestNeighborFitness < currentFitness {
-			copy(currentSolution, bestNeighbor)
+			tracker.move(totalSteps, bestI, bestJ, bestNeighborFitness-currentFitness, bestNeighborFitness, "")
+			tracker.counts.accept(bestNeighborFitness - currentFitness)
+			if cache != nil {
+				cache.Swap(bestI, bestJ)
+			} else {
+				currentSolution[bestI], currentSolution[bestJ] = currentSolution[bestJ], currentSolution[bestI]
+			}
 			currentFitness = bestNeighborFitness
+			tracker.improved(totalEvaluations, currentFitness)
 		} else {
 			// If no improvement is found, exit the loop
 			break
 		}
 	}
+	currentFitness = polishCyclic(tracker, instance, currentSolution, currentFitness, s.Polish, &totalSteps, &totalEvaluations)
 
 	// Calculate elapsed time
 	elapsedTime := time.Since(startTime)
 
 	// Record metrics if the collector is provided
-	if metricsCollector != nil {
-		metricsCollector.AddRunMetrics(metrics.RunMetrics{
-			InstanceName:     instanceName,
-			SolverName:       s.Name(),
-			Run:              runNumber,
-			InitialFitness:   initialFitness,
-			FinalFitness:     currentFitness,
-			TimeElapsed:      elapsedTime,
-			StepsCount:       totalSteps,
-			EvaluationsCount: totalEvaluations,
-			SolutionsChecked: totalSolutionsChecked,
-			Solution:         currentSolution,
-		})
-	}
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     currentFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalSolutionsChecked,
+		Solution:         currentSolution,
+	})
 
 	// Return the result
 	return SolverResult{
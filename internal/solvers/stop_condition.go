@@ -0,0 +1,267 @@
+package solvers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// StopCondition is a user-defined stopping rule such as
+// "evals>5e6 || gap<0.5 || time>120s". Comparisons of a variable with a number
+// are combined with &&, ||, ! and parentheses. The variables are
+//
+//	evals    evaluations reported by the solver loop
+//	moves    accepted moves
+//	time     seconds since the run started, numbers may carry a unit (120s, 2m)
+//	idle     seconds since the last improvement of the best solution
+//	stall    evaluations since the last improvement of the best solution
+//	fitness  objective value of the best solution
+//	gap      percent of the best fitness above the best known one
+//
+// Comparisons with fitness and gap are false while they are unknown: before
+// the first solution, and for gap when no optimum is known.
+type StopCondition struct {
+	source string
+	root   stopNode
+}
+
+// StopState holds the values of the variables of a StopCondition
+type StopState struct {
+	Evaluations int
+	Moves       int
+	Elapsed     time.Duration
+	Idle        time.Duration
+	Stall       int
+
+	Fitness    int // objective value, valid with HasFitness
+	HasFitness bool
+	Gap        float64 // in percent, valid with HasGap
+	HasGap     bool
+}
+
+// ParseStopCondition parses a stopping expression, see StopCondition
+func ParseStopCondition(source string) (*StopCondition, error) {
+	p := &stopParser{source: source}
+	p.next()
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("stop condition %q: %v", source, err)
+	}
+	if p.token != "" {
+		return nil, fmt.Errorf("stop condition %q: unexpected %q", source, p.token)
+	}
+	return &StopCondition{source: source, root: root}, nil
+}
+
+// String returns the expression the condition was parsed from
+func (c *StopCondition) String() string {
+	return c.source
+}
+
+// Eval reports whether the run has to stop in the given state
+func (c *StopCondition) Eval(state StopState) bool {
+	return c.root.eval(&state)
+}
+
+type stopNode interface {
+	eval(state *StopState) bool
+}
+
+type stopOr struct{ left, right stopNode }
+type stopAnd struct{ left, right stopNode }
+type stopNot struct{ operand stopNode }
+
+func (n stopOr) eval(s *StopState) bool  { return n.left.eval(s) || n.right.eval(s) }
+func (n stopAnd) eval(s *StopState) bool { return n.left.eval(s) && n.right.eval(s) }
+func (n stopNot) eval(s *StopState) bool { return !n.operand.eval(s) }
+
+// stopComparison compares a variable with a constant
+type stopComparison struct {
+	variable string
+	op       string
+	value    float64
+}
+
+func (n stopComparison) eval(s *StopState) bool {
+	var v float64
+	switch n.variable {
+	case "evals":
+		v = float64(s.Evaluations)
+	case "moves":
+		v = float64(s.Moves)
+	case "time":
+		v = s.Elapsed.Seconds()
+	case "idle":
+		v = s.Idle.Seconds()
+	case "stall":
+		v = float64(s.Stall)
+	case "fitness":
+		if !s.HasFitness {
+			return false
+		}
+		v = float64(s.Fitness)
+	case "gap":
+		if !s.HasGap {
+			return false
+		}
+		v = s.Gap
+	}
+
+	switch n.op {
+	case "<":
+		return v < n.value
+	case "<=":
+		return v <= n.value
+	case ">":
+		return v > n.value
+	case ">=":
+		return v >= n.value
+	case "==":
+		return v == n.value
+	default: // "!="
+		return v != n.value
+	}
+}
+
+var stopVariables = map[string]bool{
+	"evals": true, "moves": true, "time": true, "idle": true, "stall": true, "fitness": true, "gap": true,
+}
+
+// stopParser is a recursive descent parser over the tokens of an expression
+type stopParser struct {
+	source string
+	pos    int
+	token  string // current token, "" at the end
+}
+
+// next advances to the next token: an operator, a parenthesis, a variable
+// name or a number with an optional unit
+func (p *stopParser) next() {
+	for p.pos < len(p.source) && unicode.IsSpace(rune(p.source[p.pos])) {
+		p.pos++
+	}
+	if p.pos >= len(p.source) {
+		p.token = ""
+		return
+	}
+
+	rest := p.source[p.pos:]
+	for _, op := range []string{"||", "&&", "<=", ">=", "==", "!=", "<", ">", "!", "(", ")"} {
+		if strings.HasPrefix(rest, op) {
+			p.token = op
+			p.pos += len(op)
+			return
+		}
+	}
+
+	end := 0
+	for end < len(rest) {
+		r := rune(rest[end])
+		isExponentSign := (r == '+' || r == '-') && end > 0 && (rest[end-1] == 'e' || rest[end-1] == 'E') &&
+			unicode.IsDigit(rune(rest[0]))
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '_' && !isExponentSign {
+			break
+		}
+		end++
+	}
+	if end == 0 {
+		end = 1 // a single unexpected character
+	}
+	p.token = rest[:end]
+	p.pos += end
+}
+
+func (p *stopParser) parseOr() (stopNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.token == "||" {
+		p.next()
+		var right stopNode
+		if right, err = p.parseAnd(); err == nil {
+			left = stopOr{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *stopParser) parseAnd() (stopNode, error) {
+	left, err := p.parseUnary()
+	for err == nil && p.token == "&&" {
+		p.next()
+		var right stopNode
+		if right, err = p.parseUnary(); err == nil {
+			left = stopAnd{left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *stopParser) parseUnary() (stopNode, error) {
+	switch p.token {
+	case "!":
+		p.next()
+		operand, err := p.parseUnary()
+		return stopNot{operand}, err
+	case "(":
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.token != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.next()
+		return inner, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *stopParser) parseComparison() (stopNode, error) {
+	variable := strings.ToLower(p.token)
+	if !stopVariables[variable] {
+		if variable == "" {
+			return nil, fmt.Errorf("unexpected end")
+		}
+		return nil, fmt.Errorf("unknown variable %q", p.token)
+	}
+	p.next()
+
+	op := p.token
+	switch op {
+	case "<", "<=", ">", ">=", "==", "!=":
+	default:
+		return nil, fmt.Errorf("expected a comparison after %s, got %q", variable, op)
+	}
+	p.next()
+
+	sign := 1.0
+	if p.token == "-" {
+		sign = -1
+		p.next()
+	}
+	value, err := parseStopValue(p.token, variable == "time" || variable == "idle")
+	if err != nil {
+		return nil, err
+	}
+	p.next()
+	return stopComparison{variable: variable, op: op, value: sign * value}, nil
+}
+
+// parseStopValue parses a number like 5e6 or 0.5. Time variables also accept
+// a duration like 120s or 1m30s, their plain numbers are seconds.
+func parseStopValue(token string, isTime bool) (float64, error) {
+	if value, err := strconv.ParseFloat(token, 64); err == nil {
+		return value, nil
+	}
+	if isTime {
+		if d, err := time.ParseDuration(token); err == nil {
+			return d.Seconds(), nil
+		}
+	}
+	if token == "" {
+		return 0, fmt.Errorf("unexpected end")
+	}
+	return 0, fmt.Errorf("invalid number %q", token)
+}
@@ -0,0 +1,72 @@
+package solvers
+
+import (
+	"fmt"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"strconv"
+	"strings"
+)
+
+// SwapRadiusArg is the solver argument "radius=R" accepted by every solver
+// with SupportsSwapRadius, see SolveOptions.SwapRadius
+const SwapRadiusArg = "radius"
+
+// radiusSolver is a solver configured with radius=R: its runs only swap
+// facilities whose locations lie at most R apart
+type radiusSolver struct {
+	Solver
+	radius int
+}
+
+// parseSwapRadius removes a radius=R argument from args and returns R, zero
+// when there is none
+func parseSwapRadius(args []string) ([]string, int, error) {
+	var rest []string
+	radius := 0
+	for _, arg := range args {
+		key, value, _ := strings.Cut(arg, "=")
+		if !strings.EqualFold(strings.TrimSpace(key), SwapRadiusArg) {
+			rest = append(rest, arg)
+			continue
+		}
+		r, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || r < 0 {
+			return nil, 0, fmt.Errorf("invalid radius %q, expected a distance of 0 or more", value)
+		}
+		radius = r
+	}
+	return rest, radius, nil
+}
+
+func (s *radiusSolver) Description() string {
+	return fmt.Sprintf("%s, swaps within distance %d", s.Solver.Description(), s.radius)
+}
+
+func (s *radiusSolver) Deterministic() bool {
+	return IsDeterministic(s.Solver)
+}
+
+func (s *radiusSolver) Capabilities() Capabilities {
+	return CapabilitiesOf(s.Solver)
+}
+
+// Solve runs without metrics, the radius needs SolveWithMetrics
+func (s *radiusSolver) Solve(instance *qap.QAPInstance) SolverResult {
+	return s.SolveWithMetrics(instance, nil, "", 0, SolveOptions{})
+}
+
+func (s *radiusSolver) SolveWithMetrics(
+	instance *qap.QAPInstance,
+	metricsCollector *metrics.MetricsCollector,
+	instanceName string,
+	runNumber int,
+	opts SolveOptions,
+) SolverResult {
+	inner, ok := s.Solver.(metricsSolver)
+	if !ok {
+		return s.Solver.Solve(instance)
+	}
+	opts.SwapRadius = s.radius
+	return inner.SolveWithMetrics(instance, metricsCollector, instanceName, runNumber, opts)
+}
@@ -0,0 +1,59 @@
+package solvers
+
+import "math"
+
+// TabuList is a flat table of iteration stamps for tabu attributes indexed by
+// two integers, such as a facility and a location. An entry stays tabu while
+// its stamp is greater than the current iteration, so checks and updates are a
+// single array access. Stamps are 32-bit and stored row-major in one slice,
+// half the memory of an [][]int matrix and without the row indirection, which
+// keeps the table in cache for larger instances. Iterations must be
+// non-negative and fit into an int32.
+type TabuList struct {
+	cols   int
+	stamps []int32
+	offset int32 // added to every stamp written, raised by Clear to expire all entries
+	high   int32 // highest stamp written so far
+}
+
+// NewTabuList creates a table of rows×cols attributes, none of them tabu
+func NewTabuList(rows, cols int) *TabuList {
+	return &TabuList{cols: cols, stamps: make([]int32, rows*cols)}
+}
+
+// IsTabu reports whether attribute (a, b) is tabu at iteration
+func (l *TabuList) IsTabu(a, b, iteration int) bool {
+	return l.stamps[a*l.cols+b] > int32(iteration)+l.offset
+}
+
+// MakeTabu keeps attribute (a, b) tabu while the iteration is below until. In
+// the unlikely case that the stamp would overflow, the table is cleared first.
+func (l *TabuList) MakeTabu(a, b, until int) {
+	if int64(until)+int64(l.offset) > math.MaxInt32 {
+		l.reset()
+	}
+	stamp := int32(until) + l.offset
+	l.stamps[a*l.cols+b] = stamp
+	l.high = max(l.high, stamp)
+}
+
+// Until returns the iteration attribute (a, b) stays tabu until, not above
+// the current one when it is not tabu
+func (l *TabuList) Until(a, b int) int {
+	return int(l.stamps[a*l.cols+b] - l.offset)
+}
+
+// Clear makes every attribute non-tabu. It only moves the offset past the
+// highest stamp, the table is rewritten only when the offset would overflow.
+func (l *TabuList) Clear() {
+	if l.high > math.MaxInt32/2 {
+		l.reset()
+		return
+	}
+	l.offset = l.high
+}
+
+func (l *TabuList) reset() {
+	clear(l.stamps)
+	l.offset, l.high = 0, 0
+}
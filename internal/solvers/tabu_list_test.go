@@ -0,0 +1,140 @@
+package solvers
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestTabuList checks stamps through Clear, which only moves the offset,
+// and through the resets that keep stamps and offset from overflowing
+func TestTabuList(t *testing.T) {
+	list := NewTabuList(3, 4)
+	for a := 0; a < 3; a++ {
+		for b := 0; b < 4; b++ {
+			if list.IsTabu(a, b, 0) || list.Until(a, b) > 0 {
+				t.Fatalf("(%d, %d) is tabu in a new list", a, b)
+			}
+		}
+	}
+
+	// An entry is tabu before its stamp, not at or after it
+	list.MakeTabu(1, 2, 10)
+	list.MakeTabu(2, 3, 25)
+	for _, c := range []struct {
+		a, b, iteration int
+		tabu            bool
+	}{{1, 2, 0, true}, {1, 2, 9, true}, {1, 2, 10, false}, {1, 2, 11, false}, {2, 3, 24, true}, {2, 3, 25, false}, {2, 1, 5, false}} {
+		if got := list.IsTabu(c.a, c.b, c.iteration); got != c.tabu {
+			t.Errorf("IsTabu(%d, %d, %d) = %t, want %t", c.a, c.b, c.iteration, got, c.tabu)
+		}
+	}
+	if list.Until(1, 2) != 10 || list.Until(2, 3) != 25 {
+		t.Errorf("Until gives %d and %d, want 10 and 25", list.Until(1, 2), list.Until(2, 3))
+	}
+
+	// Clear expires everything, entries set afterwards are tabu again
+	list.Clear()
+	if list.IsTabu(1, 2, 0) || list.IsTabu(2, 3, 20) || list.Until(2, 3) > 0 {
+		t.Fatal("entries are still tabu after Clear")
+	}
+	list.MakeTabu(1, 2, 30)
+	if !list.IsTabu(1, 2, 29) || list.IsTabu(1, 2, 30) || list.Until(1, 2) != 30 {
+		t.Errorf("an entry set after Clear is tabu until %d, want 30", list.Until(1, 2))
+	}
+	if list.IsTabu(2, 3, 20) {
+		t.Error("an entry expired by Clear became tabu again")
+	}
+	list.Clear()
+	list.Clear()
+	if list.IsTabu(1, 2, 0) {
+		t.Error("an entry is still tabu after a second Clear")
+	}
+
+	// A stamp near MaxInt32 would overflow with the offset: the list is reset
+	list.MakeTabu(0, 0, 100)
+	list.MakeTabu(0, 1, math.MaxInt32-10)
+	if !list.IsTabu(0, 1, math.MaxInt32-11) || list.Until(0, 1) != math.MaxInt32-10 {
+		t.Errorf("the entry near MaxInt32 is tabu until %d", list.Until(0, 1))
+	}
+	if list.IsTabu(0, 0, 0) {
+		t.Error("an entry survived the reset of an overflowing stamp")
+	}
+
+	// Clearing after such a high stamp resets the list instead of moving the offset
+	list.Clear()
+	if list.IsTabu(0, 1, 0) {
+		t.Error("the entry near MaxInt32 is still tabu after Clear")
+	}
+	list.MakeTabu(2, 0, 7)
+	if !list.IsTabu(2, 0, 6) || list.Until(2, 0) != 7 {
+		t.Errorf("an entry set after the reset is tabu until %d, want 7", list.Until(2, 0))
+	}
+}
+
+// tabuBenchmarkSizes span a matrix that fits in cache to one that does not
+var tabuBenchmarkSizes = []int{20, 100, 256, 500}
+
+// tabuSink keeps the benchmarked checks from being optimized away
+var tabuSink int
+
+// BenchmarkTabuMatrix times the tabu checks of a full swap neighborhood with
+// assignment attributes, as tabu search performs them every iteration, and
+// two updates on the former [][]int matrix
+func BenchmarkTabuMatrix(b *testing.B) {
+	for _, n := range tabuBenchmarkSizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			solution := rng.Perm(n)
+			until := make([][]int, n)
+			for i := range until {
+				until[i] = make([]int, n)
+			}
+
+			tabu := 0
+			for iteration := 1; iteration <= b.N; iteration++ {
+				for i := 0; i < n-1; i++ {
+					for j := i + 1; j < n; j++ {
+						if until[i][solution[j]] > iteration || until[j][solution[i]] > iteration {
+							tabu++
+						}
+					}
+				}
+				i, j := rng.Intn(n), rng.Intn(n)
+				until[i][solution[i]] = iteration + n/2
+				until[j][solution[j]] = iteration + n/2
+				solution[i], solution[j] = solution[j], solution[i]
+			}
+			tabuSink = tabu
+		})
+	}
+}
+
+// BenchmarkTabuList times the same iteration as BenchmarkTabuMatrix on
+// TabuList
+func BenchmarkTabuList(b *testing.B) {
+	for _, n := range tabuBenchmarkSizes {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			solution := rng.Perm(n)
+			list := NewTabuList(n, n)
+
+			tabu := 0
+			for iteration := 1; iteration <= b.N; iteration++ {
+				for i := 0; i < n-1; i++ {
+					for j := i + 1; j < n; j++ {
+						if list.IsTabu(i, solution[j], iteration) || list.IsTabu(j, solution[i], iteration) {
+							tabu++
+						}
+					}
+				}
+				i, j := rng.Intn(n), rng.Intn(n)
+				list.MakeTabu(i, solution[i], iteration+n/2)
+				list.MakeTabu(j, solution[j], iteration+n/2)
+				solution[i], solution[j] = solution[j], solution[i]
+			}
+			tabuSink = tabu
+		})
+	}
+}
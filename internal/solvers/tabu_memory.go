@@ -0,0 +1,99 @@
+package solvers
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Attributes a tabu search can make tabu after a swap
+const (
+	TabuOnAssignments = "assignments" // the facility-location pairs left by the swap
+	TabuOnPairs       = "pairs"       // the swapped pair of facilities
+	TabuOnFacilities  = "facilities"  // both swapped facilities, in any move
+)
+
+// ValidateTabuOn checks a tabu attribute name
+func ValidateTabuOn(tabuOn string) error {
+	switch tabuOn {
+	case TabuOnAssignments, TabuOnPairs, TabuOnFacilities:
+		return nil
+	default:
+		return fmt.Errorf("unknown tabu attribute %q (expected %s, %s or %s)",
+			tabuOn, TabuOnAssignments, TabuOnPairs, TabuOnFacilities)
+	}
+}
+
+// tabuMemory maps the attributes selected by tabuOn to a TabuList
+type tabuMemory struct {
+	tabuOn string
+	list   *TabuList // [facility][location], [facility][facility] or [facility][0]
+}
+
+func newTabuMemory(tabuOn string, n int) *tabuMemory {
+	cols := n
+	if tabuOn == TabuOnFacilities {
+		cols = 1
+	}
+	return &tabuMemory{tabuOn: tabuOn, list: NewTabuList(n, cols)}
+}
+
+// isTabu reports whether swapping facilities i and j of solution is tabu at iteration
+func (m *tabuMemory) isTabu(solution []int, i, j, iteration int) bool {
+	switch m.tabuOn {
+	case TabuOnPairs:
+		return m.list.IsTabu(min(i, j), max(i, j), iteration)
+	case TabuOnFacilities:
+		return m.list.IsTabu(i, 0, iteration) || m.list.IsTabu(j, 0, iteration)
+	default:
+		// Moving a facility back to a location it recently left is tabu
+		return m.list.IsTabu(i, solution[j], iteration) || m.list.IsTabu(j, solution[i], iteration)
+	}
+}
+
+// add makes the attributes of the swap of i and j tabu until the given
+// iteration. It must be called before the swap is applied to solution.
+func (m *tabuMemory) add(solution []int, i, j, until int) {
+	switch m.tabuOn {
+	case TabuOnPairs:
+		m.list.MakeTabu(min(i, j), max(i, j), until)
+	case TabuOnFacilities:
+		m.list.MakeTabu(i, 0, until)
+		m.list.MakeTabu(j, 0, until)
+	default:
+		m.list.MakeTabu(i, solution[i], until)
+		m.list.MakeTabu(j, solution[j], until)
+	}
+}
+
+// clear forgets all tabu attributes, e.g. after jumping to another solution
+func (m *tabuMemory) clear() {
+	m.list.Clear()
+}
+
+// rows lists the attributes still tabu at iteration with the iteration they
+// stay tabu until, for the run artifacts
+func (m *tabuMemory) rows(n, iteration int) [][]string {
+	var rows [][]string
+	switch m.tabuOn {
+	case TabuOnPairs:
+		rows = append(rows, []string{"Facility1", "Facility2", "TabuUntil"})
+	case TabuOnFacilities:
+		rows = append(rows, []string{"Facility", "TabuUntil"})
+	default:
+		rows = append(rows, []string{"Facility", "Location", "TabuUntil"})
+	}
+	for a := 0; a < n; a++ {
+		for b := 0; b < m.list.cols; b++ {
+			if !m.list.IsTabu(a, b, iteration) {
+				continue
+			}
+			until := strconv.Itoa(m.list.Until(a, b))
+			if m.tabuOn == TabuOnFacilities {
+				rows = append(rows, []string{strconv.Itoa(a), until})
+			} else {
+				rows = append(rows, []string{strconv.Itoa(a), strconv.Itoa(b), until})
+			}
+		}
+	}
+	return rows
+}
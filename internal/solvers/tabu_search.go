@@ -1,6 +1,7 @@
 package solvers
 
 import (
+	"fmt"
 	"math/rand"
 	"qap_solver/internal/metrics"
 	"qap_solver/internal/qap"
@@ -9,11 +10,13 @@ import (
 )
 
 type TabuSearchSolver struct {
-	P int
+	P      int
+	TabuOn string // attribute made tabu after a move, see TabuOnAssignments
+	Polish int    // longest cyclic exchange polishing the best solution, 0 disables it
 }
 
-func NewTabuSearchSolver(p int) *TabuSearchSolver {
-	return &TabuSearchSolver{P: p}
+func NewTabuSearchSolver(p int, tabuOn string, polish int) *TabuSearchSolver {
+	return &TabuSearchSolver{P: p, TabuOn: tabuOn, Polish: polish}
 }
 
 func (s *TabuSearchSolver) Name() string {
@@ -21,7 +24,11 @@ func (s *TabuSearchSolver) Name() string {
 }
 
 func (s *TabuSearchSolver) Description() string {
-	return "Tabu Search with elite candidate list, aspiration criteria, and fixed tabu tenure"
+	return fmt.Sprintf("Tabu Search with elite candidate list, aspiration criteria, and fixed tabu tenure (tabu on %s)", s.TabuOn)
+}
+
+func (s *TabuSearchSolver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsWarmStart: true, SupportsCancellation: true, SupportsTimeBudget: true, SupportsSwapRadius: true}
 }
 
 type move struct {
@@ -35,10 +42,7 @@ func (s *TabuSearchSolver) Solve(instance *qap.QAPInstance) SolverResult {
 	n := instance.Size
 	maxNoImprovement := s.P * n
 	tabuTenure := n / 2
-	tabuList := make([][]int, n)
-	for i := range tabuList {
-		tabuList[i] = make([]int, n)
-	}
+	tabuList := newTabuMemory(s.TabuOn, n)
 
 	current := RandomSolution(n)
 	currentFitness := qap.CalculateFitness(instance, current)
@@ -70,7 +74,7 @@ func (s *TabuSearchSolver) Solve(instance *qap.QAPInstance) SolverResult {
 
 			newFitness := qap.CalculateFitness(instance, newSolution)
 
-			isTabu := tabuList[i][current[j]] > iteration || tabuList[j][current[i]] > iteration
+			isTabu := tabuList.isTabu(current, i, j, iteration)
 			aspiration := newFitness < bestFitness
 
 			candidateMoves = append(candidateMoves, move{i, j, newFitness, isTabu, aspiration})
@@ -103,13 +107,10 @@ func (s *TabuSearchSolver) Solve(instance *qap.QAPInstance) SolverResult {
 
 		// Apply the move
 		i, j := chosen.i, chosen.j
+		tabuList.add(current, i, j, iteration+tabuTenure)
 		current[i], current[j] = current[j], current[i]
 		currentFitness = chosen.newFitness
 
-		// Update tabu list
-		tabuList[i][current[i]] = iteration + tabuTenure
-		tabuList[j][current[j]] = iteration + tabuTenure
-
 		// Update best solution if needed
 		if currentFitness < bestFitness {
 			copy(best, current)
@@ -131,19 +132,19 @@ func (s *TabuSearchSolver) SolveWithMetrics(
 	metricsCollector *metrics.MetricsCollector,
 	instanceName string,
 	runNumber int,
+	opts SolveOptions,
 ) SolverResult {
 	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
 
 	n := instance.Size
 	maxNoImprovement := s.P * n
 	tabuTenure := n / 2
-	tabuList := make([][]int, n)
-	for i := range tabuList {
-		tabuList[i] = make([]int, n)
-	}
+	tabuList := newTabuMemory(s.TabuOn, n)
 
-	current := RandomSolution(n)
-	currentFitness := qap.CalculateFitness(instance, current)
+	current := tracker.initialSolution(n)
+	tracker.repair(current)
+	currentFitness := tracker.fitness(current)
 
 	best := make([]int, n)
 	copy(best, current)
@@ -152,6 +153,7 @@ func (s *TabuSearchSolver) SolveWithMetrics(
 	initialFitness := currentFitness
 	initialSolution := make([]int, n)
 	copy(initialSolution, current)
+	tracker.improved(0, initialFitness)
 
 	noImprovementCounter := 0
 	iteration := 0
@@ -159,34 +161,57 @@ func (s *TabuSearchSolver) SolveWithMetrics(
 	totalEvaluations := 0
 	totalSolutionsChecked := 0
 
+	// Iterations every facility spent at every location, for the run artifacts
+	var residence [][]int
+	if opts.Artifacts.Enabled() {
+		residence = make([][]int, n)
+		for i := range residence {
+			residence[i] = make([]int, n)
+		}
+	}
+
+	tracker.tenure(tabuTenure)
 	for noImprovementCounter < maxNoImprovement {
+		if tracker.shouldStop() {
+			break
+		}
+		tracker.progress(totalEvaluations, totalSteps)
+
 		iteration++
 		var candidateMoves []move
 
 		possibleSwaps := allSwaps(n)
 		sampleSize := len(possibleSwaps) / 5
-		rand.Shuffle(len(possibleSwaps), func(i, j int) {
+		tracker.rng.Shuffle(len(possibleSwaps), func(i, j int) {
 			possibleSwaps[i], possibleSwaps[j] = possibleSwaps[j], possibleSwaps[i]
 		})
 		sampledSwaps := possibleSwaps[:sampleSize]
 
 		for _, sw := range sampledSwaps {
 			i, j := sw[0], sw[1]
+			if !tracker.allows(current, i, j) {
+				continue
+			}
 
 			newSolution := make([]int, n)
 			copy(newSolution, current)
 			newSolution[i], newSolution[j] = newSolution[j], newSolution[i]
 
-			newFitness := qap.CalculateFitness(instance, newSolution)
+			newFitness := tracker.fitness(newSolution)
 			totalEvaluations++
 			totalSolutionsChecked++
 
-			isTabu := tabuList[i][current[j]] > iteration || tabuList[j][current[i]] > iteration
+			isTabu := tabuList.isTabu(current, i, j, iteration)
 			aspiration := newFitness < bestFitness
 
 			candidateMoves = append(candidateMoves, move{i, j, newFitness, isTabu, aspiration})
 		}
 
+		if len(candidateMoves) == 0 {
+			noImprovementCounter++
+			continue
+		}
+
 		// Sort candidate moves by newFitness ascending
 		sort.Slice(candidateMoves, func(i, j int) bool {
 			return candidateMoves[i].newFitness < candidateMoves[j].newFitness
@@ -205,45 +230,61 @@ func (s *TabuSearchSolver) SolveWithMetrics(
 				chosen = m
 				break
 			}
+			tracker.tabuBlocked(n, m.i, current[m.j], false)
+			tracker.counts.reject()
 		}
 		if chosen == (move{}) && len(candidateMoves) > 0 {
 			chosen = candidateMoves[0]
 		}
+		if chosen.isTabu && chosen.aspiration {
+			tracker.tabuBlocked(n, chosen.i, current[chosen.j], true)
+		}
 
 		// Apply the move
 		i, j := chosen.i, chosen.j
+		tracker.tabuAssigned(n, i, current[i])
+		tracker.tabuAssigned(n, j, current[j])
+		if tracker.tracing() {
+			tracker.move(iteration, i, j, chosen.newFitness-currentFitness, chosen.newFitness,
+				fmt.Sprintf("tenure=%d tabu=%t aspiration=%t", tabuTenure, chosen.isTabu, chosen.aspiration))
+		}
+		tabuList.add(current, i, j, iteration+tabuTenure)
 		current[i], current[j] = current[j], current[i]
+		tracker.counts.accept(chosen.newFitness - currentFitness)
 		currentFitness = chosen.newFitness
 
-		tabuList[i][current[i]] = iteration + tabuTenure
-		tabuList[j][current[j]] = iteration + tabuTenure
 		totalSteps++
+		for facility, counts := range residence {
+			counts[current[facility]]++
+		}
 
 		if currentFitness < bestFitness {
 			copy(best, current)
 			bestFitness = currentFitness
+			tracker.improved(totalEvaluations, bestFitness)
 			noImprovementCounter = 0
 		} else {
 			noImprovementCounter++
 		}
 	}
+	tracker.artifact("tabu_list.csv", func() [][]string { return tabuList.rows(n, iteration) })
+	tracker.artifactMatrix("residence.csv", "Facility", "Location", residence)
+	bestFitness = polishCyclic(tracker, instance, best, bestFitness, s.Polish, &totalSteps, &totalEvaluations)
 
 	elapsedTime := time.Since(startTime)
 
-	if metricsCollector != nil {
-		metricsCollector.AddRunMetrics(metrics.RunMetrics{
-			InstanceName:     instanceName,
-			SolverName:       s.Name(),
-			Run:              runNumber,
-			InitialFitness:   initialFitness,
-			FinalFitness:     bestFitness,
-			TimeElapsed:      elapsedTime,
-			StepsCount:       totalSteps,
-			EvaluationsCount: totalEvaluations,
-			SolutionsChecked: totalSolutionsChecked,
-			Solution:         best,
-		})
-	}
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     bestFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalSolutionsChecked,
+		Solution:         best,
+	})
 
 	return SolverResult{
 		Solution: best,
@@ -0,0 +1,61 @@
+package solvers
+
+import (
+	"fmt"
+	"time"
+)
+
+// Verbosity levels of SolveOptions.Verbosity, each one logging what the
+// lower ones do as well
+const (
+	VerbosityQuiet        = 0 // nothing
+	VerbosityRestarts     = 1 // every restart of the search from another solution
+	VerbosityImprovements = 2 // every new best solution
+	VerbositySamples      = 3 // the progress of the search, sampled every verbositySampleInterval
+)
+
+// verbositySampleInterval is the minimum time between two progress lines
+const verbositySampleInterval = time.Second
+
+// ValidateVerbosity checks a verbosity level
+func ValidateVerbosity(verbosity int) error {
+	if verbosity < VerbosityQuiet || verbosity > VerbositySamples {
+		return fmt.Errorf("invalid verbosity %d, expected %d to %d", verbosity, VerbosityQuiet, VerbositySamples)
+	}
+	return nil
+}
+
+// logf writes a line to the run's log when the verbosity is at least level
+func (t *runTracker) logf(level int, format string, args ...any) {
+	if t.log == nil || t.verbosity < level {
+		return
+	}
+	defer t.instrumented(time.Now())
+	t.log.Printf(format, args...)
+}
+
+// restarted counts a search continued from another solution, such as an
+// elite one or the start of a restart segment, and logs what happened
+func (t *runTracker) restarted(what string) {
+	liveRestarts.Add(1)
+	t.logf(VerbosityRestarts, "restart after %v: %s", time.Since(t.start).Round(time.Millisecond), what)
+}
+
+// logSample logs the progress of the run at most every verbositySampleInterval
+func (t *runTracker) logSample() {
+	if t.log == nil || t.verbosity < VerbositySamples || time.Since(t.lastLogged) < verbositySampleInterval {
+		return
+	}
+	t.lastLogged = time.Now()
+	line := fmt.Sprintf("%v: %d evaluations, %d moves", time.Since(t.start).Round(time.Millisecond), t.evaluations, t.moves)
+	if t.hasBest {
+		line += fmt.Sprintf(", best %d", t.instance.Objective(t.bestFitness))
+	}
+	if t.hasTemperature {
+		line += fmt.Sprintf(", temperature %.4g", t.currentTemperature)
+	}
+	if t.currentTenure > 0 {
+		line += fmt.Sprintf(", tenure %d", t.currentTenure)
+	}
+	t.logf(VerbositySamples, "%s", line)
+}
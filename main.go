@@ -1,13 +1,18 @@
 package main
 
 import (
+	"cmp"
 	"flag"
 	"os"
 	"path/filepath"
+	"qap_solver/internal/config"
 	"qap_solver/internal/experiment"
+	"qap_solver/internal/metrics"
 	"qap_solver/internal/qap"
 	"qap_solver/internal/solvers"
 	"qap_solver/pkg"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,56 +20,367 @@ import (
 var logger = pkg.NewLogger()
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "summarize" {
+		summary.Mode = "summarize"
+		runSummarize(os.Args[2:])
+		summary.exit()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "recommend" {
+		summary.Mode = "recommend"
+		runRecommend(os.Args[2:])
+		summary.exit()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "evaluate" {
+		summary.Mode = "evaluate"
+		runEvaluate(os.Args[2:])
+		summary.exit()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "neighbors" {
+		summary.Mode = "neighbors"
+		runNeighbors(os.Args[2:])
+		summary.exit()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "bundle" {
+		summary.Mode = "bundle"
+		runBundle(os.Args[2:])
+		summary.exit()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		summary.Mode = "replay"
+		runReplay(os.Args[2:])
+		summary.exit()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		summary.Mode = "generate"
+		runGenerate(os.Args[2:])
+		summary.exit()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "tune" {
+		summary.Mode = "tune"
+		runTune(os.Args[2:])
+		summary.exit()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "champion" {
+		summary.Mode = "champion"
+		runChampion(os.Args[2:])
+		summary.exit()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "calibrate" {
+		summary.Mode = "calibrate"
+		runCalibrate(os.Args[2:])
+		summary.exit()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "sessions" {
+		summary.Mode = "sessions"
+		runSessions(os.Args[2:])
+		summary.exit()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "new-solver" {
+		summary.Mode = "new-solver"
+		runNewSolver(os.Args[2:])
+		summary.exit()
+	}
+
+	// Defaults come from ~/.qap_solver.yaml and QAP_SOLVER_* variables
+	defaults, err := config.Load()
+	if err != nil {
+		fatalf("Invalid configuration: %v", err)
+	}
+
 	// Parse command line arguments
-	instanceDir := flag.String("instances", "instances", "Directory containing instance files")
-	outputDir := flag.String("output", "results", "Directory for output files")
-	solverConfigs := flag.String("solvers", "random:iterations=1000", "See README or baseline for more info. "+
+	instanceDir := flag.String("instances", defaults.InstancesDir, "Directory containing instance files")
+	outputDir := flag.String("output", defaults.OutputDir, "Directory for output files")
+	solverConfigs := flag.String("solvers", defaults.Solvers, "See README or -list for more info. "+
 		"Separate solvers by ; and arguments with ,. List arguments after :")
-	runsPerInstance := flag.Int("runs", 10, "Number of runs per solver per instance")
+	runsPerInstance := flag.Int("runs", defaults.RunsPerInstance, "Number of runs per solver per instance, the minimum with -adaptive-ci")
+	adaptiveCI := flag.Float64("adaptive-ci", 0, "Keep running each solver on an instance until the 95% CI of its final fitness is within ± this percent of the mean (0 = fixed -runs)")
+	maxRuns := flag.Int("max-runs", 100, "Cap on the runs per solver per instance with -adaptive-ci or -weighted-pilot")
+	weightedPilot := flag.Int("weighted-pilot", 0, "Make this many pilot runs per solver and instance, then share the rest of the -runs budget among the instances by the variation of their pilot results (0 = the same runs everywhere)")
+	parallelism := flag.Int("parallel", defaults.Parallelism, "Number of runs executed concurrently in experiment mode")
 	sample := flag.Int("sample", -1, "if positive, number of instances to include in the experiment")
+	recursive := flag.Bool("recursive", false, "Also search subdirectories of the instance directory")
+	include := flag.String("include", "", "Comma-separated glob patterns, only matching instances are used (e.g. \"tai*\")")
+	exclude := flag.String("exclude", "", "Comma-separated glob patterns of instances to skip (e.g. \"*esc*\")")
+	maxSize := flag.Int("max-size", 0, "Skip instances with more than this many facilities (0 = no limit)")
+	instancesList := flag.String("instances-list", "", "File naming the instances to use, one per line and in this order (e.g. the instances of a paper)")
+	dryRun := flag.Bool("dry-run", false, "Predict the duration of the experiment from the cost model of this host instead of running it (see calibrate)")
+	costModel := flag.String("cost-model", defaults.CostModel, "Cost model store used by -dry-run")
+	preflight := flag.Bool("preflight", true, "In experiment mode, check every instance before the runs (malformed matrices, negative entries, overflow risk, size) and ask before running when problems are found")
+	preflightMaxSize := flag.Int("preflight-max-size", 0, "Pre-flight: instances with more facilities count as a problem (0 = "+strconv.Itoa(experiment.DefaultPreflightMaxSize)+", negative = any size)")
+	yes := flag.Bool("yes", false, "Run the experiment without asking when the pre-flight check finds problems")
+	limit := flag.Int("limit", 0, "Use at most the first N instances after filtering (0 = all)")
 	experimentMode := flag.Bool("experiment", false, "Run in experiment mode (batch processing)")
+	strict := flag.Bool("strict", false, "Fail instead of silently degrading: ignored solver parameters, repeated solver configs, experiment-only flags in single-instance mode and, in experiment mode, solvers without metrics, invalid instances and instances without a .sln optimum are errors")
 	singleInstanceFile := flag.String("instance", "", "Path to a single instance file (ignored in experiment mode)")
 	listSolvers := flag.Bool("list", false, "List available solvers")
+	memoryLimitMB := flag.Int("mem-limit", 0, "Soft limit on the process heap in MB, checked by every run; runs finding it exceeded stop early (0 = no limit)")
+	currentLayoutFile := flag.String("current-layout", "", "File with the current facility locations, enables re-layout mode")
+	relocationCostsFile := flag.String("relocation-costs", "", "Matrix of location to location relocation costs (default: distance matrix)")
+	relocationWeight := flag.Float64("relocation-weight", 1.0, "Weight of the relocation cost in re-layout mode")
+	detectTriangular := flag.Bool("detect-triangular", true, "Accept instance matrices given as their upper triangle and mirror them (false = require full matrices)")
+	zeroDiagonal := flag.Bool("zero-diagonal", false, "Clear nonzero diagonals (self-flows and self-distances) of every instance, with a warning")
+	maximize := flag.Bool("maximize", false, "Maximize the QAP objective instead of minimizing it, e.g. for adjacency rewards")
+	facilityNamesFile := flag.String("facility-names", "", "File with one facility name per line, used in solution_pretty output and reports")
+	locationNamesFile := flag.String("location-names", "", "File with one location name per line, used in solution_pretty output and reports")
+	constraintsFile := flag.String("constraints", "", "File with facility grouping and forbidden-location constraints")
+	forbiddenStrategy := flag.String("forbidden-strategy", solvers.ForbiddenPenalty, "Handling of forbidden locations: penalty or hard")
+	flowStdFile := flag.String("flow-std", "", "Matrix of flow standard deviations, the instance flows are the means; solvers then optimize the -stochastic cost")
+	stochasticMode := flag.String("stochastic", qap.StochasticExpected, "Cost optimized with -flow-std: expected or percentile")
+	percentile := flag.Float64("percentile", 90, "Percentile of the scenario costs optimized with -stochastic=percentile")
+	flowScenarios := flag.Int("flow-scenarios", 100, "Number of Monte Carlo flow scenarios drawn per instance with -flow-std")
+	validate := flag.Bool("validate", true, "Recompute the fitness of every stored solution after an experiment and flag mismatches")
+	robustnessNoise := flag.Float64("robustness-noise", 0.1, "Relative flow noise of robustness scenarios (0.1 = ±10%)")
+	robustnessScenarios := flag.Int("robustness-scenarios", 0, "Evaluate every final solution on this many perturbed instances (0 = off)")
+	precision := flag.Int("precision", metrics.DefaultOutputOptions().Precision, "Decimal digits of fractional values in the output CSV files")
+	timeUnit := flag.String("time-unit", metrics.TimeUnitMilliseconds, "Unit of time columns: ms (integer milliseconds) or s (fractional seconds)")
+	columns := flag.String("columns", defaults.Columns, "Comma-separated columns of the results CSV in this order, e.g. \"Instance,Solver,FinalFitness,Time\" (default: all)")
+	summaryColumns := flag.String("summary-columns", defaults.SummaryColumns, "Comma-separated columns of the summary CSV in this order (default: all)")
+	derived := flag.String("derived", defaults.Derived, "Derived run metrics added as columns, e.g. \"EvalsPerSecond=Evaluations/Time;Improvement=(InitialFitness-FinalFitness)/InitialFitness\"")
+	seed := flag.Int64("seed", 0, "Base seed making runs reproducible (0 = random)")
+	commonRandomNumbers := flag.Bool("crn", false, "Common random numbers: run k of every solver uses the same seed, paired tests go to paired_*.csv")
+	tidy := flag.Bool("tidy", false, "Also write a long-format tidy_*.csv with one row per run and metric")
+	report := flag.Bool("report", false, "Also write report_*.html with boxplots of the final gaps per instance family")
+	controlFile := flag.String("control", "", "Control file polled during an experiment to change per-run time limits or abort a solver's remaining runs")
+	screening := flag.Duration("screening", 0, "Two-phase experiment: first screen every solver with runs of at most this long (e.g. 2s, 0 = off)")
+	screeningRuns := flag.Int("screening-runs", 1, "Number of screening runs per solver per instance")
+	screeningTopK := flag.Int("screening-top-k", 2, "Number of solvers per instance kept for the full runs after screening")
+	timeBudget := flag.Duration("time-budget", 0, "Total experiment time, the full runs share what screening leaves (0 = unlimited)")
+	racing := flag.Bool("racing", false, "Stop running a solver on an instance once a Hoeffding race shows another solver is better")
+	racingConfidence := flag.Float64("racing-confidence", experiment.DefaultRacingConfidence, "Confidence a solver must be dominated with before racing drops it")
+	stopAtOptimum := flag.Bool("stop-at-optimum", false, "Skip the remaining runs of a solver on an instance once one reached the known optimum of the .sln file")
+	racingMinRuns := flag.Int("racing-min-runs", experiment.DefaultRacingMinRuns, "Runs both solvers need before racing compares them")
+	trajectoryPoints := flag.Int("trajectory-points", metrics.DefaultTrajectoryPoints, "Maximum convergence trajectory points stored per run, longer trajectories are thinned (0 = keep all)")
+	debugAddr := flag.String("debug-addr", "", "Serve live solver counters with expvar at this address, e.g. localhost:6060 (/debug/vars)")
+	stopExpression := flag.String("stop", defaults.Stop, "Stop every run once the condition holds, e.g. \"evals>5e6 || gap<0.5 || time>120s\" (see README)")
+	streaming := flag.Bool("stream", false, "Process one instance at a time, releasing its memory before the next is loaded; writes memory_*.csv (for batches with large instances)")
+	diagnostics := flag.Bool("diagnostics", false, "Write tabu heatmaps and annealing temperature curves of every run as CSV and SVG to the diagnostics directory")
+	artifacts := flag.Bool("artifacts", false, "Let solvers dump algorithm-specific state of every run, e.g. the final tabu list, to the artifacts directory")
+	constructionCache := flag.String("construction-cache", "", "Reuse the solutions of deterministic constructions (clusterinit, faqinit) across experiments: output (the cache directory of the output), user (the user cache directory) or a directory; empty keeps none")
+	profilesPath := flag.String("profiles", defaults.Profiles, "Parameter profiles of solvers configured with params=auto, written by the tune subcommand")
+	anomalyZ := flag.Float64("anomaly-z", metrics.DefaultAnomalyZ, "List runs whose last improvement lies this many standard deviations before that of their sibling runs in anomalies_*.csv (negative = off)")
+	verbosity := flag.Int("verbosity", solvers.VerbosityQuiet, "How much solvers log of their progress: 0 nothing, 1 restarts, 2 every new best, 3 also a progress sample every second")
+	jobLogs := flag.Bool("job-logs", false, "Also write the log lines of every run to its own file in the logs directory")
+	ioRetries := flag.Int("io-retries", experiment.DefaultIORetries, "Retries of a result file write that fails with a transient I/O error, e.g. on a network file system, before results go to a local temporary directory (0 = none)")
+	ioBackoff := flag.Duration("io-backoff", experiment.DefaultIOBackoff, "Wait before the first retry of a failed result file write, doubled after every retry")
+	flushEvery := flag.Duration("flush-every", 0, "Rewrite the results and summary files with the runs finished so far at this interval, e.g. 1m (0 = only at the end)")
+	sessionName := flag.String("name", "", "Session name of the experiment, stored in session_*.json and shown in the report (see the sessions subcommand)")
+	sessionNote := flag.String("note", "", "Free-text note on the purpose of the experiment, stored with the session")
+	trace := flag.String("trace", "", "Write every accepted move of the named solver (e.g. TabuSearch, or all) to trace files in the output directory")
+	cpuProfile := flag.String("cpuprofile", "", "Write a pprof CPU profile of the run to this file")
+	memProfile := flag.String("memprofile", "", "Write a pprof heap profile to this file when the run ends")
+	execTrace := flag.String("exec-trace", "", "Write a Go execution trace of the run to this file (go tool trace)")
 	flag.Parse()
+	if *experimentMode {
+		summary.Mode = "experiment"
+	}
+	qap.DetectTriangular = *detectTriangular
+
+	profiling := pkg.Profiling{CPU: *cpuProfile, Memory: *memProfile, Trace: *execTrace}
+	stopProfiling, err := profiling.Start()
+	if err != nil {
+		fatalf("Cannot start profiling: %v", err)
+	}
+	summary.atExit = append(summary.atExit, func() {
+		if err := stopProfiling(); err != nil {
+			logger.Printf("Error writing profiles: %v", err)
+		}
+	})
+
+	outputOptions := metrics.OutputOptions{Precision: *precision, TimeUnit: *timeUnit, Tidy: *tidy, Report: *report,
+		Columns: metrics.ParseColumns(*columns), SummaryColumns: metrics.ParseColumns(*summaryColumns)}
+	if outputOptions.Derived, err = metrics.ParseDerivedMetrics(*derived); err != nil {
+		fatalf("Invalid derived metrics: %v", err)
+	}
+	if err := outputOptions.Validate(); err != nil {
+		fatalf("Invalid output options: %v", err)
+	}
+	if err := solvers.ValidateVerbosity(*verbosity); err != nil {
+		fatalf("%v", err)
+	}
+
+	if *debugAddr != "" {
+		if err := pkg.ServeDebug(*debugAddr, logger); err != nil {
+			fatalf("Cannot serve live counters: %v", err)
+		}
+	}
+
+	var stop *solvers.StopCondition
+	if *stopExpression != "" {
+		var err error
+		if stop, err = solvers.ParseStopCondition(*stopExpression); err != nil {
+			fatalf("%v", err)
+		}
+	}
 
 	// Create solver factory
 	factory := solvers.NewSolverFactory()
+	factory.SetStrict(*strict)
+	profiles, err := solvers.LoadProfiles(*profilesPath)
+	if err != nil {
+		fatalf("Invalid profiles: %v", err)
+	}
+	factory.SetProfiles(profiles)
+	restartPolicies := make(map[string]*solvers.RestartPolicy)
+	for solver, spec := range defaults.Restarts {
+		policy, err := solvers.ParseRestartPolicy(spec)
+		if err != nil {
+			fatalf("Invalid configuration: %s%s: %v", config.RestartPrefix, solver, err)
+		}
+		restartPolicies[solver] = policy
+	}
+	factory.SetRestartPolicies(restartPolicies)
+	if strings.Contains(strings.ToLower(*solverConfigs), solvers.ParamsAuto) && len(profiles.Entries) == 0 {
+		if *strict {
+			fatalf("Strict: no profiles in %s for params=auto (run tune first)", *profilesPath)
+		}
+		logger.Printf("Warning: no profiles in %s, params=auto uses the default parameters (run tune first)", *profilesPath)
+	}
 
 	// List available solvers if requested
 	if *listSolvers {
+		summary.Mode = "list"
 		for _, line := range factory.ListAvailable() {
 			logger.Println(line)
 		}
-		return
+		summary.exit()
 	}
 
 	// Parse solver configurations
 	solverList := strings.Split(*solverConfigs, ";")
 	solverInstances := make([]solvers.Solver, 0, len(solverList))
+	createdConfigs := make([]string, 0, len(solverList))
 
 	for _, config := range solverList {
 		solver, err := factory.Create(config)
 		if err != nil {
+			if *strict {
+				fatalf("Strict: invalid solver config '%s': %v", config, err)
+			}
 			logger.Printf("Error creating solver from config '%s': %v", config, err)
 			continue
 		}
 		solverInstances = append(solverInstances, solver)
+		createdConfigs = append(createdConfigs, config)
+	}
+
+	// A repeated configuration would double the runs of a solver in the summary
+	solverInstances, duplicates, err := solvers.DeduplicateSolvers(solverInstances)
+	if err != nil {
+		fatalf("Invalid solvers: %v", err)
+	}
+	var keptConfigs []string
+	for i := range createdConfigs {
+		if earlier, ok := duplicates[i]; ok {
+			if *strict {
+				fatalf("Strict: solver config '%s' repeats '%s'", createdConfigs[i], createdConfigs[earlier])
+			}
+			logger.Printf("Warning: ignoring solver config '%s', it repeats '%s'", createdConfigs[i], createdConfigs[earlier])
+		} else {
+			keptConfigs = append(keptConfigs, strings.TrimSpace(createdConfigs[i]))
+		}
 	}
 
 	if len(solverInstances) == 0 {
-		logger.Fatalf("No valid solvers specified")
+		fatalf("No valid solvers specified")
+	}
+
+	filter := experiment.InstanceFilter{
+		Recursive: *recursive,
+		Include:   splitPatterns(*include),
+		Exclude:   splitPatterns(*exclude),
+		MaxSize:   *maxSize,
+		Names:     readInstanceList(*instancesList),
+		Limit:     *limit,
+	}
+
+	// Load the current layout for re-layout mode
+	var currentLayout []int
+	var relocationCosts [][]int
+	if *currentLayoutFile != "" {
+		var err error
+		currentLayout, err = qap.ReadAssignment(*currentLayoutFile)
+		if err != nil {
+			fatalf("Failed to read current layout: %v", err)
+		}
+		if *relocationCostsFile != "" {
+			relocationCosts, err = qap.ReadMatrix(*relocationCostsFile)
+			if err != nil {
+				fatalf("Failed to read relocation costs: %v", err)
+			}
+		}
+	}
+
+	var names *qap.Names
+	if *facilityNamesFile != "" || *locationNamesFile != "" {
+		names = &qap.Names{}
+		var err error
+		if *facilityNamesFile != "" {
+			if names.Facilities, err = qap.ReadNames(*facilityNamesFile); err != nil {
+				fatalf("Failed to read facility names: %v", err)
+			}
+		}
+		if *locationNamesFile != "" {
+			if names.Locations, err = qap.ReadNames(*locationNamesFile); err != nil {
+				fatalf("Failed to read location names: %v", err)
+			}
+		}
+	}
+
+	var constraints *qap.Constraints
+	if *constraintsFile != "" {
+		var err error
+		constraints, err = qap.ReadConstraints(*constraintsFile)
+		if err != nil {
+			fatalf("Failed to read constraints: %v", err)
+		}
+	}
+	var flowUncertainty *qap.FlowUncertainty
+	if *flowStdFile != "" {
+		std, err := qap.ReadMatrix(*flowStdFile)
+		if err != nil {
+			fatalf("Failed to read flow deviations: %v", err)
+		}
+		flowUncertainty = &qap.FlowUncertainty{
+			Std:        std,
+			Mode:       strings.ToLower(*stochasticMode),
+			Percentile: *percentile,
+			Samples:    *flowScenarios,
+			Seed:       *seed,
+		}
+		if err := flowUncertainty.Validate(len(std)); err != nil {
+			fatalf("Invalid flow uncertainty: %v", err)
+		}
+	}
+	if err := solvers.ValidateForbiddenStrategy(*forbiddenStrategy); err != nil {
+		fatalf("%v", err)
+	}
+	if *adaptiveCI > 0 && *racing {
+		fatalf("-adaptive-ci and -racing cannot be combined, racing needs a fixed number of runs")
+	}
+	if *weightedPilot > 0 {
+		switch {
+		case *weightedPilot < 2:
+			fatalf("-weighted-pilot needs at least 2 runs to measure their variation")
+		case *weightedPilot >= *runsPerInstance:
+			fatalf("-weighted-pilot must be below -runs, the runs left after the pilot are shared out")
+		case *adaptiveCI > 0 || *racing || *timeBudget > 0:
+			fatalf("-weighted-pilot cannot be combined with -adaptive-ci, -racing or -time-budget, it shares a fixed number of runs")
+		}
 	}
 
 	// Run in experiment mode or single instance mode
 	if !*experimentMode {
 		// Run on a single instance
+		if ignored := setFlags(experimentFlags); *strict && len(ignored) > 0 {
+			fatalf("Strict: experiment-only flags in single-instance mode: %s", strings.Join(ignored, ", "))
+		}
 		instanceFile := *singleInstanceFile
 		if instanceFile == "" {
-			// Find first .dat file in instance directory
+			// Find first instance file in instance directory
 			entries, err := os.ReadDir(*instanceDir)
 			if err == nil {
 				for _, entry := range entries {
-					if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".dat") {
+					if !entry.IsDir() && qap.IsInstanceFile(entry.Name()) {
 						instanceFile = filepath.Join(*instanceDir, entry.Name())
 						break
 					}
@@ -72,7 +388,7 @@ func main() {
 			}
 
 			if instanceFile == "" {
-				logger.Fatalf("No instance file specified and none found in instance directory")
+				fatalf("No instance file specified and none found in instance directory")
 			}
 		}
 
@@ -80,44 +396,259 @@ func main() {
 		startTime := time.Now()
 		instance, err := qap.ReadInstance(instanceFile)
 		if err != nil {
-			logger.Fatalf("Failed to read instance: %v", err)
+			fatalf("Failed to read instance: %v", err)
 		}
 		pkg.TimeTrack(startTime, "Instance loading", logger)
 
 		logger.Printf("Loaded instance: %s (Size = %d)", instanceFile, instance.Size)
+		if instance.Facilities > 0 {
+			logger.Printf("Rectangular instance: %d facilities on %d locations, %d locations stay empty",
+				instance.Facilities, instance.Size, instance.Size-instance.Facilities)
+		}
+		instance.Maximize = *maximize
+		if d := instance.Diagonal(); d.Nonzero() {
+			logger.Printf("Warning: %s", experiment.DiagonalWarning(filepath.Base(instanceFile), d, *zeroDiagonal))
+			if *zeroDiagonal {
+				instance.ZeroDiagonals()
+			}
+		}
+
+		if currentLayout != nil {
+			if err := instance.SetRelocation(currentLayout, relocationCosts, *relocationWeight); err != nil {
+				fatalf("Invalid re-layout setup: %v", err)
+			}
+			logger.Printf("Re-layout mode: current layout costs %d", qap.QAPCost(instance, currentLayout))
+		}
+
+		if constraints != nil {
+			if err := constraints.Validate(instance.Size); err != nil {
+				fatalf("Invalid constraints: %v", err)
+			}
+			instance.Constraints = constraints
+		}
+
+		if flowUncertainty != nil {
+			if err := instance.SetFlowUncertainty(flowUncertainty); err != nil {
+				fatalf("Invalid flow uncertainty: %v", err)
+			}
+		}
 
 		// Run all solvers on the instance
-		bestOverallSolution := solvers.SolverResult{Fitness: -1}
+		var bestOverallSolution solvers.SolverResult
+		var bestSolver string
 
+		stem := metrics.InstanceFileStem(filepath.Base(instanceFile))
 		for _, solver := range solverInstances {
 			logger.Printf("Running solver: %s (%s)", solver.Name(), solver.Description())
 			startTime := time.Now()
-			result := solver.Solve(instance)
+			var result solvers.SolverResult
+			traceWriter := experiment.OpenTrace(*outputDir, *trace, solver, stem, 1, logger)
+			diag := experiment.OpenDiagnostics(*outputDir, *diagnostics, solver, stem, 1)
+			dumps := experiment.OpenArtifacts(*outputDir, *artifacts, solver, stem, 1)
+			hardForbidden := *forbiddenStrategy == solvers.ForbiddenHard && constraints != nil
+			if metricsSolver, ok := solver.(experiment.MetricsSolver); ok && (traceWriter != nil || diag != nil || dumps != nil || hardForbidden || stop != nil || *verbosity > 0 ||
+				*seed != 0 || *memoryLimitMB > 0) {
+				result = metricsSolver.SolveWithMetrics(instance, nil, filepath.Base(instanceFile), 1,
+					solvers.SolveOptions{Trace: traceWriter, Diagnostics: diag, Artifacts: dumps, Forbidden: *forbiddenStrategy, Stop: stop,
+						Log: logger, Verbosity: *verbosity, Seed: *seed, MemoryLimit: uint64(*memoryLimitMB) << 20})
+				if err := traceWriter.Close(); err != nil {
+					logger.Printf("Error writing trace: %v", err)
+				}
+				if err := diag.Close(); err != nil {
+					logger.Printf("Error writing diagnostics: %v", err)
+				}
+				if err := dumps.Close(); err != nil {
+					logger.Printf("Error writing artifacts: %v", err)
+				}
+			} else {
+				result = solver.Solve(instance)
+			}
 			pkg.TimeTrack(startTime, solver.Name()+" execution", logger)
 
-			logger.Printf("%s fitness: %d", solver.Name(), result.Fitness)
+			logger.Printf("%s fitness: %d", solver.Name(), instance.Objective(result.Fitness))
 
-			if bestOverallSolution.Fitness == -1 || result.Fitness < bestOverallSolution.Fitness {
+			if bestOverallSolution.Solution == nil || result.Fitness < bestOverallSolution.Fitness {
 				bestOverallSolution = result
+				bestSolver = solver.Name()
 				logger.Printf("New best solution found by %s", solver.Name())
 			}
 		}
 
-		logger.Printf("Best overall solution has fitness: %d", bestOverallSolution.Fitness)
+		logger.Printf("Best overall solution has fitness: %d", instance.Objective(bestOverallSolution.Fitness))
+		summary.BestFitness[filepath.Base(instanceFile)] = instance.Objective(bestOverallSolution.Fitness)
+		if instance.Relocation != nil {
+			logger.Printf("QAP cost: %d, relocation cost: %d",
+				qap.QAPCost(instance, bestOverallSolution.Solution),
+				qap.RelocationCost(instance, bestOverallSolution.Solution))
+		}
+		if instance.Constraints != nil {
+			logger.Printf("Constraint violation: %d",
+				instance.Constraints.Violation(instance, bestOverallSolution.Solution))
+		}
+		if instance.Stochastic != nil {
+			costs := instance.Stochastic.ScenarioCosts(instance, bestOverallSolution.Solution)
+			slices.Sort(costs)
+			logger.Printf("Cost with mean flows: %d, over %d scenarios: min %d, median %d, max %d",
+				qap.QAPCost(instance, bestOverallSolution.Solution), len(costs),
+				costs[0], costs[len(costs)/2], costs[len(costs)-1])
+		}
 		logger.Printf("Solution: %v", bestOverallSolution.Solution)
+
+		if names != nil {
+			if err := names.Validate(instance.FacilityCount(), instance.Size); err != nil {
+				logger.Printf("Ignoring names: %v", err)
+				names = nil
+			}
+			prettyPath := filepath.Join(*outputDir, "solution_pretty.txt")
+			if err := writePrettySolution(prettyPath, filepath.Base(instanceFile), bestSolver,
+				instance.Objective(bestOverallSolution.Fitness), bestOverallSolution.Solution, names); err != nil {
+				logger.Printf("Error writing %s: %v", prettyPath, err)
+			} else {
+				logger.Printf("Readable solution written to %s", prettyPath)
+			}
+		}
+	} else if *dryRun {
+		summary.Mode = "dry-run"
+		predictExperiment(*costModel, *instanceDir, filter, *sample, keptConfigs, solverInstances,
+			*runsPerInstance, *parallelism, *timeBudget)
 	} else {
 		// Run batch experiment on all instances
-		err := experiment.RunAll(experiment.ExperimentConfig{
-			InstancesDir:    *instanceDir,
-			InstanceSample:  *sample,
-			OutputDir:       *outputDir,
-			Solvers:         solverInstances,
-			RunsPerInstance: *runsPerInstance,
-			Logger:          logger,
+		cacheDir, err := experiment.ConstructionCacheDir(*constructionCache, *outputDir)
+		if err != nil {
+			fatalf("Invalid construction cache: %v", err)
+		}
+		confirm := confirmPreflight
+		if *yes {
+			confirm = nil
+		}
+		outcome, err := experiment.RunAll(experiment.ExperimentConfig{
+			InstancesDir:      *instanceDir,
+			InstanceSample:    *sample,
+			OutputDir:         *outputDir,
+			Solvers:           solverInstances,
+			RunsPerInstance:   *runsPerInstance,
+			Parallelism:       *parallelism,
+			MemoryLimit:       uint64(*memoryLimitMB) << 20,
+			Logger:            logger,
+			Output:            outputOptions,
+			Trace:             *trace,
+			Diagnostics:       *diagnostics,
+			Artifacts:         *artifacts,
+			ConstructionCache: cacheDir,
+			Strict:            *strict,
+			Preflight:         *preflight,
+			PreflightMaxSize:  *preflightMaxSize,
+			Confirm:           confirm,
+			AnomalyZ:          *anomalyZ,
+			Verbosity:         *verbosity,
+			JobLogs:           *jobLogs,
+			FlushInterval:     *flushEvery,
+			IORetries:         cmp.Or(*ioRetries, -1), // 0 means the default in the config
+			IOBackoff:         *ioBackoff,
+			Streaming:         *streaming,
+			Validate:          *validate,
+			ControlFile:       *controlFile,
+
+			TrajectoryPoints: *trajectoryPoints,
+			Stop:             stop,
+
+			ScreeningTimeLimit: *screening,
+			ScreeningRuns:      *screeningRuns,
+			ScreeningTopK:      *screeningTopK,
+			TimeBudget:         *timeBudget,
+
+			Racing:           *racing,
+			RacingConfidence: *racingConfidence,
+			RacingMinRuns:    *racingMinRuns,
+			StopAtOptimum:    *stopAtOptimum,
+
+			AdaptiveCI:    *adaptiveCI,
+			MaxRuns:       *maxRuns,
+			WeightedPilot: *weightedPilot,
+
+			Filter: filter,
+
+			RobustnessNoise:     *robustnessNoise,
+			RobustnessScenarios: *robustnessScenarios,
+
+			CurrentLayout:    currentLayout,
+			RelocationCosts:  relocationCosts,
+			RelocationWeight: *relocationWeight,
+
+			Constraints:       constraints,
+			ForbiddenStrategy: *forbiddenStrategy,
+			FlowUncertainty:   flowUncertainty,
+			ZeroDiagonals:     *zeroDiagonal,
+			Maximize:          *maximize,
+			Names:             names,
+
+			Seed:                *seed,
+			CommonRandomNumbers: *commonRandomNumbers,
+
+			Session: metrics.Session{Name: *sessionName, Note: *sessionNote, Args: os.Args[1:]},
 		})
 
 		if err != nil {
-			logger.Fatalf("Experiment failed: %v", err)
+			fatalf("Experiment failed: %v", err)
+		}
+		summary.BestFitness, summary.JobsFailed = outcome.BestFitness, outcome.JobsFailed
+	}
+	summary.exit()
+}
+
+// experimentFlags are the flags only experiment mode uses
+var experimentFlags = []string{
+	"runs", "adaptive-ci", "max-runs", "weighted-pilot", "parallel", "sample", "recursive", "include", "exclude",
+	"max-size", "instances-list", "limit", "preflight", "preflight-max-size", "yes", "validate",
+	"robustness-noise", "robustness-scenarios", "precision", "time-unit", "columns", "summary-columns", "derived",
+	"crn", "tidy", "report", "control", "screening", "screening-runs", "screening-top-k", "time-budget",
+	"racing", "racing-confidence", "racing-min-runs", "stop-at-optimum", "trajectory-points", "stream",
+	"construction-cache", "anomaly-z", "job-logs", "io-retries", "io-backoff", "flush-every", "name", "note",
+}
+
+// setFlags lists the flags among names given on the command line
+func setFlags(names []string) []string {
+	var set []string
+	flag.Visit(func(f *flag.Flag) {
+		if slices.Contains(names, f.Name) {
+			set = append(set, "-"+f.Name)
 		}
+	})
+	return set
+}
+
+// splitPatterns splits a comma-separated list of glob patterns, dropping empty entries
+func splitPatterns(list string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(list, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// readInstanceList reads the -instances-list file, nil when none is given
+func readInstanceList(path string) []string {
+	if path == "" {
+		return nil
+	}
+	names, err := experiment.ReadInstanceList(path)
+	if err != nil {
+		fatalf("Error reading instance list: %v", err)
+	}
+	return names
+}
+
+// writePrettySolution writes a solution with facility and location names to path
+func writePrettySolution(path, instanceName, solverName string, objective int, solution []int, names *qap.Names) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	defer file.Close()
+	return metrics.WritePrettySolution(file, instanceName, solverName, objective, solution, names)
 }
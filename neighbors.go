@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/experiment"
+	"qap_solver/internal/qap"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// runNeighbors implements the "neighbors" subcommand: it enumerates every
+// swap neighbor of a solution with its change of the objective, checks the
+// delta evaluations against full evaluations and writes the neighborhood as
+// CSV.
+func runNeighbors(args []string) {
+	fs := flag.NewFlagSet("neighbors", flag.ExitOnError)
+	instanceFile := fs.String("instance", "", "Instance file")
+	solutionFile := fs.String("solution", "", "Solution file: .sln, JSON array or object, or space-separated values (default: a random solution)")
+	seed := fs.Int64("seed", 0, "Seed of the random solution used without -solution (0 = random)")
+	output := fs.String("output", "neighbors.csv", "CSV file the neighbors are written to")
+	maximize := fs.Bool("maximize", false, "Maximize the QAP objective instead of minimizing it")
+	detectTriangular := fs.Bool("detect-triangular", true, "Accept instance matrices given as their upper triangle")
+	zeroDiagonal := fs.Bool("zero-diagonal", false, "Clear nonzero diagonals of the instance first, as the solver flag does")
+	fs.Usage = func() {
+		logger.Printf("Usage: %s neighbors -instance X.dat [-solution sol.txt] [flags]", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *instanceFile == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	qap.DetectTriangular = *detectTriangular
+	instance, err := qap.ReadInstance(*instanceFile)
+	if err != nil {
+		fatalf("Error loading instance: %v", err)
+	}
+	instance.Maximize = *maximize
+	if d := instance.Diagonal(); d.Nonzero() {
+		logger.Printf("Warning: %s", experiment.DiagonalWarning(filepath.Base(*instanceFile), d, *zeroDiagonal))
+		if *zeroDiagonal {
+			instance.ZeroDiagonals()
+		}
+	}
+
+	var solution []int
+	if *solutionFile != "" {
+		if solution, err = qap.ReadSolution(*solutionFile, instance.Size); err != nil {
+			fatalf("Invalid solution: %v", err)
+		}
+	} else {
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+		solution = rand.New(rand.NewSource(*seed)).Perm(instance.Size)
+		logger.Printf("Random solution with seed %d", *seed)
+	}
+
+	// Deltas are written as changes of the objective, so a negative delta
+	// improves a minimized instance and a positive one a maximized instance
+	sense := instance.Sense()
+	cost := qap.QAPCost(instance, solution)
+	neighbors := qap.SwapNeighborhood(instance, solution)
+	order := make([]int, len(neighbors))
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(a, b int) bool { return neighbors[order[a]].Delta < neighbors[order[b]].Delta })
+	rank := make([]int, len(neighbors))
+	for r, k := range order {
+		rank[k] = r + 1
+	}
+
+	rows := [][]string{{"I", "J", "LocationI", "LocationJ", "Delta", "CachedDelta", "FullDelta", "NeighborFitness", "Improving", "Rank", "Consistent"}}
+	improving, inconsistent := 0, 0
+	for k, nb := range neighbors {
+		if nb.Delta < 0 {
+			improving++
+		}
+		if !nb.Consistent() {
+			inconsistent++
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(nb.I), strconv.Itoa(nb.J),
+			strconv.Itoa(solution[nb.I]), strconv.Itoa(solution[nb.J]),
+			strconv.Itoa(sense * nb.Delta), strconv.Itoa(sense * nb.CachedDelta), strconv.Itoa(sense * nb.FullDelta),
+			strconv.Itoa(cost + sense*nb.FullDelta),
+			strconv.FormatBool(nb.Delta < 0), strconv.Itoa(rank[k]), strconv.FormatBool(nb.Consistent()),
+		})
+	}
+	if err := writeNeighborsCSV(*output, rows); err != nil {
+		fatalf("Error writing %s: %v", *output, err)
+	}
+
+	logger.Printf("Instance: %s (n=%d)", filepath.Base(*instanceFile), instance.Size)
+	logger.Printf("Solution: %v", solution)
+	logger.Printf("Fitness: %d", cost)
+	summary.BestFitness[filepath.Base(*instanceFile)] = cost
+	if len(neighbors) > 0 {
+		best, worst := neighbors[order[0]], neighbors[order[len(order)-1]]
+		logger.Printf("Neighbors: %d swaps, %d improving, best swap (%d, %d) changes the fitness by %d, worst (%d, %d) by %d",
+			len(neighbors), improving, best.I, best.J, sense*best.Delta, worst.I, worst.J, sense*worst.Delta)
+	}
+	if improving == 0 {
+		logger.Printf("The solution is a local optimum of the swap neighborhood")
+	}
+	logger.Printf("Neighborhood written to %s", *output)
+	if inconsistent > 0 {
+		fatalf("Delta check: %d of %d swaps disagree with a full evaluation, see the Consistent column", inconsistent, len(neighbors))
+	}
+	logger.Printf("Delta check: SwapDelta and the delta cache agree with full evaluations on all %d swaps", len(neighbors))
+}
+
+func writeNeighborsCSV(path string, rows [][]string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	writer.WriteAll(rows)
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
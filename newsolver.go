@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// runNewSolver implements the "new-solver" subcommand: it writes a skeleton
+// solver and test file to the solvers package and prints the factory
+// registration snippet, so new algorithms follow the existing layout.
+func runNewSolver(args []string) {
+	fs := flag.NewFlagSet("new-solver", flag.ExitOnError)
+	name := fs.String("name", "", "Solver name used on the command line, e.g. hillclimb")
+	dir := fs.String("dir", filepath.Join("internal", "solvers"), "Directory of the solvers package")
+	force := fs.Bool("force", false, "Overwrite existing files")
+	fs.Usage = func() {
+		logger.Printf("Usage: %s new-solver -name=<name> [flags]", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	key := strings.ToLower(*name)
+	if key == "" || strings.IndexFunc(key, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' }) >= 0 {
+		fatalf("Solver name must be a non-empty identifier of letters, digits and _, got %q", *name)
+	}
+	data := scaffoldData{Key: key, Type: exportedName(key)}
+
+	files := map[string]*template.Template{
+		filepath.Join(*dir, key+".go"):      solverTemplate,
+		filepath.Join(*dir, key+"_test.go"): solverTestTemplate,
+	}
+	for path, tmpl := range files {
+		if _, err := os.Stat(path); err == nil && !*force {
+			fatalf("%s already exists, use -force to overwrite", path)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			fatalf("Error generating %s: %v", path, err)
+		}
+		source, err := format.Source(buf.Bytes())
+		if err != nil {
+			fatalf("Error formatting %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, source, 0644); err != nil {
+			fatalf("Error writing %s: %v", path, err)
+		}
+		logger.Printf("Created %s", path)
+	}
+
+	var snippet bytes.Buffer
+	registrationTemplate.Execute(&snippet, data)
+	logger.Printf("Finish the registration in solver_factory.go:\n%s", snippet.String())
+}
+
+type scaffoldData struct {
+	Key  string // lower-case name used in -solvers
+	Type string // Go type prefix, the struct is Type+"Solver"
+}
+
+// exportedName turns hill_climb or hillclimb into HillClimb or Hillclimb
+func exportedName(key string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(key, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+var solverTemplate = template.Must(template.New("solver").Parse(`package solvers
+
+import (
+	"fmt"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"time"
+)
+
+type {{.Type}}Solver struct {
+	MaxIterations int
+}
+
+// New{{.Type}}Solver creates a new {{.Key}} solver
+func New{{.Type}}Solver(maxIterations int) *{{.Type}}Solver {
+	return &{{.Type}}Solver{MaxIterations: maxIterations}
+}
+
+func (s *{{.Type}}Solver) Name() string {
+	return "{{.Type}}"
+}
+
+func (s *{{.Type}}Solver) Description() string {
+	return fmt.Sprintf("{{.Type}} (%d iterations)", s.MaxIterations)
+}
+
+// Capabilities tells the experiment runner which options the solver honors,
+// keep it in sync with what SolveWithMetrics does
+func (s *{{.Type}}Solver) Capabilities() Capabilities {
+	return Capabilities{SupportsMetrics: true, SupportsCancellation: true, SupportsTimeBudget: true}
+}
+
+func (s *{{.Type}}Solver) Solve(instance *qap.QAPInstance) SolverResult {
+	return s.SolveWithMetrics(instance, nil, "", 0, SolveOptions{})
+}
+
+func (s *{{.Type}}Solver) SolveWithMetrics(
+	instance *qap.QAPInstance,
+	metricsCollector *metrics.MetricsCollector,
+	instanceName string,
+	runNumber int,
+	opts SolveOptions,
+) SolverResult {
+	startTime := time.Now()
+	tracker := newRunTracker(instance, opts)
+
+	current := RandomSolution(instance.Size)
+	tracker.repair(current)
+	currentFitness := qap.CalculateFitness(instance, current)
+	initialFitness := currentFitness
+	tracker.improved(0, initialFitness)
+
+	totalSteps := 0
+	totalEvaluations := 1
+
+	for iter := 0; iter < s.MaxIterations && !tracker.shouldStop(); iter++ {
+		// TODO: explore the neighborhood of current. Skip swaps for which
+		// tracker.allows returns false, evaluate the rest with qap.SwapDelta,
+		// report accepted swaps with tracker.move and new best fitness values
+		// with tracker.improved.
+		totalSteps++
+	}
+
+	elapsedTime := time.Since(startTime)
+
+	tracker.record(metricsCollector, metrics.RunMetrics{
+		InstanceName:     instanceName,
+		SolverName:       s.Name(),
+		Run:              runNumber,
+		InitialFitness:   initialFitness,
+		FinalFitness:     currentFitness,
+		TimeElapsed:      elapsedTime,
+		StepsCount:       totalSteps,
+		EvaluationsCount: totalEvaluations,
+		SolutionsChecked: totalEvaluations,
+		Solution:         current,
+	})
+
+	return SolverResult{
+		Solution: current,
+		Fitness:  currentFitness,
+	}
+}
+`))
+
+var solverTestTemplate = template.Must(template.New("test").Parse(`package solvers
+
+import (
+	"qap_solver/internal/qap"
+	"testing"
+)
+
+func Test{{.Type}}SolverReturnsValidSolution(t *testing.T) {
+	instance := &qap.QAPInstance{
+		Size:           3,
+		FlowMatrix:     [][]int{{"{{"}}0, 1, 2}, {1, 0, 3}, {2, 3, 0{{"}}"}},
+		DistanceMatrix: [][]int{{"{{"}}0, 2, 1}, {2, 0, 4}, {1, 4, 0{{"}}"}},
+	}
+
+	result := New{{.Type}}Solver(100).Solve(instance)
+	if err := qap.ValidatePermutation(result.Solution); err != nil {
+		t.Fatalf("invalid solution %v: %v", result.Solution, err)
+	}
+	if fitness := qap.CalculateFitness(instance, result.Solution); fitness != result.Fitness {
+		t.Fatalf("reported fitness %d, recomputed %d", result.Fitness, fitness)
+	}
+}
+`))
+
+var registrationTemplate = template.Must(template.New("registration").Parse(`
+	// in NewSolverFactory
+	factory.Register("{{.Key}}", factory.create{{.Type}}Solver)
+
+	// in ListAvailable
+	result = append(result, "  {{.Key}}:maxIter=10000 - {{.Type}} search")
+
+	// with the other creator functions
+	func (f *SolverFactory) create{{.Type}}Solver(args []string) (Solver, error) {
+		maxIterations := 10000
+
+		for _, arg := range args {
+			parts := strings.SplitN(arg, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			key := strings.ToLower(parts[0])
+			value := parts[1]
+			switch key {
+			case "maxiter":
+				if v, err := strconv.Atoi(value); err == nil && v > 0 {
+					maxIterations = v
+				}
+			}
+		}
+		return New{{.Type}}Solver(maxIterations), nil
+	}
+`))
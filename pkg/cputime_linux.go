@@ -0,0 +1,20 @@
+package pkg
+
+import (
+	"syscall"
+	"time"
+)
+
+// rusageThread is RUSAGE_THREAD, which the syscall package does not export
+const rusageThread = 1
+
+// ThreadCPUTime returns the user+system CPU time consumed by the calling OS
+// thread. Callers must lock the goroutine to its thread (runtime.LockOSThread)
+// for the difference of two readings to be meaningful.
+func ThreadCPUTime() (time.Duration, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(rusageThread, &usage); err != nil {
+		return 0, false
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package pkg
+
+import "time"
+
+// ThreadCPUTime is only supported on Linux. Elsewhere it reports false and
+// CPU time columns stay at zero.
+func ThreadCPUTime() (time.Duration, bool) {
+	return 0, false
+}
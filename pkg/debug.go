@@ -0,0 +1,25 @@
+package pkg
+
+import (
+	_ "expvar" // registers /debug/vars
+	"log"
+	"net"
+	"net/http"
+)
+
+// ServeDebug serves the expvar variables at http://addr/debug/vars in the
+// background. It returns once the address is bound, so a port that is in use
+// is reported immediately.
+func ServeDebug(addr string, logger *log.Logger) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	logger.Printf("Serving live counters at http://%s/debug/vars", listener.Addr())
+	go func() {
+		if err := http.Serve(listener, nil); err != nil {
+			logger.Printf("Debug server stopped: %v", err)
+		}
+	}()
+	return nil
+}
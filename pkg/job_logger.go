@@ -0,0 +1,45 @@
+package pkg
+
+import (
+	"io"
+	"log"
+	"sync"
+)
+
+// LockedWriter serializes the writes to W, so the lines of several loggers
+// sharing it never interleave, whatever W is
+type LockedWriter struct {
+	mu sync.Mutex
+	W  io.Writer
+}
+
+func (l *LockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.W.Write(p)
+}
+
+// prefixWriter writes every line of a logger to w behind prefix, in one write
+type prefixWriter struct {
+	prefix string
+	w      io.Writer
+}
+
+func (p prefixWriter) Write(line []byte) (int, error) {
+	if _, err := p.w.Write(append([]byte(p.prefix), line...)); err != nil {
+		return 0, err
+	}
+	return len(line), nil
+}
+
+// NewJobLogger returns a logger for one job of a parallel run. Its lines go to
+// the destination of parent, tagged with the job ID after the timestamp, e.g.
+// "[QAP Solver] 2024/01/02 15:04:05 [nug12.dat TabuSearch run 1] started",
+// and to file as well when it is not nil.
+func NewJobLogger(parent *log.Logger, job string, file io.Writer) *log.Logger {
+	var w io.Writer = prefixWriter{parent.Prefix(), parent.Writer()}
+	if file != nil {
+		w = io.MultiWriter(w, file)
+	}
+	return log.New(w, "["+job+"] ", parent.Flags()|log.Lmsgprefix)
+}
@@ -6,5 +6,6 @@ import (
 )
 
 func NewLogger() *log.Logger {
-    return log.New(os.Stdout, "[QAP Solver] ", log.LstdFlags)
+    // Job loggers of parallel runs share the locked writer, see NewJobLogger
+    return log.New(&LockedWriter{W: os.Stdout}, "[QAP Solver] ", log.LstdFlags)
 }
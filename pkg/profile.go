@@ -0,0 +1,79 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// Profiling holds the output files of the pprof profiles and the execution
+// trace of a run, empty names are not written
+type Profiling struct {
+	CPU    string
+	Memory string
+	Trace  string
+}
+
+// Start starts the CPU profile and the execution trace. The returned stop
+// function ends them and writes the heap profile; it must be called before
+// the program exits, or the files stay incomplete.
+func (p Profiling) Start() (stop func() error, err error) {
+	var cpuFile, traceFile *os.File
+	stop = func() error {
+		var errs []error
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			errs = append(errs, cpuFile.Close())
+		}
+		if traceFile != nil {
+			trace.Stop()
+			errs = append(errs, traceFile.Close())
+		}
+		if p.Memory != "" {
+			errs = append(errs, writeHeapProfile(p.Memory))
+		}
+		return errors.Join(errs...)
+	}
+
+	if p.CPU != "" {
+		if cpuFile, err = os.Create(p.CPU); err != nil {
+			return nil, fmt.Errorf("CPU profile: %v", err)
+		}
+		if err := pprof.StartCPUProfile(cpuFile); err != nil {
+			cpuFile.Close()
+			return nil, fmt.Errorf("CPU profile: %v", err)
+		}
+	}
+	if p.Trace != "" {
+		if traceFile, err = os.Create(p.Trace); err != nil {
+			p.Memory = ""
+			stop()
+			return nil, fmt.Errorf("execution trace: %v", err)
+		}
+		if err := trace.Start(traceFile); err != nil {
+			traceFile.Close()
+			traceFile, p.Memory = nil, ""
+			stop()
+			return nil, fmt.Errorf("execution trace: %v", err)
+		}
+	}
+	return stop, nil
+}
+
+// writeHeapProfile writes the heap profile after a garbage collection, so it
+// shows the live memory at the end of the run
+func writeHeapProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("memory profile: %v", err)
+	}
+	defer file.Close()
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(file); err != nil {
+		return fmt.Errorf("memory profile: %v", err)
+	}
+	return nil
+}
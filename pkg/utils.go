@@ -13,15 +13,29 @@ func RandomIntPair(min, max int) (int, int) {
 		panic("Range too small to generate two different numbers")
 	}
 
-	first := RandomInt(min, max)
-	second := first
+	first, second := RandomDistinctPair(nil, max-min+1)
+	return min + first, min + second
+}
 
-	// Faster than modulo for larger instances.
-	// The infinite loop is inplausible
-	for second == first {
-		second = RandomInt(min, max)
+// RandomDistinctPair returns two different indices in [0, n), uniformly over
+// all ordered pairs. The second index is drawn from the n-1 values left after
+// the first, so no rejection loop or modulo arithmetic is needed.
+// A nil rng uses the global math/rand source.
+func RandomDistinctPair(rng *rand.Rand, n int) (int, int) {
+	if n < 2 {
+		panic("Range too small to generate two different numbers")
 	}
 
+	intn := rand.Intn
+	if rng != nil {
+		intn = rng.Intn
+	}
+
+	first := intn(n)
+	second := intn(n - 1)
+	if second >= first {
+		second++
+	}
 	return first, second
 }
 
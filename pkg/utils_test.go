@@ -0,0 +1,79 @@
+package pkg
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestRandomDistinctPairRange(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{2, 3, 10, 257} {
+		for k := 0; k < 10000; k++ {
+			i, j := RandomDistinctPair(rng, n)
+			if i == j || i < 0 || i >= n || j < 0 || j >= n {
+				t.Fatalf("n=%d: got (%d, %d)", n, i, j)
+			}
+		}
+	}
+}
+
+// TestRandomDistinctPairUniform checks that all n(n-1) ordered pairs appear
+// about equally often, with a chi-square test at the 0.999 level
+func TestRandomDistinctPairUniform(t *testing.T) {
+	// 0.999 quantiles of the chi-square distribution with n(n-1)-1 degrees of freedom
+	critical := map[int]float64{2: 10.83, 3: 20.52, 5: 43.82}
+	for n, bound := range critical {
+		t.Run(fmt.Sprintf("n=%d", n), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			pairs := n * (n - 1)
+			draws := 2000 * pairs
+			counts := make(map[[2]int]int)
+			for k := 0; k < draws; k++ {
+				i, j := RandomDistinctPair(rng, n)
+				counts[[2]int{i, j}]++
+			}
+			if len(counts) != pairs {
+				t.Fatalf("%d distinct pairs drawn, want %d", len(counts), pairs)
+			}
+			expected := float64(draws) / float64(pairs)
+			statistic := 0.0
+			for _, c := range counts {
+				statistic += (float64(c) - expected) * (float64(c) - expected) / expected
+			}
+			if statistic > bound {
+				t.Fatalf("chi-square %.2f exceeds %.2f: counts %v", statistic, bound, counts)
+			}
+		})
+	}
+}
+
+func TestRandomDistinctPairNilRng(t *testing.T) {
+	for k := 0; k < 1000; k++ {
+		if i, j := RandomDistinctPair(nil, 2); i == j {
+			t.Fatalf("got (%d, %d)", i, j)
+		}
+	}
+}
+
+func TestRandomDistinctPairTooSmall(t *testing.T) {
+	for _, n := range []int{-1, 0, 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("n=%d: no panic", n)
+				}
+			}()
+			RandomDistinctPair(rand.New(rand.NewSource(1)), n)
+		}()
+	}
+}
+
+func TestRandomIntPair(t *testing.T) {
+	for k := 0; k < 1000; k++ {
+		i, j := RandomIntPair(5, 7)
+		if i == j || i < 5 || i > 7 || j < 5 || j > 7 {
+			t.Fatalf("got (%d, %d), want distinct values in [5, 7]", i, j)
+		}
+	}
+}
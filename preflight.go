@@ -0,0 +1,23 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"qap_solver/internal/experiment"
+	"strings"
+)
+
+// confirmPreflight asks on the terminal whether to run an experiment whose
+// pre-flight check found problems. Anything but yes, including a closed
+// standard input, aborts.
+func confirmPreflight(problems []experiment.PreflightProblem) bool {
+	fmt.Fprintf(os.Stderr, "The pre-flight check found %d problems, see above. Run the experiment anyway? [y/N] ", len(problems))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	logger.Printf("Pre-flight: aborted, fix the instances or pass -yes to run anyway")
+	return false
+}
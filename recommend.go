@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/experiment"
+	"qap_solver/internal/qap"
+)
+
+// runRecommend implements the "recommend" subcommand: it estimates the
+// difficulty of every instance and suggests a per-run time budget and solvers.
+func runRecommend(args []string) {
+	fs := flag.NewFlagSet("recommend", flag.ExitOnError)
+	instanceDir := fs.String("instances", "instances", "Directory containing instance files")
+	singleInstance := fs.String("instance", "", "Single instance file, overrides -instances")
+	outputDir := fs.String("output", "results", "Directory for the recommendations file")
+	probes := fs.Int("probes", 5, "Number of probe runs per instance")
+	maximize := fs.Bool("maximize", false, "Maximize the QAP objective instead of minimizing it")
+	recursive := fs.Bool("recursive", false, "Also search subdirectories of the instance directory")
+	include := fs.String("include", "", "Comma-separated glob patterns, only matching instances are used")
+	exclude := fs.String("exclude", "", "Comma-separated glob patterns of instances to skip")
+	maxSize := fs.Int("max-size", 0, "Skip instances with more than this many facilities (0 = no limit)")
+	instancesList := fs.String("instances-list", "", "File naming the instances to use, one per line and in this order")
+	limit := fs.Int("limit", 0, "Use at most the first N instances after filtering (0 = all)")
+	fs.Usage = func() {
+		logger.Printf("Usage: %s recommend [flags]", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	files := []string{*singleInstance}
+	if *singleInstance == "" {
+		var err error
+		files, err = experiment.FindInstanceFiles(*instanceDir, experiment.InstanceFilter{
+			Recursive: *recursive,
+			Include:   splitPatterns(*include),
+			Exclude:   splitPatterns(*exclude),
+			MaxSize:   *maxSize,
+			Names:     readInstanceList(*instancesList),
+			Limit:     *limit,
+		})
+		if err != nil {
+			fatalf("Error finding instance files: %v", err)
+		}
+	}
+
+	var recommendations []experiment.Recommendation
+	for _, file := range files {
+		instance, err := qap.ReadInstance(file)
+		if err != nil {
+			logger.Printf("Error loading instance %s: %v", file, err)
+			summary.JobsFailed++
+			continue
+		}
+		instance.Maximize = *maximize
+
+		name := filepath.Base(file)
+		if *singleInstance == "" {
+			name = experiment.InstanceName(*instanceDir, file)
+		}
+		r := experiment.Recommend(instance, name, *probes)
+		logger.Printf("%s (n=%d): %s, difficulty %.1f, flow dominance %.1f, probe spread %.2f%%",
+			r.InstanceName, r.Features.Size, r.Class, r.Difficulty, r.Features.FlowDominance, r.ProbeSpread)
+		logger.Printf("    budget %v per run, -solvers=%q", r.Budget, r.Solvers)
+		recommendations = append(recommendations, r)
+	}
+
+	path, err := experiment.SaveRecommendationsCSV(*outputDir, recommendations)
+	if err != nil {
+		fatalf("Error saving recommendations: %v", err)
+	}
+	logger.Printf("Recommendations saved to %s", path)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/config"
+	"qap_solver/internal/metrics"
+	"time"
+)
+
+// runSessions implements the "sessions" subcommand: it lists the experiment
+// sessions found in result directories with their names, run counts and notes.
+func runSessions(args []string) {
+	defaults, err := config.Load()
+	if err != nil {
+		fatalf("Invalid configuration: %v", err)
+	}
+
+	fs := flag.NewFlagSet("sessions", flag.ExitOnError)
+	fs.Usage = func() {
+		logger.Printf("Usage: %s sessions [directory ...] (default: %s)", filepath.Base(os.Args[0]), defaults.OutputDir)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		dirs = []string{defaults.OutputDir}
+	}
+	found := 0
+	for _, dir := range dirs {
+		entries, errs := metrics.FindSessions(dir)
+		for _, err := range errs {
+			logger.Printf("Warning: %v", err)
+		}
+		for _, entry := range entries {
+			session := entry.Session
+			status := "interrupted or running"
+			if session.Finished != nil {
+				status = "took " + session.Finished.Sub(session.Started).Round(time.Second).String()
+			}
+			logger.Printf("%s  %-20s %6d runs, %s  %s", session.Started.Format("2006-01-02 15:04"),
+				session.Title("(unnamed)"), session.Runs, status, filepath.Dir(entry.Path))
+			if session.Note != "" {
+				logger.Printf("    %s", session.Note)
+			}
+		}
+		found += len(entries)
+	}
+	logger.Printf("%d sessions", found)
+}
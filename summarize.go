@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"strings"
+)
+
+// runSummarize implements the "summarize" subcommand: it rebuilds the summary
+// files from existing results CSV files without running any solver.
+func runSummarize(args []string) {
+	fs := flag.NewFlagSet("summarize", flag.ExitOnError)
+	instanceDir := fs.String("instances", "instances", "Directory with .sln files used as reference optima")
+	outputDir := fs.String("output", "", "Directory for the rebuilt files (default: directory of the first results file)")
+	precision := fs.Int("precision", metrics.DefaultOutputOptions().Precision, "Decimal digits of fractional values")
+	timeUnit := fs.String("time-unit", metrics.TimeUnitMilliseconds, "Unit of time columns: ms or s")
+	tidy := fs.Bool("tidy", false, "Also write a long-format tidy_*.csv with one row per run and metric")
+	maximize := fs.Bool("maximize", false, "The results are from maximization instances, see -maximize of the solver")
+	report := fs.Bool("report", false, "Also write report_*.html with boxplots of the final gaps per instance family")
+	summaryColumns := fs.String("summary-columns", "", "Comma-separated columns of the summary CSV (default: all)")
+	derived := fs.String("derived", "", "Derived run metrics added as columns, e.g. \"EvalsPerSecond=Evaluations/Time\"")
+	fs.Usage = func() {
+		logger.Printf("Usage: %s summarize [flags] results.csv [more.csv ...]", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if *outputDir == "" {
+		*outputDir = filepath.Dir(files[0])
+	}
+	collector := metrics.NewMetricsCollector(*outputDir)
+	collector.Output = metrics.OutputOptions{Precision: *precision, TimeUnit: *timeUnit, Tidy: *tidy, Report: *report,
+		SummaryColumns: metrics.ParseColumns(*summaryColumns)}
+	var err error
+	if collector.Output.Derived, err = metrics.ParseDerivedMetrics(*derived); err != nil {
+		fatalf("Invalid derived metrics: %v", err)
+	}
+	if err := collector.Output.Validate(); err != nil {
+		fatalf("Invalid output options: %v", err)
+	}
+
+	// Name the rebuilt files after the input, results_X.csv gives summary_X.csv,
+	// and head the report with the session of the input
+	if len(files) == 1 {
+		base := strings.TrimSuffix(filepath.Base(files[0]), ".csv")
+		if stamp, ok := strings.CutPrefix(base, "results_"); ok {
+			collector.Timestamp = stamp
+		}
+		if collector.Session, err = metrics.SessionOf(files[0]); err != nil {
+			logger.Printf("Could not load session: %v", err)
+		}
+	}
+
+	optima, err := qap.LoadOptimalSolutions(*instanceDir)
+	if err != nil {
+		logger.Printf("Could not load optimal solutions: %v", err)
+	}
+	collector.Optima = optima
+	collector.Maximize = *maximize
+
+	total, duplicates := 0, 0
+	for _, file := range files {
+		runs, err := metrics.LoadRunsCSV(file, *maximize)
+		if err != nil {
+			fatalf("Failed to load runs: %v", err)
+		}
+		for _, run := range runs {
+			// Overlapping files, e.g. a resumed experiment and its earlier
+			// part, repeat runs; the first file wins
+			if collector.HasRun(run.InstanceName, run.SolverName, run.Run) {
+				duplicates++
+				continue
+			}
+			collector.AddRunMetrics(run)
+			objective := run.FinalFitness
+			if *maximize {
+				objective = -objective
+			}
+			if best, ok := summary.BestFitness[run.InstanceName]; !ok || (objective < best) != *maximize {
+				summary.BestFitness[run.InstanceName] = objective
+			}
+		}
+		total += len(runs)
+	}
+	logger.Printf("Loaded %d runs from %d files", total-duplicates, len(files))
+	if duplicates > 0 {
+		logger.Printf("Warning: skipped %d runs whose instance, solver and run number repeat an earlier run", duplicates)
+	}
+
+	if err := collector.SaveSummaryCSV(); err != nil {
+		fatalf("Error saving summary: %v", err)
+	}
+	if err := collector.SaveRobustnessCSV(); err != nil {
+		fatalf("Error saving robustness results: %v", err)
+	}
+	if len(collector.PairedComparisons()) > 0 {
+		if err := collector.SavePairedCSV(); err != nil {
+			fatalf("Error saving paired comparisons: %v", err)
+		}
+	}
+	if collector.Output.Tidy {
+		if err := collector.SaveTidyCSV(); err != nil {
+			fatalf("Error saving tidy results: %v", err)
+		}
+	}
+
+	if collector.Output.Report {
+		if err := collector.SaveHTMLReport(); err != nil {
+			fatalf("Error saving report: %v", err)
+		}
+	}
+	logger.Printf("Summary saved to %s", *outputDir)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"qap_solver/internal/config"
+	"qap_solver/internal/experiment"
+	"qap_solver/internal/metrics"
+	"qap_solver/internal/qap"
+	"qap_solver/internal/solvers"
+	"strconv"
+	"strings"
+)
+
+// runTune implements the "tune" subcommand: it runs every parameter
+// combination of a grid on the instances and stores the best combination
+// per instance family and size range as profiles, used with params=auto.
+func runTune(args []string) {
+	defaults, err := config.Load()
+	if err != nil {
+		fatalf("Invalid configuration: %v", err)
+	}
+
+	fs := flag.NewFlagSet("tune", flag.ExitOnError)
+	instanceDir := fs.String("instances", defaults.InstancesDir, "Directory containing instance files")
+	outputDir := fs.String("output", defaults.OutputDir, "Directory for the results of the tuning runs")
+	profilesPath := fs.String("profiles", defaults.Profiles, "Profile store updated with the learned parameters")
+	solverType := fs.String("solver", "simanneal", "Solver type to tune")
+	grid := fs.String("grid", "", "Parameter grid, values separated by |, e.g. \"alpha=0.95|0.99,p=5|10\"")
+	sizes := fs.String("size-ranges", "", "Comma-separated upper bounds of the size ranges learned separately, e.g. \"30,60\" (default: one range)")
+	runs := fs.Int("runs", 3, "Runs of every candidate per instance")
+	parallelism := fs.Int("parallel", defaults.Parallelism, "Number of runs executed concurrently")
+	seed := fs.Int64("seed", 0, "Base seed making the tuning runs reproducible (0 = random)")
+	recursive := fs.Bool("recursive", false, "Also search subdirectories of the instance directory")
+	include := fs.String("include", "", "Comma-separated glob patterns, only matching instances are used")
+	exclude := fs.String("exclude", "", "Comma-separated glob patterns of instances to skip")
+	maxSize := fs.Int("max-size", 0, "Skip instances with more than this many facilities (0 = no limit)")
+	instancesList := fs.String("instances-list", "", "File naming the instances to use, one per line and in this order")
+	limit := fs.Int("limit", 0, "Use at most the first N instances after filtering (0 = all)")
+	fs.Usage = func() {
+		logger.Printf("Usage: %s tune -solver simanneal -grid \"alpha=0.95|0.99,p=5|10\" [flags]", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	candidates, err := experiment.TuneCandidates(*grid)
+	if err != nil {
+		fatalf("Invalid grid: %v", err)
+	}
+	if len(candidates) < 2 {
+		fatalf("The grid must list at least two parameter combinations")
+	}
+	var bounds []int
+	for _, field := range splitPatterns(*sizes) {
+		bound, err := strconv.Atoi(field)
+		if err != nil || bound < 1 {
+			fatalf("Invalid size bound %q", field)
+		}
+		bounds = append(bounds, bound)
+	}
+
+	// Candidates are labeled with their parameters to tell their runs apart
+	factory := solvers.NewSolverFactory()
+	byName := make(map[string]string)
+	var solverList []solvers.Solver
+	for _, params := range candidates {
+		label := *solverType + ":" + params
+		solver, err := factory.Create(label + "@label=" + label)
+		if err != nil {
+			fatalf("Invalid candidate %s: %v", label, err)
+		}
+		solverList = append(solverList, solver)
+		byName[label] = params
+	}
+	logger.Printf("Tuning %s with %d candidates", *solverType, len(candidates))
+
+	// A fresh directory keeps the results of earlier tuning runs apart
+	if err := os.MkdirAll(*outputDir, 0755); err != nil {
+		fatalf("Cannot create %s: %v", *outputDir, err)
+	}
+	runDir, err := os.MkdirTemp(*outputDir, "tune_")
+	if err != nil {
+		fatalf("Cannot create tuning directory: %v", err)
+	}
+	outcome, err := experiment.RunAll(experiment.ExperimentConfig{
+		InstancesDir:    *instanceDir,
+		OutputDir:       runDir,
+		Solvers:         solverList,
+		RunsPerInstance: *runs,
+		Parallelism:     *parallelism,
+		Logger:          logger,
+		Validate:        true,
+		Seed:            *seed,
+		Filter: experiment.InstanceFilter{
+			Recursive: *recursive,
+			Include:   splitPatterns(*include),
+			Exclude:   splitPatterns(*exclude),
+			MaxSize:   *maxSize,
+			Names:     readInstanceList(*instancesList),
+			Limit:     *limit,
+		},
+	})
+	if err != nil {
+		fatalf("Tuning runs failed: %v", err)
+	}
+	summary.BestFitness, summary.JobsFailed = outcome.BestFitness, outcome.JobsFailed
+
+	// Gaps refer to the .sln optima, or the best run of the candidates
+	optima, err := qap.LoadOptimalSolutions(*instanceDir)
+	if err != nil {
+		logger.Printf("Could not load optimal solutions: %v", err)
+	}
+	collector := tuneCollector(loadExperimentRuns(runDir), optima)
+
+	profiles, err := solvers.LoadProfiles(*profilesPath)
+	if err != nil {
+		fatalf("Error reading profiles: %v", err)
+	}
+	for _, profile := range experiment.LearnProfiles(collector, strings.ToLower(*solverType), byName, experiment.SizeRanges(bounds)) {
+		profiles.Set(profile)
+		sizeRange := strconv.Itoa(profile.MinSize) + "+"
+		if profile.MaxSize > 0 {
+			sizeRange = strconv.Itoa(profile.MinSize) + "-" + strconv.Itoa(profile.MaxSize)
+		}
+		logger.Printf("  %-8s n=%-7s %s (mean gap %.2f%% over %d runs)", profile.Family, sizeRange, profile.Params, profile.MeanGap, profile.Runs)
+	}
+	if err := profiles.Save(*profilesPath); err != nil {
+		fatalf("Error saving profiles: %v", err)
+	}
+	logger.Printf("Profiles saved to %s, use them with -solvers \"%s:params=auto\"", *profilesPath, strings.ToLower(*solverType))
+}
+
+// tuneCollector wraps the runs of a results file for LearnProfiles
+func tuneCollector(runs []metrics.RunMetrics, optima qap.OptimalSolutions) *metrics.MetricsCollector {
+	collector := &metrics.MetricsCollector{Experiments: make(map[string]map[string]*metrics.ExperimentMetrics), Optima: optima}
+	for _, run := range runs {
+		collector.AddRunMetrics(run)
+	}
+	return collector
+}
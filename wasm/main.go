@@ -0,0 +1,34 @@
+//go:build js && wasm
+
+// Command wasm exposes the solvers to JavaScript. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o qap_solver.wasm ./wasm
+//
+// and load it with wasm_exec.js from the Go distribution. It defines the
+// global functions solveInstance(json) and listSolvers(), see jsapi.
+package main
+
+import (
+	"qap_solver/internal/jsapi"
+	"qap_solver/internal/solvers"
+	"strings"
+	"syscall/js"
+)
+
+func main() {
+	// solveInstance takes a jsapi.Request as JSON string and returns a
+	// jsapi.Result as JSON string. It runs synchronously, so long runs
+	// belong in a web worker or need time_limit_ms.
+	js.Global().Set("solveInstance", js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) != 1 || args[0].Type() != js.TypeString {
+			return string(jsapi.SolveJSON(nil))
+		}
+		return string(jsapi.SolveJSON([]byte(args[0].String())))
+	}))
+	js.Global().Set("listSolvers", js.FuncOf(func(this js.Value, args []js.Value) any {
+		return strings.Join(solvers.NewSolverFactory().ListAvailable(), "\n")
+	}))
+
+	// Keep the functions alive for the lifetime of the page
+	select {}
+}